@@ -54,8 +54,10 @@ var kubeVipCmd = &cobra.Command{
 
 func init() {
 	// Basic flags
-	kubeVipCmd.PersistentFlags().StringVar(&initConfig.Interface, "interface", "", "Name of the interface to bind to")
-	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ServicesInterface, "serviceInterface", "", "Name of the interface to bind to (for services)")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.Interface, "interface", "", "Name of the interface to bind to, a comma separated list will announce the VIP on each interface (ARP only)")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ServicesInterface, "serviceInterface", "", "Name of the interface to bind to (for services), a comma separated list will announce on each interface (ARP only)")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ManagedInterfaceType, "managedInterfaceType", "", "Create a [dummy|macvlan] interface for the VIPs instead of adding them to the interface")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ManagedInterfaceName, "managedInterfaceName", vip.DefaultManagedInterface, "Name of the managed interface")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.VIP, "vip", "", "The Virtual IP address")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.VIPSubnet, "vipSubnet", "", "The Virtual IP address subnet e.g. /32 /24 /8 etc..")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.NodeName, "nodeName", "", "Name to be used for lease holder. Must be unique for each node/instance")
@@ -65,6 +67,14 @@ func init() {
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.Address, "address", "", "an address (IP or DNS name) to use as a VIP")
 	kubeVipCmd.PersistentFlags().IntVar(&initConfig.Port, "port", 6443, "Port for the VIP")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableARP, "arp", false, "Enable Arp for VIP changes")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.MonitorCarrier, "monitorCarrier", false, "Relinquish leadership of the VIPs whilst their interface has no carrier, and rejoin the election when it returns")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.MonitorNodeHealth, "monitorNodeHealth", false, "Relinquish leadership of the VIPs whilst the node is NotReady or under disk, memory or PID pressure, and rejoin the election when it is healthy")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.ManageARPSysctls, "manageArpSysctls", false, "Set arp_ignore, arp_announce and proxy_ndp on the VIP interfaces to avoid ARP flux, the original values are restored when stopping")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.ProxyARP, "proxyArp", false, "Answer ARP requests for the VIP without adding it to the interface (e.g. for DSR, where the real servers own the VIP)")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ArpBurstCount, "arpBurstCount", 0, "The number of gratuitous ARPs that are sent in a burst when a VIP is first announced")
+	kubeVipCmd.PersistentFlags().Int64Var(&initConfig.ArpBurstInterval, "arpBurstInterval", 0, "The time in milliseconds between the gratuitous ARPs of a burst (defaults to 200)")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.NdpAdvertisementCount, "ndpAdvertisementCount", 0, "The number of unsolicited neighbor advertisements that are sent in a burst when an IPv6 VIP is first announced (defaults to 3)")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.AnnouncementRateLimit, "announcementRateLimit", 0, "The maximum number of gratuitous ARPs and neighbor advertisements per second across all VIPs, announcements above it are dropped (0 is unlimited)")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableWireguard, "wireguard", false, "Enable Wireguard for services VIPs")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableRoutingTable, "table", false, "Enable Routing Table for services VIPs")
 
@@ -77,11 +87,16 @@ func init() {
 
 	// Clustering type (leaderElection)
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableLeaderElection, "leaderElection", false, "Use the Kubernetes leader election mechanism for clustering")
-	kubeVipCmd.PersistentFlags().StringVar(&initConfig.LeaderElectionType, "leaderElectionType", "kubernetes", "Defines the backend to run the leader election: kubernetes or etcd. Defaults to kubernetes.")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.LeaderElectionType, "leaderElectionType", "kubernetes", "Defines the backend to run the leader election: kubernetes, etcd, vrrp or raft. Defaults to kubernetes.")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.LeaseName, "leaseName", "plndr-cp-lock", "Name of the lease that is used for leader election")
 	kubeVipCmd.PersistentFlags().IntVar(&initConfig.LeaseDuration, "leaseDuration", 5, "Length of time (in seconds) a Kubernetes leader lease can be held for")
 	kubeVipCmd.PersistentFlags().IntVar(&initConfig.RenewDeadline, "leaseRenewDuration", 3, "Length of time (in seconds) a Kubernetes leader can attempt to renew its lease")
 	kubeVipCmd.PersistentFlags().IntVar(&initConfig.RetryPeriod, "leaseRetry", 1, "Length of time (in seconds) the LeaderElector clients should wait between tries of actions")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableLeaderPriority, "leaderPriority", false, "Nodes with a higher priority (kube-vip.io/priority label) win the Kubernetes elections, the others only take over as a fallback")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.LeaderPreemption, "leaderPreemption", false, "A node with a higher priority takes over the lease from a leader with a lower priority")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.LeaderFailbackDelay, "leaderFailbackDelay", 0, "Length of time (in seconds) a leader holds the lease before a node with a higher priority can take it back")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ElectionExcludeSelector, "electionExcludeSelector", "", "Label selector of the nodes that never take part in the elections, e.g. node-role.kubernetes.io/storage")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ElectionExcludeTaints, "electionExcludeTaints", "", "Comma separated taint keys, the nodes with one of these taints never take part in the elections")
 
 	// Equinix Metal flags
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableMetal, "metal", false, "This will use the Equinix Metal API (requires the token ENV) to update the EIP <-> VIP")
@@ -92,17 +107,52 @@ func init() {
 
 	// BGP flags
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableBGP, "bgp", false, "This will enable BGP support within kube-vip")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPConfig.Backend, "bgpBackend", "", "The routing daemon that advertises the VIPs, either gobgp (embedded, the default) or frr (running on the node)")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPConfig.VtyshPath, "bgpVtyshPath", "", "The path of vtysh that is used to program FRR (defaults to vtysh in the PATH)")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPConfig.RouterID, "bgpRouterID", "", "The routerID for the bgp server")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPConfig.SourceIF, "sourceIF", "", "The source interface for bgp peering (not to be used with sourceIP)")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPConfig.SourceIP, "sourceIP", "", "The source address for bgp peering (not to be used with sourceIF)")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPConfig.SourceAuto, "sourceAuto", false, "The source address for bgp peering is taken from the route towards each peer (not to be used with sourceIP or sourceIF)")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPConfig.GRPCAddress, "bgpGRPCAddress", "", "Expose the gobgp API for use with the gobgp CLI, on a loopback address (127.0.0.1:50051) or unix socket (unix:///path)")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPConfig.IPv4NextHop, "bgpIPv4NextHop", "", "The next-hop for IPv4 addresses advertised over bgp (required for IPv4 addresses over IPv6 peering)")
+	kubeVipCmd.PersistentFlags().StringSliceVar(&initConfig.BGPConfig.Aggregates, "bgpAggregates", []string{}, "Comma separated prefixes that are advertised over bgp whilst any VIP within them is advertised")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPConfig.SuppressAggregated, "bgpSuppressAggregated", false, "This will stop VIPs that are covered by an aggregate from being advertised over bgp")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.MaxPrefixes, "bgpMaxPrefixes", 0, "The maximum number of prefixes that are advertised over bgp, additional VIPs aren't advertised (0 is unlimited)")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.MEDRamp.Start, "bgpMEDRampStart", 0, "The MED that new VIPs are advertised with before it is lowered to their own MED (0 disables ramping)")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.MEDRamp.Steps, "bgpMEDRampSteps", 0, "The number of steps that the MED of new VIPs is lowered in (defaults to 5)")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.MEDRamp.Interval, "bgpMEDRampInterval", 0, "The time in seconds between each step of the MED ramp (defaults to 10)")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPConfig.IPv6NextHop, "bgpIPv6NextHop", "", "The next-hop for IPv6 addresses advertised over bgp (required for IPv6 addresses over IPv4 peering)")
 	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.AS, "localAS", 65000, "The local AS number for the bgp server")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPConfig.AutoAS, "bgpASAuto", false, "Assign a private 4-byte AS derived from the routerID (or node address) in place of localAS")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.Confederation.Identifier, "bgpConfederationID", 0, "The AS of the bgp confederation that localAS is a member of")
+	kubeVipCmd.PersistentFlags().StringSliceVar(&initConfig.BGPConfederationMembers, "bgpConfederationMembers", []string{}, "Comma separated AS of the other members of the bgp confederation")
 	kubeVipCmd.PersistentFlags().Uint64Var(&initConfig.BGPConfig.HoldTime, "bgpHoldTimer", 30, "The hold timer for all bgp peers (it defines the time a session is held)")
 	kubeVipCmd.PersistentFlags().Uint64Var(&initConfig.BGPConfig.KeepaliveInterval, "bgpKeepAliveInterval", 10, "The keepalive interval for all bgp peers (it defines the heartbeat of keepalive messages)")
+	kubeVipCmd.PersistentFlags().StringSliceVar(&initConfig.BGPPeerTimers, "bgpPeerTimers", []string{}, "Comma separated timers for individual bgp peers, format: peer=holdtime:keepalive (these override bgpHoldTimer and bgpKeepAliveInterval)")
+	kubeVipCmd.PersistentFlags().StringSliceVar(&initConfig.BGPPeerGroups, "bgpPeerGroups", []string{}, "Comma separated groups of bgp peers that services can be advertised to (kube-vip.io/bgp-peers), format: group=peer|peer")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPConfig.GracefulRestart.Enabled, "bgpGracefulRestart", false, "This will advertise the graceful restart capability to all bgp peers")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.GracefulRestart.RestartTime, "bgpGracefulRestartTime", 120, "The time (in seconds) that bgp peers should retain routes whilst kube-vip restarts")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPConfig.GracefulRestart.LongLived, "bgpLongLivedGracefulRestart", false, "This will advertise the long-lived graceful restart capability to all bgp peers")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.GracefulRestart.LongLivedRestartTime, "bgpLongLivedGracefulRestartTime", 3600, "The time (in seconds) that bgp peers should retain stale routes once the graceful restart time has expired")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPConfig.AddPath.Receive, "bgpAddPathReceive", false, "This will negotiate receiving multiple paths for a prefix from bgp peers (ADD-PATH)")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.AddPath.SendMax, "bgpAddPathSendMax", 0, "The maximum number of paths for a prefix that are sent to bgp peers (ADD-PATH), 0 disables sending")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPPeerConfig.Address, "peerAddress", "", "The address of a BGP peer")
 	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPPeerConfig.AS, "peerAS", 65000, "The AS number for a BGP peer")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPPeerConfig.Password, "peerPass", "", "The md5 password for a BGP peer")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPPeerConfig.PasswordSecret, "peerPassSecret", "", "A Secret (namespace/name/key) holding the md5 password for BGP peers")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPPeerConfig.MultiHop, "multihop", false, "This will enable BGP multihop support")
-	kubeVipCmd.PersistentFlags().StringSliceVar(&initConfig.BGPPeers, "bgppeers", []string{}, "Comma separated BGP Peer, format: address:as:password:multihop")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPPeerConfig.NextHop, "peerNextHop", "", "The next-hop of VIPs advertised to a BGP peer, either an address or self (the local address of the session)")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPPeerConfig.BFD.Enabled, "bfd", false, "This will enable BFD for detecting failed BGP peers")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPPeerConfig.BFD.Interval, "bfdInterval", 300, "The BFD transmit and receive interval in milliseconds")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPPeerConfig.BFD.Multiplier, "bfdMultiplier", 3, "The number of missed BFD packets before a BGP peer is declared down")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPPeersFromNode, "bgpPeersFromNode", false, "This will discover bgp peers from the kube-vip.io/bgp-peers annotation on the node")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.BGPPeersConfigMap, "bgpPeersConfigMap", "", "This will read the bgp peers from the bgp-peers key of a configmap, changes are applied without a restart")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPNodeCondition, "bgpNodeCondition", false, "This will publish the health of the bgp sessions as the KubeVipBGPPeersEstablished condition on the node")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.BGPDrainOnCordon, "bgpDrainOnCordon", false, "This will withdraw the bgp advertisements whilst the node is cordoned or draining")
+	kubeVipCmd.PersistentFlags().Uint32Var(&initConfig.BGPConfig.DrainPrepend, "bgpDrainPrepend", 0, "The number of times the AS is prepended whilst the node is draining, instead of withdrawing the bgp advertisements")
+	kubeVipCmd.PersistentFlags().StringSliceVar(&initConfig.BGPPeers, "bgppeers", []string{}, "Comma separated BGP Peer, format: address:as:password:multihop:bfd:nexthop (an interface name in place of the address will use unnumbered peering)")
+	kubeVipCmd.PersistentFlags().StringSliceVar(&initConfig.BGPDynamicNeighbors, "bgpDynamicNeighbors", []string{}, "Comma separated prefixes that inbound bgp sessions are accepted from, format: prefix:as:password")
+	kubeVipCmd.PersistentFlags().StringSliceVar(&initConfig.BGPExportPolicies, "bgpExportPolicies", []string{}, "Comma separated VIPs that are advertised to a bgp peer, format: peer=prefix|community (peers without a policy are sent every VIP)")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.Annotations, "annotations", "", "Set Node annotations prefix for parsing")
 
 	// Namespace for kube-vip
@@ -117,7 +167,12 @@ func init() {
 	// Routing Table flags
 	kubeVipCmd.PersistentFlags().IntVar(&initConfig.RoutingTableID, "tableID", 198, "The routing table used for all table entries")
 	kubeVipCmd.PersistentFlags().IntVar(&initConfig.RoutingTableType, "tableType", 0, "The type of route that will be added to the routing table")
-	kubeVipCmd.PersistentFlags().IntVar(&initConfig.RoutingProtocol, "routingProtocol", 248, "The routing protocol value used to create routes")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.RoutingProtocol, "routingProtocol", 248, "The routing protocol value used to create routes, it marks the routes that are owned (and garbage collected) by kube-vip")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.RoutingMetric, "routingMetric", 0, "The metric (priority) of the routes, a lower metric is preferred over the routes of other agents")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.RoutingTableECMP, "routingTableECMP", false, "Install the routes with the ready local endpoints as next-hops, so that the kernel balances the traffic across them")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.RoutingBlackholeGracePeriod, "routingBlackholeGracePeriod", 0, "The seconds that a blackhole route is installed for once a VIP is withdrawn, to prevent routing loops whilst upstream converges (0 disables it)")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.RoutingSource, "routingSource", "", "The source address of the routes (an IPv4 and/or IPv6 address, comma separated) used for traffic from the node to the VIPs")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.RoutingVRF, "routingVRF", "", "The VRF that the routes are installed in (instead of tableID), managed interfaces are also added to it")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.CleanRoutingTable, "cleanRoutingTable", false, "Clean routing table of redundant routes on start")
 
 	// Behaviour flags
@@ -127,15 +182,37 @@ func init() {
 
 	// Extended behaviour flags
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableServicesElection, "servicesElection", false, "Enable leader election per kubernetes service")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.ServicesAvoidUnschedulable, "servicesAvoidUnschedulable", false, "Relinquish the services elections whilst the node is cordoned or tainted for draining, and rejoin them when it is schedulable")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ServicesWorkers, "servicesWorkers", 1, "The number of services that are processed in parallel, so that a slow service (e.g. DHCP) doesn't hold up the others")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ServicesLabelSelector, "servicesLabelSelector", "", "Only watch the services that match this label selector, so that the services can be split between multiple kube-vip deployments")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ServicesIgnoreLabelSelector, "servicesIgnoreLabelSelector", "", "Ignore the services that match this label selector, as with the kube-vip.io/ignore annotation")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ServicesIgnoreAnnotations, "servicesIgnoreAnnotations", "", "Ignore the services with one of these annotations, a comma separated list of keys or key=value pairs e.g. metallb.universe.tf/address-pool")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ServicesIgnoreNamespaces, "servicesIgnoreNamespaces", "", "Ignore the services in these namespaces, a comma separated list")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableServicesExternalIPs, "servicesExternalIPs", false, "Also announce the addresses in the spec.externalIPs of the LoadBalancer services")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableServicesFinalizer, "servicesFinalizer", false, "Add a finalizer to the services, so that they aren't removed until they've been cleaned up (kube-vip must be running for them to be deleted)")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ServicesDriftInterval, "servicesDriftInterval", 0, "How often (in seconds) the addresses, routes and BGP paths of the services are checked and repaired if they've been removed, 0 disables the checks")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ServicesMinReadyEndpoints, "servicesMinReadyEndpoints", 0, "The number of ready endpoints (on the node for the Local traffic policy, across the cluster otherwise) that a service requires before it is announced")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ServicesElectionPool, "servicesElectionPool", "", "Elect a leader per pool of services instead of per service, as the prefix length of the pool e.g. 24 or 24,64 (IPv4,IPv6)")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ServicesElectionShards, "servicesElectionShards", 0, "Elect a leader per shard of services instead of per service, with this many shards (and leases), unless servicesElectionPool is set")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ServicesLeaseDuration, "servicesLeaseDuration", 0, "Length of time (in seconds) the lease of a services election can be held for (defaults to leaseDuration)")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ServicesRenewDeadline, "servicesLeaseRenewDuration", 0, "Length of time (in seconds) the leader of a services election can attempt to renew its lease (defaults to leaseRenewDuration)")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ServicesRetryPeriod, "servicesLeaseRetry", 0, "Length of time (in seconds) between the tries of a services election (defaults to leaseRetry)")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableServicesSpread, "servicesSpread", false, "Bias the services elections so that the services are spread evenly across the nodes")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ServicesRebalanceInterval, "servicesRebalanceInterval", 300, "Length of time (in seconds) between rebalancing the spread services across the nodes, 0 disables rebalancing")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.ServicesRebalanceMaxMoves, "servicesRebalanceMaxMoves", 1, "Maximum number of services that a node moves to other nodes at every rebalance")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.ServicesRebalanceOnJoin, "servicesRebalanceOnJoin", false, "Rebalance the spread services when a node joins, handing it a share of the services")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableServicesTopology, "servicesTopology", false, "Prefer the nodes with ready local endpoints, then the nodes in the zone of most endpoints, in the services elections")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.LoadBalancerClassOnly, "lbClassOnly", false, "Enable load balancing only for services with LoadBalancerClass \"kube-vip.io/kube-vip-class\"")
-	kubeVipCmd.PersistentFlags().StringVar(&initConfig.LoadBalancerClassName, "lbClassName", "kube-vip.io/kube-vip-class", "Name of load balancer class for kube-VIP, defaults to \"kube-vip.io/kube-vip-class\"")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.LoadBalancerClassName, "lbClassName", kubevip.DefaultLoadBalancerClass, "Name of load balancer class for kube-VIP, or a comma separated list of names, defaults to \"kube-vip.io/kube-vip-class\"")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.LoadBalancerClassLegacyHandling, "lbClassNameLegacyHandling", true, "Use legacy LoadBalancer class name handling (e.g. accepting services both with empty and non-empty class)")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.LoadBalancerClassAdoptUnclassed, "lbClassAdoptUnclassed", false, "Also load balance the services without a LoadBalancer class when the legacy handling is disabled")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableServiceSecurity, "onlyAllowTrafficServicePorts", false, "Only allow traffic to service ports, others will be dropped, defaults to false")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableNodeLabeling, "enableNodeLabeling", false, "Enable leader node labeling with \"kube-vip.io/has-ip=<VIP address>\", defaults to false")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.ServicesLeaseName, "servicesLeaseName", "plndr-svcs-lock", "Name of the lease that is used for leader election for services (in arp mode)")
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.DNSMode, "dnsMode", "first", "Name of the mode that DNS lookup will be performed (first, ipv4, ipv6, dual)")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.DisableServiceUpdates, "disableServiceUpdates", false, "If true, kube-vip will process services as usual, but will not update service's Status.LoadBalancer.Ingress slice")
 	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.EnableEndpointSlices, "enableEndpointSlices", false, "If enabled, kube-vip will only advertise services, but will use EndpointSlices instead of endpoints to get IPs of Pods")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.EndpointsProvider, "endpointsProvider", "", "The provider of the endpoints of the services (auto, endpointslices, endpoints or hybrid), defaults to auto unless EndpointSlices are enabled")
 
 	// Prometheus HTTP Server
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.PrometheusHTTPServer, "prometheusHTTPServer", ":2112", "Host and port used to expose Prometheus metrics via an HTTP server")
@@ -146,6 +223,18 @@ func init() {
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.Etcd.ClientKeyFile, "etcdKey", "", "Identify secure client using this TLS key file")
 	kubeVipCmd.PersistentFlags().StringSliceVar(&initConfig.Etcd.Endpoints, "etcdEndpoints", nil, "Etcd member endpoints")
 
+	// VRRP
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.VRRP.VirtualRouterID, "vrrpVirtualRouterID", 0, "The VRRP virtual router ID (1-255), this must match the other routers of the group")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.VRRP.Priority, "vrrpPriority", 100, "The VRRP priority of this node (1-255), the highest priority becomes the master")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.VRRP.AdvertisementInterval, "vrrpAdvertisementInterval", 1000, "Time in milliseconds between VRRP advertisements")
+	kubeVipCmd.PersistentFlags().BoolVar(&initConfig.VRRP.Preempt, "vrrpPreempt", true, "Allow a node with a higher VRRP priority to take over from the current master")
+
+	// Raft
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.Raft.Members, "raftMembers", "", "The members of the Raft election as name=host:port (comma separated), including this node by its node name")
+	kubeVipCmd.PersistentFlags().StringVar(&initConfig.Raft.Key, "raftKey", "", "Shared key that authenticates the Raft messages between the members (the clocks of the members must agree within 10s)")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.Raft.HeartbeatInterval, "raftHeartbeatInterval", 500, "Time in milliseconds between the heartbeats of the Raft leader")
+	kubeVipCmd.PersistentFlags().IntVar(&initConfig.Raft.ElectionTimeout, "raftElectionTimeout", 3000, "Time in milliseconds without a heartbeat before a Raft member starts an election")
+
 	// Kubernetes client specific flags
 
 	kubeVipCmd.PersistentFlags().StringVar(&initConfig.K8sConfigFile, "k8sConfigPath", "/etc/kubernetes/admin.conf", "Path to the configuration file used with the Kubernetes client")
@@ -236,6 +325,7 @@ var kubeVipManager = &cobra.Command{
 
 		// Welome messages
 		log.Infof("Starting kube-vip.io [%s]", Release.Version)
+		initConfig.Version = Release.Version
 		log.Debugf("Build kube-vip.io [%s]", Release.Build)
 
 		// start prometheus server
@@ -0,0 +1,92 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	api "github.com/osrg/gobgp/v3/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// aggregate is a prefix that is advertised whilst any of the hosts within it are advertised
+type aggregate struct {
+	prefix *net.IPNet
+	hosts  map[string]bool
+}
+
+// parseAggregates will parse the aggregate prefixes from the configuration
+func parseAggregates(prefixes []string) ([]*aggregate, error) {
+	aggregates := make([]*aggregate, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		_, cidr, err := net.ParseCIDR(strings.TrimSpace(prefix))
+		if err != nil {
+			return nil, fmt.Errorf("BGP aggregate format error [%s]: %w", prefix, err)
+		}
+		aggregates = append(aggregates, &aggregate{prefix: cidr, hosts: map[string]bool{}})
+	}
+	return aggregates, nil
+}
+
+// findAggregate returns the aggregate that covers an address, or nil
+func (b *Server) findAggregate(ip net.IP) *aggregate {
+	for _, a := range b.aggregates {
+		if a.prefix.Contains(ip) {
+			return a
+		}
+	}
+	return nil
+}
+
+// aggregatePath returns the path that is used to advertise an aggregate prefix
+func (b *Server) aggregatePath(a *aggregate) *api.Path {
+	ones, _ := a.prefix.Mask.Size()
+	return b.getPrefixPath(a.prefix.IP, uint32(ones), nil)
+}
+
+// addToAggregate will advertise the aggregate that covers a host once the first host is added,
+// it returns true if the host itself shouldn't be advertised
+func (b *Server) addToAggregate(ip net.IP) (suppress bool, err error) {
+	a := b.findAggregate(ip)
+	if a == nil {
+		return false, nil
+	}
+
+	if len(a.hosts) == 0 {
+		log.Infof("[BGP] advertising aggregate [%s]", a.prefix)
+		if _, err = b.s.AddPath(context.Background(), &api.AddPathRequest{
+			Path: b.aggregatePath(a),
+		}); err != nil {
+			return false, err
+		}
+	}
+	a.hosts[ip.String()] = true
+
+	return b.c.SuppressAggregated, nil
+}
+
+// delFromAggregate will withdraw the aggregate that covers a host once the last host is removed,
+// it returns true if the host itself wasn't advertised
+func (b *Server) delFromAggregate(ip net.IP) (suppress bool, err error) {
+	a := b.findAggregate(ip)
+	if a == nil {
+		return false, nil
+	}
+
+	if !a.hosts[ip.String()] {
+		return b.c.SuppressAggregated, nil
+	}
+	delete(a.hosts, ip.String())
+
+	if len(a.hosts) == 0 {
+		log.Infof("[BGP] withdrawing aggregate [%s]", a.prefix)
+		if err = b.s.DeletePath(context.Background(), &api.DeletePathRequest{
+			Path: b.aggregatePath(a),
+		}); err != nil {
+			return false, err
+		}
+	}
+
+	return b.c.SuppressAggregated, nil
+}
@@ -0,0 +1,62 @@
+package bgp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// privateASNBase is the start of the 4-byte private ASN range (RFC 6996, 4200000000-4294967294)
+const privateASNBase = 4200000000
+
+// ConfederationConfig defines the BGP confederation (RFC 5065) that the local AS is a member of
+type ConfederationConfig struct {
+	// Identifier is the AS of the confederation that is seen by peers outside of it
+	Identifier uint32
+	// MemberAS are the other member AS of the confederation, peers in these are confederation peers
+	MemberAS []uint32
+}
+
+// AutoASN returns a private 4-byte ASN that is derived from the last 24 bits of an address, so each
+// node in the same /8 (or IPv6 /104) will have a unique ASN
+func AutoASN(ip net.IP) uint32 {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	n := len(ip)
+	return privateASNBase + (uint32(ip[n-3])<<16 | uint32(ip[n-2])<<8 | uint32(ip[n-1]))
+}
+
+// resolveAutoAS will set the local AS from the router ID when the AS is assigned automatically
+func resolveAutoAS(c *Config) error {
+	if !c.AutoAS {
+		return nil
+	}
+	ip := net.ParseIP(c.RouterID)
+	if ip == nil {
+		return fmt.Errorf("a valid RouterID is required to assign the AS automatically, found [%s]", c.RouterID)
+	}
+	c.AS = AutoASN(ip)
+	log.Infof("[BGP] assigned AS [%d] from RouterID [%s]", c.AS, c.RouterID)
+	return nil
+}
+
+// ParseASList - take a string and parses it into a list of AS numbers, the format is AS,AS
+func ParseASList(config string) ([]uint32, error) {
+	var list []uint32
+	for _, asStr := range strings.Split(config, ",") {
+		asStr = strings.TrimSpace(asStr)
+		if asStr == "" {
+			continue
+		}
+		as, err := strconv.ParseUint(asStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("BGP AS format error [%s]", asStr)
+		}
+		list = append(list, uint32(as))
+	}
+	return list, nil
+}
@@ -0,0 +1,37 @@
+package bgp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestAutoASN(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want uint32
+	}{
+		{"10.0.0.1", 4200000001},
+		{"192.168.1.10", 4200000000 + 168<<16 + 1<<8 + 10},
+		{"10.255.255.255", 4216777215},
+		{"fd00::a8:10a", 4200000000 + 0xa8<<16 + 0x01<<8 + 0x0a},
+	}
+	for _, tt := range tests {
+		if got := AutoASN(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("AutoASN(%s) = %d, want %d", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestParseASList(t *testing.T) {
+	got, err := ParseASList("65001, 65002,4200000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint32{65001, 65002, 4200000001}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseASList() = %v, want %v", got, want)
+	}
+	if _, err = ParseASList("65001,sixty"); err == nil {
+		t.Error("ParseASList() expected an error")
+	}
+}
@@ -0,0 +1,161 @@
+package bgp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes" //nolint
+	"github.com/golang/protobuf/ptypes/any"
+	api "github.com/osrg/gobgp/v3/api"
+)
+
+// Well-known communities (RFC 1997, RFC 7999)
+var wellKnownCommunities = map[string]uint32{
+	"no-export":           0xFFFFFF01,
+	"no-advertise":        0xFFFFFF02,
+	"no-export-subconfed": 0xFFFFFF03,
+	"blackhole":           0xFFFF029A,
+}
+
+// PathAttributes defines the optional attributes that are attached to an advertised host
+type PathAttributes struct {
+	// Communities are standard (RFC 1997) communities encoded as ASN<<16 | value
+	Communities []uint32
+	// LargeCommunities are RFC 8092 large communities
+	LargeCommunities []LargeCommunity
+	// ASPathPrepend is the number of additional times the local AS is prepended to the AS path
+	ASPathPrepend uint32
+	// MED is the multi-exit discriminator, a lower value is preferred by the neighboring AS
+	MED *uint32
+	// LocalPref is the local preference, a higher value is preferred (this is only sent to iBGP peers)
+	LocalPref *uint32
+	// Peers are the peers (or peer groups) that the path is only advertised to, it is advertised
+	// to every peer if this is empty
+	Peers []string
+}
+
+// LargeCommunity defines a large community in the format GlobalAdmin:LocalData1:LocalData2
+type LargeCommunity struct {
+	GlobalAdmin uint32
+	LocalData1  uint32
+	LocalData2  uint32
+}
+
+// ParseCommunities will parse a comma separated list of communities, each entry can be
+// a standard community (65000:100), a large community (65000:1:2) or a well-known
+// community name (no-export, no-advertise, no-export-subconfed, blackhole)
+func ParseCommunities(config string) (attrs PathAttributes, err error) {
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if c, exists := wellKnownCommunities[strings.ToLower(entry)]; exists {
+			attrs.Communities = append(attrs.Communities, c)
+			continue
+		}
+
+		fields := strings.Split(entry, ":")
+		switch len(fields) {
+		case 2:
+			asn, err := strconv.ParseUint(fields[0], 10, 16)
+			if err != nil {
+				return attrs, fmt.Errorf("BGP community format error (asn:value) [%s]", entry)
+			}
+			value, err := strconv.ParseUint(fields[1], 10, 16)
+			if err != nil {
+				return attrs, fmt.Errorf("BGP community format error (asn:value) [%s]", entry)
+			}
+			attrs.Communities = append(attrs.Communities, uint32(asn<<16|value))
+		case 3:
+			var large [3]uint32
+			for x := range fields {
+				u64, err := strconv.ParseUint(fields[x], 10, 32)
+				if err != nil {
+					return attrs, fmt.Errorf("BGP large community format error (asn:value:value) [%s]", entry)
+				}
+				large[x] = uint32(u64)
+			}
+			attrs.LargeCommunities = append(attrs.LargeCommunities, LargeCommunity{
+				GlobalAdmin: large[0],
+				LocalData1:  large[1],
+				LocalData2:  large[2],
+			})
+		default:
+			return attrs, fmt.Errorf("unknown BGP community format [%s]", entry)
+		}
+	}
+	return attrs, nil
+}
+
+// MaxASPathPrepend is the largest number of times that the local AS can be prepended
+const MaxASPathPrepend = 10
+
+// marshal returns the gobgp path attributes, a nil PathAttributes returns no attributes
+func (a *PathAttributes) marshal(localAS uint32) (pattrs []*any.Any) {
+	if a == nil {
+		return nil
+	}
+
+	// gobgp will add the local AS once when advertising to an eBGP peer, so any
+	// prepending is in addition to that
+	if a.ASPathPrepend != 0 {
+		numbers := make([]uint32, a.ASPathPrepend)
+		for x := range numbers {
+			numbers[x] = localAS
+		}
+		//nolint
+		attr, _ := ptypes.MarshalAny(&api.AsPathAttribute{
+			Segments: []*api.AsSegment{
+				{
+					Type:    api.AsSegment_AS_SEQUENCE,
+					Numbers: numbers,
+				},
+			},
+		})
+		pattrs = append(pattrs, attr)
+	}
+
+	if a.MED != nil {
+		//nolint
+		attr, _ := ptypes.MarshalAny(&api.MultiExitDiscAttribute{
+			Med: *a.MED,
+		})
+		pattrs = append(pattrs, attr)
+	}
+
+	if a.LocalPref != nil {
+		//nolint
+		attr, _ := ptypes.MarshalAny(&api.LocalPrefAttribute{
+			LocalPref: *a.LocalPref,
+		})
+		pattrs = append(pattrs, attr)
+	}
+
+	if len(a.Communities) != 0 {
+		//nolint
+		attr, _ := ptypes.MarshalAny(&api.CommunitiesAttribute{
+			Communities: a.Communities,
+		})
+		pattrs = append(pattrs, attr)
+	}
+
+	if len(a.LargeCommunities) != 0 {
+		large := make([]*api.LargeCommunity, 0, len(a.LargeCommunities))
+		for _, c := range a.LargeCommunities {
+			large = append(large, &api.LargeCommunity{
+				GlobalAdmin: c.GlobalAdmin,
+				LocalData1:  c.LocalData1,
+				LocalData2:  c.LocalData2,
+			})
+		}
+		//nolint
+		attr, _ := ptypes.MarshalAny(&api.LargeCommunitiesAttribute{
+			Communities: large,
+		})
+		pattrs = append(pattrs, attr)
+	}
+	return pattrs
+}
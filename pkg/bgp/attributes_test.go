@@ -0,0 +1,39 @@
+package bgp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommunities(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    PathAttributes
+		wantErr bool
+	}{
+		{"empty", "", PathAttributes{}, false},
+		{"standard", "65000:100, 65000:200", PathAttributes{Communities: []uint32{65000<<16 | 100, 65000<<16 | 200}}, false},
+		{"well-known", "no-export", PathAttributes{Communities: []uint32{0xFFFFFF01}}, false},
+		{"large", "4200000000:1:2", PathAttributes{LargeCommunities: []LargeCommunity{{4200000000, 1, 2}}}, false},
+		{"mixed", "65000:100,4200000000:1:2", PathAttributes{
+			Communities:      []uint32{65000<<16 | 100},
+			LargeCommunities: []LargeCommunity{{4200000000, 1, 2}},
+		}, false},
+		{"standard asn too large", "70000:100", PathAttributes{}, true},
+		{"unknown format", "65000", PathAttributes{}, true},
+		{"not a number", "65000:abc", PathAttributes{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCommunities(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCommunities() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommunities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
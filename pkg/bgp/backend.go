@@ -0,0 +1,50 @@
+package bgp
+
+import (
+	"fmt"
+
+	api "github.com/osrg/gobgp/v3/api"
+)
+
+const (
+	// BackendGoBGP is the embedded gobgp server, this is the default backend
+	BackendGoBGP = "gobgp"
+	// BackendFRR programs the FRR daemon that is already running on the node
+	BackendFRR = "frr"
+)
+
+// Backend is the routing daemon that advertises the VIPs to the BGP peers
+type Backend interface {
+	AddHost(addr string) error
+	AddHostWithAttributes(addr string, attrs *PathAttributes) error
+	DelHost(addr string) error
+	PeerStatus() ([]PeerStatus, error)
+	Close() error
+}
+
+// Reconciler is implemented by the backends that run outside of kube-vip, where the advertised hosts can be
+// removed out-of-band and need to be restored
+type Reconciler interface {
+	ReconcileHosts() ([]string, error)
+}
+
+// NewBackend returns the routing backend that is selected in the configuration, the callback is
+// only used by the embedded gobgp server
+func NewBackend(c *Config, peerStateChangeCallback func(*api.WatchEventResponse_PeerEvent)) (Backend, error) {
+	switch c.Backend {
+	case "", BackendGoBGP:
+		s, err := NewBGPServer(c, peerStateChangeCallback)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case BackendFRR:
+		f, err := NewFRR(c)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("unknown BGP backend [%s], it should be %s or %s", c.Backend, BackendGoBGP, BackendFRR)
+	}
+}
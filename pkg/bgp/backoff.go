@@ -0,0 +1,122 @@
+package bgp
+
+import (
+	"context"
+	"time"
+
+	"github.com/jpillora/backoff"
+	api "github.com/osrg/gobgp/v3/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// reconnectBackoffMin and reconnectBackoffMax bound the time a failing peer is held down
+	reconnectBackoffMin = 5 * time.Second
+	reconnectBackoffMax = 5 * time.Minute
+
+	// A session needs to be established for this long before its backoff is reset
+	reconnectStablePeriod = time.Minute
+)
+
+// peerBackoff tracks the reconnect backoff of a single peer
+type peerBackoff struct {
+	backoff     backoff.Backoff
+	established time.Time
+	lastDown    time.Time
+	held        bool
+	// bfdDown is set while BFD has disabled the peer, which BFD re-enables rather than the backoff
+	bfdDown bool
+	// state is the last session state, failures are only counted when a session moves into idle
+	state api.PeerState_SessionState
+}
+
+// sessionStateChange will hold a peer down with an exponential backoff (with jitter) each time its
+// session fails, this stops a misconfigured peer from continually reconnecting
+func (b *Server) sessionStateChange(p *api.WatchEventResponse_PeerEvent) {
+	// Dynamic neighbors are removed by gobgp once their session goes down, so are never held down
+	address := p.GetPeer().GetState().GetNeighborAddress()
+	if address == "" || p.GetPeer().GetConf().GetPeerGroup() != "" {
+		return
+	}
+
+	b.backoffMutex.Lock()
+	defer b.backoffMutex.Unlock()
+
+	pb := b.backoffOf(address)
+
+	previous := pb.state
+	pb.state = p.GetPeer().GetState().GetSessionState()
+
+	switch pb.state {
+	case api.PeerState_ESTABLISHED:
+		pb.established = time.Now()
+	case api.PeerState_IDLE:
+		if previous == api.PeerState_ESTABLISHED {
+			pb.lastDown = time.Now()
+		}
+		// A newly added peer starts in idle, and being held down (or disabled) will also move it to idle
+		if previous == api.PeerState_UNKNOWN || previous == api.PeerState_IDLE ||
+			pb.held || p.GetPeer().GetState().GetAdminState() != api.PeerState_UP {
+			return
+		}
+		if !pb.established.IsZero() && time.Since(pb.established) > reconnectStablePeriod {
+			pb.backoff.Reset()
+		}
+		pb.established = time.Time{}
+		pb.held = true
+
+		d := pb.backoff.Duration()
+		log.Warnf("[BGP] session with peer [%s] failed, holding down for %v", address, d.Round(time.Second))
+		go func() {
+			if err := b.s.DisablePeer(context.Background(), &api.DisablePeerRequest{
+				Address:       address,
+				Communication: "reconnect backoff",
+			}); err != nil {
+				log.Errorf("[BGP] unable to hold down peer [%s]: %v", address, err)
+			}
+			time.AfterFunc(d, func() { b.releasePeer(address) })
+		}()
+	}
+}
+
+// backoffOf returns the backoff of the peer, the backoff mutex must be held
+func (b *Server) backoffOf(address string) *peerBackoff {
+	pb, exists := b.backoffs[address]
+	if !exists {
+		pb = &peerBackoff{
+			backoff: backoff.Backoff{
+				Factor: 2,
+				Jitter: true,
+				Min:    reconnectBackoffMin,
+				Max:    reconnectBackoffMax,
+			},
+		}
+		b.backoffs[address] = pb
+	}
+	return pb
+}
+
+// releasePeer will re-enable a peer that has been held down, unless BFD has disabled it since
+func (b *Server) releasePeer(address string) {
+	b.backoffMutex.Lock()
+	pb, exists := b.backoffs[address]
+	if !exists || !pb.held {
+		b.backoffMutex.Unlock()
+		return
+	}
+	pb.held = false
+	bfdDown := pb.bfdDown
+	b.backoffMutex.Unlock()
+
+	if bfdDown {
+		log.Debugf("[BGP] peer [%s] is no longer held down, it will be enabled once BFD is up", address)
+		return
+	}
+
+	log.Debugf("[BGP] releasing peer [%s]", address)
+	if err := b.s.EnablePeer(context.Background(), &api.EnablePeerRequest{
+		Address: address,
+	}); err != nil {
+		log.Errorf("[BGP] unable to release peer [%s]: %v", address, err)
+	}
+}
@@ -0,0 +1,27 @@
+package bgp
+
+import "testing"
+
+// The server isn't started, so the peers must not be enabled (or disabled) with it
+func TestBackoffAndBFD(t *testing.T) {
+	const address = "10.0.0.1"
+
+	t.Run("BFD up doesn't enable a peer that is held down", func(t *testing.T) {
+		b := &Server{backoffs: make(map[string]*peerBackoff)}
+		b.backoffOf(address).held = true
+		b.bfdStateChange(address, BFDStateUp)
+		if pb := b.backoffs[address]; !pb.held || pb.bfdDown {
+			t.Errorf("held = %t, bfdDown = %t, want held and BFD up", pb.held, pb.bfdDown)
+		}
+	})
+
+	t.Run("the release doesn't enable a peer that BFD disabled", func(t *testing.T) {
+		b := &Server{backoffs: make(map[string]*peerBackoff)}
+		pb := b.backoffOf(address)
+		pb.held, pb.bfdDown = true, true
+		b.releasePeer(address)
+		if pb.held || !pb.bfdDown {
+			t.Errorf("held = %t, bfdDown = %t, want released and BFD down", pb.held, pb.bfdDown)
+		}
+	})
+}
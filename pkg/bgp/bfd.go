@@ -0,0 +1,539 @@
+package bgp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// This file implements a minimal single-hop BFD (RFC 5880/5881) in asynchronous
+// mode, it is used to detect the loss of a BGP peer far quicker than the BGP
+// hold timer would.
+
+const (
+	bfdControlPort   = 3784
+	bfdVersion       = 1
+	bfdPacketLen     = 24
+	bfdSourcePortMin = 49152
+	bfdSourcePortMax = 65535
+
+	// A session that isn't Up must not transmit faster than once a second
+	bfdSlowTxInterval = time.Second
+
+	defaultBFDInterval   = 300
+	defaultBFDMultiplier = 3
+)
+
+// BFDState is the state of a BFD session
+type BFDState uint8
+
+// BFD session states (RFC 5880 section 4.1)
+const (
+	BFDStateAdminDown BFDState = iota
+	BFDStateDown
+	BFDStateInit
+	BFDStateUp
+)
+
+func (s BFDState) String() string {
+	switch s {
+	case BFDStateAdminDown:
+		return "AdminDown"
+	case BFDStateDown:
+		return "Down"
+	case BFDStateInit:
+		return "Init"
+	case BFDStateUp:
+		return "Up"
+	}
+	return "Unknown"
+}
+
+// BFD diagnostic codes that this implementation will set
+const (
+	bfdDiagNone             uint8 = 0
+	bfdDiagTimeExpired      uint8 = 1
+	bfdDiagNeighborSignaled uint8 = 3
+)
+
+// bfdPacket is the mandatory section of a BFD control packet
+type bfdPacket struct {
+	diag                  uint8
+	state                 BFDState
+	poll                  bool
+	final                 bool
+	detectMult            uint8
+	myDiscriminator       uint32
+	yourDiscriminator     uint32
+	desiredMinTxInterval  uint32
+	requiredMinRxInterval uint32
+}
+
+func (p *bfdPacket) marshal() []byte {
+	b := make([]byte, bfdPacketLen)
+	b[0] = bfdVersion<<5 | (p.diag & 0x1f)
+	b[1] = uint8(p.state) << 6
+	if p.poll {
+		b[1] |= 0x20
+	}
+	if p.final {
+		b[1] |= 0x10
+	}
+	b[2] = p.detectMult
+	b[3] = bfdPacketLen
+	binary.BigEndian.PutUint32(b[4:], p.myDiscriminator)
+	binary.BigEndian.PutUint32(b[8:], p.yourDiscriminator)
+	binary.BigEndian.PutUint32(b[12:], p.desiredMinTxInterval)
+	binary.BigEndian.PutUint32(b[16:], p.requiredMinRxInterval)
+	return b
+}
+
+func unmarshalBFDPacket(b []byte) (*bfdPacket, error) {
+	if len(b) < bfdPacketLen {
+		return nil, fmt.Errorf("bfd packet too short [%d]", len(b))
+	}
+	if b[0]>>5 != bfdVersion {
+		return nil, fmt.Errorf("unsupported bfd version [%d]", b[0]>>5)
+	}
+	if int(b[3]) < bfdPacketLen || int(b[3]) > len(b) {
+		return nil, fmt.Errorf("invalid bfd packet length [%d]", b[3])
+	}
+	p := &bfdPacket{
+		diag:                  b[0] & 0x1f,
+		state:                 BFDState(b[1] >> 6),
+		poll:                  b[1]&0x20 != 0,
+		final:                 b[1]&0x10 != 0,
+		detectMult:            b[2],
+		myDiscriminator:       binary.BigEndian.Uint32(b[4:]),
+		yourDiscriminator:     binary.BigEndian.Uint32(b[8:]),
+		desiredMinTxInterval:  binary.BigEndian.Uint32(b[12:]),
+		requiredMinRxInterval: binary.BigEndian.Uint32(b[16:]),
+	}
+	if p.detectMult == 0 || p.myDiscriminator == 0 {
+		return nil, fmt.Errorf("invalid bfd packet, detect multiplier and discriminator must be set")
+	}
+	return p, nil
+}
+
+// bfdSession tracks the state of BFD with a single peer
+type bfdSession struct {
+	mu sync.Mutex
+
+	peer       string
+	conn       *net.UDPConn
+	txInterval time.Duration
+	rxInterval time.Duration
+	multiplier uint8
+
+	state            BFDState
+	remoteState      BFDState
+	diag             uint8
+	localDisc        uint32
+	remoteDisc       uint32
+	remoteMinRx      time.Duration
+	remoteDetectMult uint8
+	remoteDesiredTx  time.Duration
+	lastRx           time.Time
+	sendFinal        bool
+	onStateChange    func(peer string, state BFDState)
+	cancel           context.CancelFunc
+
+	// config is the configuration that the session was added with, including its defaults
+	config BFDConfig
+}
+
+// bfdManager owns the shared receive socket and all of the BFD sessions
+type bfdManager struct {
+	mu       sync.Mutex
+	sourceIP string
+	listener *net.UDPConn
+	sessions map[string]*bfdSession
+	discs    map[uint32]*bfdSession
+	callback func(peer string, state BFDState)
+}
+
+func newBFDManager(sourceIP string, callback func(peer string, state BFDState)) (*bfdManager, error) {
+	lc := net.ListenConfig{Control: setReceiveTTL}
+	pc, err := lc.ListenPacket(context.Background(), "udp", fmt.Sprintf(":%d", bfdControlPort))
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for bfd control packets: %w", err)
+	}
+	m := &bfdManager{
+		sourceIP: sourceIP,
+		listener: pc.(*net.UDPConn),
+		sessions: make(map[string]*bfdSession),
+		discs:    make(map[uint32]*bfdSession),
+		callback: callback,
+	}
+	go m.receive()
+	return m, nil
+}
+
+// setTTL ensures control packets are sent with a TTL of 255 as required by RFC 5881
+func setTTL(network, _ string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		if network == "udp6" {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_UNICAST_HOPS, 255)
+			return
+		}
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TTL, 255)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// setReceiveTTL requests the TTL (or hop limit) of each received packet, so that the packets that haven't been sent
+// from a directly connected peer can be discarded (RFC 5881 section 5)
+func setReceiveTTL(network, _ string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		// A dual-stack socket receives the IPv4 packets as well, the TTL of which has to be requested separately
+		if sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_RECVTTL, 1); sockErr != nil {
+			return
+		}
+		if network == "udp6" {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_RECVHOPLIMIT, 1)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// receivedTTL returns the TTL (or hop limit) of a received packet from its control messages
+func receivedTTL(oob []byte) (int, bool) {
+	messages, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, false
+	}
+	for _, m := range messages {
+		if len(m.Data) < 4 {
+			continue
+		}
+		if (m.Header.Level == unix.IPPROTO_IP && m.Header.Type == unix.IP_TTL) ||
+			(m.Header.Level == unix.IPPROTO_IPV6 && m.Header.Type == unix.IPV6_HOPLIMIT) {
+			return int(binary.NativeEndian.Uint32(m.Data)), true
+		}
+	}
+	return 0, false
+}
+
+// bfdKey normalises a peer address so that it matches the source of received packets
+func bfdKey(peer string) string {
+	if ip := net.ParseIP(peer); ip != nil {
+		return ip.String()
+	}
+	return peer
+}
+
+// bfdDefaults returns the configuration of a session with the default interval and multiplier of those that aren't set
+func bfdDefaults(cfg BFDConfig) BFDConfig {
+	if cfg.Interval == 0 {
+		cfg.Interval = defaultBFDInterval
+	}
+	if cfg.Multiplier == 0 {
+		cfg.Multiplier = defaultBFDMultiplier
+	}
+	return cfg
+}
+
+func (m *bfdManager) addSession(peer string, cfg BFDConfig) error {
+	peer = bfdKey(peer)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.sessions[peer]; exists {
+		return nil
+	}
+
+	conn, err := m.dial(peer)
+	if err != nil {
+		return err
+	}
+
+	cfg = bfdDefaults(cfg)
+	interval, multiplier := cfg.Interval, cfg.Multiplier
+
+	var disc uint32
+	for disc == 0 || m.discs[disc] != nil {
+		disc = rand.Uint32() //nolint:gosec
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &bfdSession{
+		peer:          peer,
+		conn:          conn,
+		txInterval:    time.Duration(interval) * time.Millisecond,
+		rxInterval:    time.Duration(interval) * time.Millisecond,
+		multiplier:    uint8(multiplier),
+		state:         BFDStateDown,
+		remoteState:   BFDStateDown,
+		localDisc:     disc,
+		remoteMinRx:   time.Microsecond,
+		onStateChange: m.callback,
+		cancel:        cancel,
+		config:        cfg,
+	}
+	m.sessions[peer] = s
+	m.discs[disc] = s
+
+	log.Infof("[BFD] starting session with peer [%s], interval [%dms], multiplier [%d]", peer, interval, multiplier)
+	go s.run(ctx)
+	return nil
+}
+
+// dial will create a socket for transmitting to a peer from a source port within
+// the range mandated by RFC 5881
+func (m *bfdManager) dial(peer string) (*net.UDPConn, error) {
+	raddr := &net.UDPAddr{IP: net.ParseIP(peer), Port: bfdControlPort}
+	if raddr.IP == nil {
+		return nil, fmt.Errorf("invalid bfd peer address [%s]", peer)
+	}
+	var lip net.IP
+	if m.sourceIP != "" {
+		lip = net.ParseIP(m.sourceIP)
+	}
+
+	d := net.Dialer{Control: setTTL}
+	var err error
+	for i := 0; i < 32; i++ {
+		port := bfdSourcePortMin + rand.Intn(bfdSourcePortMax-bfdSourcePortMin) //nolint:gosec
+		d.LocalAddr = &net.UDPAddr{IP: lip, Port: port}
+		var c net.Conn
+		c, err = d.Dial("udp", raddr.String())
+		if err == nil {
+			return c.(*net.UDPConn), nil
+		}
+	}
+	return nil, fmt.Errorf("unable to create bfd socket for peer [%s]: %w", peer, err)
+}
+
+// removeSession stops the session of a peer, it returns false if the peer has no session
+func (m *bfdManager) removeSession(peer string) bool {
+	peer = bfdKey(peer)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.sessions[peer]
+	if !exists {
+		return false
+	}
+	s.cancel()
+	s.conn.Close()
+	delete(m.discs, s.localDisc)
+	delete(m.sessions, peer)
+	return true
+}
+
+// hasSession returns whether the peer has a session with the configuration
+func (m *bfdManager) hasSession(peer string, cfg BFDConfig) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, exists := m.sessions[bfdKey(peer)]
+	return exists && s.config == bfdDefaults(cfg)
+}
+
+// sessionState returns the state of the BFD session for a peer, and false if no session exists
+func (m *bfdManager) sessionState(peer string) (BFDState, bool) {
+	peer = bfdKey(peer)
+	m.mu.Lock()
+	s, exists := m.sessions[peer]
+	m.mu.Unlock()
+	if !exists {
+		return BFDStateDown, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, true
+}
+
+func (m *bfdManager) close() {
+	m.mu.Lock()
+	peers := make([]string, 0, len(m.sessions))
+	for peer := range m.sessions {
+		peers = append(peers, peer)
+	}
+	m.mu.Unlock()
+
+	for _, peer := range peers {
+		m.removeSession(peer)
+	}
+	m.listener.Close()
+}
+
+func (m *bfdManager) receive() {
+	buf := make([]byte, 1500)
+	oob := make([]byte, unix.CmsgSpace(4)*2)
+	for {
+		n, oobn, _, addr, err := m.listener.ReadMsgUDP(buf, oob)
+		if err != nil {
+			log.Debugf("[BFD] receiver stopped: %v", err)
+			return
+		}
+		// Only a packet that hasn't been routed (i.e. still has the TTL of 255 it was sent with) can come from a
+		// directly connected peer, anything else could have been spoofed from further away (GTSM, RFC 5082)
+		if ttl, ok := receivedTTL(oob[:oobn]); !ok || ttl != 255 {
+			log.Debugf("[BFD] discarding packet from [%s] with a TTL of %d", addr.IP, ttl)
+			continue
+		}
+		p, err := unmarshalBFDPacket(buf[:n])
+		if err != nil {
+			log.Debugf("[BFD] discarding packet from [%s]: %v", addr.IP, err)
+			continue
+		}
+
+		m.mu.Lock()
+		var s *bfdSession
+		if p.yourDiscriminator != 0 {
+			s = m.discs[p.yourDiscriminator]
+		} else if p.state == BFDStateDown || p.state == BFDStateAdminDown {
+			s = m.sessions[addr.IP.String()]
+		}
+		m.mu.Unlock()
+
+		if s == nil {
+			log.Debugf("[BFD] no session found for packet from [%s]", addr.IP)
+			continue
+		}
+		s.handle(p)
+	}
+}
+
+// handle processes a received control packet (RFC 5880 section 6.8.6)
+func (s *bfdSession) handle(p *bfdPacket) {
+	s.mu.Lock()
+
+	s.remoteDisc = p.myDiscriminator
+	s.remoteState = p.state
+	s.remoteDetectMult = p.detectMult
+	s.remoteMinRx = time.Duration(p.requiredMinRxInterval) * time.Microsecond
+	s.remoteDesiredTx = time.Duration(p.desiredMinTxInterval) * time.Microsecond
+	s.lastRx = time.Now()
+	if p.poll {
+		s.sendFinal = true
+	}
+
+	previous := s.state
+	switch {
+	case s.state == BFDStateAdminDown:
+		// Packets are discarded when administratively down
+	case p.state == BFDStateAdminDown:
+		if s.state != BFDStateDown {
+			s.diag = bfdDiagNeighborSignaled
+			s.state = BFDStateDown
+		}
+	case s.state == BFDStateDown:
+		if p.state == BFDStateDown {
+			s.state = BFDStateInit
+		} else if p.state == BFDStateInit {
+			s.state = BFDStateUp
+		}
+	case s.state == BFDStateInit:
+		if p.state == BFDStateInit || p.state == BFDStateUp {
+			s.state = BFDStateUp
+		}
+	case s.state == BFDStateUp:
+		if p.state == BFDStateDown {
+			s.diag = bfdDiagNeighborSignaled
+			s.state = BFDStateDown
+		}
+	}
+	current := s.state
+	if current == BFDStateUp && previous != BFDStateUp {
+		s.diag = bfdDiagNone
+	}
+	s.mu.Unlock()
+
+	s.notify(previous, current)
+}
+
+func (s *bfdSession) notify(previous, current BFDState) {
+	if previous == current {
+		return
+	}
+	log.Infof("[BFD] peer [%s] session state changed [%s] -> [%s]", s.peer, previous, current)
+	if s.onStateChange != nil {
+		s.onStateChange(s.peer, current)
+	}
+}
+
+// detectionTime is the time without receiving a packet after which the session is declared down
+func (s *bfdSession) detectionTime() time.Duration {
+	interval := s.rxInterval
+	if s.remoteDesiredTx > interval {
+		interval = s.remoteDesiredTx
+	}
+	mult := s.remoteDetectMult
+	if mult == 0 {
+		mult = s.multiplier
+	}
+	return time.Duration(mult) * interval
+}
+
+// transmitInterval returns the interval, with jitter, until the next control packet is sent
+func (s *bfdSession) transmitInterval() time.Duration {
+	interval := s.txInterval
+	if s.state != BFDStateUp && interval < bfdSlowTxInterval {
+		interval = bfdSlowTxInterval
+	}
+	if s.remoteMinRx > interval {
+		interval = s.remoteMinRx
+	}
+	// Reduce the interval by a random 0-25% (RFC 5880 section 6.8.7)
+	jitter := time.Duration(rand.Int63n(int64(interval) / 4)) //nolint:gosec
+	return interval - jitter
+}
+
+func (s *bfdSession) run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		s.mu.Lock()
+		previous := s.state
+		if (s.state == BFDStateInit || s.state == BFDStateUp) && time.Since(s.lastRx) > s.detectionTime() {
+			s.diag = bfdDiagTimeExpired
+			s.state = BFDStateDown
+			s.remoteDisc = 0
+		}
+		current := s.state
+
+		p := &bfdPacket{
+			diag:                  s.diag,
+			state:                 s.state,
+			final:                 s.sendFinal,
+			detectMult:            s.multiplier,
+			myDiscriminator:       s.localDisc,
+			yourDiscriminator:     s.remoteDisc,
+			desiredMinTxInterval:  uint32(s.txInterval / time.Microsecond),
+			requiredMinRxInterval: uint32(s.rxInterval / time.Microsecond),
+		}
+		s.sendFinal = false
+		next := s.transmitInterval()
+		s.mu.Unlock()
+
+		s.notify(previous, current)
+
+		if _, err := s.conn.Write(p.marshal()); err != nil {
+			log.Debugf("[BFD] error sending control packet to [%s]: %v", s.peer, err)
+		}
+		timer.Reset(next)
+	}
+}
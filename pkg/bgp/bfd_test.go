@@ -0,0 +1,63 @@
+package bgp
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestReceivedTTL(t *testing.T) {
+	lc := net.ListenConfig{Control: setReceiveTTL}
+	pc, err := lc.ListenPacket(context.Background(), "udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	listener := pc.(*net.UDPConn)
+
+	tests := []struct {
+		name string
+		ttl  int
+	}{
+		{"directly connected", 255},
+		{"routed", 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := net.Dialer{Control: func(_, _ string, c syscall.RawConn) error {
+				var sockErr error
+				err := c.Control(func(fd uintptr) {
+					sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TTL, tt.ttl)
+				})
+				if err != nil {
+					return err
+				}
+				return sockErr
+			}}
+			c, err := d.Dial("udp", listener.LocalAddr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if _, err := c.Write([]byte{0}); err != nil {
+				t.Fatal(err)
+			}
+
+			buf := make([]byte, 16)
+			oob := make([]byte, unix.CmsgSpace(4)*2)
+			_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, oobn, _, _, err := listener.ReadMsgUDP(buf, oob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ttl, ok := receivedTTL(oob[:oobn])
+			if !ok || ttl != tt.ttl {
+				t.Errorf("receivedTTL() = %d, %v, want %d, true", ttl, ok, tt.ttl)
+			}
+		})
+	}
+}
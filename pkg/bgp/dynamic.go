@@ -0,0 +1,112 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	api "github.com/osrg/gobgp/v3/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// DynamicNeighbor defines a prefix that inbound BGP sessions are accepted from, this allows
+// peers within that range to connect without each of them being configured
+type DynamicNeighbor struct {
+	Prefix   string
+	AS       uint32
+	Password string
+}
+
+// peerGroup returns the name of the peer group that is created for a dynamic neighbor prefix
+func (d DynamicNeighbor) peerGroup() string {
+	return "dynamic-" + d.Prefix
+}
+
+// addDynamicNeighbors will create a peer group for each dynamic neighbor prefix, gobgp will then
+// accept any session from within that prefix as a member of the group
+func (b *Server) addDynamicNeighbors() error {
+	for _, d := range b.c.DynamicNeighbors {
+		pg := &api.PeerGroup{
+			Conf: &api.PeerGroupConf{
+				PeerGroupName: d.peerGroup(),
+				PeerAsn:       d.AS,
+				AuthPassword:  d.Password,
+			},
+			Timers: &api.Timers{
+				Config: &api.TimersConfig{
+					HoldTime:          b.c.HoldTime,
+					KeepaliveInterval: b.c.KeepaliveInterval,
+				},
+			},
+		}
+		pg.AfiSafis, pg.GracefulRestart = b.peerAfiSafis()
+
+		if err := b.s.AddPeerGroup(context.Background(), &api.AddPeerGroupRequest{
+			PeerGroup: pg,
+		}); err != nil {
+			return fmt.Errorf("unable to add peer group for dynamic neighbors [%s]: %w", d.Prefix, err)
+		}
+
+		if err := b.s.AddDynamicNeighbor(context.Background(), &api.AddDynamicNeighborRequest{
+			DynamicNeighbor: &api.DynamicNeighbor{
+				Prefix:    d.Prefix,
+				PeerGroup: d.peerGroup(),
+			},
+		}); err != nil {
+			return fmt.Errorf("unable to add dynamic neighbors [%s]: %w", d.Prefix, err)
+		}
+		log.Infof("[BGP] accepting sessions from dynamic neighbors [%s] AS [%d]", d.Prefix, d.AS)
+	}
+	return nil
+}
+
+// ParseDynamicNeighborConfig - take a string and parses it into an array of dynamic neighbors,
+// the format is prefix:AS:password (IPv6 prefixes are wrapped in [])
+func ParseDynamicNeighborConfig(config string) (neighbors []DynamicNeighbor, err error) {
+	for _, neighborStr := range strings.Split(config, ",") {
+		if neighborStr == "" {
+			continue
+		}
+
+		var prefix string
+		if neighborStr[0] == '[' {
+			prefixEndPos := strings.IndexByte(neighborStr, ']')
+			if prefixEndPos == -1 {
+				return nil, fmt.Errorf("no matching ] found for IPv6 BGP dynamic neighbor")
+			}
+			prefix = neighborStr[1:prefixEndPos]
+			neighborStr = neighborStr[prefixEndPos+1:]
+		}
+
+		neighbor := strings.Split(neighborStr, ":")
+		if len(neighbor) < 2 {
+			return nil, fmt.Errorf("mandatory dynamic neighbor params <prefix>:<AS> incomplete")
+		}
+		if prefix == "" {
+			prefix = neighbor[0]
+		}
+
+		if _, _, err = net.ParseCIDR(prefix); err != nil {
+			return nil, fmt.Errorf("BGP dynamic neighbor prefix format error [%s]: %w", prefix, err)
+		}
+
+		ASNumber, err := strconv.ParseUint(neighbor[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("BGP dynamic neighbor AS format error [%s]", neighbor[1])
+		}
+
+		password := ""
+		if len(neighbor) >= 3 {
+			password = neighbor[2]
+		}
+
+		neighbors = append(neighbors, DynamicNeighbor{
+			Prefix:   prefix,
+			AS:       uint32(ASNumber),
+			Password: password,
+		})
+	}
+	return neighbors, nil
+}
@@ -0,0 +1,37 @@
+package bgp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDynamicNeighborConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    []DynamicNeighbor
+		wantErr bool
+	}{
+		{"ipv4", "10.0.0.0/24:65001", []DynamicNeighbor{{Prefix: "10.0.0.0/24", AS: 65001}}, false},
+		{"ipv6 with password", "[fd00::/64]:65001:secret", []DynamicNeighbor{{Prefix: "fd00::/64", AS: 65001, Password: "secret"}}, false},
+		{"multiple", "10.0.0.0/24:65001,10.0.1.0/24:65002", []DynamicNeighbor{
+			{Prefix: "10.0.0.0/24", AS: 65001},
+			{Prefix: "10.0.1.0/24", AS: 65002},
+		}, false},
+		{"not a prefix", "10.0.0.1:65001", nil, true},
+		{"missing AS", "10.0.0.0/24", nil, true},
+		{"unterminated ipv6", "[fd00::/64:65001", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDynamicNeighborConfig(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDynamicNeighborConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDynamicNeighborConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
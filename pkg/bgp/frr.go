@@ -0,0 +1,329 @@
+package bgp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FRR advertises the VIPs through an FRR daemon that is already running on the node, the
+// peers (and any policy) are configured in FRR and kube-vip only adds and removes the networks
+type FRR struct {
+	c     *Config
+	vtysh func(commands ...string) ([]byte, error)
+
+	// advertised maps each advertised prefix to its route-map (empty when it has no attributes)
+	advertised map[string]string
+	// networks are the commands that advertised each prefix, so that it can be restored
+	networks map[string][]string
+	mutex    sync.Mutex
+}
+
+// NewFRR takes a configuration and returns a backend that programs the FRR daemon on the node
+func NewFRR(c *Config) (*FRR, error) {
+	if c.AS == 0 {
+		return nil, fmt.Errorf("You need to provide AS")
+	}
+
+	if len(c.Aggregates) != 0 {
+		return nil, fmt.Errorf("aggregates aren't supported with the frr backend, they can be configured in FRR with aggregate-address")
+	}
+
+	if len(c.Peers) != 0 || len(c.DynamicNeighbors) != 0 {
+		log.Warnf("[FRR] the BGP peers are configured in FRR, ignoring [%d] configured peer(s)", len(c.Peers)+len(c.DynamicNeighbors))
+	}
+
+	if c.MEDRamp.Start != 0 {
+		log.Warnf("[FRR] MED ramping isn't supported with the frr backend, VIPs are advertised with their own MED")
+	}
+
+	path := c.VtyshPath
+	if path == "" {
+		path = "vtysh"
+	}
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find vtysh: %w", err)
+	}
+
+	f := &FRR{
+		c: c,
+		vtysh: func(commands ...string) ([]byte, error) {
+			args := make([]string, 0, len(commands)*2)
+			for _, command := range commands {
+				args = append(args, "-c", command)
+			}
+			out, err := exec.Command(path, args...).CombinedOutput()
+			if err != nil {
+				return nil, fmt.Errorf("vtysh error [%s]: %w", strings.TrimSpace(string(out)), err)
+			}
+			return out, nil
+		},
+		advertised: map[string]string{},
+		networks:   map[string][]string{},
+	}
+
+	// Ensure that FRR is reachable before any VIPs are advertised
+	if _, err = f.PeerStatus(); err != nil {
+		return nil, err
+	}
+	log.Infof("[FRR] programming the FRR daemon through [%s] with AS [%d]", path, c.AS)
+	return f, nil
+}
+
+// AddHost will advertise a host through FRR
+func (f *FRR) AddHost(addr string) error {
+	return f.AddHostWithAttributes(addr, nil)
+}
+
+// AddHostWithAttributes will advertise a host through FRR, any of the optional attributes are
+// set with a route-map for the network
+func (f *FRR) AddHostWithAttributes(addr string, attrs *PathAttributes) error {
+	ip, _, err := net.ParseCIDR(addr)
+	if err != nil {
+		return err
+	}
+	prefix := hostPrefix(ip.String())
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if attrs != nil && len(attrs.Peers) != 0 {
+		log.Warnf("[FRR] advertising [%s] to a subset of peers isn't supported with the frr backend, use a policy in FRR", addr)
+	}
+
+	previous, exists := f.advertised[prefix]
+	if !exists && f.c.MaxPrefixes != 0 && len(f.advertised) >= int(f.c.MaxPrefixes) {
+		log.Errorf("[FRR] not advertising [%s], the maximum of [%d] prefixes are advertised", addr, f.c.MaxPrefixes)
+		return fmt.Errorf("unable to advertise [%s]: %w", addr, ErrMaxPrefixes)
+	}
+
+	routeMap := ""
+	setCommands := frrSetCommands(f.c.AS, attrs)
+	if len(setCommands) != 0 {
+		routeMap = frrRouteMapName(ip)
+	}
+
+	var advertise []string
+	if routeMap != "" {
+		advertise = append(advertise, "route-map "+routeMap+" permit 10")
+		advertise = append(advertise, setCommands...)
+		advertise = append(advertise, "exit")
+	}
+	network := "network " + prefix
+	if routeMap != "" {
+		network += " route-map " + routeMap
+	}
+	advertise = append(advertise, frrAddressFamily(f.c.AS, ip, network)...)
+
+	commands := []string{"configure terminal"}
+	// The route-map is rebuilt so that any attributes that have been removed aren't left behind
+	if previous != "" {
+		commands = append(commands, "no route-map "+previous)
+	}
+	commands = append(commands, advertise...)
+
+	if _, err = f.vtysh(commands...); err != nil {
+		return fmt.Errorf("unable to advertise [%s]: %w", addr, err)
+	}
+	f.advertised[prefix] = routeMap
+	f.networks[prefix] = advertise
+	return nil
+}
+
+// DelHost will withdraw a host from FRR
+func (f *FRR) DelHost(addr string) error {
+	ip, _, err := net.ParseCIDR(addr)
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.withdraw(ip)
+}
+
+// withdraw removes the network (and route-map) of an advertised host, the caller holds the mutex
+func (f *FRR) withdraw(ip net.IP) error {
+	prefix := hostPrefix(ip.String())
+
+	commands := []string{"configure terminal"}
+	commands = append(commands, frrAddressFamily(f.c.AS, ip, "no network "+prefix)...)
+	if routeMap := f.advertised[prefix]; routeMap != "" {
+		commands = append(commands, "no route-map "+routeMap)
+	}
+
+	if _, err := f.vtysh(commands...); err != nil {
+		return fmt.Errorf("unable to withdraw [%s]: %w", prefix, err)
+	}
+	delete(f.advertised, prefix)
+	delete(f.networks, prefix)
+	return nil
+}
+
+// ReconcileHosts will re-advertise any host whose network (or route-map) has been removed from FRR out-of-band
+// (e.g. the daemon was restarted without its configuration being saved), the restored prefixes are returned
+func (f *FRR) ReconcileHosts() ([]string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.networks) == 0 {
+		return nil, nil
+	}
+	out, err := f.vtysh("show running-config")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the FRR configuration: %w", err)
+	}
+	configured := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		configured[strings.TrimSpace(line)] = true
+	}
+
+	prefixes := make([]string, 0, len(f.networks))
+	for prefix := range f.networks {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var restored []string
+	for _, prefix := range prefixes {
+		advertise := f.networks[prefix]
+		if frrConfigured(configured, advertise) {
+			continue
+		}
+		log.Warnf("[FRR] network [%s] has been removed from FRR, restoring it", prefix)
+		if _, err = f.vtysh(append([]string{"configure terminal"}, advertise...)...); err != nil {
+			return restored, fmt.Errorf("unable to restore [%s]: %w", prefix, err)
+		}
+		restored = append(restored, prefix)
+	}
+	return restored, nil
+}
+
+// frrConfigured checks that the network and route-map commands of a host are in the running configuration
+func frrConfigured(configured map[string]bool, commands []string) bool {
+	for _, command := range commands {
+		if (strings.HasPrefix(command, "network ") || strings.HasPrefix(command, "route-map ")) && !configured[command] {
+			return false
+		}
+	}
+	return true
+}
+
+// frrSummary is the output of "show bgp summary json", keyed by the address family
+type frrSummary map[string]struct {
+	Peers map[string]struct {
+		State  string `json:"state"`
+		PfxSnt uint64 `json:"pfxSnt"`
+	} `json:"peers"`
+}
+
+// PeerStatus returns the health of the session with every peer that is configured in FRR, sorted by address
+func (f *FRR) PeerStatus() ([]PeerStatus, error) {
+	out, err := f.vtysh("show bgp summary json")
+	if err != nil {
+		return nil, err
+	}
+	return parseFRRSummary(out)
+}
+
+// parseFRRSummary will parse the peers from the BGP summary, a peer is listed once for each of its
+// address families so these are combined
+func parseFRRSummary(out []byte) ([]PeerStatus, error) {
+	summary := frrSummary{}
+	if err := json.Unmarshal(out, &summary); err != nil {
+		return nil, fmt.Errorf("unable to parse the FRR BGP summary: %w", err)
+	}
+
+	peers := map[string]*PeerStatus{}
+	for _, family := range summary {
+		for address, peer := range family.Peers {
+			s, exists := peers[address]
+			if !exists {
+				// FRR states are in mixed case (and may have a reason, e.g. "Idle (Admin)")
+				state, _, _ := strings.Cut(peer.State, " ")
+				s = &PeerStatus{Address: address, State: strings.ToUpper(state)}
+				peers[address] = s
+			}
+			s.AdvertisedPrefixes += peer.PfxSnt
+		}
+	}
+
+	status := make([]PeerStatus, 0, len(peers))
+	for _, s := range peers {
+		status = append(status, *s)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Address < status[j].Address })
+	return status, nil
+}
+
+// Close will withdraw every host that has been advertised, FRR itself is left running
+func (f *FRR) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var lastErr error
+	for prefix := range f.advertised {
+		ip, _, _ := net.ParseCIDR(prefix)
+		if err := f.withdraw(ip); err != nil {
+			log.Errorf("[FRR] %v", err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// frrAddressFamily returns the commands that run a command in the address family of an address
+func frrAddressFamily(as uint32, ip net.IP, command string) []string {
+	family := "address-family ipv4 unicast"
+	if ip.To4() == nil {
+		family = "address-family ipv6 unicast"
+	}
+	return []string{fmt.Sprintf("router bgp %d", as), family, command, "exit-address-family", "exit"}
+}
+
+// frrRouteMapName returns the name of the route-map that holds the attributes of a host
+func frrRouteMapName(ip net.IP) string {
+	return "kube-vip-" + strings.NewReplacer(".", "-", ":", "-").Replace(ip.String())
+}
+
+// frrSetCommands returns the route-map set commands for the optional attributes of a path
+func frrSetCommands(as uint32, attrs *PathAttributes) []string {
+	if attrs == nil {
+		return nil
+	}
+
+	var commands []string
+	if len(attrs.Communities) != 0 {
+		communities := make([]string, 0, len(attrs.Communities))
+		for _, c := range attrs.Communities {
+			communities = append(communities, fmt.Sprintf("%d:%d", c>>16, c&0xffff))
+		}
+		commands = append(commands, "set community "+strings.Join(communities, " ")+" additive")
+	}
+	if len(attrs.LargeCommunities) != 0 {
+		communities := make([]string, 0, len(attrs.LargeCommunities))
+		for _, c := range attrs.LargeCommunities {
+			communities = append(communities, fmt.Sprintf("%d:%d:%d", c.GlobalAdmin, c.LocalData1, c.LocalData2))
+		}
+		commands = append(commands, "set large-community "+strings.Join(communities, " ")+" additive")
+	}
+	if attrs.ASPathPrepend != 0 {
+		prepend := strings.TrimSpace(strings.Repeat(fmt.Sprintf("%d ", as), int(attrs.ASPathPrepend)))
+		commands = append(commands, "set as-path prepend "+prepend)
+	}
+	if attrs.MED != nil {
+		commands = append(commands, fmt.Sprintf("set metric %d", *attrs.MED))
+	}
+	if attrs.LocalPref != nil {
+		commands = append(commands, fmt.Sprintf("set local-preference %d", *attrs.LocalPref))
+	}
+	return commands
+}
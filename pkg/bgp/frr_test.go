@@ -0,0 +1,124 @@
+package bgp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestFRRAddHostWithAttributes(t *testing.T) {
+	var got [][]string
+	f := &FRR{
+		c: &Config{AS: 65000},
+		vtysh: func(commands ...string) ([]byte, error) {
+			got = append(got, commands)
+			return nil, nil
+		},
+		advertised: map[string]string{},
+		networks:   map[string][]string{},
+	}
+
+	med := uint32(100)
+	attrs := &PathAttributes{Communities: []uint32{65000<<16 | 100}, ASPathPrepend: 2, MED: &med}
+	if err := f.AddHostWithAttributes("10.0.0.1/32", attrs); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.DelHost("10.0.0.1/32"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddHost("fd00::1/128"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{
+			"configure terminal",
+			"route-map kube-vip-10-0-0-1 permit 10",
+			"set community 65000:100 additive",
+			"set as-path prepend 65000 65000",
+			"set metric 100",
+			"exit",
+			"router bgp 65000", "address-family ipv4 unicast", "network 10.0.0.1/32 route-map kube-vip-10-0-0-1", "exit-address-family", "exit",
+		},
+		{
+			"configure terminal",
+			"router bgp 65000", "address-family ipv4 unicast", "no network 10.0.0.1/32", "exit-address-family", "exit",
+			"no route-map kube-vip-10-0-0-1",
+		},
+		{
+			"configure terminal",
+			"router bgp 65000", "address-family ipv6 unicast", "network fd00::1/128", "exit-address-family", "exit",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("vtysh commands = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(f.advertised, map[string]string{"fd00::1/128": ""}) {
+		t.Errorf("advertised = %v", f.advertised)
+	}
+}
+
+func TestParseFRRSummary(t *testing.T) {
+	out := []byte(`{
+  "ipv4Unicast": {"peers": {"10.0.0.1": {"state": "Established", "pfxSnt": 2}, "10.0.0.2": {"state": "Idle (Admin)", "pfxSnt": 0}}},
+  "ipv6Unicast": {"peers": {"10.0.0.1": {"state": "Established", "pfxSnt": 1}}}
+}`)
+	got, err := parseFRRSummary(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PeerStatus{
+		{Address: "10.0.0.1", State: "ESTABLISHED", AdvertisedPrefixes: 3},
+		{Address: "10.0.0.2", State: "IDLE"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFRRSummary() = %v, want %v", got, want)
+	}
+	if !got[0].Established() || got[1].Established() {
+		t.Errorf("unexpected established state %v", got)
+	}
+}
+
+func TestFRRRouteMapName(t *testing.T) {
+	if got := frrRouteMapName(net.ParseIP("fd00::1")); got != "kube-vip-fd00--1" {
+		t.Errorf("frrRouteMapName() = %s", got)
+	}
+}
+
+func TestFRRReconcileHosts(t *testing.T) {
+	running := "router bgp 65000\n address-family ipv4 unicast\n  network 10.0.0.1/32\n exit-address-family\nexit\n"
+	var restored [][]string
+	f := &FRR{
+		c: &Config{AS: 65000},
+		vtysh: func(commands ...string) ([]byte, error) {
+			if len(commands) == 1 && commands[0] == "show running-config" {
+				return []byte(running), nil
+			}
+			restored = append(restored, commands)
+			return nil, nil
+		},
+		advertised: map[string]string{},
+		networks:   map[string][]string{},
+	}
+	for _, addr := range []string{"10.0.0.1/32", "10.0.0.2/32"} {
+		if err := f.AddHost(addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	restored = nil
+
+	got, err := f.ReconcileHosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"10.0.0.2/32"}) {
+		t.Errorf("ReconcileHosts() = %v", got)
+	}
+	want := [][]string{{
+		"configure terminal",
+		"router bgp 65000", "address-family ipv4 unicast", "network 10.0.0.2/32", "exit-address-family", "exit",
+	}}
+	if !reflect.DeepEqual(restored, want) {
+		t.Errorf("vtysh commands = %v, want %v", restored, want)
+	}
+}
@@ -2,20 +2,112 @@ package bgp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 
 	api "github.com/osrg/gobgp/v3/api"
+	log "github.com/sirupsen/logrus"
 )
 
+// ErrMaxPrefixes is returned when a host isn't advertised as the maximum number of prefixes are already advertised
+var ErrMaxPrefixes = errors.New("maximum number of advertised prefixes reached")
+
+// SetMaxPrefixesHandler sets a function that is called with the address of any host that isn't
+// advertised because the maximum number of prefixes are already advertised
+func (b *Server) SetMaxPrefixesHandler(fn func(addr string)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.maxPrefixesHandler = fn
+}
+
+// newPrefixes returns the number of prefixes that advertising a host would add
+func (b *Server) newPrefixes(ip net.IP) int {
+	if b.advertised[ip.String()] {
+		return 0
+	}
+	a := b.findAggregate(ip)
+	if a == nil {
+		return 1
+	}
+	if a.hosts[ip.String()] {
+		return 0
+	}
+
+	count := 0
+	if len(a.hosts) == 0 {
+		count++
+	}
+	if !b.c.SuppressAggregated {
+		count++
+	}
+	return count
+}
+
+// advertisedPrefixes returns the number of host and aggregate prefixes that are advertised
+func (b *Server) advertisedPrefixes() int {
+	count := len(b.advertised)
+	for _, a := range b.aggregates {
+		if len(a.hosts) != 0 {
+			count++
+		}
+	}
+	return count
+}
+
 // AddHost will update peers of a host
 func (b *Server) AddHost(addr string) (err error) {
+	return b.AddHostWithAttributes(addr, nil)
+}
+
+// AddHostWithAttributes will update peers of a host, the path will carry any of the
+// optional attributes (such as communities)
+func (b *Server) AddHostWithAttributes(addr string, attrs *PathAttributes) (err error) {
 	ip, _, err := net.ParseCIDR(addr)
 	if err != nil {
 		return err
 	}
 
-	p := b.getPath(ip)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// Protect upstream routers from an accidental flood of prefixes (e.g. a misconfigured pool)
+	if b.c.MaxPrefixes != 0 && b.advertisedPrefixes()+b.newPrefixes(ip) > int(b.c.MaxPrefixes) {
+		log.Errorf("[BGP] not advertising [%s], the maximum of [%d] prefixes are advertised", addr, b.c.MaxPrefixes)
+		if b.maxPrefixesHandler != nil {
+			b.maxPrefixesHandler(addr)
+		}
+		return fmt.Errorf("unable to advertise [%s]: %w", addr, ErrMaxPrefixes)
+	}
+
+	suppress, err := b.addToAggregate(ip)
+	if err != nil {
+		return err
+	}
+	if suppress {
+		return nil
+	}
+
+	// The export policy is updated before the host is advertised, so it is never sent to the other peers
+	var peers []string
+	if attrs != nil {
+		peers = attrs.Peers
+	}
+	if b.setPeerSubset(ip, peers) {
+		if err = b.setExportPolicies(); err != nil {
+			return err
+		}
+	}
+
+	// A newly advertised host is ramped from a worse MED, so traffic moves gradually to this node
+	if b.c.MEDRamp.Start != 0 && !b.advertised[ip.String()] {
+		attrs = b.startRamp(ip, attrs)
+	} else {
+		// A host that is re-advertised (e.g. its attributes have changed) skips the rest of its ramp
+		b.stopRamp(ip)
+	}
+
+	p := b.getPath(ip, attrs)
 	if p == nil {
 		return fmt.Errorf("failed to get path for %v", ip)
 	}
@@ -25,8 +117,10 @@ func (b *Server) AddHost(addr string) (err error) {
 	})
 
 	if err != nil {
+		b.stopRamp(ip)
 		return err
 	}
+	b.advertised[ip.String()] = true
 
 	return
 }
@@ -37,12 +131,33 @@ func (b *Server) DelHost(addr string) (err error) {
 	if err != nil {
 		return err
 	}
-	p := b.getPath(ip)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	suppress, err := b.delFromAggregate(ip)
+	if err != nil {
+		return err
+	}
+	if suppress {
+		return nil
+	}
+
+	p := b.getPath(ip, nil)
 	if p == nil {
 		return
 	}
+	b.stopRamp(ip)
+	delete(b.advertised, ip.String())
 
-	return b.s.DeletePath(context.Background(), &api.DeletePathRequest{
+	if err = b.s.DeletePath(context.Background(), &api.DeletePathRequest{
 		Path: p,
-	})
+	}); err != nil {
+		return err
+	}
+
+	if b.setPeerSubset(ip, nil) {
+		return b.setExportPolicies()
+	}
+	return nil
 }
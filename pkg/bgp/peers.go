@@ -4,19 +4,254 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/golang/protobuf/ptypes" //nolint
 	"github.com/golang/protobuf/ptypes/any"
 	api "github.com/osrg/gobgp/v3/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultRestartTime is the graceful restart time (in seconds) if one isn't configured
+	defaultRestartTime = 120
+	// defaultLongLivedRestartTime is the long-lived graceful restart time (in seconds) if one isn't configured
+	defaultLongLivedRestartTime = 3600
 )
 
 // AddPeer will add peers to the BGP configuration
 func (b *Server) AddPeer(peer Peer) (err error) {
+	p, err := b.peerConfig(peer)
+	if err != nil {
+		return err
+	}
+
+	if err = b.s.AddPeer(context.Background(), &api.AddPeerRequest{
+		Peer: p,
+	}); err != nil {
+		return err
+	}
+
+	if peer.BFD.Enabled && peer.Interface != "" {
+		log.Warnf("[BFD] BFD isn't supported for unnumbered peer [%s]", peer.Interface)
+	} else if peer.BFD.Enabled {
+		// The peer is removed again without its BFD session, so that adding it is retried along with its session
+		if err = b.addSession(peer); err != nil {
+			if delErr := b.s.DeletePeer(context.Background(), &api.DeletePeerRequest{Address: p.Conf.NeighborAddress}); delErr != nil {
+				log.Errorf("[BGP] unable to remove peer [%s] without its BFD session: %v", peer.id(), delErr)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addSession adds the BFD session of a peer, starting BFD with the first session
+func (b *Server) addSession(peer Peer) (err error) {
+	if b.bfd == nil {
+		b.bfd, err = newBFDManager(b.c.SourceIP, b.bfdStateChange)
+		if err != nil {
+			return err
+		}
+	}
+	return b.bfd.addSession(peer.Address, peer.BFD)
+}
+
+// hasPeer returns whether the gobgp server has the peer
+func (b *Server) hasPeer(peer Peer) (bool, error) {
+	address, err := b.peerAddress(peer)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	if err = b.s.ListPeer(context.Background(), &api.ListPeerRequest{Address: address}, func(*api.Peer) {
+		found = true
+	}); err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+// UpdatePeer will update the configuration of an existing peer, gobgp will only
+// reset the session if a change requires it (such as a new password)
+func (b *Server) UpdatePeer(peer Peer) (err error) {
+	p, err := b.peerConfig(peer)
+	if err != nil {
+		return err
+	}
+
+	if _, err = b.s.UpdatePeer(context.Background(), &api.UpdatePeerRequest{
+		Peer: p,
+	}); err != nil {
+		return err
+	}
+	return b.updateSession(peer)
+}
+
+// updateSession recreates the BFD session of a peer whose BFD configuration has changed, adds the session of a peer
+// that now uses BFD and removes the session of one that no longer does
+func (b *Server) updateSession(peer Peer) error {
+	enabled := peer.BFD.Enabled && peer.Interface == ""
+	if enabled && b.bfd != nil && b.bfd.hasSession(peer.Address, peer.BFD) {
+		return nil
+	}
+	if b.bfd != nil && b.bfd.removeSession(peer.Address) && !enabled {
+		log.Infof("[BFD] peer [%s] no longer uses BFD, its session has been removed", peer.Address)
+		b.bfdRemoved(peer.Address)
+	}
+	if !enabled {
+		return nil
+	}
+	return b.addSession(peer)
+}
+
+// DelPeer will remove a peer from the BGP configuration
+func (b *Server) DelPeer(peer Peer) (err error) {
+	address, err := b.peerAddress(peer)
+	if err != nil {
+		return err
+	}
+
+	if b.bfd != nil {
+		b.bfd.removeSession(address)
+	}
+	delete(b.unnumbered, peer.Interface)
+
+	b.sourcesMutex.Lock()
+	delete(b.sources, peer.Address)
+	b.sourcesMutex.Unlock()
+
+	b.backoffMutex.Lock()
+	delete(b.backoffs, address)
+	b.backoffMutex.Unlock()
+
+	return b.s.DeletePeer(context.Background(), &api.DeletePeerRequest{
+		Address: address,
+	})
+}
+
+// SyncPeers will add, update and remove peers so that the running server matches the
+// list of peers, unchanged peers are left alone so their sessions aren't reset
+func (b *Server) SyncPeers(peers []Peer) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// The peers are recorded as each of them is applied, so that a failure part way through leaves the peers that
+	// the server has, and the next sync carries on from there rather than adding them again
+	applied := make(map[string]Peer, len(b.c.Peers))
+	for _, p := range b.c.Peers {
+		applied[p.id()] = p
+	}
+	err := b.applyPeers(peers, applied)
+	b.c.Peers = appliedPeers(peers, b.c.Peers, applied)
+	if err != nil {
+		return err
+	}
+
+	// Unnumbered peers may have a new address, so the export policies need rebuilding
+	return b.setExportPolicies()
+}
+
+// RotatePasswords updates the peers whose password has changed, the password of each peer is returned by the function
+// (which returns false to keep the password of the peer). Peers with an unchanged password are left alone so that
+// their sessions aren't reset.
+func (b *Server) RotatePasswords(password func(Peer) (string, bool)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for x := range b.c.Peers {
+		peer := b.c.Peers[x]
+		rotated, ok := password(peer)
+		if !ok || rotated == peer.Password {
+			continue
+		}
+		peer.Password = rotated
+		log.Infof("[BGP] password for peer [%s] has changed, updating", peer.id())
+		if err := b.UpdatePeer(peer); err != nil {
+			log.Errorf("[BGP] unable to update peer [%s] : %v", peer.id(), err)
+			continue
+		}
+		b.c.Peers[x] = peer
+	}
+}
+
+// applyPeers adds, updates and removes the peers of the server, recording those that have been applied
+func (b *Server) applyPeers(peers []Peer, applied map[string]Peer) error {
+	desired := make(map[string]bool, len(peers))
+	for _, p := range peers {
+		desired[p.id()] = true
+		current, found := applied[p.id()]
+		switch {
+		case !found:
+			// A peer that the server already has (e.g. it wasn't recorded by a sync that failed) is updated
+			exists, err := b.hasPeer(p)
+			switch {
+			case err != nil:
+			case exists:
+				log.Infof("[BGP] peer [%s] already exists, updating it", p.id())
+				err = b.UpdatePeer(p)
+			default:
+				log.Infof("[BGP] adding peer [%s]", p.id())
+				err = b.AddPeer(p)
+			}
+			if err != nil {
+				return fmt.Errorf("unable to add peer [%s]: %w", p.id(), err)
+			}
+		case !reflect.DeepEqual(current, p):
+			log.Infof("[BGP] updating peer [%s]", p.id())
+			if err := b.UpdatePeer(p); err != nil {
+				return fmt.Errorf("unable to update peer [%s]: %w", p.id(), err)
+			}
+		default:
+			continue
+		}
+		applied[p.id()] = p
+	}
+
+	for id, p := range applied {
+		if desired[id] {
+			continue
+		}
+		log.Infof("[BGP] removing peer [%s]", id)
+		if err := b.DelPeer(p); err != nil {
+			return fmt.Errorf("unable to remove peer [%s]: %w", id, err)
+		}
+		delete(applied, id)
+	}
+	return nil
+}
+
+// appliedPeers returns the peers that have been applied, in the order of the desired peers followed by the previous
+// peers that are yet to be removed
+func appliedPeers(desired, previous []Peer, applied map[string]Peer) []Peer {
+	peers := make([]Peer, 0, len(applied))
+	seen := make(map[string]bool, len(applied))
+	for _, list := range [][]Peer{desired, previous} {
+		for _, p := range list {
+			if current, found := applied[p.id()]; found && !seen[p.id()] {
+				seen[p.id()] = true
+				peers = append(peers, current)
+			}
+		}
+	}
+	return peers
+}
+
+// peerConfig builds the gobgp peer from the peer configuration
+func (b *Server) peerConfig(peer Peer) (*api.Peer, error) {
+	address, err := b.peerAddress(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	holdTime, keepaliveInterval := b.peerTimers(peer)
+
 	p := &api.Peer{
 		Conf: &api.PeerConf{
-			NeighborAddress: peer.Address,
+			NeighborAddress: address,
 			PeerAsn:         peer.AS,
 			AuthPassword:    peer.Password,
 		},
@@ -24,8 +259,8 @@ func (b *Server) AddPeer(peer Peer) (err error) {
 		Timers: &api.Timers{
 			Config: &api.TimersConfig{
 				ConnectRetry:      10,
-				HoldTime:          b.c.HoldTime,
-				KeepaliveInterval: b.c.KeepaliveInterval,
+				HoldTime:          holdTime,
+				KeepaliveInterval: keepaliveInterval,
 			},
 		},
 
@@ -37,25 +272,108 @@ func (b *Server) AddPeer(peer Peer) (err error) {
 
 		Transport: &api.Transport{
 			MtuDiscovery:  true,
-			RemoteAddress: peer.Address,
+			RemoteAddress: address,
 			RemotePort:    uint32(179),
 		},
 	}
 
-	if b.c.SourceIP != "" {
+	switch {
+	case peer.Interface != "":
+		// Unnumbered peers are always reached through their own interface
+		p.Transport.BindInterface = peer.Interface
+	case b.c.SourceIP != "":
 		p.Transport.LocalAddress = b.c.SourceIP
+	case b.c.SourceIF != "":
+		p.Transport.BindInterface = b.c.SourceIF
+	case b.c.SourceAuto:
+		p.Transport.LocalAddress = b.sourceAddress(peer)
 	}
 
-	if b.c.SourceIF != "" {
-		p.Transport.BindInterface = b.c.SourceIF
+	p.AfiSafis, p.GracefulRestart = b.peerAfiSafis()
+
+	return p, nil
+}
+
+// peerAfiSafis returns the address families and graceful restart capabilities that are negotiated with
+// every peer (including dynamic neighbors)
+func (b *Server) peerAfiSafis() (afiSafis []*api.AfiSafi, gracefulRestart *api.GracefulRestart) {
+	for _, family := range peerFamilies() {
+		afiSafi := &api.AfiSafi{
+			Config: &api.AfiSafiConfig{
+				Family:  family,
+				Enabled: true,
+			},
+		}
+		if ap := b.c.AddPath; ap.Receive || ap.SendMax != 0 {
+			afiSafi.AddPaths = &api.AddPaths{
+				Config: &api.AddPathsConfig{
+					Receive: ap.Receive,
+					SendMax: ap.SendMax,
+				},
+			}
+		}
+		afiSafis = append(afiSafis, afiSafi)
 	}
 
-	return b.s.AddPeer(context.Background(), &api.AddPeerRequest{
-		Peer: p,
-	})
+	if gr := b.c.GracefulRestart; gr.Enabled {
+		restartTime := gr.RestartTime
+		if restartTime == 0 {
+			restartTime = defaultRestartTime
+		}
+		llgrRestartTime := gr.LongLivedRestartTime
+		if llgrRestartTime == 0 {
+			llgrRestartTime = defaultLongLivedRestartTime
+		}
+
+		// kube-vip doesn't persist any routing state, so from the point of view of a peer
+		// the session is always being re-established by a restarting speaker
+		gracefulRestart = &api.GracefulRestart{
+			Enabled:             true,
+			RestartTime:         restartTime,
+			NotificationEnabled: true,
+			LonglivedEnabled:    gr.LongLived,
+			LocalRestarting:     true,
+		}
+
+		for _, afiSafi := range afiSafis {
+			afiSafi.MpGracefulRestart = &api.MpGracefulRestart{
+				Config: &api.MpGracefulRestartConfig{
+					Enabled: true,
+				},
+			}
+			if gr.LongLived {
+				afiSafi.LongLivedGracefulRestart = &api.LongLivedGracefulRestart{
+					Config: &api.LongLivedGracefulRestartConfig{
+						Enabled:     true,
+						RestartTime: llgrRestartTime,
+					},
+				}
+			}
+		}
+	}
+
+	return afiSafis, gracefulRestart
+}
+
+// peerFamilies returns the address families that are negotiated with every peer, both
+// IPv4 and IPv6 unicast are enabled regardless of the transport so that dual-stack
+// services can be advertised over a single session
+func peerFamilies() []*api.Family {
+	return []*api.Family{
+		{Afi: api.Family_AFI_IP, Safi: api.Family_SAFI_UNICAST},
+		{Afi: api.Family_AFI_IP6, Safi: api.Family_SAFI_UNICAST},
+	}
+}
+
+func (b *Server) getPath(ip net.IP, attrs *PathAttributes) (path *api.Path) {
+	if ip.To4() == nil {
+		return b.getPrefixPath(ip, 128, attrs)
+	}
+	return b.getPrefixPath(ip, 32, attrs)
 }
 
-func (b *Server) getPath(ip net.IP) (path *api.Path) {
+// getPrefixPath returns the path for a prefix, this is used directly for aggregate prefixes
+func (b *Server) getPrefixPath(ip net.IP, prefixLen uint32, attrs *PathAttributes) (path *api.Path) {
 	isV6 := ip.To4() == nil
 
 	//nolint
@@ -67,12 +385,12 @@ func (b *Server) getPath(ip net.IP) (path *api.Path) {
 		//nolint
 		nlri, _ := ptypes.MarshalAny(&api.IPAddressPrefix{
 			Prefix:    ip.String(),
-			PrefixLen: 32,
+			PrefixLen: prefixLen,
 		})
 
 		//nolint
 		nhAttr, _ := ptypes.MarshalAny(&api.NextHopAttribute{
-			NextHop: "0.0.0.0", // gobgp will fill this
+			NextHop: nextHop(b.c.IPv4NextHop, "0.0.0.0"), // gobgp will fill this if unspecified
 		})
 
 		path = &api.Path{
@@ -81,13 +399,13 @@ func (b *Server) getPath(ip net.IP) (path *api.Path) {
 				Safi: api.Family_SAFI_UNICAST,
 			},
 			Nlri:   nlri,
-			Pattrs: []*any.Any{originAttr, nhAttr},
+			Pattrs: append([]*any.Any{originAttr, nhAttr}, attrs.marshal(b.c.AS)...),
 		}
 	} else {
 		//nolint
 		nlri, _ := ptypes.MarshalAny(&api.IPAddressPrefix{
 			Prefix:    ip.String(),
-			PrefixLen: 128,
+			PrefixLen: prefixLen,
 		})
 
 		v6Family := &api.Family{
@@ -98,14 +416,14 @@ func (b *Server) getPath(ip net.IP) (path *api.Path) {
 		//nolint
 		mpAttr, _ := ptypes.MarshalAny(&api.MpReachNLRIAttribute{
 			Family:   v6Family,
-			NextHops: []string{"::"}, // gobgp will fill this
+			NextHops: []string{nextHop(b.c.IPv6NextHop, "::")}, // gobgp will fill this if unspecified
 			Nlris:    []*any.Any{nlri},
 		})
 
 		path = &api.Path{
 			Family: v6Family,
 			Nlri:   nlri,
-			Pattrs: []*any.Any{originAttr, mpAttr},
+			Pattrs: append([]*any.Any{originAttr, mpAttr}, attrs.marshal(b.c.AS)...),
 		}
 	}
 	return
@@ -162,14 +480,55 @@ func ParseBGPPeerConfig(config string) (bgpPeers []Peer, err error) {
 			}
 		}
 
+		bfd := false
+		if len(peer) >= 5 {
+			bfd, err = strconv.ParseBool(peer[4])
+			if err != nil {
+				return nil, fmt.Errorf("BGP BFD format error (true/false) [%s]", peer[4])
+			}
+		}
+
+		// The next-hop is the last field, so that an IPv6 next-hop can contain colons
+		nextHop := ""
+		if len(peer) >= 6 {
+			nextHop = strings.Join(peer[5:], ":")
+			if nextHop != NextHopSelf && net.ParseIP(nextHop) == nil {
+				return nil, fmt.Errorf("BGP next-hop format error (self or address) [%s]", nextHop)
+			}
+		}
+
+		// A peer that isn't an address is an interface name, for unnumbered peering
+		var iface string
+		if net.ParseIP(address) == nil {
+			if _, err := net.InterfaceByName(address); err != nil {
+				return nil, fmt.Errorf("BGP Peer is neither an address nor an interface [%s]: %w", address, err)
+			}
+			iface, address = address, ""
+		}
+
 		peerConfig := Peer{
-			Address:  address,
-			AS:       uint32(ASNumber),
-			Password: password,
-			MultiHop: multiHop,
+			Address:   address,
+			Interface: iface,
+			AS:        uint32(ASNumber),
+			Password:  password,
+			MultiHop:  multiHop,
+			BFD:       BFDConfig{Enabled: bfd},
+			NextHop:   nextHop,
 		}
 
 		bgpPeers = append(bgpPeers, peerConfig)
 	}
 	return
 }
+
+// NextHopSelf will set the next-hop advertised to a peer to the local address of the session
+const NextHopSelf = "self"
+
+// nextHop returns the configured next-hop, or the unspecified address that gobgp will
+// replace with the local address of the session
+func nextHop(configured, unspecified string) string {
+	if configured != "" {
+		return configured
+	}
+	return unspecified
+}
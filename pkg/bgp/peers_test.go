@@ -0,0 +1,136 @@
+package bgp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	api "github.com/osrg/gobgp/v3/api"
+)
+
+// newTestServer starts a BGP server (that doesn't listen for connections) with the configuration
+func newTestServer(t *testing.T, c *Config) *Server {
+	t.Helper()
+	c.AS, c.RouterID = 65000, "192.0.2.250"
+	b, err := NewBGPServer(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	return b
+}
+
+// serverPeer returns the configuration of a peer in the gobgp server
+func serverPeer(t *testing.T, b *Server, address string) *api.Peer {
+	t.Helper()
+	var peer *api.Peer
+	if err := b.s.ListPeer(context.Background(), &api.ListPeerRequest{Address: address}, func(p *api.Peer) { peer = p }); err != nil {
+		t.Fatal(err)
+	}
+	return peer
+}
+
+func TestAppliedPeers(t *testing.T) {
+	a, b, c, d := Peer{Address: "10.0.0.1"}, Peer{Address: "10.0.0.2"}, Peer{Address: "10.0.0.3"}, Peer{Address: "10.0.0.4"}
+	updated := Peer{Address: "10.0.0.1", AS: 65001}
+
+	tests := []struct {
+		name     string
+		desired  []Peer
+		previous []Peer
+		applied  []Peer
+		want     []Peer
+	}{
+		{"every peer applied", []Peer{updated, b}, []Peer{a, d}, []Peer{updated, b}, []Peer{updated, b}},
+		{"adding a peer failed", []Peer{a, b, c}, []Peer{a}, []Peer{a, b}, []Peer{a, b}},
+		{"updating a peer failed", []Peer{updated, b}, []Peer{a}, []Peer{a}, []Peer{a}},
+		{"removing a peer failed", []Peer{a}, []Peer{a, d}, []Peer{a, d}, []Peer{a, d}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied := make(map[string]Peer, len(tt.applied))
+			for _, p := range tt.applied {
+				applied[p.id()] = p
+			}
+			if got := appliedPeers(tt.desired, tt.previous, applied); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("appliedPeers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotatePasswords(t *testing.T) {
+	rotated := Peer{Address: "192.0.2.1", AS: 65001, Password: "old", PasswordSecret: "kube-system/bgp/password"}
+	unchanged := Peer{Address: "192.0.2.2", AS: 65001, Password: "static"}
+	b := newTestServer(t, &Config{Peers: []Peer{rotated, unchanged}})
+
+	b.RotatePasswords(func(p Peer) (string, bool) {
+		return "new", p.PasswordSecret != ""
+	})
+	if b.c.Peers[0].Password != "new" || b.c.Peers[1].Password != "static" {
+		t.Errorf("peers = %+v, want only the peer of the secret to be rotated", b.c.Peers)
+	}
+	if got := serverPeer(t, b, "192.0.2.1").GetConf().GetAuthPassword(); got != "new" {
+		t.Errorf("password of the gobgp peer = %q, want %q", got, "new")
+	}
+}
+
+func TestSyncPeersBFDFailure(t *testing.T) {
+	// The BFD session can't be added, as the source address isn't on the host
+	peer := Peer{Address: "192.0.2.1", AS: 65001}
+	bfd := Peer{Address: "192.0.2.2", AS: 65001, BFD: BFDConfig{Enabled: true}}
+	b := newTestServer(t, &Config{SourceIP: "192.0.2.250", Peers: []Peer{peer}})
+
+	for i := 0; i < 2; i++ {
+		if err := b.SyncPeers([]Peer{peer, bfd}); err == nil {
+			t.Fatal("SyncPeers() should fail to add the BFD session")
+		}
+		if exists, err := b.hasPeer(bfd); err != nil || exists {
+			t.Errorf("hasPeer() = %t, %v, the peer should be removed without its BFD session", exists, err)
+		}
+		if !reflect.DeepEqual(b.c.Peers, []Peer{peer}) {
+			t.Errorf("peers = %+v, the peer without its BFD session shouldn't be applied", b.c.Peers)
+		}
+	}
+}
+
+func TestUpdatePeerBFD(t *testing.T) {
+	peer := Peer{Address: "192.0.2.1", AS: 65001}
+	b := newTestServer(t, &Config{Peers: []Peer{peer}})
+
+	steps := []struct {
+		name string
+		bfd  BFDConfig
+	}{
+		{"enabled", BFDConfig{Enabled: true}},
+		{"new interval", BFDConfig{Enabled: true, Interval: 100, Multiplier: 5}},
+		{"disabled", BFDConfig{}},
+	}
+	for _, step := range steps {
+		peer.BFD = step.bfd
+		if err := b.SyncPeers([]Peer{peer}); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		hasSession := b.bfd != nil && b.bfd.hasSession(peer.Address, peer.BFD)
+		if hasSession != step.bfd.Enabled {
+			t.Errorf("%s: the peer should have a session %t", step.name, step.bfd.Enabled)
+		}
+		if _, exists := b.bfd.sessionState(peer.Address); exists != step.bfd.Enabled {
+			t.Errorf("%s: the peer has a session %t, want %t", step.name, exists, step.bfd.Enabled)
+		}
+	}
+}
+
+func TestParseBGPPeerConfigUnnumbered(t *testing.T) {
+	peers, err := ParseBGPPeerConfig("lo:65001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || peers[0].Interface != "lo" || peers[0].Address != "" {
+		t.Errorf("ParseBGPPeerConfig() = %+v, want an unnumbered peer on [lo]", peers)
+	}
+
+	if _, err := ParseBGPPeerConfig("kube-vip-missing0:65001"); err == nil {
+		t.Error("ParseBGPPeerConfig() of a peer that is neither an address nor an interface should have failed")
+	}
+}
@@ -0,0 +1,315 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	api "github.com/osrg/gobgp/v3/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// exportPolicyName is the name of the global export policy that holds the per-peer statements
+const exportPolicyName = "kube-vip-export"
+
+// ExportPolicy restricts the VIPs that are advertised to a peer, a VIP is advertised if it is within
+// any of the prefixes or carries any of the communities (see the kube-vip.io/bgp-communities annotation).
+// The services aren't matched by their labels, the communities of their annotation select them instead.
+type ExportPolicy struct {
+	Prefixes         []string
+	Communities      []string
+	LargeCommunities []string
+}
+
+// setExportPolicies will (re)build the export policy from the per-peer configuration (export
+// policies, next-hops and the hosts that are only advertised to a subset of peers), gobgp only
+// supports per-peer policies for route server clients, so a single global export policy is used
+// where each peer has its own statements (matched by a neighbor set)
+func (b *Server) setExportPolicies() error {
+	nextHops := map[string]string{}
+	for _, p := range b.c.Peers {
+		if p.NextHop != "" {
+			nextHops[p.id()] = p.NextHop
+		}
+	}
+	denied := b.deniedHosts()
+	if len(b.c.ExportPolicies) == 0 && len(nextHops) == 0 && len(denied) == 0 && !b.draining && !b.exportPolicies {
+		return nil
+	}
+	// Once set the policy is always rebuilt, so that removed peers have their statements removed
+	b.exportPolicies = true
+
+	// Sort the peers so that the policy is built in the same order every time
+	ids := make([]string, 0, len(b.c.ExportPolicies)+len(nextHops))
+	for id := range b.c.ExportPolicies {
+		ids = append(ids, id)
+	}
+	for id := range nextHops {
+		if _, exists := b.c.ExportPolicies[id]; !exists {
+			ids = append(ids, id)
+		}
+	}
+	for id := range denied {
+		_, hasPolicy := b.c.ExportPolicies[id]
+		if _, hasNextHop := nextHops[id]; !hasPolicy && !hasNextHop {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var definedSets []*api.DefinedSet
+	policy := &api.Policy{Name: exportPolicyName}
+	if b.draining {
+		policy.Statements = append(policy.Statements, b.drainStatement())
+	}
+	for _, id := range ids {
+		address := id
+		if net.ParseIP(id) == nil {
+			// Unnumbered peers can only be matched once their link-local address is known
+			var exists bool
+			if address, exists = b.unnumbered[id]; !exists {
+				continue
+			}
+		}
+		sets, statements := exportStatements(id, address, nextHops[id], b.c.ExportPolicies[id], denied[id])
+		definedSets = append(definedSets, sets...)
+		policy.Statements = append(policy.Statements, statements...)
+	}
+
+	if err := b.s.SetPolicies(context.Background(), &api.SetPoliciesRequest{
+		DefinedSets: definedSets,
+		Policies:    []*api.Policy{policy},
+	}); err != nil {
+		return fmt.Errorf("unable to set export policies: %w", err)
+	}
+
+	if err := b.s.SetPolicyAssignment(context.Background(), &api.SetPolicyAssignmentRequest{
+		Assignment: &api.PolicyAssignment{
+			Name:          "global",
+			Direction:     api.PolicyDirection_EXPORT,
+			Policies:      []*api.Policy{policy},
+			DefaultAction: api.RouteAction_ACCEPT,
+		},
+	}); err != nil {
+		return fmt.Errorf("unable to assign export policies: %w", err)
+	}
+
+	// Re-send the advertised VIPs to every peer so that the new policy takes effect
+	return b.s.ResetPeer(context.Background(), &api.ResetPeerRequest{
+		Soft:      true,
+		Direction: api.ResetPeerRequest_OUT,
+	})
+}
+
+// drainStatement returns the statement that is used whilst the node is draining, this will either
+// withdraw every VIP or (if DrainPrepend is set) de-preference them by prepending the local AS
+func (b *Server) drainStatement() *api.Statement {
+	statement := &api.Statement{
+		Name:       "drain",
+		Conditions: &api.Conditions{},
+		Actions:    &api.Actions{RouteAction: api.RouteAction_REJECT},
+	}
+	if b.c.DrainPrepend != 0 {
+		statement.Actions = &api.Actions{
+			AsPrepend: &api.AsPrependAction{
+				Asn:    b.c.AS,
+				Repeat: b.c.DrainPrepend,
+			},
+		}
+	}
+	return statement
+}
+
+// Drain will withdraw (or de-preference) the advertised VIPs from every peer whilst the node is
+// draining, the VIPs are advertised normally again once draining is stopped
+func (b *Server) Drain(draining bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.draining == draining {
+		return nil
+	}
+	b.draining = draining
+	return b.setExportPolicies()
+}
+
+// exportStatements returns the statements (and the sets they use) for a peer, any denied hosts
+// are rejected, the next-hop is set and then only the VIPs matching the export policy of the peer
+// are accepted (anything else sent to that peer is rejected)
+func exportStatements(id, address, nextHop string, p ExportPolicy, denied []string) (sets []*api.DefinedSet, statements []*api.Statement) {
+	name := "export-" + id
+
+	neighborSet := &api.DefinedSet{
+		DefinedType: api.DefinedType_NEIGHBOR,
+		Name:        name,
+		List:        []string{hostPrefix(address)},
+	}
+	sets = append(sets, neighborSet)
+	neighbor := &api.MatchSet{Type: api.MatchSet_ANY, Name: neighborSet.Name}
+
+	// Hosts that are only advertised to other peers are never sent to this peer
+	if len(denied) != 0 {
+		prefixes := make([]*api.Prefix, 0, len(denied))
+		for _, host := range denied {
+			ones := uint32(32)
+			if !isIPv4Prefix(host) {
+				ones = 128
+			}
+			prefixes = append(prefixes, &api.Prefix{IpPrefix: host, MaskLengthMin: ones, MaskLengthMax: ones})
+		}
+		for _, prefixSet := range prefixSets(name+"-denied", prefixes) {
+			sets = append(sets, prefixSet)
+			statements = append(statements, &api.Statement{
+				Name: prefixSet.Name,
+				Conditions: &api.Conditions{
+					NeighborSet: neighbor,
+					PrefixSet:   &api.MatchSet{Type: api.MatchSet_ANY, Name: prefixSet.Name},
+				},
+				Actions: &api.Actions{RouteAction: api.RouteAction_REJECT},
+			})
+		}
+	}
+
+	// A statement without a route action doesn't stop the policy, so the next-hop is set on
+	// every VIP before the statements below decide if it is sent
+	if nextHop != "" {
+		action := &api.NexthopAction{Self: nextHop == NextHopSelf}
+		if !action.Self {
+			action.Address = nextHop
+		}
+		statements = append(statements, &api.Statement{
+			Name:       name + "-nexthop",
+			Conditions: &api.Conditions{NeighborSet: neighbor},
+			Actions:    &api.Actions{Nexthop: action},
+		})
+	}
+
+	if p.Prefixes == nil && p.Communities == nil && p.LargeCommunities == nil {
+		return sets, statements
+	}
+
+	accept := func(suffix string, conditions *api.Conditions) {
+		conditions.NeighborSet = neighbor
+		statements = append(statements, &api.Statement{
+			Name:       name + "-" + suffix,
+			Conditions: conditions,
+			Actions:    &api.Actions{RouteAction: api.RouteAction_ACCEPT},
+		})
+	}
+
+	if len(p.Prefixes) != 0 {
+		prefixes := make([]*api.Prefix, 0, len(p.Prefixes))
+		for _, prefix := range p.Prefixes {
+			_, cidr, err := net.ParseCIDR(prefix)
+			if err != nil {
+				log.Warnf("[BGP] ignoring invalid export prefix [%s] for peer [%s]", prefix, id)
+				continue
+			}
+			ones, bits := cidr.Mask.Size()
+			prefixes = append(prefixes, &api.Prefix{
+				IpPrefix:      cidr.String(),
+				MaskLengthMin: uint32(ones),
+				MaskLengthMax: uint32(bits),
+			})
+		}
+		for _, prefixSet := range prefixSets(name+"-prefixes", prefixes) {
+			sets = append(sets, prefixSet)
+			accept(strings.TrimPrefix(prefixSet.Name, name+"-"), &api.Conditions{PrefixSet: &api.MatchSet{Type: api.MatchSet_ANY, Name: prefixSet.Name}})
+		}
+	}
+
+	if len(p.Communities) != 0 {
+		communitySet := &api.DefinedSet{DefinedType: api.DefinedType_COMMUNITY, Name: name + "-communities", List: p.Communities}
+		sets = append(sets, communitySet)
+		accept("communities", &api.Conditions{CommunitySet: &api.MatchSet{Type: api.MatchSet_ANY, Name: communitySet.Name}})
+	}
+
+	if len(p.LargeCommunities) != 0 {
+		largeCommunitySet := &api.DefinedSet{DefinedType: api.DefinedType_LARGE_COMMUNITY, Name: name + "-large-communities", List: p.LargeCommunities}
+		sets = append(sets, largeCommunitySet)
+		accept("large-communities", &api.Conditions{LargeCommunitySet: &api.MatchSet{Type: api.MatchSet_ANY, Name: largeCommunitySet.Name}})
+	}
+
+	statements = append(statements, &api.Statement{
+		Name:       name + "-reject",
+		Conditions: &api.Conditions{NeighborSet: neighbor},
+		Actions:    &api.Actions{RouteAction: api.RouteAction_REJECT},
+	})
+	return sets, statements
+}
+
+// prefixSets returns the prefix sets that hold a list of prefixes, gobgp only allows a single
+// address family within a set so the IPv4 and IPv6 prefixes are split
+func prefixSets(name string, prefixes []*api.Prefix) (sets []*api.DefinedSet) {
+	var ipv4, ipv6 []*api.Prefix
+	for _, prefix := range prefixes {
+		if isIPv4Prefix(prefix.IpPrefix) {
+			ipv4 = append(ipv4, prefix)
+		} else {
+			ipv6 = append(ipv6, prefix)
+		}
+	}
+	if len(ipv4) != 0 {
+		sets = append(sets, &api.DefinedSet{DefinedType: api.DefinedType_PREFIX, Name: name + "-ipv4", Prefixes: ipv4})
+	}
+	if len(ipv6) != 0 {
+		sets = append(sets, &api.DefinedSet{DefinedType: api.DefinedType_PREFIX, Name: name + "-ipv6", Prefixes: ipv6})
+	}
+	return sets
+}
+
+// isIPv4Prefix returns true if a prefix (or address) is IPv4
+func isIPv4Prefix(prefix string) bool {
+	address, _, _ := strings.Cut(prefix, "/")
+	ip := net.ParseIP(address)
+	return ip != nil && ip.To4() != nil
+}
+
+// hostPrefix returns the host prefix of an address, any zone (for link-local peers) is removed
+func hostPrefix(address string) string {
+	address, _, _ = strings.Cut(address, "%")
+	if ip := net.ParseIP(address); ip != nil && ip.To4() == nil {
+		return address + "/128"
+	}
+	return address + "/32"
+}
+
+// ParseExportPolicies - take a string and parses it into the export policies of each peer, the format
+// is peer=match|match,peer=match where a peer is an address (or interface for unnumbered peers) and
+// each match is either a prefix or a community
+func ParseExportPolicies(config string) (map[string]ExportPolicy, error) {
+	policies := map[string]ExportPolicy{}
+	for _, policyStr := range strings.Split(config, ",") {
+		policyStr = strings.TrimSpace(policyStr)
+		if policyStr == "" {
+			continue
+		}
+
+		peer, matches, found := strings.Cut(policyStr, "=")
+		if !found || peer == "" || matches == "" {
+			return nil, fmt.Errorf("BGP export policy format error (peer=match|match) [%s]", policyStr)
+		}
+
+		policy := policies[peer]
+		for _, match := range strings.Split(matches, "|") {
+			if _, cidr, err := net.ParseCIDR(match); err == nil {
+				policy.Prefixes = append(policy.Prefixes, cidr.String())
+				continue
+			}
+
+			attrs, err := ParseCommunities(match)
+			if err != nil {
+				return nil, fmt.Errorf("BGP export policy for peer [%s] has an invalid match [%s], it should be a prefix or community", peer, match)
+			}
+			if len(attrs.LargeCommunities) != 0 {
+				policy.LargeCommunities = append(policy.LargeCommunities, match)
+			} else {
+				policy.Communities = append(policy.Communities, match)
+			}
+		}
+		policies[peer] = policy
+	}
+	return policies, nil
+}
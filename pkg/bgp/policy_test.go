@@ -0,0 +1,41 @@
+package bgp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExportPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    map[string]ExportPolicy
+		wantErr bool
+	}{
+		{"prefixes", "10.0.0.1=192.168.0.0/24|192.168.1.0/24", map[string]ExportPolicy{
+			"10.0.0.1": {Prefixes: []string{"192.168.0.0/24", "192.168.1.0/24"}},
+		}, false},
+		{"communities", "10.0.0.1=65000:100|4200000000:1:2", map[string]ExportPolicy{
+			"10.0.0.1": {Communities: []string{"65000:100"}, LargeCommunities: []string{"4200000000:1:2"}},
+		}, false},
+		{"multiple peers", "10.0.0.1=192.168.0.0/24,fd00::1=65000:100,eth1=fd00:1::/64", map[string]ExportPolicy{
+			"10.0.0.1": {Prefixes: []string{"192.168.0.0/24"}},
+			"fd00::1":  {Communities: []string{"65000:100"}},
+			"eth1":     {Prefixes: []string{"fd00:1::/64"}},
+		}, false},
+		{"missing matches", "10.0.0.1=", nil, true},
+		{"invalid match", "10.0.0.1=internal", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExportPolicies(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseExportPolicies() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExportPolicies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package bgp
+
+import (
+	"context"
+	"net"
+	"time"
+
+	api "github.com/osrg/gobgp/v3/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultMEDRampSteps and defaultMEDRampInterval are used when only the starting MED is configured
+	defaultMEDRampSteps    = 5
+	defaultMEDRampInterval = 10
+)
+
+// MEDRampConfig defines how a newly advertised VIP is ramped from a worse MED to its own MED, this
+// allows upstream routers (and conntrack) to converge before traffic moves to this node
+type MEDRampConfig struct {
+	// Start is the MED that a VIP is first advertised with, 0 disables ramping
+	Start uint32
+	// Steps is the number of times the MED is lowered before it reaches the MED of the VIP
+	Steps uint32
+	// Interval is the time (in seconds) between each step
+	Interval uint32
+}
+
+// rampMED returns the MED of a step in the ramp from start to target
+func rampMED(start, target, step, steps uint32) uint32 {
+	if step >= steps {
+		return target
+	}
+	return start - uint32(uint64(start-target)*uint64(step)/uint64(steps))
+}
+
+// startRamp will advertise a new host with the starting MED and then lower it to the MED of the
+// host in steps, it returns the attributes that the host is first advertised with (the caller holds the mutex)
+func (b *Server) startRamp(ip net.IP, attrs *PathAttributes) *PathAttributes {
+	b.stopRamp(ip)
+
+	var target uint32
+	if attrs != nil && attrs.MED != nil {
+		target = *attrs.MED
+	}
+	ramp := b.c.MEDRamp
+	if ramp.Start <= target {
+		return attrs
+	}
+	if ramp.Steps == 0 {
+		ramp.Steps = defaultMEDRampSteps
+	}
+	if ramp.Interval == 0 {
+		ramp.Interval = defaultMEDRampInterval
+	}
+
+	withMED := func(med uint32) *PathAttributes {
+		a := PathAttributes{}
+		if attrs != nil {
+			a = *attrs
+		}
+		a.MED = &med
+		return &a
+	}
+
+	stop := make(chan struct{})
+	b.ramps[ip.String()] = stop
+	interval := time.Duration(ramp.Interval) * time.Second
+	log.Infof("[BGP] advertising [%s] with MED [%d], ramping to [%d] over %v", ip, ramp.Start, target, interval*time.Duration(ramp.Steps))
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for step := uint32(1); step <= ramp.Steps; step++ {
+			select {
+			case <-stop:
+				return
+			case <-b.done:
+				return
+			case <-ticker.C:
+			}
+
+			med := rampMED(ramp.Start, target, step, ramp.Steps)
+			b.mutex.Lock()
+			// The host may have been withdrawn (or re-advertised) whilst waiting
+			if b.ramps[ip.String()] != stop {
+				b.mutex.Unlock()
+				return
+			}
+			_, err := b.s.AddPath(context.Background(), &api.AddPathRequest{
+				Path: b.getPath(ip, withMED(med)),
+			})
+			if step == ramp.Steps {
+				delete(b.ramps, ip.String())
+			}
+			b.mutex.Unlock()
+
+			if err != nil {
+				log.Errorf("[BGP] unable to update the MED of [%s]: %v", ip, err)
+				continue
+			}
+			log.Debugf("[BGP] advertising [%s] with MED [%d]", ip, med)
+		}
+	}()
+
+	return withMED(ramp.Start)
+}
+
+// stopRamp will stop any MED ramp of a host (the caller holds the mutex)
+func (b *Server) stopRamp(ip net.IP) {
+	if stop, exists := b.ramps[ip.String()]; exists {
+		close(stop)
+		delete(b.ramps, ip.String())
+	}
+}
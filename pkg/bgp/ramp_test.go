@@ -0,0 +1,23 @@
+package bgp
+
+import "testing"
+
+func TestRampMED(t *testing.T) {
+	tests := []struct {
+		start, target, step, steps uint32
+		want                       uint32
+	}{
+		{1000, 0, 0, 5, 1000},
+		{1000, 0, 1, 5, 800},
+		{1000, 0, 4, 5, 200},
+		{1000, 0, 5, 5, 0},
+		{1000, 100, 1, 3, 700},
+		{1000, 100, 3, 3, 100},
+		{4294967295, 0, 1, 2, 2147483648},
+	}
+	for _, tt := range tests {
+		if got := rampMED(tt.start, tt.target, tt.step, tt.steps); got != tt.want {
+			t.Errorf("rampMED(%d, %d, %d, %d) = %d, want %d", tt.start, tt.target, tt.step, tt.steps, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,11 @@ package bgp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"strings"
 	"time"
 
 	api "github.com/osrg/gobgp/v3/api"
@@ -12,6 +16,10 @@ import (
 
 // NewBGPServer takes a configuration and returns a running BGP server instance
 func NewBGPServer(c *Config, peerStateChangeCallback func(*api.WatchEventResponse_PeerEvent)) (b *Server, err error) {
+	if err = resolveAutoAS(c); err != nil {
+		return nil, err
+	}
+
 	if c.AS == 0 {
 		return nil, fmt.Errorf("You need to provide AS")
 	}
@@ -20,22 +28,84 @@ func NewBGPServer(c *Config, peerStateChangeCallback func(*api.WatchEventRespons
 		return nil, fmt.Errorf("SourceIP and SourceIF are mutually exclusive")
 	}
 
-	if len(c.Peers) == 0 {
+	if c.SourceAuto && (c.SourceIP != "" || c.SourceIF != "") {
+		return nil, fmt.Errorf("SourceAuto can't be used with SourceIP or SourceIF")
+	}
+
+	if c.IPv4NextHop != "" {
+		if ip := net.ParseIP(c.IPv4NextHop); ip == nil || ip.To4() == nil {
+			return nil, fmt.Errorf("IPv4 next-hop [%s] is not a valid IPv4 address", c.IPv4NextHop)
+		}
+	}
+
+	if c.IPv6NextHop != "" {
+		if ip := net.ParseIP(c.IPv6NextHop); ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("IPv6 next-hop [%s] is not a valid IPv6 address", c.IPv6NextHop)
+		}
+	}
+
+	if err = validateTimers(c.HoldTime, c.KeepaliveInterval); err != nil {
+		return nil, fmt.Errorf("BGP timers: %w", err)
+	}
+
+	if c.Confederation.Identifier == 0 && len(c.Confederation.MemberAS) != 0 {
+		return nil, fmt.Errorf("a confederation identifier is required with confederation members")
+	}
+
+	if len(c.Peers) == 0 && len(c.DynamicNeighbors) == 0 {
 		return nil, fmt.Errorf("You need to provide at least one peer")
 	}
 
+	aggregates, err := parseAggregates(c.Aggregates)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []gobgp.ServerOption
+	if c.GRPCAddress != "" {
+		if err = prepareGRPCAddress(c.GRPCAddress); err != nil {
+			return nil, err
+		}
+		log.Infof("[BGP] exposing the gobgp API on [%s]", c.GRPCAddress)
+		opts = append(opts, gobgp.GrpcListenAddress(c.GRPCAddress))
+	}
+
 	b = &Server{
-		s: gobgp.NewBgpServer(),
-		c: c,
+		s:          gobgp.NewBgpServer(opts...),
+		c:          c,
+		unnumbered: map[string]string{},
+		aggregates: aggregates,
+		advertised: map[string]bool{},
+		ramps:      map[string]chan struct{}{},
+		subsets:    map[string][]string{},
+		backoffs:   map[string]*peerBackoff{},
+		sources:    map[string]string{},
+		done:       make(chan struct{}),
 	}
 	go b.s.Serve()
 
+	// The server only needs to listen for connections when dynamic neighbors are configured
+	global := &api.Global{
+		Asn:        c.AS,
+		RouterId:   c.RouterID,
+		ListenPort: -1,
+	}
+	if c.Confederation.Identifier != 0 {
+		global.Confederation = &api.Confederation{
+			Enabled:      true,
+			Identifier:   c.Confederation.Identifier,
+			MemberAsList: c.Confederation.MemberAS,
+		}
+	}
+	if len(c.DynamicNeighbors) != 0 {
+		global.ListenPort = 179
+		if c.SourceIP != "" {
+			global.ListenAddresses = []string{c.SourceIP}
+		}
+	}
+
 	if err = b.s.StartBgp(context.Background(), &api.StartBgpRequest{
-		Global: &api.Global{
-			Asn:        c.AS,
-			RouterId:   c.RouterID,
-			ListenPort: -1,
-		},
+		Global: global,
 	}); err != nil {
 		return
 	}
@@ -43,6 +113,7 @@ func NewBGPServer(c *Config, peerStateChangeCallback func(*api.WatchEventRespons
 	if err = b.s.WatchEvent(context.Background(), &api.WatchEventRequest{Peer: &api.WatchEventRequest_Peer{}}, func(r *api.WatchEventResponse) {
 		if p := r.GetPeer(); p != nil && p.Type == api.WatchEventResponse_PeerEvent_STATE {
 			log.Infof("[BGP] %s", p.String())
+			b.sessionStateChange(p)
 			if peerStateChangeCallback != nil {
 				peerStateChangeCallback(p)
 			}
@@ -57,11 +128,107 @@ func NewBGPServer(c *Config, peerStateChangeCallback func(*api.WatchEventRespons
 		}
 	}
 
+	if err = b.setExportPolicies(); err != nil {
+		return
+	}
+
+	if c.SourceAuto {
+		if err = b.watchSourceAddresses(); err != nil {
+			return
+		}
+	}
+
+	err = b.addDynamicNeighbors()
+
 	return
 }
 
+// prepareGRPCAddress ensures the gobgp API is only exposed locally, as it allows full control of
+// the BGP server. Any stale unix socket is removed as gobgp will exit if it is unable to listen.
+func prepareGRPCAddress(address string) error {
+	if path, found := strings.CutPrefix(address, "unix://"); found {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to remove existing gobgp API socket [%s]: %w", path, err)
+		}
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("gobgp API address [%s] format error: %w", address, err)
+	}
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return fmt.Errorf("gobgp API address [%s] must be a loopback address or unix socket", address)
+		}
+	}
+	return nil
+}
+
+// bfdStateChange will disable a BGP peer when BFD detects that it has gone away, this tears
+// down the session (and the advertised VIPs) without waiting for the hold timer to expire. A peer
+// that the reconnect backoff is holding down is enabled when it is released, rather than by BFD
+func (b *Server) bfdStateChange(peer string, state BFDState) {
+	b.backoffMutex.Lock()
+	pb := b.backoffOf(peer)
+	switch state {
+	case BFDStateDown:
+		pb.bfdDown = true
+	case BFDStateUp:
+		pb.bfdDown = false
+	}
+	held := pb.held
+	b.backoffMutex.Unlock()
+
+	var err error
+	switch state {
+	case BFDStateDown:
+		log.Warnf("[BFD] peer [%s] is down, disabling BGP session", peer)
+		err = b.s.DisablePeer(context.Background(), &api.DisablePeerRequest{
+			Address:       peer,
+			Communication: "BFD session down",
+		})
+	case BFDStateUp:
+		if held {
+			log.Infof("[BFD] peer [%s] is up, its BGP session is enabled once it is no longer held down", peer)
+			return
+		}
+		log.Infof("[BFD] peer [%s] is up, enabling BGP session", peer)
+		err = b.s.EnablePeer(context.Background(), &api.EnablePeerRequest{
+			Address: peer,
+		})
+	}
+	if err != nil {
+		log.Errorf("[BFD] unable to update BGP session for peer [%s]: %v", peer, err)
+	}
+}
+
+// bfdRemoved enables a peer that BFD had disabled once its BFD session has been removed, unless the reconnect
+// backoff is holding it down
+func (b *Server) bfdRemoved(peer string) {
+	b.backoffMutex.Lock()
+	pb := b.backoffOf(bfdKey(peer))
+	bfdDown := pb.bfdDown
+	pb.bfdDown = false
+	held := pb.held
+	b.backoffMutex.Unlock()
+
+	if !bfdDown || held {
+		return
+	}
+	if err := b.s.EnablePeer(context.Background(), &api.EnablePeerRequest{
+		Address: peer,
+	}); err != nil {
+		log.Errorf("[BFD] unable to enable BGP session for peer [%s]: %v", peer, err)
+	}
+}
+
 // Close will stop a running BGP Server
 func (b *Server) Close() error {
+	close(b.done)
+	if b.bfd != nil {
+		b.bfd.close()
+	}
 	ctx, cf := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cf()
 	return b.s.StopBgp(ctx, &api.StopBgpRequest{})
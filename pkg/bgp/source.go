@@ -0,0 +1,109 @@
+package bgp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// sourceResolveDelay is how long interface and route changes are collected before the source
+// addresses are resolved again, as a single change will typically generate multiple updates
+const sourceResolveDelay = 2 * time.Second
+
+// routeSource returns the source address of the route towards a peer
+func routeSource(address string) (string, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("peer address [%s] is not an IP address", address)
+	}
+	routes, err := netlink.RouteGet(ip)
+	if err != nil {
+		return "", fmt.Errorf("unable to find a route to peer [%s]: %w", address, err)
+	}
+	for _, route := range routes {
+		if route.Src != nil {
+			return route.Src.String(), nil
+		}
+	}
+	return "", fmt.Errorf("route to peer [%s] has no source address", address)
+}
+
+// sourceAddress returns the local address that is used for a peer when the source address is
+// selected automatically, an empty address leaves the selection to the kernel
+func (b *Server) sourceAddress(peer Peer) string {
+	source, err := routeSource(peer.Address)
+	if err != nil {
+		log.Warnf("[BGP] %v", err)
+	}
+
+	b.sourcesMutex.Lock()
+	b.sources[peer.Address] = source
+	b.sourcesMutex.Unlock()
+	return source
+}
+
+// watchSourceAddresses will re-resolve the source address of every peer when the addresses or
+// routes of this node change, peers whose source address has changed are reconfigured
+func (b *Server) watchSourceAddresses() error {
+	addrCh := make(chan netlink.AddrUpdate)
+	if err := netlink.AddrSubscribe(addrCh, b.done); err != nil {
+		return fmt.Errorf("subscribe address failed, error: %w", err)
+	}
+	routeCh := make(chan netlink.RouteUpdate)
+	if err := netlink.RouteSubscribe(routeCh, b.done); err != nil {
+		return fmt.Errorf("subscribe route failed, error: %w", err)
+	}
+
+	go func() {
+		resolve := time.NewTimer(sourceResolveDelay)
+		resolve.Stop()
+		for {
+			select {
+			case <-addrCh:
+				resolve.Reset(sourceResolveDelay)
+			case <-routeCh:
+				resolve.Reset(sourceResolveDelay)
+			case <-resolve.C:
+				b.resolveSourceAddresses()
+			case <-b.done:
+				resolve.Stop()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// resolveSourceAddresses will update any peer whose source address has changed
+func (b *Server) resolveSourceAddresses() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, peer := range b.c.Peers {
+		// Unnumbered peers are bound to their interface, so don't need a source address
+		if peer.Interface != "" {
+			continue
+		}
+
+		source, err := routeSource(peer.Address)
+		if err != nil {
+			log.Warnf("[BGP] %v", err)
+			continue
+		}
+
+		b.sourcesMutex.Lock()
+		current := b.sources[peer.Address]
+		b.sourcesMutex.Unlock()
+		if source == current {
+			continue
+		}
+
+		log.Infof("[BGP] source address for peer [%s] has changed from [%s] to [%s]", peer.Address, current, source)
+		if err = b.UpdatePeer(peer); err != nil {
+			log.Errorf("[BGP] unable to update peer [%s]: %v", peer.Address, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package bgp
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	api "github.com/osrg/gobgp/v3/api"
+)
+
+// PeerStatus is the health of the session with a peer
+type PeerStatus struct {
+	Address string
+	// State is the session state (e.g. ESTABLISHED, ACTIVE, IDLE)
+	State string
+	// AdvertisedPrefixes is the number of prefixes that are advertised to the peer
+	AdvertisedPrefixes uint64
+	// LastDown is when an established session with the peer last went down (zero if it hasn't)
+	LastDown time.Time
+	// NotificationsReceived is the number of NOTIFICATION messages (errors) received from the peer
+	NotificationsReceived uint64
+}
+
+// Established returns true if the session with the peer is established
+func (p PeerStatus) Established() bool {
+	return p.State == api.PeerState_ESTABLISHED.String()
+}
+
+// PeerStatus returns the health of the session with every peer, sorted by address
+func (b *Server) PeerStatus() ([]PeerStatus, error) {
+	var status []PeerStatus
+	err := b.s.ListPeer(context.Background(), &api.ListPeerRequest{EnableAdvertised: true}, func(p *api.Peer) {
+		s := PeerStatus{
+			Address:               p.GetState().GetNeighborAddress(),
+			State:                 p.GetState().GetSessionState().String(),
+			NotificationsReceived: p.GetState().GetMessages().GetReceived().GetNotification(),
+		}
+		for _, afiSafi := range p.GetAfiSafis() {
+			s.AdvertisedPrefixes += afiSafi.GetState().GetAdvertised()
+		}
+		status = append(status, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(status, func(i, j int) bool { return status[i].Address < status[j].Address })
+
+	b.backoffMutex.Lock()
+	for x := range status {
+		if pb, exists := b.backoffs[status[x].Address]; exists {
+			status[x].LastDown = pb.lastDown
+		}
+	}
+	b.backoffMutex.Unlock()
+	return status, nil
+}
@@ -0,0 +1,86 @@
+package bgp
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setPeerSubset records the peers (or peer groups) that a host is only advertised to, it returns
+// true if the export policy needs to be rebuilt (the caller holds the mutex)
+func (b *Server) setPeerSubset(ip net.IP, peers []string) bool {
+	current, exists := b.subsets[ip.String()]
+	if len(peers) == 0 {
+		if !exists {
+			return false
+		}
+		delete(b.subsets, ip.String())
+		return true
+	}
+	if exists && slices.Equal(current, peers) {
+		return false
+	}
+	b.subsets[ip.String()] = slices.Clone(peers)
+	return true
+}
+
+// resolvePeers returns the identities of the peers within a list of peers and peer groups
+func (b *Server) resolvePeers(names []string) map[string]bool {
+	peers := map[string]bool{}
+	for _, name := range names {
+		if members, exists := b.c.PeerGroups[name]; exists {
+			for _, member := range members {
+				peers[member] = true
+			}
+			continue
+		}
+		peers[name] = true
+	}
+	return peers
+}
+
+// deniedHosts returns the host prefixes that each peer isn't sent because they are only advertised
+// to a subset of the other peers, keyed by the peer address (or interface for unnumbered peers)
+func (b *Server) deniedHosts() map[string][]string {
+	denied := map[string][]string{}
+	for host, names := range b.subsets {
+		allowed := b.resolvePeers(names)
+		for _, p := range b.c.Peers {
+			if !allowed[p.id()] {
+				denied[p.id()] = append(denied[p.id()], hostPrefix(host))
+			}
+		}
+		for name := range allowed {
+			if !slices.ContainsFunc(b.c.Peers, func(p Peer) bool { return p.id() == name }) {
+				log.Warnf("[BGP] host [%s] is advertised to unknown peer [%s]", host, name)
+			}
+		}
+	}
+	for id := range denied {
+		sort.Strings(denied[id])
+	}
+	return denied
+}
+
+// ParsePeerGroups - take a string and parses it into named groups of peers, the format is
+// group=peer|peer,group=peer where a peer is an address (or interface for unnumbered peers)
+func ParsePeerGroups(config string) (map[string][]string, error) {
+	groups := map[string][]string{}
+	for _, groupStr := range strings.Split(config, ",") {
+		groupStr = strings.TrimSpace(groupStr)
+		if groupStr == "" {
+			continue
+		}
+
+		group, peers, found := strings.Cut(groupStr, "=")
+		if !found || group == "" || peers == "" {
+			return nil, fmt.Errorf("BGP peer group format error (group=peer|peer) [%s]", groupStr)
+		}
+		groups[group] = append(groups[group], strings.Split(peers, "|")...)
+	}
+	return groups, nil
+}
@@ -0,0 +1,54 @@
+package bgp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParsePeerGroups(t *testing.T) {
+	got, err := ParsePeerGroups("internal=10.0.0.1|eth1,edge=fd00::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"internal": {"10.0.0.1", "eth1"}, "edge": {"fd00::1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePeerGroups() = %v, want %v", got, want)
+	}
+	if _, err = ParsePeerGroups("internal"); err == nil {
+		t.Error("ParsePeerGroups() expected an error")
+	}
+}
+
+func TestDeniedHosts(t *testing.T) {
+	b := &Server{
+		c: &Config{
+			Peers:      []Peer{{Address: "10.0.0.1"}, {Interface: "eth1"}, {Address: "fd00::1"}},
+			PeerGroups: map[string][]string{"internal": {"10.0.0.1", "eth1"}},
+		},
+		subsets: map[string][]string{},
+	}
+	if !b.setPeerSubset(net.ParseIP("192.168.0.1"), []string{"internal"}) {
+		t.Fatal("setPeerSubset() expected a change")
+	}
+	if b.setPeerSubset(net.ParseIP("192.168.0.1"), []string{"internal"}) {
+		t.Fatal("setPeerSubset() expected no change")
+	}
+	b.setPeerSubset(net.ParseIP("fd01::1"), []string{"fd00::1"})
+
+	want := map[string][]string{
+		"10.0.0.1": {"fd01::1/128"},
+		"eth1":     {"fd01::1/128"},
+		"fd00::1":  {"192.168.0.1/32"},
+	}
+	if got := b.deniedHosts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("deniedHosts() = %v, want %v", got, want)
+	}
+
+	if !b.setPeerSubset(net.ParseIP("fd01::1"), nil) {
+		t.Fatal("setPeerSubset() expected a change")
+	}
+	if got := b.deniedHosts(); !reflect.DeepEqual(got, map[string][]string{"fd00::1": {"192.168.0.1/32"}}) {
+		t.Errorf("deniedHosts() = %v", got)
+	}
+}
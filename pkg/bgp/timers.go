@@ -0,0 +1,67 @@
+package bgp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PeerTimers overrides the hold time and keepalive interval (in seconds) for a peer
+type PeerTimers struct {
+	HoldTime          uint64
+	KeepaliveInterval uint64
+}
+
+// validateTimers checks the timers are usable, a hold time is either 0 (keepalives are disabled)
+// or at least 3 seconds (RFC 4271) and keepalives have to be sent more often than the hold time
+func validateTimers(holdTime, keepaliveInterval uint64) error {
+	if holdTime != 0 && holdTime < 3 {
+		return fmt.Errorf("hold time [%d] must be 0 or at least 3 seconds", holdTime)
+	}
+	if holdTime != 0 && keepaliveInterval >= holdTime {
+		return fmt.Errorf("keepalive interval [%d] must be less than the hold time [%d]", keepaliveInterval, holdTime)
+	}
+	return nil
+}
+
+// peerTimers returns the timers for a peer, these are the global timers unless they are overridden
+func (b *Server) peerTimers(peer Peer) (holdTime, keepaliveInterval uint64) {
+	if t, exists := b.c.PeerTimers[peer.id()]; exists {
+		return t.HoldTime, t.KeepaliveInterval
+	}
+	return b.c.HoldTime, b.c.KeepaliveInterval
+}
+
+// ParsePeerTimers - take a string and parses it into the timers of each peer, the format is
+// peer=holdtime:keepalive,peer=holdtime:keepalive where a peer is an address (or interface for
+// unnumbered peers)
+func ParsePeerTimers(config string) (map[string]PeerTimers, error) {
+	timers := map[string]PeerTimers{}
+	for _, timerStr := range strings.Split(config, ",") {
+		timerStr = strings.TrimSpace(timerStr)
+		if timerStr == "" {
+			continue
+		}
+
+		peer, values, found := strings.Cut(timerStr, "=")
+		hold, keepalive, foundTimers := strings.Cut(values, ":")
+		if !found || !foundTimers || peer == "" {
+			return nil, fmt.Errorf("BGP peer timers format error (peer=holdtime:keepalive) [%s]", timerStr)
+		}
+
+		holdTime, err := strconv.ParseUint(hold, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("BGP hold time format error [%s]", hold)
+		}
+		keepaliveInterval, err := strconv.ParseUint(keepalive, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("BGP keepalive interval format error [%s]", keepalive)
+		}
+		if err = validateTimers(holdTime, keepaliveInterval); err != nil {
+			return nil, fmt.Errorf("BGP timers for peer [%s]: %w", peer, err)
+		}
+
+		timers[peer] = PeerTimers{HoldTime: holdTime, KeepaliveInterval: keepaliveInterval}
+	}
+	return timers, nil
+}
@@ -0,0 +1,37 @@
+package bgp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePeerTimers(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    map[string]PeerTimers
+		wantErr bool
+	}{
+		{"single", "10.0.0.1=90:30", map[string]PeerTimers{"10.0.0.1": {HoldTime: 90, KeepaliveInterval: 30}}, false},
+		{"multiple", "fd00::1=9:3,eth0=0:0", map[string]PeerTimers{
+			"fd00::1": {HoldTime: 9, KeepaliveInterval: 3},
+			"eth0":    {},
+		}, false},
+		{"hold time too short", "10.0.0.1=2:1", nil, true},
+		{"keepalive not less than hold time", "10.0.0.1=30:30", nil, true},
+		{"missing keepalive", "10.0.0.1=30", nil, true},
+		{"not a number", "10.0.0.1=thirty:10", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePeerTimers(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePeerTimers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePeerTimers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
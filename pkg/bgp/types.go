@@ -1,30 +1,177 @@
 package bgp
 
-import gobgp "github.com/osrg/gobgp/v3/pkg/server"
+import (
+	"sync"
+
+	gobgp "github.com/osrg/gobgp/v3/pkg/server"
+)
 
 // Peer defines a BGP Peer
 type Peer struct {
-	Address  string
-	AS       uint32
-	Password string
-	MultiHop bool
+	Address string
+	// Interface is used for unnumbered peering, the neighbor is found by its IPv6 link-local address on this interface
+	Interface string
+	AS        uint32
+	Password  string
+	MultiHop  bool
+
+	// NextHop overrides the next-hop of the VIPs advertised to this peer, this is either an
+	// address or "self" (the local address of the session)
+	NextHop string
+
+	// PasswordSecret references a Kubernetes Secret (namespace/name/key) that holds the password
+	PasswordSecret string
+
+	BFD BFDConfig
+}
+
+// id returns the identity of a peer, which is the interface for unnumbered peers
+func (p Peer) id() string {
+	if p.Interface != "" {
+		return p.Interface
+	}
+	return p.Address
+}
+
+// BFDConfig defines the Bidirectional Forwarding Detection settings for a peer
+type BFDConfig struct {
+	Enabled bool
+	// Interval is the desired transmit and required receive interval (in milliseconds)
+	Interval uint32
+	// Multiplier is the number of missed control packets before a session is declared down
+	Multiplier uint32
 }
 
 // Config defines the BGP server configuration
 type Config struct {
-	AS       uint32
+	// Backend is the routing daemon that advertises the VIPs (gobgp or frr), the embedded gobgp
+	// server is used by default
+	Backend string
+	// VtyshPath is the path of the vtysh binary that is used to program FRR
+	VtyshPath string
+
+	AS uint32
+	// AutoAS assigns a private 4-byte AS derived from the RouterID, replacing AS
+	AutoAS bool
+	// Confederation is set when the local AS is a member of a confederation
+	Confederation ConfederationConfig
+
 	RouterID string
 	SourceIP string
 	SourceIF string
+	// SourceAuto will use the source address of the route towards each peer, this is re-resolved
+	// when the addresses or routes of the node change
+	SourceAuto bool
+
+	// GRPCAddress will expose the gobgp management API (for use with the gobgp CLI) on a loopback
+	// address (127.0.0.1:50051) or a unix socket (unix:///var/run/kube-vip/gobgp.sock)
+	GRPCAddress string
+
+	// IPv4NextHop and IPv6NextHop set the next-hop of advertised VIPs, these are required when
+	// a family is advertised over a session of the other family (e.g. IPv6 VIPs to an IPv4 peer)
+	IPv4NextHop string
+	IPv6NextHop string
 
 	HoldTime          uint64
 	KeepaliveInterval uint64
+	// PeerTimers override the hold time and keepalive interval for a peer, keyed by the peer
+	// address (or interface for unnumbered peers)
+	PeerTimers map[string]PeerTimers
+
+	GracefulRestart GracefulRestartConfig
+
+	// AddPath negotiates the ADD-PATH capability (RFC 7911) with all peers
+	AddPath AddPathConfig
+
+	// Aggregates are prefixes that are advertised whilst any VIP within them is advertised
+	Aggregates []string
+	// SuppressAggregated stops VIPs that are covered by an aggregate from being advertised themselves
+	SuppressAggregated bool
 
 	Peers []Peer
+
+	// PeerGroups are named groups of peers (addresses or interfaces for unnumbered peers), these are
+	// used to advertise a VIP to a subset of the peers
+	PeerGroups map[string][]string
+
+	// ExportPolicies restrict the VIPs that are advertised to a peer, keyed by the peer address
+	// (or interface for unnumbered peers), peers without a policy are sent every VIP
+	ExportPolicies map[string]ExportPolicy
+
+	// DrainPrepend is the number of times the local AS is prepended whilst the node is draining,
+	// 0 will withdraw the VIPs instead
+	DrainPrepend uint32
+
+	// MEDRamp advertises new VIPs with a worse MED that is lowered in steps (make-before-break)
+	MEDRamp MEDRampConfig
+
+	// MaxPrefixes is the maximum number of prefixes (hosts and aggregates) that are advertised, 0 is unlimited
+	MaxPrefixes uint32
+
+	// DynamicNeighbors are prefixes that inbound sessions are accepted from, enabling these
+	// will start the BGP server listening on port 179
+	DynamicNeighbors []DynamicNeighbor
+}
+
+// AddPathConfig defines the ADD-PATH capability, this allows peers (such as route reflectors) to
+// exchange the path from every node advertising a VIP rather than only the best path, so that
+// upstream routers can install all of them for ECMP
+type AddPathConfig struct {
+	// Receive will accept multiple paths for the same prefix from peers
+	Receive bool
+	// SendMax is the maximum number of paths for the same prefix that are sent to peers (0 disables sending)
+	SendMax uint32
+}
+
+// GracefulRestartConfig defines the graceful restart capabilities that are advertised to all peers,
+// this allows peers to retain the advertised VIPs whilst kube-vip is restarted or upgraded
+type GracefulRestartConfig struct {
+	Enabled bool
+	// RestartTime is the time (in seconds) that peers should retain routes whilst the session is re-established
+	RestartTime uint32
+	// LongLived enables long-lived graceful restart (RFC 9494)
+	LongLived bool
+	// LongLivedRestartTime is the time (in seconds) that stale routes are retained once the RestartTime has expired
+	LongLivedRestartTime uint32
 }
 
 // Server manages a server object
 type Server struct {
-	s *gobgp.BgpServer
-	c *Config
+	s   *gobgp.BgpServer
+	c   *Config
+	bfd *bfdManager
+
+	// unnumbered maps the interface of an unnumbered peer to its link-local address
+	unnumbered map[string]string
+
+	aggregates []*aggregate
+
+	// advertised holds the hosts that are advertised (that aren't suppressed by an aggregate)
+	advertised         map[string]bool
+	maxPrefixesHandler func(addr string)
+
+	// subsets holds the peers (or peer groups) that a host is only advertised to
+	subsets map[string][]string
+
+	// ramps holds the hosts whose MED is being ramped, closing the channel stops the ramp
+	ramps map[string]chan struct{}
+
+	// sources holds the source address of each peer when the source address is selected automatically
+	sources      map[string]string
+	sourcesMutex sync.Mutex
+
+	// done is closed when the server is stopped
+	done chan struct{}
+
+	// exportPolicies is set once the export policy has been built
+	exportPolicies bool
+	// draining is set whilst the node is draining
+	draining bool
+
+	// This mutex protects the advertisement of hosts and aggregates, and the list of peers
+	mutex sync.Mutex
+
+	// backoffs holds the reconnect backoff of each peer, keyed by the neighbor address
+	backoffs     map[string]*peerBackoff
+	backoffMutex sync.Mutex
 }
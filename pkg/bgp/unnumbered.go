@@ -0,0 +1,80 @@
+package bgp
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/mdlayher/ndp"
+	"github.com/osrg/gobgp/v3/pkg/config/oc"
+	log "github.com/sirupsen/logrus"
+)
+
+// unnumberedDiscoveryTimeout is how long we will wait for a router advertisement from an unnumbered peer
+const unnumberedDiscoveryTimeout = 10 * time.Second
+
+// peerAddress returns the address that is used to configure a peer, unnumbered peers (RFC 5549)
+// are resolved to the IPv6 link-local address of the neighbor on that interface
+func (b *Server) peerAddress(peer Peer) (string, error) {
+	if peer.Interface == "" {
+		return peer.Address, nil
+	}
+
+	if address, exists := b.unnumbered[peer.Interface]; exists {
+		return address, nil
+	}
+
+	address, err := discoverLinkLocalPeer(peer.Interface, unnumberedDiscoveryTimeout)
+	if err != nil {
+		return "", err
+	}
+	log.Infof("[BGP] found unnumbered peer [%s] on interface [%s]", address, peer.Interface)
+	b.unnumbered[peer.Interface] = address
+	return address, nil
+}
+
+// discoverLinkLocalPeer will find the IPv6 link-local address of the neighbor on a point-to-point
+// interface. The neighbor table is checked first, if the neighbor isn't present then a router
+// solicitation is sent and the address is taken from the router advertisement that is returned.
+func discoverLinkLocalPeer(ifname string, timeout time.Duration) (string, error) {
+	if address, err := oc.GetIPv6LinkLocalNeighborAddress(ifname); err == nil {
+		return address, nil
+	}
+
+	iface, err := net.InterfaceByName(ifname)
+	if err != nil {
+		return "", fmt.Errorf("failed to get interface %q: %v", ifname, err)
+	}
+
+	conn, _, err := ndp.Listen(iface, ndp.LinkLocal)
+	if err != nil {
+		return "", fmt.Errorf("creating NDP connection for %q: %v", ifname, err)
+	}
+	defer conn.Close()
+
+	rs := &ndp.RouterSolicitation{
+		Options: []ndp.Option{
+			&ndp.LinkLayerAddress{
+				Direction: ndp.Source,
+				Addr:      iface.HardwareAddr,
+			},
+		},
+	}
+	if err = conn.WriteTo(rs, nil, netip.IPv6LinkLocalAllRouters()); err != nil {
+		return "", fmt.Errorf("sending router solicitation on %q: %v", ifname, err)
+	}
+
+	if err = conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return "", err
+	}
+	for {
+		msg, _, from, err := conn.ReadFrom()
+		if err != nil {
+			return "", fmt.Errorf("no unnumbered peer found on interface %q: %v", ifname, err)
+		}
+		if _, ok := msg.(*ndp.RouterAdvertisement); ok && from.IsLinkLocalUnicast() {
+			return fmt.Sprintf("%s%%%s", from.WithZone(""), ifname), nil
+		}
+	}
+}
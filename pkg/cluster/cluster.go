@@ -15,16 +15,20 @@ type Cluster struct {
 	completed chan bool
 	once      sync.Once
 	Network   []vip.Network
+
+	// host is the state that the VIPs share with the other clusters of the manager
+	host *vip.Host
 }
 
-// InitCluster - Will attempt to initialise all of the required settings for the cluster
-func InitCluster(c *kubevip.Config, disableVIP bool) (*Cluster, error) {
+// InitCluster - Will attempt to initialise all of the required settings for the cluster, the VIPs share the state
+// of the host with the other clusters of the manager
+func InitCluster(c *kubevip.Config, host *vip.Host, disableVIP bool) (*Cluster, error) {
 	var networks []vip.Network
 	var err error
 
 	if !disableVIP {
 		// Start the Virtual IP Networking configuration
-		networks, err = startNetworking(c)
+		networks, err = startNetworking(c, host)
 		if err != nil {
 			return nil, err
 		}
@@ -32,6 +36,7 @@ func InitCluster(c *kubevip.Config, disableVIP bool) (*Cluster, error) {
 	// Initialise the Cluster structure
 	newCluster := &Cluster{
 		Network: networks,
+		host:    host,
 	}
 
 	log.Debugf("init enable service security: %t", c.EnableServiceSecurity)
@@ -39,7 +44,7 @@ func InitCluster(c *kubevip.Config, disableVIP bool) (*Cluster, error) {
 	return newCluster, nil
 }
 
-func startNetworking(c *kubevip.Config) ([]vip.Network, error) {
+func startNetworking(c *kubevip.Config, host *vip.Host) ([]vip.Network, error) {
 	address := c.VIP
 
 	if c.Address != "" {
@@ -48,13 +53,25 @@ func startNetworking(c *kubevip.Config) ([]vip.Network, error) {
 
 	addresses := vip.GetIPs(address)
 
+	// A VIP can only be announced on multiple interfaces in ARP mode, routes are only added to a single interface
+	interfaces := vip.GetInterfaces(c.Interface)
+	if len(interfaces) > 1 && (!c.EnableARP || c.EnableRoutingTable) {
+		log.Warnf("multiple interfaces [%s] are only supported with ARP, using [%s]", c.Interface, interfaces[0])
+		interfaces = interfaces[:1]
+	}
+	if len(interfaces) == 0 {
+		interfaces = []string{c.Interface}
+	}
+
 	networks := []vip.Network{}
 	for _, addr := range addresses {
-		network, err := vip.NewConfig(addr, c.Interface, c.VIPSubnet, c.DDNS, c.RoutingTableID, c.RoutingTableType, c.RoutingProtocol, c.DNSMode, c.LoadBalancerForwardingMethod, c.IptablesBackend)
-		if err != nil {
-			return nil, err
+		for _, iface := range interfaces {
+			network, err := vip.NewConfig(host, addr, iface, c.VIPSubnet, c.DDNS, c.RoutingTableID, c.RoutingTableType, c.RoutingProtocol, c.RoutingMetric, c.RoutingSource, c.DNSMode, c.LoadBalancerForwardingMethod, c.IptablesBackend)
+			if err != nil {
+				return nil, err
+			}
+			networks = append(networks, network...)
 		}
-		networks = append(networks, network...)
 	}
 
 	return networks, nil
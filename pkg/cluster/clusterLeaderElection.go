@@ -2,10 +2,14 @@ package cluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -16,6 +20,10 @@ import (
 	"github.com/kube-vip/kube-vip/pkg/k8s"
 	"github.com/kube-vip/kube-vip/pkg/kubevip"
 	"github.com/kube-vip/kube-vip/pkg/loadbalancer"
+	"github.com/kube-vip/kube-vip/pkg/priority"
+	"github.com/kube-vip/kube-vip/pkg/raft"
+	"github.com/kube-vip/kube-vip/pkg/vip"
+	"github.com/kube-vip/kube-vip/pkg/vrrp"
 
 	"github.com/packethost/packngo"
 
@@ -32,9 +40,13 @@ import (
 	watchtools "k8s.io/client-go/tools/watch"
 )
 
+// ErrLeadershipLost is returned once this node has lost the leadership, kube-vip is then restarted so that it rejoins
+// the election in a clean state
+var ErrLeadershipLost = errors.New("lost leadership, restarting kube-vip")
+
 // Manager degines the manager of the load-balancing services
 type Manager struct {
-	KubernetesClient *kubernetes.Clientset
+	KubernetesClient kubernetes.Interface
 	// This channel is used to signal a shutdown
 	SignalChan chan os.Signal
 
@@ -75,7 +87,7 @@ func NewManager(path string, inCluster bool, port int) (*Manager, error) {
 }
 
 // StartCluster - Begins a running instance of the Leader Election cluster
-func (cluster *Cluster) StartCluster(c *kubevip.Config, sm *Manager, bgpServer *bgp.Server) error {
+func (cluster *Cluster) StartCluster(c *kubevip.Config, sm *Manager, bgpServer bgp.Backend) error {
 	var err error
 
 	log.Infof("Beginning cluster membership, namespace [%s], lock name [%s], id [%s]", c.Namespace, c.LeaseName, c.NodeName)
@@ -105,12 +117,42 @@ func (cluster *Cluster) StartCluster(c *kubevip.Config, sm *Manager, bgpServer *
 	// Add Notification for SIGTERM (sent from Kubernetes)
 	signal.Notify(signalChan, syscall.SIGTERM)
 
+	// withdraw stops announcing the VIP, it is called before the lease is released on shutdown so that the
+	// next leader doesn't announce it at the same time
+	var leading, lost atomic.Bool
+	var withdrawOnce sync.Once
+	withdraw := func() {
+		withdrawOnce.Do(func() {
+			// Stop the dns context
+			cancelDNS()
+			// Stop the Arp context if it is running
+			cancelArp()
+
+			// Stop the BGP server
+			if bgpServer != nil {
+				err := bgpServer.Close()
+				if err != nil {
+					log.Warnf("%v", err)
+				}
+			}
+
+			for i := range cluster.Network {
+				err := cluster.Network[i].DeleteIP()
+				if err != nil {
+					log.Warnf("%v", err)
+				}
+			}
+		})
+	}
+
 	go func() {
 		<-signalChan
 		log.Info("Received termination, signaling cluster shutdown")
-		// Cancel the context, which will in turn cancel the leadership
+		if leading.Load() {
+			withdraw()
+		}
+		// Cancel the context, which will in turn release the leadership
 		cancel()
-		// Cancel the arp context, which will in turn stop any broadcasts
 	}()
 
 	// (attempt to) Remove the virtual IP, in case it already exists
@@ -161,7 +203,7 @@ func (cluster *Cluster) StartCluster(c *kubevip.Config, sm *Manager, bgpServer *
 	if c.EnableBGP && bgpServer == nil {
 		// Lets start BGP
 		log.Info("Starting the BGP server to advertise VIP routes to VGP peers")
-		bgpServer, err = bgp.NewBGPServer(&c.BGPConfig, nil)
+		bgpServer, err = bgp.NewBackend(&c.BGPConfig, nil)
 		if err != nil {
 			log.Error(err)
 		}
@@ -172,6 +214,7 @@ func (cluster *Cluster) StartCluster(c *kubevip.Config, sm *Manager, bgpServer *
 		leaseID: c.NodeName,
 		sm:      sm,
 		onStartedLeading: func(ctx context.Context) {
+			leading.Store(true)
 			// As we're leading lets start the vip service
 			err := cluster.vipService(ctxArp, ctxDNS, c, sm, bgpServer, packetClient)
 			if err != nil {
@@ -181,28 +224,13 @@ func (cluster *Cluster) StartCluster(c *kubevip.Config, sm *Manager, bgpServer *
 		onStoppedLeading: func() {
 			// we can do cleanup here
 			log.Info("This node is becoming a follower within the cluster")
+			withdraw()
 
-			// Stop the dns context
-			cancelDNS()
-			// Stop the Arp context if it is running
-			cancelArp()
-
-			// Stop the BGP server
-			if bgpServer != nil {
-				err := bgpServer.Close()
-				if err != nil {
-					log.Warnf("%v", err)
-				}
-			}
-
-			for i := range cluster.Network {
-				err := cluster.Network[i].DeleteIP()
-				if err != nil {
-					log.Warnf("%v", err)
-				}
+			// The cluster stops, rather than rejoining the election, unless it is already shutting down
+			if ctx.Err() == nil {
+				lost.Store(true)
+				cancel()
 			}
-
-			log.Fatal("lost leadership, restarting kube-vip")
 		},
 		onNewLeader: func(identity string) {
 			// we're notified when new leader elected
@@ -210,15 +238,81 @@ func (cluster *Cluster) StartCluster(c *kubevip.Config, sm *Manager, bgpServer *
 		},
 	}
 
-	switch c.LeaderElectionType {
-	case "kubernetes", "":
-		cluster.runKubernetesLeaderElectionOrDie(ctx, run)
-	case "etcd":
-		cluster.runEtcdLeaderElectionOrDie(ctx, run)
-	default:
-		log.Info(fmt.Sprintf("LeaderElectionMode %s not supported, exiting", c.LeaderElectionType))
+	// Only take part in the election whilst the interface has carrier, if it is lost then leadership
+	// is relinquished (which restarts kube-vip) and we rejoin the election when it returns
+	iface := c.Interface
+	if interfaces := vip.GetInterfaces(iface); len(interfaces) > 0 {
+		iface = interfaces[0]
 	}
+	for {
+		electionCtx, cancelElection := context.WithCancel(ctx)
+		if c.MonitorCarrier {
+			cancelElection()
+			if err := cluster.host.WaitForCarrier(ctx, iface); err != nil {
+				return nil
+			}
+			electionCtx, cancelElection = cluster.host.CarrierContext(ctx, iface)
+		}
+		// Likewise whilst the node is NotReady or under pressure
+		monitorHealth := c.MonitorNodeHealth && sm.KubernetesClient != nil
+		if monitorHealth {
+			if err := k8s.WaitForNodeHealthy(electionCtx, sm.KubernetesClient, c.NodeName); err != nil {
+				cancelElection()
+				if ctx.Err() != nil {
+					return nil
+				}
+				continue
+			}
+			healthCtx, cancelHealth := k8s.NodeHealthContext(electionCtx, sm.KubernetesClient, c.NodeName)
+			cancelCarrier := cancelElection
+			electionCtx, cancelElection = healthCtx, func() {
+				cancelHealth()
+				cancelCarrier()
+			}
+		}
 
+		// An excluded node doesn't take part in the election of the control plane, until it is no longer excluded
+		if sm.KubernetesClient != nil {
+			if err := k8s.WaitForNodeIncluded(electionCtx, sm.KubernetesClient, c.NodeName, c.ElectionExcludeSelector, c.ElectionExcludeTaints); err != nil {
+				cancelElection()
+				if ctx.Err() != nil {
+					return nil
+				}
+				continue
+			}
+			includedCtx, cancelIncluded := k8s.NodeIncludedContext(electionCtx, sm.KubernetesClient, c.NodeName, c.ElectionExcludeSelector, c.ElectionExcludeTaints)
+			cancelPrevious := cancelElection
+			electionCtx, cancelElection = includedCtx, func() {
+				cancelIncluded()
+				cancelPrevious()
+			}
+		}
+
+		switch c.LeaderElectionType {
+		case "kubernetes", "":
+			cluster.runKubernetesLeaderElectionOrDie(electionCtx, run)
+		case "etcd":
+			cluster.runEtcdLeaderElectionOrDie(electionCtx, run)
+		case "vrrp":
+			cluster.runVRRPLeaderElectionOrDie(electionCtx, run)
+		case "raft":
+			cluster.runRaftLeaderElectionOrDie(electionCtx, run)
+		default:
+			log.Info(fmt.Sprintf("LeaderElectionMode %s not supported, exiting", c.LeaderElectionType))
+			cancelElection()
+			return nil
+		}
+		cancelElection()
+
+		if (!c.MonitorCarrier && !monitorHealth && sm.KubernetesClient == nil) || ctx.Err() != nil {
+			break
+		}
+		log.Warnf("interface [%s] has lost carrier or node [%s] is unhealthy or excluded, this node will rejoin the election once it recovers", iface, c.NodeName)
+	}
+
+	if lost.Load() {
+		return ErrLeadershipLost
+	}
 	return nil
 }
 
@@ -252,8 +346,7 @@ func (cluster *Cluster) runKubernetesLeaderElectionOrDie(ctx context.Context, ru
 		},
 	}
 
-	// start the leader election code loop
-	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+	config := leaderelection.LeaderElectionConfig{
 		Lock: lock,
 		// IMPORTANT: you MUST ensure that any code you have that
 		// is protected by the lease must terminate **before**
@@ -266,11 +359,33 @@ func (cluster *Cluster) runKubernetesLeaderElectionOrDie(ctx context.Context, ru
 		RenewDeadline:   time.Duration(run.config.RenewDeadline) * time.Second,
 		RetryPeriod:     time.Duration(run.config.RetryPeriod) * time.Second,
 		Callbacks: leaderelection.LeaderCallbacks{
-			OnStartedLeading: run.onStartedLeading,
+			OnStartedLeading: func(ctx context.Context) {
+				go run.annotateLease(ctx)
+				run.onStartedLeading(ctx)
+			},
 			OnStoppedLeading: run.onStoppedLeading,
 			OnNewLeader:      run.onNewLeader,
 		},
-	})
+	}
+
+	// start the leader election code loop
+	if run.config.EnableLeaderPriority {
+		priority.RunOrDie(ctx, run.sm.KubernetesClient, run.config.LeaderPreemption, time.Duration(run.config.LeaderFailbackDelay)*time.Second, config)
+		return
+	}
+	leaderelection.RunOrDie(ctx, config)
+}
+
+// annotateLease records this node, and how it advertises the VIP, on the lease of the control plane along with
+// the configured lease annotations (which a new lease isn't created with)
+func (run *runConfig) annotateLease(ctx context.Context) {
+	annotations := k8s.HolderAnnotations(ctx, run.sm.KubernetesClient, run.leaseID, run.config.Interface, run.config.Mode(), run.config.Version)
+	for key, value := range run.config.LeaseAnnotations {
+		annotations[key] = value
+	}
+	if err := k8s.AnnotateLease(ctx, run.sm.KubernetesClient, run.config.Namespace, run.config.LeaseName, run.leaseID, annotations); err != nil && ctx.Err() == nil {
+		log.Warnf("unable to record the holder of lease [%s/%s]: %v", run.config.Namespace, run.config.LeaseName, err)
+	}
 }
 
 func (cluster *Cluster) runEtcdLeaderElectionOrDie(ctx context.Context, run *runConfig) {
@@ -287,6 +402,67 @@ func (cluster *Cluster) runEtcdLeaderElectionOrDie(ctx context.Context, run *run
 	})
 }
 
+func (cluster *Cluster) runVRRPLeaderElectionOrDie(ctx context.Context, run *runConfig) {
+	vrrpConfig := run.config.VRRP
+	if vrrpConfig.VirtualRouterID < 1 || vrrpConfig.VirtualRouterID > 255 || vrrpConfig.Priority < 1 || vrrpConfig.Priority > vrrp.OwnerPriority {
+		log.Fatalf("VRRP virtual router ID [%d] and priority [%d] must be between 1 and 255", vrrpConfig.VirtualRouterID, vrrpConfig.Priority)
+	}
+
+	// A VRRP group only advertises addresses of a single family, which is the family of the first VIP
+	var addresses []net.IP
+	for i := range cluster.Network {
+		ip := net.ParseIP(cluster.Network[i].IP())
+		if ip == nil {
+			continue
+		}
+		if len(addresses) > 0 && (ip.To4() == nil) != (addresses[0].To4() == nil) {
+			log.Warnf("[VRRP] address [%s] isn't the same family as [%s], it won't be advertised", ip, addresses[0])
+			continue
+		}
+		addresses = append(addresses, ip)
+	}
+
+	iface := run.config.Interface
+	if interfaces := vip.GetInterfaces(iface); len(interfaces) > 0 {
+		iface = interfaces[0]
+	}
+
+	vrrp.RunElectionOrDie(ctx, &vrrp.LeaderElectionConfig{
+		Interface:             iface,
+		VirtualRouterID:       uint8(vrrpConfig.VirtualRouterID),
+		Priority:              uint8(vrrpConfig.Priority),
+		AdvertisementInterval: time.Duration(vrrpConfig.AdvertisementInterval) * time.Millisecond,
+		Preempt:               vrrpConfig.Preempt,
+		Addresses:             addresses,
+		Callbacks: vrrp.LeaderCallbacks{
+			OnStartedLeading: run.onStartedLeading,
+			OnStoppedLeading: run.onStoppedLeading,
+			OnNewLeader:      run.onNewLeader,
+		},
+	})
+}
+
+func (cluster *Cluster) runRaftLeaderElectionOrDie(ctx context.Context, run *runConfig) {
+	raftConfig := run.config.Raft
+	members, err := raft.ParseMembers(raftConfig.Members)
+	if err != nil {
+		log.Fatalf("invalid Raft members: %v", err)
+	}
+
+	raft.RunElectionOrDie(ctx, &raft.LeaderElectionConfig{
+		Name:              run.leaseID,
+		Members:           members,
+		Key:               []byte(raftConfig.Key),
+		HeartbeatInterval: time.Duration(raftConfig.HeartbeatInterval) * time.Millisecond,
+		ElectionTimeout:   time.Duration(raftConfig.ElectionTimeout) * time.Millisecond,
+		Callbacks: raft.LeaderCallbacks{
+			OnStartedLeading: run.onStartedLeading,
+			OnStoppedLeading: run.onStoppedLeading,
+			OnNewLeader:      run.onNewLeader,
+		},
+	})
+}
+
 func (sm *Manager) NodeWatcher(lb *loadbalancer.IPVSLoadBalancer, port int) error {
 	// Use a restartable watcher, as this should help in the event of etcd or timeout issues
 	log.Infof("Kube-Vip is watching nodes for control-plane labels")
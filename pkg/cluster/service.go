@@ -17,7 +17,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func (cluster *Cluster) vipService(ctxArp, ctxDNS context.Context, c *kubevip.Config, sm *Manager, bgpServer *bgp.Server, packetClient *packngo.Client) error {
+// defaultArpBurstInterval is the time in milliseconds between gratuitous ARPs of a burst
+const defaultArpBurstInterval = 200
+
+func (cluster *Cluster) vipService(ctxArp, ctxDNS context.Context, c *kubevip.Config, sm *Manager, bgpServer bgp.Backend, packetClient *packngo.Client) error {
 	var err error
 
 	// listen for interrupts or the Linux SIGTERM signal and cancel
@@ -31,23 +34,27 @@ func (cluster *Cluster) vipService(ctxArp, ctxDNS context.Context, c *kubevip.Co
 	signal.Notify(signalChan, syscall.SIGTERM)
 
 	for i := range cluster.Network {
+		network := cluster.Network[i]
 
-		if cluster.Network[i].IsDDNS() {
+		if network.IsDDNS() {
 			if err := cluster.StartDDNS(ctxDNS); err != nil {
 				log.Error(err)
 			}
 		}
 
 		// start the dns updater if address is dns
-		if cluster.Network[i].IsDNS() {
-			log.Infof("starting the DNS updater for the address %s", cluster.Network[i].DNSName())
-			ipUpdater := vip.NewIPUpdater(cluster.Network[i])
+		if network.IsDNS() {
+			log.Infof("starting the DNS updater for the address %s", network.DNSName())
+			ipUpdater := vip.NewIPUpdater(network)
 			ipUpdater.Run(ctxDNS)
 		}
 
-		err = cluster.Network[i].AddIP()
-		if err != nil {
-			log.Fatalf("%v", err)
+		// With proxy ARP the address isn't bound, the ARP requests are answered in user space instead
+		if !c.ProxyARP {
+			err = network.AddIP()
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
 		}
 
 		if c.EnableMetal {
@@ -64,7 +71,7 @@ func (cluster *Cluster) vipService(ctxArp, ctxDNS context.Context, c *kubevip.Co
 
 		if c.EnableBGP {
 			// Lets advertise the VIP over BGP, the host needs to be passed using CIDR notation
-			cidrVip := fmt.Sprintf("%s/%s", cluster.Network[i].IP(), c.VIPCIDR)
+			cidrVip := fmt.Sprintf("%s/%s", network.IP(), c.VIPCIDR)
 			log.Debugf("Attempting to advertise the address [%s] over BGP", cidrVip)
 
 			err = bgpServer.AddHost(cidrVip)
@@ -77,7 +84,7 @@ func (cluster *Cluster) vipService(ctxArp, ctxDNS context.Context, c *kubevip.Co
 
 			log.Infof("Starting IPVS LoadBalancer")
 
-			lb, err := loadbalancer.NewIPVSLB(cluster.Network[i].IP(), c.LoadBalancerPort, c.LoadBalancerForwardingMethod, c.BackendHealthCheckInterval)
+			lb, err := loadbalancer.NewIPVSLB(network.IP(), c.LoadBalancerPort, c.LoadBalancerForwardingMethod, c.BackendHealthCheckInterval)
 			if err != nil {
 				log.Errorf("Error creating IPVS LoadBalancer [%s]", err)
 			}
@@ -102,13 +109,13 @@ func (cluster *Cluster) vipService(ctxArp, ctxDNS context.Context, c *kubevip.Co
 		if c.EnableARP {
 			// ctxArp, cancelArp = context.WithCancel(context.Background())
 
-			go func(ctx context.Context) {
-				ipString := cluster.Network[i].IP()
+			go func(ctx context.Context, network vip.Network) {
+				ipString := network.IP()
 				isIPv6 := vip.IsIPv6(ipString)
 
 				var ndp *vip.NdpResponder
 				if isIPv6 {
-					ndp, err = vip.NewNDPResponder(cluster.Network[i].Interface())
+					ndp, err = cluster.host.NewNDPResponder(network.Interface())
 					if err != nil {
 						log.Fatalf("failed to create new NDP Responder")
 					}
@@ -116,33 +123,48 @@ func (cluster *Cluster) vipService(ctxArp, ctxDNS context.Context, c *kubevip.Co
 
 				if ndp != nil {
 					defer ndp.Close()
+					go respondNDP(ndp, ipString)
+				} else if c.ProxyARP {
+					arp, err := vip.NewARPResponder(network.Interface())
+					if err != nil {
+						log.Fatalf("failed to create new ARP Responder: %v", err)
+					}
+					defer arp.Close()
+					go respondARP(arp, ipString)
+				}
+				log.Infof("Gratuitous Arp broadcast will repeat every 3 seconds for [%s/%s]", ipString, network.Interface())
+				if !cluster.gratuitousBurst(ctx, c, network, ndp) {
+					return
 				}
-				log.Infof("Gratuitous Arp broadcast will repeat every 3 seconds for [%s/%s]", ipString, cluster.Network[i].Interface())
 				for {
 					select {
 					case <-ctx.Done(): // if cancel() execute
 						return
 					default:
-						cluster.ensureIPAndSendGratuitous(cluster.Network[i].Interface(), ndp)
+						cluster.ensureIPAndSendGratuitous(c, network, ndp)
+					}
+					if !cluster.waitForAnnouncement(ctx, c, network, ndp, 3*time.Second) {
+						return
 					}
-					time.Sleep(3 * time.Second)
 				}
-			}(ctxArp)
+			}(ctxArp, network)
 		}
 
 		if c.EnableRoutingTable {
-			err = cluster.Network[i].AddRoute()
+			err = network.AddRoute()
 			if err != nil {
 				log.Warnf("%v", err)
 			}
 		}
+
+		go cluster.restoreNetwork(ctxArp, network)
 	}
 
 	return nil
 }
 
 // StartLoadBalancerService will start a VIP instance and leave it for kube-proxy to handle
-func (cluster *Cluster) StartLoadBalancerService(c *kubevip.Config, bgp *bgp.Server) {
+func (cluster *Cluster) StartLoadBalancerService(c *kubevip.Config, bgp bgp.Backend) {
 	// use a Go context so we can tell the arp loop code when we
 	// want to step down
 	//nolint
@@ -163,7 +185,7 @@ func (cluster *Cluster) StartLoadBalancerService(c *kubevip.Config, bgp *bgp.Ser
 			if err != nil {
 				log.Warnf("%v", err)
 			}
-		} else if !c.EnableRoutingTable {
+		} else if !c.EnableRoutingTable && !c.ProxyARP {
 			err = network.AddIP()
 			if err != nil {
 				log.Warnf("%v", err)
@@ -176,7 +198,7 @@ func (cluster *Cluster) StartLoadBalancerService(c *kubevip.Config, bgp *bgp.Ser
 			ipString := network.IP()
 			var ndp *vip.NdpResponder
 			if vip.IsIPv6(ipString) {
-				ndp, err = vip.NewNDPResponder(network.Interface())
+				ndp, err = cluster.host.NewNDPResponder(network.Interface())
 				if err != nil {
 					log.Fatalf("failed to create new NDP Responder")
 				}
@@ -184,8 +206,20 @@ func (cluster *Cluster) StartLoadBalancerService(c *kubevip.Config, bgp *bgp.Ser
 			go func(ctx context.Context) {
 				if ndp != nil {
 					defer ndp.Close()
+					go respondNDP(ndp, ipString)
+				} else if c.ProxyARP {
+					arp, err := vip.NewARPResponder(network.Interface())
+					if err != nil {
+						log.Errorf("failed to create new ARP Responder for %s: %v", ipString, err)
+						return
+					}
+					defer arp.Close()
+					go respondARP(arp, ipString)
 				}
 				log.Debugf("(svcs) broadcasting ARP update for %s via %s, every %dms", ipString, network.Interface(), c.ArpBroadcastRate)
+				if !cluster.gratuitousBurst(ctx, c, network, ndp) {
+					return
+				}
 
 				for {
 					select {
@@ -193,22 +227,26 @@ func (cluster *Cluster) StartLoadBalancerService(c *kubevip.Config, bgp *bgp.Ser
 						log.Debugf("(svcs) ending ARP update for %s via %s, every %dms", ipString, network.Interface(), c.ArpBroadcastRate)
 						return
 					default:
-						cluster.ensureIPAndSendGratuitous(network.Interface(), ndp)
+						cluster.ensureIPAndSendGratuitous(c, network, ndp)
 					}
 					if c.ArpBroadcastRate < 500 {
 						log.Errorf("arp broadcast rate is [%d], this shouldn't be lower that 300ms (defaulting to 3000)", c.ArpBroadcastRate)
 						c.ArpBroadcastRate = 3000
 					}
-					time.Sleep(time.Duration(c.ArpBroadcastRate) * time.Millisecond)
+					if !cluster.waitForAnnouncement(ctx, c, network, ndp, time.Duration(c.ArpBroadcastRate)*time.Millisecond) {
+						return
+					}
 				}
 			}(ctxArp)
 		}
 
+		go cluster.restoreNetwork(ctxArp, network)
+
 		if c.EnableBGP && (c.EnableLeaderElection || c.EnableServicesElection) {
 			// Lets advertise the VIP over BGP, the host needs to be passed using CIDR notation
 			cidrVip := fmt.Sprintf("%s/%s", network.IP(), c.VIPCIDR)
 			log.Debugf("(svcs) attempting to advertise the address [%s] over BGP", cidrVip)
-			err = bgp.AddHost(cidrVip)
+			err = bgp.AddHostWithAttributes(cidrVip, &c.BGPPathAttributes)
 			if err != nil {
 				log.Error(err)
 			}
@@ -244,48 +282,127 @@ func (cluster *Cluster) StartLoadBalancerService(c *kubevip.Config, bgp *bgp.Ser
 	}()
 }
 
+// gratuitousBurst sends the first gratuitous ARPs (or NDPs) of an announcement in a rapid burst, as
+// some switch fabrics need several before they update, it returns false if the context is cancelled
+func (cluster *Cluster) gratuitousBurst(ctx context.Context, c *kubevip.Config, network vip.Network, ndp *vip.NdpResponder) bool {
+	count, interval := c.ArpBurstCount, c.ArpBurstInterval
+	if ndp != nil {
+		count = c.NdpAdvertisementCount
+		if count == 0 {
+			count = vip.MaxNeighborAdvertisements
+		}
+	}
+	if interval <= 0 {
+		interval = defaultArpBurstInterval
+	}
+	// The last gratuitous ARP of the burst is the first of the regular broadcasts
+	for x := 1; x < count; x++ {
+		select {
+		case <-ctx.Done():
+			return false
+		default:
+			cluster.ensureIPAndSendGratuitous(c, network, ndp)
+		}
+		time.Sleep(time.Duration(interval) * time.Millisecond)
+	}
+	return true
+}
+
+// waitForAnnouncement waits until the next periodic announcement, if the link flaps (or a bond fails over)
+// in the meantime the burst is sent straight away so that the switches relearn the address. It returns
+// false if the context is cancelled.
+func (cluster *Cluster) waitForAnnouncement(ctx context.Context, c *kubevip.Config, network vip.Network, ndp *vip.NdpResponder, interval time.Duration) bool {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	case <-cluster.host.LinkFlapped(network.Interface()):
+		log.Infof("re-announcing [%s] as the link [%s] has flapped", network.IP(), network.Interface())
+		return cluster.gratuitousBurst(ctx, c, network, ndp)
+	}
+}
+
+// restoreNetwork re-applies the address and route of the VIP each time that the interface comes back up, as
+// they are lost if the interface is re-created (e.g. by an SR-IOV VF reset or a bond rebuild)
+func (cluster *Cluster) restoreNetwork(ctx context.Context, network vip.Network) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cluster.host.LinkFlapped(network.Interface()):
+			if err := network.Restore(); err != nil {
+				log.Warnf("unable to restore [%s] on interface [%s]: %v", network.IP(), network.Interface(), err)
+			}
+		}
+	}
+}
+
+// respondNDP answers the neighbor solicitations for an IPv6 VIP until the responder is closed
+func respondNDP(ndp *vip.NdpResponder, address string) {
+	if err := ndp.Respond(address); err != nil {
+		log.Warnf("NDP responder for [%s] has stopped: %v", address, err)
+	}
+}
+
+// respondARP answers the ARP requests for an unbound VIP until the responder is closed
+func respondARP(arp *vip.ARPResponder, address string) {
+	if err := arp.Respond(address); err != nil {
+		log.Warnf("ARP responder for [%s] has stopped: %v", address, err)
+	}
+}
+
 // ensureIPAndSendGratuitous - adds IP to the interface if missing, and send
 // either a gratuitous ARP or gratuitous NDP. Re-adds the interface if it is IPv6
 // and in a dadfailed state.
-func (cluster *Cluster) ensureIPAndSendGratuitous(iface string, ndp *vip.NdpResponder) {
-	for i := range cluster.Network {
-		ipString := cluster.Network[i].IP()
-		isIPv6 := vip.IsIPv6(ipString)
-		// Check if IP is dadfailed
-		if cluster.Network[i].IsDADFAIL() {
-			log.Warnf("IP address is in dadfailed state, removing [%s] from interface [%s]", ipString, iface)
-			err := cluster.Network[i].DeleteIP()
-			if err != nil {
-				log.Warnf("%v", err)
-			}
+func (cluster *Cluster) ensureIPAndSendGratuitous(c *kubevip.Config, network vip.Network, ndp *vip.NdpResponder) {
+	ipString := network.IP()
+	iface := network.Interface()
+	if c.ProxyARP {
+		// The address is deliberately not bound to the interface
+		cluster.sendGratuitous(ipString, iface, ndp)
+		return
+	}
+	// Check if IP is dadfailed
+	if network.IsDADFAIL() {
+		log.Warnf("IP address is in dadfailed state, removing [%s] from interface [%s]", ipString, iface)
+		err := network.DeleteIP()
+		if err != nil {
+			log.Warnf("%v", err)
 		}
+	}
 
-		// Ensure the address exists on the interface before attempting to ARP
-		set, err := cluster.Network[i].IsSet()
+	// Ensure the address exists on the interface before attempting to ARP
+	set, err := network.IsSet()
+	if err != nil {
+		log.Warnf("%v", err)
+	}
+	if !set {
+		log.Warnf("Re-applying the VIP configuration [%s] to the interface [%s]", ipString, iface)
+		err = network.AddIP()
 		if err != nil {
 			log.Warnf("%v", err)
 		}
-		if !set {
-			log.Warnf("Re-applying the VIP configuration [%s] to the interface [%s]", ipString, iface)
-			err = cluster.Network[i].AddIP()
-			if err != nil {
-				log.Warnf("%v", err)
-			}
-		}
+	}
 
-		if isIPv6 {
-			// Gratuitous NDP, will broadcast new MAC <-> IPv6 address
-			err := ndp.SendGratuitous(ipString)
-			if err != nil {
-				log.Warnf("%v", err)
-			}
-		} else {
-			// Gratuitous ARP, will broadcast to new MAC <-> IPv4 address
-			err := vip.ARPSendGratuitous(ipString, iface)
-			if err != nil {
-				log.Warnf("%v", err)
-			}
+	cluster.sendGratuitous(ipString, iface, ndp)
+}
+
+// sendGratuitous sends either a gratuitous ARP or gratuitous NDP
+func (cluster *Cluster) sendGratuitous(ipString, iface string, ndp *vip.NdpResponder) {
+	if vip.IsIPv6(ipString) {
+		// Gratuitous NDP, will broadcast new MAC <-> IPv6 address
+		err := ndp.SendGratuitous(ipString)
+		if err != nil {
+			log.Warnf("%v", err)
+		}
+	} else {
+		// Gratuitous ARP, will broadcast to new MAC <-> IPv4 address
+		err := cluster.host.ARPSendGratuitous(ipString, iface)
+		if err != nil {
+			log.Warnf("%v", err)
 		}
 	}
-
 }
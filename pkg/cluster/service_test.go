@@ -0,0 +1,65 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	"github.com/kube-vip/kube-vip/pkg/vip"
+)
+
+// announcedNetwork is a network that records whether its address has been checked before an announcement
+type announcedNetwork struct {
+	vip.Network
+	address string
+
+	mutex   sync.Mutex
+	checked bool
+}
+
+func (n *announcedNetwork) IP() string        { return n.address }
+func (n *announcedNetwork) Interface() string { return "kube-vip-test0" }
+func (n *announcedNetwork) IsDNS() bool       { return false }
+func (n *announcedNetwork) IsDDNS() bool      { return false }
+func (n *announcedNetwork) IsDADFAIL() bool   { return false }
+func (n *announcedNetwork) AddIP() error      { return nil }
+
+func (n *announcedNetwork) IsSet() (bool, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.checked = true
+	return true, nil
+}
+
+func (n *announcedNetwork) announced() bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.checked
+}
+
+func TestVipServiceAnnouncesEachNetwork(t *testing.T) {
+	networks := []*announcedNetwork{{address: "192.0.2.1"}, {address: "192.0.2.2"}}
+	cluster := &Cluster{host: vip.NewHost(0, 0)}
+	for _, network := range networks {
+		cluster.Network = append(cluster.Network, network)
+	}
+	c := &kubevip.Config{EnableARP: true, ArpBurstCount: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := cluster.vipService(ctx, ctx, c, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, network := range networks {
+		for !network.announced() {
+			if time.Now().After(deadline) {
+				t.Fatalf("the address [%s] was never announced", network.address)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
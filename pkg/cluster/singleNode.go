@@ -8,7 +8,6 @@ import (
 
 	"github.com/kube-vip/kube-vip/pkg/bgp"
 	"github.com/kube-vip/kube-vip/pkg/kubevip"
-	"github.com/kube-vip/kube-vip/pkg/vip"
 )
 
 // StartSingleNode will start a single node cluster
@@ -39,7 +38,7 @@ func (cluster *Cluster) StartSingleNode(c *kubevip.Config, disableVIP bool) erro
 
 		if c.EnableARP {
 			// Gratuitous ARP, will broadcast to new MAC <-> IP
-			err := vip.ARPSendGratuitous(cluster.Network[i].IP(), c.Interface)
+			err := cluster.host.ARPSendGratuitous(cluster.Network[i].IP(), cluster.Network[i].Interface())
 			if err != nil {
 				log.Warnf("%v", err)
 			}
@@ -64,7 +63,7 @@ func (cluster *Cluster) StartSingleNode(c *kubevip.Config, disableVIP bool) erro
 	return nil
 }
 
-func (cluster *Cluster) StartVipService(c *kubevip.Config, sm *Manager, bgp *bgp.Server, packetClient *packngo.Client) error {
+func (cluster *Cluster) StartVipService(c *kubevip.Config, sm *Manager, bgp bgp.Backend, packetClient *packngo.Client) error {
 	// use a Go context so we can tell the arp loop code when we
 	// want to step down
 	ctxArp, cancelArp := context.WithCancel(context.Background())
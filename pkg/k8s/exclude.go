@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ExcludeAnnotation is the node annotation that excludes a node from the VIP elections
+const ExcludeAnnotation = "kube-vip.io/exclude-from-election"
+
+// nodeSyncTimeout is how long the node is waited for before it is assumed to be included in the elections, e.g.
+// whilst the API server isn't reachable yet
+const nodeSyncTimeout = 10 * time.Second
+
+// WaitForNodeIncluded blocks until the node is no longer excluded from the VIP elections. A node is excluded by the
+// exclude annotation, by matching the label selector or by having a taint with one of the keys. A node that can't be
+// found is assumed to be included.
+func WaitForNodeIncluded(ctx context.Context, client kubernetes.Interface, name, selector, taints string) error {
+	w := watchNode(client, name)
+	syncCtx, cancel := context.WithTimeout(ctx, nodeSyncTimeout)
+	cache.WaitForCacheSync(syncCtx.Done(), w.informer.HasSynced)
+	cancel()
+
+	check := excludedCheck(selector, taints)
+	logged := ""
+	for {
+		node, changed := w.watch()
+		if node == nil {
+			return ctx.Err()
+		}
+		reason := check(node)
+		if reason == "" {
+			return ctx.Err()
+		} else if reason != logged {
+			log.Infof("node [%s] is %s, it doesn't take part in the elections", name, reason)
+			logged = reason
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// NodeIncludedContext returns a copy of the context that is cancelled when the node is excluded from the VIP elections
+func NodeIncludedContext(ctx context.Context, client kubernetes.Interface, name, selector, taints string) (context.Context, context.CancelFunc) {
+	return nodeContext(ctx, client, name, excludedCheck(selector, taints), "included")
+}
+
+// excludedCheck returns the check of the nodes that are excluded from the elections, a node with an invalid selector
+// isn't excluded
+func excludedCheck(selector, taints string) func(*v1.Node) string {
+	return func(node *v1.Node) string {
+		reason, err := nodeExcluded(node, selector, taints)
+		if err != nil {
+			log.Errorf("unable to find if [%s] is excluded from the elections: %v", node.Name, err)
+			return ""
+		}
+		if reason == "" {
+			return ""
+		}
+		return "excluded by its " + reason
+	}
+}
+
+func nodeExcluded(node *v1.Node, selector, taints string) (string, error) {
+	if node.Annotations[ExcludeAnnotation] == "true" {
+		return fmt.Sprintf("annotation [%s]", ExcludeAnnotation), nil
+	}
+	if selector != "" {
+		s, err := labels.Parse(selector)
+		if err != nil {
+			return "", fmt.Errorf("invalid election exclude selector [%s]: %w", selector, err)
+		}
+		if s.Matches(labels.Set(node.Labels)) {
+			return fmt.Sprintf("labels matching [%s]", selector), nil
+		}
+	}
+	for _, key := range strings.Split(taints, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		for _, taint := range node.Spec.Taints {
+			if taint.Key == key {
+				return fmt.Sprintf("taint [%s]", key), nil
+			}
+		}
+	}
+	return "", nil
+}
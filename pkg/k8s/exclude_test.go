@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeExcluded(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"node-role": "storage"}},
+		Spec:       v1.NodeSpec{Taints: []v1.Taint{{Key: "edge", Effect: v1.TaintEffectNoSchedule}}},
+	}
+	tests := []struct {
+		selector, taints string
+		excluded         bool
+	}{
+		{"", "", false},
+		{"node-role=storage", "", true},
+		{"node-role=compute", "", false},
+		{"", "dedicated, edge", true},
+		{"", "dedicated", false},
+	}
+	for _, tt := range tests {
+		reason, err := nodeExcluded(node, tt.selector, tt.taints)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (reason != "") != tt.excluded {
+			t.Errorf("selector [%s] taints [%s]: excluded [%s], want %v", tt.selector, tt.taints, reason, tt.excluded)
+		}
+	}
+
+	if _, err := nodeExcluded(node, "node-role in (", ""); err == nil {
+		t.Error("invalid selector should be an error")
+	}
+	node.Annotations = map[string]string{ExcludeAnnotation: "true"}
+	if reason, _ := nodeExcluded(node, "", ""); reason == "" {
+		t.Error("annotated node should be excluded")
+	}
+}
+
+func TestWatchNodeExcluded(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Annotations: map[string]string{ExcludeAnnotation: "true"}}}
+	client := fake.NewSimpleClientset(node)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	included := make(chan error)
+	go func() { included <- WaitForNodeIncluded(ctx, client, "node1", "", "") }()
+	select {
+	case err := <-included:
+		t.Fatalf("WaitForNodeIncluded() returned %v for an excluded node", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	node.Annotations = nil
+	if _, err := client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-included; err != nil {
+		t.Fatalf("WaitForNodeIncluded() = %v", err)
+	}
+
+	// The node is excluded whilst it leads, e.g. by a taint
+	nodeCtx, nodeCancel := NodeIncludedContext(ctx, client, "node1", "", "dedicated")
+	defer nodeCancel()
+	node.Spec.Taints = []v1.Taint{{Key: "dedicated", Effect: v1.TaintEffectNoSchedule}}
+	if _, err := client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	<-nodeCtx.Done()
+	if ctx.Err() != nil {
+		t.Fatal("NodeIncludedContext() wasn't cancelled when the node was excluded")
+	}
+}
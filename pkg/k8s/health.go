@@ -0,0 +1,206 @@
+package k8s
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// nodeUnhealthy returns why the node is unhealthy, or an empty string if it is healthy. A node is unhealthy
+// when it isn't Ready or it is under disk, memory or PID pressure.
+func nodeUnhealthy(node *v1.Node) string {
+	ready := false
+	for _, condition := range node.Status.Conditions {
+		switch condition.Type {
+		case v1.NodeReady:
+			ready = condition.Status == v1.ConditionTrue
+		case v1.NodeDiskPressure, v1.NodeMemoryPressure, v1.NodePIDPressure:
+			if condition.Status == v1.ConditionTrue {
+				return string(condition.Type)
+			}
+		}
+	}
+	if !ready {
+		return "NotReady"
+	}
+	return ""
+}
+
+// drainTaints are the taints that are added to a node when it is being drained or removed
+var drainTaints = []string{
+	"node.kubernetes.io/unschedulable",
+	"ToBeDeletedByClusterAutoscaler",
+}
+
+// NodeDraining returns true if a node has been cordoned or tainted for draining
+func NodeDraining(node *v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return true
+	}
+	for _, taint := range node.Spec.Taints {
+		for _, key := range drainTaints {
+			if taint.Key == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nodeUnschedulable returns why the node is unschedulable, or an empty string if it is schedulable
+func nodeUnschedulable(node *v1.Node) string {
+	if NodeDraining(node) {
+		return "Unschedulable"
+	}
+	return ""
+}
+
+// nodeWatcher is the watch of a node that is shared by everything waiting on (or monitoring) its conditions, so that
+// there is a single field selected informer of the node per process rather than polling it for each election
+type nodeWatcher struct {
+	informer cache.SharedIndexInformer
+	name     string
+
+	mutex sync.Mutex
+	// changed is closed (and replaced) when the node changes
+	changed chan struct{}
+}
+
+// nodeWatcherKey identifies the watch of a node, by the client and the name of the node
+type nodeWatcherKey struct {
+	client kubernetes.Interface
+	name   string
+}
+
+var (
+	nodeWatchersMutex sync.Mutex
+	nodeWatchers      = make(map[nodeWatcherKey]*nodeWatcher)
+)
+
+// watchNode returns the shared watch of the node, starting its informer (which runs for the life of the process)
+// when it is first used
+func watchNode(client kubernetes.Interface, name string) *nodeWatcher {
+	nodeWatchersMutex.Lock()
+	defer nodeWatchersMutex.Unlock()
+	key := nodeWatcherKey{client: client, name: name}
+	if w, exists := nodeWatchers[key]; exists {
+		return w
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}))
+	w := &nodeWatcher{
+		informer: factory.Core().V1().Nodes().Informer(),
+		name:     name,
+		changed:  make(chan struct{}),
+	}
+	notify := func(interface{}) { w.notify() }
+	if _, err := w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, obj interface{}) { notify(obj) },
+		DeleteFunc: notify,
+	}); err != nil {
+		log.Errorf("unable to watch node [%s]: %v", name, err)
+	}
+	go w.informer.Run(make(chan struct{}))
+	nodeWatchers[key] = w
+	return w
+}
+
+func (w *nodeWatcher) notify() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
+
+// watch returns the node (nil until it is known), and a channel that is closed when it changes
+func (w *nodeWatcher) watch() (*v1.Node, <-chan struct{}) {
+	w.mutex.Lock()
+	changed := w.changed
+	w.mutex.Unlock()
+	obj, exists, err := w.informer.GetStore().GetByKey(w.name)
+	if err != nil || !exists {
+		return nil, changed
+	}
+	node, _ := obj.(*v1.Node)
+	return node, changed
+}
+
+// WaitForNodeHealthy blocks until the node is Ready and not under pressure
+func WaitForNodeHealthy(ctx context.Context, client kubernetes.Interface, name string) error {
+	return waitForNode(ctx, client, name, nodeUnhealthy, "healthy")
+}
+
+// WaitForNodeSchedulable blocks until the node is no longer cordoned or tainted for draining
+func WaitForNodeSchedulable(ctx context.Context, client kubernetes.Interface, name string) error {
+	return waitForNode(ctx, client, name, nodeUnschedulable, "schedulable")
+}
+
+// waitForNode blocks until the node passes the check
+func waitForNode(ctx context.Context, client kubernetes.Interface, name string, check func(*v1.Node) string, state string) error {
+	w := watchNode(client, name)
+	logged := ""
+	for {
+		node, changed := w.watch()
+		if node != nil {
+			reason := check(node)
+			if reason == "" {
+				return nil
+			} else if reason != logged {
+				log.Infof("waiting for node [%s] to be %s, it is %s", name, state, reason)
+				logged = reason
+			}
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// NodeHealthContext returns a copy of the context that is cancelled when the node becomes NotReady or comes
+// under pressure. The node is assumed to still be healthy whilst it is unknown to the watch, as the API
+// server being unreachable says nothing about the node.
+func NodeHealthContext(ctx context.Context, client kubernetes.Interface, name string) (context.Context, context.CancelFunc) {
+	return nodeContext(ctx, client, name, nodeUnhealthy, "healthy")
+}
+
+// NodeSchedulableContext returns a copy of the context that is cancelled when the node is cordoned or tainted
+// for draining
+func NodeSchedulableContext(ctx context.Context, client kubernetes.Interface, name string) (context.Context, context.CancelFunc) {
+	return nodeContext(ctx, client, name, nodeUnschedulable, "schedulable")
+}
+
+// nodeContext returns a copy of the context that is cancelled when the node fails the check
+func nodeContext(ctx context.Context, client kubernetes.Interface, name string, check func(*v1.Node) string, state string) (context.Context, context.CancelFunc) {
+	nodeCtx, cancel := context.WithCancel(ctx)
+	w := watchNode(client, name)
+	go func() {
+		for {
+			node, changed := w.watch()
+			if node != nil {
+				if reason := check(node); reason != "" {
+					log.Warnf("node [%s] is %s, relinquishing leadership", name, reason)
+					cancel()
+					return
+				}
+			}
+			select {
+			case <-changed:
+			case <-nodeCtx.Done():
+				return
+			}
+		}
+	}()
+	return nodeCtx, cancel
+}
@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeUnhealthy(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []v1.NodeCondition
+		want       string
+	}{
+		{"ready", []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}, ""},
+		{"not ready", []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}, "NotReady"},
+		{"unknown", []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionUnknown}}, "NotReady"},
+		{"no conditions", nil, "NotReady"},
+		{"disk pressure", []v1.NodeCondition{
+			{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			{Type: v1.NodeDiskPressure, Status: v1.ConditionTrue},
+		}, "DiskPressure"},
+		{"no memory pressure", []v1.NodeCondition{
+			{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse},
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &v1.Node{Status: v1.NodeStatus{Conditions: tt.conditions}}
+			if got := nodeUnhealthy(node); got != tt.want {
+				t.Errorf("nodeUnhealthy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeUnschedulable(t *testing.T) {
+	tests := []struct {
+		name string
+		node *v1.Node
+		want string
+	}{
+		{"schedulable", &v1.Node{}, ""},
+		{"cordoned", &v1.Node{Spec: v1.NodeSpec{Unschedulable: true}}, "Unschedulable"},
+		{"autoscaler", &v1.Node{Spec: v1.NodeSpec{Taints: []v1.Taint{{Key: "ToBeDeletedByClusterAutoscaler"}}}}, "Unschedulable"},
+		{"other taint", &v1.Node{Spec: v1.NodeSpec{Taints: []v1.Taint{{Key: "dedicated"}}}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nodeUnschedulable(tt.node); got != tt.want {
+				t.Errorf("nodeUnschedulable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchNode(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}},
+	}
+	client := fake.NewSimpleClientset(node)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	healthy := make(chan error)
+	go func() { healthy <- WaitForNodeHealthy(ctx, client, "node1") }()
+	select {
+	case err := <-healthy:
+		t.Fatalf("WaitForNodeHealthy() returned %v for a NotReady node", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	node.Status.Conditions[0].Status = v1.ConditionTrue
+	if _, err := client.CoreV1().Nodes().UpdateStatus(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-healthy; err != nil {
+		t.Fatalf("WaitForNodeHealthy() = %v", err)
+	}
+
+	nodeCtx, nodeCancel := NodeHealthContext(ctx, client, "node1")
+	defer nodeCancel()
+	node.Status.Conditions[0].Status = v1.ConditionFalse
+	if _, err := client.CoreV1().Nodes().UpdateStatus(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	<-nodeCtx.Done()
+	if ctx.Err() != nil {
+		t.Fatal("NodeHealthContext() wasn't cancelled when the node became NotReady")
+	}
+
+	if watchNode(client, "node1") != watchNode(client, "node1") {
+		t.Error("watchNode() doesn't share the watch of the node")
+	}
+}
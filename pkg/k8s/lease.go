@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// The annotations that describe the holder of a lease, so that "who owns this VIP" is answered by the lease
+const (
+	HolderAnnotation          = "kube-vip.io/holder"
+	HolderAddressAnnotation   = "kube-vip.io/holder-address"
+	HolderInterfaceAnnotation = "kube-vip.io/holder-interface"
+	HolderModeAnnotation      = "kube-vip.io/holder-mode"
+	HolderVersionAnnotation   = "kube-vip.io/holder-version"
+)
+
+// HolderAnnotations returns the annotations that describe the node as the holder of a lease, the address of
+// the node is left out if it can't be found
+func HolderAnnotations(ctx context.Context, client kubernetes.Interface, identity, iface, mode, version string) map[string]string {
+	annotations := map[string]string{
+		HolderAnnotation:          identity,
+		HolderInterfaceAnnotation: iface,
+		HolderModeAnnotation:      mode,
+		HolderVersionAnnotation:   version,
+	}
+	if node, err := client.CoreV1().Nodes().Get(ctx, identity, metav1.GetOptions{}); err == nil {
+		if address := nodeAddress(node); address != "" {
+			annotations[HolderAddressAnnotation] = address
+		}
+	}
+	return annotations
+}
+
+// nodeAddress returns the internal address of the node, or failing that its external address
+func nodeAddress(node *v1.Node) string {
+	for _, addressType := range []v1.NodeAddressType{v1.NodeInternalIP, v1.NodeExternalIP} {
+		for _, address := range node.Status.Addresses {
+			if address.Type == addressType {
+				return address.Address
+			}
+		}
+	}
+	return ""
+}
+
+// AnnotateLease adds the annotations to the lease, as long as it is still held by the identity. The renewal of
+// the lease reads it before updating it, so the annotations are kept until the lease changes hands.
+func AnnotateLease(ctx context.Context, client kubernetes.Interface, namespace, name, identity string, annotations map[string]string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lease, err := client.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != identity {
+			return fmt.Errorf("lease [%s/%s] is no longer held by [%s]", namespace, name, identity)
+		}
+		if lease.Annotations == nil {
+			lease.Annotations = make(map[string]string)
+		}
+		for key, value := range annotations {
+			if value == "" {
+				delete(lease.Annotations, key)
+				continue
+			}
+			lease.Annotations[key] = value
+		}
+		_, err = client.CoordinationV1().Leases(namespace).Update(ctx, lease, metav1.UpdateOptions{})
+		return err
+	})
+}
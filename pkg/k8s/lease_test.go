@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAnnotateLease(t *testing.T) {
+	holder := "node-1"
+	client := fake.NewSimpleClientset(
+		&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "plndr-cp-lock", Namespace: "kube-system", Annotations: map[string]string{"other": "kept"}},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder},
+		},
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: holder},
+			Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: holder},
+				{Type: v1.NodeInternalIP, Address: "192.168.0.10"},
+			}},
+		},
+	)
+	ctx := context.Background()
+
+	annotations := HolderAnnotations(ctx, client, holder, "eth0", "arp", "v0.7.0")
+	if annotations[HolderAddressAnnotation] != "192.168.0.10" {
+		t.Errorf("HolderAnnotations() address = %q, want %q", annotations[HolderAddressAnnotation], "192.168.0.10")
+	}
+	if err := AnnotateLease(ctx, client, "kube-system", "plndr-cp-lock", holder, annotations); err != nil {
+		t.Fatalf("AnnotateLease() error = %v", err)
+	}
+	lease, err := client.CoordinationV1().Leases("kube-system").Get(ctx, "plndr-cp-lock", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		HolderAnnotation:          holder,
+		HolderInterfaceAnnotation: "eth0",
+		HolderModeAnnotation:      "arp",
+		HolderVersionAnnotation:   "v0.7.0",
+		"other":                   "kept",
+	} {
+		if got := lease.Annotations[key]; got != want {
+			t.Errorf("lease annotation [%s] = %q, want %q", key, got, want)
+		}
+	}
+
+	if err := AnnotateLease(ctx, client, "kube-system", "plndr-cp-lock", "node-2", annotations); err == nil {
+		t.Errorf("AnnotateLease() by a node that doesn't hold the lease should fail")
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"math"
 	"math/bits"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/kube-vip/kube-vip/pkg/bgp"
 	"github.com/kube-vip/kube-vip/pkg/detector"
@@ -44,6 +46,17 @@ func ParseEnvironment(c *Config) error {
 		c.ServicesInterface = env
 	}
 
+	// Find the managed interface
+	env = os.Getenv(vipManagedInterfaceType)
+	if env != "" {
+		c.ManagedInterfaceType = env
+	}
+
+	env = os.Getenv(vipManagedInterfaceName)
+	if env != "" {
+		c.ManagedInterfaceName = env
+	}
+
 	// Find provider configuration
 	env = os.Getenv(providerConfig)
 	if env != "" {
@@ -103,6 +116,45 @@ func ParseEnvironment(c *Config) error {
 		}
 	}
 
+	// Find the leader priority configuration
+	env = os.Getenv(vipLeaderPriority)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.EnableLeaderPriority = b
+	}
+
+	env = os.Getenv(vipLeaderPreemption)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.LeaderPreemption = b
+	}
+
+	env = os.Getenv(vipLeaderFailbackDelay)
+	if env != "" {
+		i, err := strconv.ParseInt(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.LeaderFailbackDelay = int(i)
+	}
+
+	// Find the nodes that are excluded from the elections
+	env = os.Getenv(vipElectionExcludeSelector)
+	if env != "" {
+		c.ElectionExcludeSelector = env
+	}
+
+	env = os.Getenv(vipElectionExcludeTaints)
+	if env != "" {
+		c.ElectionExcludeTaints = env
+	}
+
 	env = os.Getenv(nodeName)
 	if env != "" {
 		c.NodeName = env
@@ -184,6 +236,66 @@ func ParseEnvironment(c *Config) error {
 		}
 		c.EnableServices = b
 
+		// Find the number of services that are processed in parallel
+		env = os.Getenv(svcWorkers)
+		if env != "" {
+			i, err := strconv.ParseInt(env, 10, 32)
+			if err != nil {
+				return err
+			}
+			c.ServicesWorkers = int(i)
+		}
+
+		// Find how often the services are checked for drift
+		env = os.Getenv(svcDriftInterval)
+		if env != "" {
+			i, err := strconv.ParseInt(env, 10, 32)
+			if err != nil {
+				return err
+			}
+			c.ServicesDriftInterval = int(i)
+		}
+
+		// Find the number of ready endpoints that the services require before they are announced
+		env = os.Getenv(svcMinReadyEndpoints)
+		if env != "" {
+			i, err := strconv.ParseInt(env, 10, 32)
+			if err != nil {
+				return err
+			}
+			c.ServicesMinReadyEndpoints = int(i)
+		}
+
+		// Find if the external IPs of the services are announced
+		env = os.Getenv(svcExternalIPs)
+		if env != "" {
+			b, err := strconv.ParseBool(env)
+			if err != nil {
+				return err
+			}
+			c.EnableServicesExternalIPs = b
+		}
+
+		// Find if a finalizer is added to the services
+		env = os.Getenv(svcFinalizer)
+		if env != "" {
+			b, err := strconv.ParseBool(env)
+			if err != nil {
+				return err
+			}
+			c.EnableServicesFinalizer = b
+		}
+
+		// Find if the services elections are relinquished whilst the node is cordoned
+		env = os.Getenv(svcAvoidUnschedulable)
+		if env != "" {
+			b, err := strconv.ParseBool(env)
+			if err != nil {
+				return err
+			}
+			c.ServicesAvoidUnschedulable = b
+		}
+
 		// Find Services leader Election
 		env = os.Getenv(svcElection)
 		if env != "" {
@@ -194,6 +306,97 @@ func ParseEnvironment(c *Config) error {
 			c.EnableServicesElection = b
 		}
 
+		// Find the size of the pool that services are elected by
+		env = os.Getenv(svcElectionPool)
+		if env != "" {
+			c.ServicesElectionPool = env
+		}
+
+		// Find the number of shards that services are elected by
+		env = os.Getenv(svcElectionShards)
+		if env != "" {
+			i, err := strconv.ParseInt(env, 10, 32)
+			if err != nil {
+				return err
+			}
+			c.ServicesElectionShards = int(i)
+		}
+
+		// Find the timings of the services elections
+		env = os.Getenv(svcLeaseDuration)
+		if env != "" {
+			i, err := strconv.ParseInt(env, 10, 32)
+			if err != nil {
+				return err
+			}
+			c.ServicesLeaseDuration = int(i)
+		}
+
+		env = os.Getenv(svcRenewDeadline)
+		if env != "" {
+			i, err := strconv.ParseInt(env, 10, 32)
+			if err != nil {
+				return err
+			}
+			c.ServicesRenewDeadline = int(i)
+		}
+
+		env = os.Getenv(svcRetryPeriod)
+		if env != "" {
+			i, err := strconv.ParseInt(env, 10, 32)
+			if err != nil {
+				return err
+			}
+			c.ServicesRetryPeriod = int(i)
+		}
+
+		// Find the spreading of the services across the nodes
+		env = os.Getenv(svcSpread)
+		if env != "" {
+			b, err := strconv.ParseBool(env)
+			if err != nil {
+				return err
+			}
+			c.EnableServicesSpread = b
+		}
+
+		env = os.Getenv(svcRebalanceInterval)
+		if env != "" {
+			i, err := strconv.ParseInt(env, 10, 32)
+			if err != nil {
+				return err
+			}
+			c.ServicesRebalanceInterval = int(i)
+		}
+
+		env = os.Getenv(svcRebalanceMaxMoves)
+		if env != "" {
+			i, err := strconv.ParseInt(env, 10, 32)
+			if err != nil {
+				return err
+			}
+			c.ServicesRebalanceMaxMoves = int(i)
+		}
+
+		env = os.Getenv(svcRebalanceOnJoin)
+		if env != "" {
+			b, err := strconv.ParseBool(env)
+			if err != nil {
+				return err
+			}
+			c.ServicesRebalanceOnJoin = b
+		}
+
+		// Find the topology awareness of the services elections
+		env = os.Getenv(svcTopology)
+		if env != "" {
+			b, err := strconv.ParseBool(env)
+			if err != nil {
+				return err
+			}
+			c.EnableServicesTopology = b
+		}
+
 		// Find load-balancer class only
 		env = os.Getenv(lbClassOnly)
 		if env != "" {
@@ -220,12 +423,42 @@ func ParseEnvironment(c *Config) error {
 			c.LoadBalancerClassLegacyHandling = b
 		}
 
+		// Load-balancer for the services without a class
+		env = os.Getenv(lbClassAdoptUnclassed)
+		if env != "" {
+			b, err := strconv.ParseBool(env)
+			if err != nil {
+				return err
+			}
+			c.LoadBalancerClassAdoptUnclassed = b
+		}
+
 		// Find the namespace that the control plane should use (for leaderElection lock)
 		env = os.Getenv(svcNamespace)
 		if env != "" {
 			c.ServiceNamespace = env
 		}
 
+		// Find the label selector of the services that are watched
+		env = os.Getenv(svcLabelSelector)
+		if env != "" {
+			c.ServicesLabelSelector = env
+		}
+
+		// Find the criteria of the services that are ignored
+		env = os.Getenv(svcIgnoreLabelSelector)
+		if env != "" {
+			c.ServicesIgnoreLabelSelector = env
+		}
+		env = os.Getenv(svcIgnoreAnnotations)
+		if env != "" {
+			c.ServicesIgnoreAnnotations = env
+		}
+		env = os.Getenv(svcIgnoreNamespaces)
+		if env != "" {
+			c.ServicesIgnoreNamespaces = env
+		}
+
 		// Gets the leaseName for services in arp mode
 		env = os.Getenv(svcLeaseName)
 		if env != "" {
@@ -283,6 +516,46 @@ func ParseEnvironment(c *Config) error {
 		c.EnableARP = b
 	}
 
+	// Find if proxy ARP is enabled
+	env = os.Getenv(vipProxyArp)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.ProxyARP = b
+	}
+
+	// Find if the arp sysctls are managed
+	env = os.Getenv(vipManageARPSysctls)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.ManageARPSysctls = b
+	}
+
+	// Find if the carrier of the interface is monitored
+	env = os.Getenv(vipMonitorCarrier)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.MonitorCarrier = b
+	}
+
+	// Find if the health of the node is monitored
+	env = os.Getenv(vipMonitorNodeHealth)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.MonitorNodeHealth = b
+	}
+
 	// Find if ARP is enabled
 	env = os.Getenv(vipArpRate)
 	if env != "" {
@@ -296,6 +569,40 @@ func ParseEnvironment(c *Config) error {
 		c.ArpBroadcastRate = 3000
 	}
 
+	// Find the size and cadence of the gARP burst when a VIP is first announced
+	env = os.Getenv(vipArpBurstCount)
+	if env != "" {
+		i, err := strconv.Atoi(env)
+		if err != nil {
+			return err
+		}
+		c.ArpBurstCount = i
+	}
+	env = os.Getenv(vipArpBurstInterval)
+	if env != "" {
+		i64, err := strconv.ParseInt(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.ArpBurstInterval = i64
+	}
+	env = os.Getenv(vipNdpAdvertisementCount)
+	if env != "" {
+		i, err := strconv.Atoi(env)
+		if err != nil {
+			return err
+		}
+		c.NdpAdvertisementCount = i
+	}
+	env = os.Getenv(vipAnnouncementRateLimit)
+	if env != "" {
+		i, err := strconv.Atoi(env)
+		if err != nil {
+			return err
+		}
+		c.AnnouncementRateLimit = i
+	}
+
 	// Wireguard Mode
 	env = os.Getenv(vipWireguard)
 	if env != "" {
@@ -316,6 +623,51 @@ func ParseEnvironment(c *Config) error {
 		c.EnableRoutingTable = b
 	}
 
+	// Routing metric
+	env = os.Getenv(vipRoutingMetric)
+	if env != "" {
+		i, err := strconv.ParseInt(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		if i < 0 {
+			return fmt.Errorf("no support of negative [%d] in env var %q", i, vipRoutingMetric)
+		}
+		c.RoutingMetric = int(i)
+	}
+
+	// Routing source
+	env = os.Getenv(vipRoutingSource)
+	if env != "" {
+		c.RoutingSource = env
+	}
+
+	// Routing table ECMP
+	env = os.Getenv(vipRoutingTableECMP)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.RoutingTableECMP = b
+	}
+
+	// Routing blackhole grace period
+	env = os.Getenv(vipRoutingBlackholeGracePeriod)
+	if env != "" {
+		i, err := strconv.ParseInt(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.RoutingBlackholeGracePeriod = int(i)
+	}
+
+	// Routing VRF
+	env = os.Getenv(vipRoutingVRF)
+	if env != "" {
+		c.RoutingVRF = env
+	}
+
 	// Routing Table ID
 	env = os.Getenv(vipRoutingTableID)
 	if env != "" {
@@ -399,6 +751,16 @@ func ParseEnvironment(c *Config) error {
 		c.BGPConfig.RouterID = address
 	}
 
+	// Routing backend
+	env = os.Getenv(bgpBackend)
+	if env != "" {
+		c.BGPConfig.Backend = env
+	}
+	env = os.Getenv(bgpVtyshPath)
+	if env != "" {
+		c.BGPConfig.VtyshPath = env
+	}
+
 	// RouterID
 	env = os.Getenv(bgpRouterID)
 	if env != "" {
@@ -415,6 +777,34 @@ func ParseEnvironment(c *Config) error {
 		c.BGPConfig.AS = uint32(u64)
 	}
 
+	// Automatic AS
+	env = os.Getenv(bgpASAuto)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.AutoAS = b
+	}
+
+	// Confederation
+	env = os.Getenv(bgpConfederationID)
+	if env != "" {
+		u64, err := strconv.ParseUint(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.Confederation.Identifier = uint32(u64)
+	}
+	env = os.Getenv(bgpConfederationMembers)
+	if env != "" {
+		members, err := bgp.ParseASList(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.Confederation.MemberAS = members
+	}
+
 	// Peer AS
 	env = os.Getenv(bgpPeerAS)
 	if env != "" {
@@ -425,6 +815,87 @@ func ParseEnvironment(c *Config) error {
 		c.BGPPeerConfig.AS = uint32(u64)
 	}
 
+	// Discover the BGP peers from the node annotations
+	env = os.Getenv(bgpPeersFromNode)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPPeersFromNode = b
+	}
+
+	// Read the BGP peers from a ConfigMap
+	env = os.Getenv(bgpPeersConfigMap)
+	if env != "" {
+		c.BGPPeersConfigMap = env
+	}
+
+	// Publish the health of the BGP sessions as a node condition
+	env = os.Getenv(bgpNodeCondition)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPNodeCondition = b
+	}
+
+	// Withdraw (or de-preference) the BGP advertisements whilst the node is draining
+	env = os.Getenv(bgpDrainOnCordon)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPDrainOnCordon = b
+	}
+	env = os.Getenv(bgpDrainPrepend)
+	if env != "" {
+		u64, err := strconv.ParseUint(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		if u64 > bgp.MaxASPathPrepend {
+			return fmt.Errorf("%s is [%d], the maximum is [%d]", bgpDrainPrepend, u64, bgp.MaxASPathPrepend)
+		}
+		c.BGPConfig.DrainPrepend = uint32(u64)
+	}
+
+	// BGP BFD options, these act as the defaults for all peers
+	env = os.Getenv(bgpBFD)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPPeerConfig.BFD.Enabled = b
+	}
+
+	env = os.Getenv(bgpBFDInterval)
+	if env != "" {
+		u64, err := strconv.ParseUint(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.BGPPeerConfig.BFD.Interval = uint32(u64)
+	}
+
+	env = os.Getenv(bgpBFDMultiplier)
+	if env != "" {
+		u64, err := strconv.ParseUint(env, 10, 8)
+		if err != nil {
+			return err
+		}
+		c.BGPPeerConfig.BFD.Multiplier = uint32(u64)
+	}
+
+	// BGP Peer password Secret reference, used by any peer without a password
+	env = os.Getenv(bgpPeerPasswordSecret)
+	if env != "" {
+		c.BGPPeerConfig.PasswordSecret = env
+	}
+
 	// Peer AS
 	env = os.Getenv(bgpPeers)
 	if env != "" {
@@ -432,9 +903,37 @@ func ParseEnvironment(c *Config) error {
 		if err != nil {
 			return err
 		}
+		for x := range peers {
+			if peers[x].Password == "" {
+				peers[x].PasswordSecret = c.BGPPeerConfig.PasswordSecret
+			}
+			peers[x].BFD.Enabled = peers[x].BFD.Enabled || c.BGPPeerConfig.BFD.Enabled
+			peers[x].BFD.Interval = c.BGPPeerConfig.BFD.Interval
+			peers[x].BFD.Multiplier = c.BGPPeerConfig.BFD.Multiplier
+		}
 		c.BGPConfig.Peers = peers
 	}
 
+	// BGP dynamic neighbors
+	env = os.Getenv(bgpDynamicNeighbors)
+	if env != "" {
+		neighbors, err := bgp.ParseDynamicNeighborConfig(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.DynamicNeighbors = neighbors
+	}
+
+	// BGP per-peer export policies
+	env = os.Getenv(bgpExportPolicies)
+	if env != "" {
+		policies, err := bgp.ParseExportPolicies(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.ExportPolicies = policies
+	}
+
 	// BGP Peer mutlihop
 	env = os.Getenv(bgpMultiHop)
 	if env != "" {
@@ -451,6 +950,15 @@ func ParseEnvironment(c *Config) error {
 		c.BGPPeerConfig.Password = env
 	}
 
+	// BGP Peer next-hop
+	env = os.Getenv(bgpPeerNextHop)
+	if env != "" {
+		if env != bgp.NextHopSelf && net.ParseIP(env) == nil {
+			return fmt.Errorf("BGP next-hop format error (self or address) [%s]", env)
+		}
+		c.BGPPeerConfig.NextHop = env
+	}
+
 	// BGP Source Interface
 	env = os.Getenv(bgpSourceIF)
 	if env != "" {
@@ -463,6 +971,48 @@ func ParseEnvironment(c *Config) error {
 		c.BGPConfig.SourceIP = env
 	}
 
+	// BGP Source Address selection from the route towards each peer
+	env = os.Getenv(bgpSourceAuto)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.SourceAuto = b
+	}
+
+	// BGP gobgp API address, used for debugging with the gobgp CLI
+	env = os.Getenv(bgpGRPCAddress)
+	if env != "" {
+		c.BGPConfig.GRPCAddress = env
+	}
+
+	// BGP Next-hop addresses
+	env = os.Getenv(bgpIPv4NextHop)
+	if env != "" {
+		c.BGPConfig.IPv4NextHop = env
+	}
+
+	env = os.Getenv(bgpIPv6NextHop)
+	if env != "" {
+		c.BGPConfig.IPv6NextHop = env
+	}
+
+	// BGP Aggregate prefixes
+	env = os.Getenv(bgpAggregates)
+	if env != "" {
+		c.BGPConfig.Aggregates = strings.Split(env, ",")
+	}
+
+	env = os.Getenv(bgpSuppressAggregated)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.SuppressAggregated = b
+	}
+
 	// BGP Peer options, add them if relevant
 	env = os.Getenv(bgpPeerAddress)
 	if env != "" {
@@ -489,6 +1039,117 @@ func ParseEnvironment(c *Config) error {
 		c.BGPConfig.KeepaliveInterval = u64
 	}
 
+	// BGP maximum number of advertised prefixes
+	env = os.Getenv(bgpMaxPrefixes)
+	if env != "" {
+		u64, err := strconv.ParseUint(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.MaxPrefixes = uint32(u64)
+	}
+
+	// BGP MED ramping of new VIPs
+	env = os.Getenv(bgpMEDRampStart)
+	if env != "" {
+		u64, err := strconv.ParseUint(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.MEDRamp.Start = uint32(u64)
+	}
+	env = os.Getenv(bgpMEDRampSteps)
+	if env != "" {
+		u64, err := strconv.ParseUint(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.MEDRamp.Steps = uint32(u64)
+	}
+	env = os.Getenv(bgpMEDRampInterval)
+	if env != "" {
+		u64, err := strconv.ParseUint(env, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.MEDRamp.Interval = uint32(u64)
+	}
+
+	// BGP per-peer timers, these override the timers above
+	env = os.Getenv(bgpPeerTimers)
+	if env != "" {
+		timers, err := bgp.ParsePeerTimers(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.PeerTimers = timers
+	}
+
+	// BGP peer groups, used to advertise services to a subset of peers
+	env = os.Getenv(bgpPeerGroups)
+	if env != "" {
+		groups, err := bgp.ParsePeerGroups(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.PeerGroups = groups
+	}
+
+	// BGP Graceful Restart options
+	env = os.Getenv(bgpGracefulRestart)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.GracefulRestart.Enabled = b
+	}
+	env = os.Getenv(bgpGracefulRestartTime)
+	if env != "" {
+		// The restart time is a 12-bit field within the capability
+		u64, err := strconv.ParseUint(env, 10, 12)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.GracefulRestart.RestartTime = uint32(u64)
+	}
+	env = os.Getenv(bgpLongLivedGracefulRestart)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.GracefulRestart.LongLived = b
+	}
+	env = os.Getenv(bgpLongLivedGracefulRestartTime)
+	if env != "" {
+		// The long-lived stale time is a 24-bit field within the capability
+		u64, err := strconv.ParseUint(env, 10, 24)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.GracefulRestart.LongLivedRestartTime = uint32(u64)
+	}
+
+	// BGP ADD-PATH options
+	env = os.Getenv(bgpAddPathReceive)
+	if env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.AddPath.Receive = b
+	}
+	env = os.Getenv(bgpAddPathSendMax)
+	if env != "" {
+		// gobgp limits the number of paths that are sent to 255
+		u64, err := strconv.ParseUint(env, 10, 8)
+		if err != nil {
+			return err
+		}
+		c.BGPConfig.AddPath.SendMax = uint32(u64)
+	}
+
 	// Enable the Equinix Metal API calls
 	env = os.Getenv(vipPacket)
 	if env != "" {
@@ -585,6 +1246,11 @@ func ParseEnvironment(c *Config) error {
 		c.EgressWithNftables = b
 	}
 
+	env = os.Getenv(egressBackend)
+	if env != "" {
+		c.EgressBackend = env
+	}
+
 	// check to see if we're using a specific path to the Kubernetes config file
 	env = os.Getenv(k8sConfigFile)
 	if env != "" {
@@ -600,6 +1266,11 @@ func ParseEnvironment(c *Config) error {
 		c.EnableEndpointSlices = b
 	}
 
+	env = os.Getenv(endpointsProvider)
+	if env != "" {
+		c.EndpointsProvider = env
+	}
+
 	env = os.Getenv(mirrorDestInterface)
 	if env != "" {
 		c.MirrorDestInterface = env
@@ -6,9 +6,33 @@ const (
 	// vipArp - defines if the arp broadcast should be enabled
 	vipArp = "vip_arp"
 
+	// vipProxyArp - defines if the arp requests are answered without adding the vip to the interface
+	vipProxyArp = "vip_proxyarp"
+
+	// vipManageARPSysctls - defines if the arp sysctls of the interface are set by kube-vip
+	vipManageARPSysctls = "vip_managearpsysctls"
+
+	// vipMonitorCarrier - defines if leadership is relinquished whilst the interface has no carrier
+	vipMonitorCarrier = "vip_monitorcarrier"
+
+	// vipMonitorNodeHealth - defines if leadership is relinquished whilst the node is NotReady or under pressure
+	vipMonitorNodeHealth = "vip_monitornodehealth"
+
 	// vip_arpRate - defines the rate of gARP broadcasts
 	vipArpRate = "vip_arpRate"
 
+	// vipArpBurstCount - defines the number of gARP broadcasts that are sent when a VIP is first announced
+	vipArpBurstCount = "vip_arpBurstCount"
+
+	// vipArpBurstInterval - defines the time between the gARP broadcasts of a burst
+	vipArpBurstInterval = "vip_arpBurstInterval"
+
+	// vipNdpAdvertisementCount - defines the number of unsolicited neighbor advertisements that are sent when an IPv6 VIP is first announced
+	vipNdpAdvertisementCount = "vip_ndpAdvertisementCount"
+
+	// vipAnnouncementRateLimit - defines the maximum number of gARP and NDP announcements per second, across all vips
+	vipAnnouncementRateLimit = "vip_announcementRateLimit"
+
 	// vipLeaderElection - defines if the kubernetes algorithm should be used
 	vipLeaderElection = "vip_leaderelection"
 
@@ -27,6 +51,21 @@ const (
 	// vipLeaderElection - defines the annotations given to the lease lock
 	vipLeaseAnnotations = "vip_leaseannotations"
 
+	// vipLeaderPriority - defines if the priority of the nodes is used in the kubernetes elections
+	vipLeaderPriority = "vip_leaderpriority"
+
+	// vipLeaderPreemption - defines if a node with a higher priority takes over the lease
+	vipLeaderPreemption = "vip_leaderpreemption"
+
+	// vipLeaderFailbackDelay - defines how long a leader holds the lease before it can be preempted
+	vipLeaderFailbackDelay = "vip_leaderfailbackdelay"
+
+	// vipElectionExcludeSelector - defines the label selector of the nodes that don't take part in the elections
+	vipElectionExcludeSelector = "vip_electionexcludeselector"
+
+	// vipElectionExcludeTaints - defines the taint keys of the nodes that don't take part in the elections
+	vipElectionExcludeTaints = "vip_electionexcludetaints"
+
 	// vipLogLevel - defines the level of logging to produce (5 being the most verbose)
 	vipLogLevel = "vip_loglevel"
 
@@ -36,6 +75,12 @@ const (
 	// vipServicesInterface - defines the interface that the service vips should bind too
 	vipServicesInterface = "vip_servicesinterface"
 
+	// vipManagedInterfaceType - creates a dummy or macvlan interface that the vips are added to
+	vipManagedInterfaceType = "vip_managedinterfacetype"
+
+	// vipManagedInterfaceName - defines the name of the managed interface
+	vipManagedInterfaceName = "vip_managedinterfacename"
+
 	// vipCidr - defines the cidr that the vip will use (for BGP)
 	vipCidr = "vip_cidr"
 
@@ -51,6 +96,9 @@ const (
 	// egressWithNftables - enables using nftables over iptables
 	egressWithNftables = "egress_withnftables"
 
+	// egressBackend - the backend of the egress rules, iptables or (native) nftables
+	egressBackend = "egress_backend"
+
 	/////////////////////////////////////
 	// TO DO:
 	// Determine how to tidy this mess up
@@ -97,30 +145,100 @@ const (
 
 	// bgpEnable defines if BGP should be enabled
 	bgpEnable = "bgp_enable"
+	// bgpBackend defines the routing daemon that advertises the VIPs (gobgp or frr)
+	bgpBackend = "bgp_backend"
+	// bgpVtyshPath defines the path of vtysh that is used to program FRR
+	bgpVtyshPath = "bgp_vtysh_path"
 	// bgpRouterID defines the routerID for the BGP server
 	bgpRouterID = "bgp_routerid"
 	// bgpRouterInterface defines the interface that we can find the address for
 	bgpRouterInterface = "bgp_routerinterface"
 	// bgpRouterAS defines the AS for the BGP server
 	bgpRouterAS = "bgp_as"
+	// bgpASAuto assigns a private 4-byte AS for the BGP server that is derived from the routerID
+	bgpASAuto = "bgp_as_auto"
+	// bgpConfederationID defines the AS of the BGP confederation
+	bgpConfederationID = "bgp_confederation_id"
+	// bgpConfederationMembers defines the other member AS of the BGP confederation
+	bgpConfederationMembers = "bgp_confederation_members"
 	// bgpPeerAddress defines the address for a BGP peer
 	bgpPeerAddress = "bgp_peeraddress"
 	// bgpPeers defines the address for a BGP peer
 	bgpPeers = "bgp_peers"
+	// bgpPeersFromNode enables discovering the BGP peers from the annotations of this node
+	bgpPeersFromNode = "bgp_peers_from_node"
+	// bgpPeersConfigMap defines a ConfigMap that holds the BGP peers
+	bgpPeersConfigMap = "bgp_peers_configmap"
+	// bgpNodeCondition enables publishing the health of the BGP sessions as a node condition
+	bgpNodeCondition = "bgp_node_condition"
+	// bgpDrainOnCordon enables withdrawing the BGP advertisements whilst the node is cordoned or draining
+	bgpDrainOnCordon = "bgp_drain_on_cordon"
+	// bgpDrainPrepend defines the number of times the AS is prepended whilst draining, instead of withdrawing
+	bgpDrainPrepend = "bgp_drain_prepend"
+	// bgpDynamicNeighbors defines the prefixes that inbound BGP sessions are accepted from
+	bgpDynamicNeighbors = "bgp_dynamic_neighbors"
+	// bgpExportPolicies defines the VIPs (by prefix or community) that are advertised to each BGP peer
+	bgpExportPolicies = "bgp_export_policies"
 	// bgpPeerAS defines the AS for a BGP peer
 	bgpPeerAS = "bgp_peeras"
 	// bgpPeerAS defines the AS for a BGP peer
 	bgpPeerPassword = "bgp_peerpass" // nolint
+	// bgpPeerPasswordSecret defines a Secret (namespace/name/key) that holds the password for BGP peers
+	bgpPeerPasswordSecret = "bgp_peerpass_secret" // nolint
+	// bgpPeerNextHop defines the next-hop (an address or self) of the VIPs advertised to a BGP peer
+	bgpPeerNextHop = "bgp_peer_nexthop"
 	// bgpMultiHop enables mulithop routing
 	bgpMultiHop = "bgp_multihop"
 	// bgpSourceIF defines the source interface for BGP peering
 	bgpSourceIF = "bgp_sourceif"
 	// bgpSourceIP defines the source address for BGP peering
 	bgpSourceIP = "bgp_sourceip"
+	// bgpSourceAuto enables selecting the BGP source address from the route towards each peer
+	bgpSourceAuto = "bgp_source_auto"
+	// bgpGRPCAddress defines the loopback address or unix socket that the gobgp API is exposed on
+	bgpGRPCAddress = "bgp_grpc_address"
+	// bgpIPv4NextHop defines the next-hop for advertised IPv4 addresses
+	bgpIPv4NextHop = "bgp_ipv4_nexthop"
+	// bgpIPv6NextHop defines the next-hop for advertised IPv6 addresses
+	bgpIPv6NextHop = "bgp_ipv6_nexthop"
+	// bgpAggregates defines the prefixes that are advertised in place of the VIPs within them
+	bgpAggregates = "bgp_aggregates"
+	// bgpSuppressAggregated stops VIPs that are covered by an aggregate from being advertised
+	bgpSuppressAggregated = "bgp_suppress_aggregated"
+	// bgpMaxPrefixes defines the maximum number of prefixes that are advertised over BGP
+	bgpMaxPrefixes = "bgp_max_prefixes"
+	// bgpMEDRampStart defines the MED that new VIPs are advertised with before it is lowered to their own MED
+	bgpMEDRampStart = "bgp_med_ramp_start"
+	// bgpMEDRampSteps defines the number of steps that the MED of new VIPs is lowered in
+	bgpMEDRampSteps = "bgp_med_ramp_steps"
+	// bgpMEDRampInterval defines the time (in seconds) between each step of the MED ramp
+	bgpMEDRampInterval = "bgp_med_ramp_interval"
 	// bgpHoldTime defines bgp timers hold time
 	bgpHoldTime = "bgp_hold_time"
 	// bgpKeepaliveInterval defines bgp timers keepalive interval
 	bgpKeepaliveInterval = "bgp_keepalive_interval"
+	// bgpPeerTimers defines the hold time and keepalive interval for individual BGP peers
+	bgpPeerTimers = "bgp_peer_timers"
+	// bgpPeerGroups defines named groups of BGP peers that services can be advertised to
+	bgpPeerGroups = "bgp_peer_groups"
+	// bgpGracefulRestart enables the graceful restart capability for all BGP peers
+	bgpGracefulRestart = "bgp_graceful_restart"
+	// bgpGracefulRestartTime defines the graceful restart time in seconds
+	bgpGracefulRestartTime = "bgp_graceful_restart_time"
+	// bgpLongLivedGracefulRestart enables the long-lived graceful restart capability for all BGP peers
+	bgpLongLivedGracefulRestart = "bgp_long_lived_graceful_restart"
+	// bgpLongLivedGracefulRestartTime defines the long-lived graceful restart time in seconds
+	bgpLongLivedGracefulRestartTime = "bgp_long_lived_graceful_restart_time"
+	// bgpAddPathReceive enables receiving multiple paths for a prefix from BGP peers (ADD-PATH)
+	bgpAddPathReceive = "bgp_addpath_receive"
+	// bgpAddPathSendMax defines the maximum number of paths for a prefix that are sent to BGP peers (ADD-PATH)
+	bgpAddPathSendMax = "bgp_addpath_send_max"
+	// bgpBFD enables BFD for all BGP peers
+	bgpBFD = "bgp_bfd"
+	// bgpBFDInterval defines the BFD transmit/receive interval in milliseconds
+	bgpBFDInterval = "bgp_bfd_interval"
+	// bgpBFDMultiplier defines the number of missed BFD packets before a peer is declared down
+	bgpBFDMultiplier = "bgp_bfd_multiplier"
 
 	// vipWireguard - defines if wireguard will be used for vips
 	vipWireguard = "vip_wireguard" //nolint
@@ -142,6 +260,21 @@ const (
 	// vipRoutingProtocol - defines what value will be used as protocol when creating routes
 	vipRoutingProtocol = "vip_routingprotocol" //nolint
 
+	// vipRoutingMetric - defines the metric (priority) that will be used when creating routes
+	vipRoutingMetric = "vip_routingmetric" //nolint
+
+	// vipRoutingSource - defines the source address hint that will be used when creating routes
+	vipRoutingSource = "vip_routingsource" //nolint
+
+	// vipRoutingTableECMP - defines if the routes will have the local endpoints as next-hops
+	vipRoutingTableECMP = "vip_routingtableecmp" //nolint
+
+	// vipRoutingBlackholeGracePeriod - defines how long a blackhole route is kept for a withdrawn vip
+	vipRoutingBlackholeGracePeriod = "vip_routingblackholegraceperiod" //nolint
+
+	// vipRoutingVRF - defines the VRF that routes will be created in, instead of the routing table id
+	vipRoutingVRF = "vip_routingvrf" //nolint
+
 	// vipCleanRoutingTable - defines if routing table will be cleaned of redundant routes on kube-vip's start
 	vipCleanRoutingTable = "vip_cleanroutingtable" //nolint
 
@@ -160,12 +293,63 @@ const (
 	// svcEnable enables the Kubernetes service feature
 	svcEnable = "svc_enable"
 
-	// svcNamespace defines the namespace the service pods will run in
+	// svcWorkers defines the number of services that are processed in parallel
+	svcWorkers = "svc_workers"
+
+	// svcDriftInterval defines how often (in seconds) the configuration of the services is checked for drift
+	svcDriftInterval = "svc_driftinterval"
+
+	// svcMinReadyEndpoints defines the number of ready endpoints that a service requires before it is announced
+	svcMinReadyEndpoints = "svc_min_ready_endpoints"
+
+	// svcExternalIPs enables the announcing of the external IPs of the services
+	svcExternalIPs = "svc_externalips"
+
+	// svcFinalizer enables the finalizer that holds the deletion of a service until it has been cleaned up
+	svcFinalizer = "svc_finalizer"
+
+	// svcAvoidUnschedulable relinquishes the services elections whilst the node is cordoned
+	svcAvoidUnschedulable = "svc_avoidunschedulable"
+
+	// svcNamespace defines the namespaces (a comma separated list) that the services are watched in
 	svcNamespace = "svc_namespace"
 
+	// svcLabelSelector restricts the services that are watched to those that match the label selector
+	svcLabelSelector = "svc_labelselector"
+
+	// svcIgnoreLabelSelector ignores the services that match the label selector
+	svcIgnoreLabelSelector = "svc_ignore_labelselector"
+
+	// svcIgnoreAnnotations ignores the services with one of the annotations (a comma separated list of keys or key=value pairs)
+	svcIgnoreAnnotations = "svc_ignore_annotations"
+
+	// svcIgnoreNamespaces ignores the services in the namespaces (a comma separated list)
+	svcIgnoreNamespaces = "svc_ignore_namespaces"
+
 	// svcElection enables election per Kubernetes service
 	svcElection = "svc_election"
 
+	// svcElectionPool defines the prefix length of the pools that services are elected by
+	svcElectionPool = "svc_election_pool"
+
+	// svcElectionShards defines the number of shards that services are elected by
+	svcElectionShards = "svc_election_shards"
+
+	// svcLeaseDuration, svcRenewDeadline and svcRetryPeriod define the timings of the services elections
+	svcLeaseDuration = "svc_leaseduration"
+	svcRenewDeadline = "svc_renewdeadline"
+	svcRetryPeriod   = "svc_retryperiod"
+
+	// svcSpread defines if the services are spread across the nodes, how often they are rebalanced, how
+	// many services are moved at a time and if they are rebalanced when a node joins
+	svcSpread            = "svc_spread"
+	svcRebalanceInterval = "svc_rebalanceinterval"
+	svcRebalanceMaxMoves = "svc_rebalancemaxmoves"
+	svcRebalanceOnJoin   = "svc_rebalanceonjoin"
+
+	// svcTopology defines if the topology of the endpoints is preferred in the services elections
+	svcTopology = "svc_topology"
+
 	// svcLeaseName Name of the lease that is used for leader election for services (in arp mode)
 	svcLeaseName = "svc_leasename"
 
@@ -178,6 +362,9 @@ const (
 	// lbClassLegacyHandling enables legacy handing of load-balancer class
 	lbClassLegacyHandling = "lb_class_legacy_handling"
 
+	// lbClassAdoptUnclassed enables load-balancer for the services without a class, as well as for the specific classes
+	lbClassAdoptUnclassed = "lb_class_adopt_unclassed"
+
 	// lbEnable defines if the load-balancer should be enabled
 	lbEnable = "lb_enable"
 
@@ -211,6 +398,9 @@ const (
 	// enableEndpointSlices enables use of EndpointSlices instead of Endpoints
 	enableEndpointSlices = "enable_endpointslices"
 
+	// endpointsProvider is the provider of the endpoints of the services (auto, endpointslices, endpoints or hybrid)
+	endpointsProvider = "endpoints_provider"
+
 	// mirrorDestInterface is the network interface where all traffics that go through service interface
 	// will be mirrored to. The source interface is ServicesInterface by default, fall back to Interface if not set.
 	// + optional
@@ -3,6 +3,7 @@ package kubevip
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	appv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -49,17 +50,47 @@ func GenerateCR() *applyRbacV1.ClusterRoleApplyConfiguration {
 			{
 				APIGroups: []string{""},
 				Resources: []string{"services", "endpoints"},
-				Verbs:     []string{"list", "get", "watch", "endoints"},
+				Verbs:     []string{"list", "get", "watch", "update", "endoints"},
+			},
+			{
+				APIGroups: []string{"discovery.k8s.io"},
+				Resources: []string{"endpointslices"},
+				Verbs:     []string{"list", "get", "watch"},
 			},
 			{
 				APIGroups: []string{""},
 				Resources: []string{"nodes"},
 				Verbs:     []string{"list", "get", "watch", "update", "patch"},
 			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"events"},
+				Verbs:     []string{"create", "patch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"nodes/status"},
+				Verbs:     []string{"patch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "namespaces"},
+				Verbs:     []string{"list", "get", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"configmaps"},
+				Verbs:     []string{"list", "get", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"secrets"},
+				Verbs:     []string{"list", "get", "watch"},
+			},
 			{
 				APIGroups: []string{"coordination.k8s.io"},
 				Resources: []string{"leases"},
-				Verbs:     []string{"list", "get", "watch", "update", "create"},
+				Verbs:     []string{"list", "get", "watch", "update", "create", "delete"},
 			},
 		},
 	}
@@ -105,8 +136,9 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 
 	// Determine where the pods should be living (for multi-tenancy)
 	var namespace string
-	if c.ServiceNamespace != "" {
-		namespace = c.ServiceNamespace
+	// The pod lives in the namespace of its services, unless it watches more than one
+	if namespaces := c.ServiceNamespaces(); len(namespaces) == 1 && namespaces[0] != "" {
+		namespace = namespaces[0]
 	} else {
 		namespace = metav1.NamespaceSystem
 	}
@@ -154,6 +186,20 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 		newEnvironment = append(newEnvironment, svcInterface...)
 	}
 
+	// Detect if the VIPs should be placed on a managed interface
+	if c.ManagedInterfaceType != "" {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  vipManagedInterfaceType,
+			Value: c.ManagedInterfaceType,
+		})
+		if c.ManagedInterfaceName != "" {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  vipManagedInterfaceName,
+				Value: c.ManagedInterfaceName,
+			})
+		}
+	}
+
 	// If a CIDR is used add it to the manifest
 	if c.VIPCIDR != "" {
 		// build environment variables
@@ -178,6 +224,59 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 		newEnvironment = append(newEnvironment, cidr...)
 	}
 
+	// If the gARP burst has been configured
+	if c.EnableARP && c.ArpBurstCount > 1 {
+		burst := []corev1.EnvVar{
+			{
+				Name:  vipArpBurstCount,
+				Value: strconv.Itoa(c.ArpBurstCount),
+			},
+		}
+		if c.ArpBurstInterval != 0 {
+			burst = append(burst, corev1.EnvVar{
+				Name:  vipArpBurstInterval,
+				Value: fmt.Sprintf("%d", c.ArpBurstInterval),
+			})
+		}
+		newEnvironment = append(newEnvironment, burst...)
+	}
+	if c.EnableARP && c.NdpAdvertisementCount != 0 {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  vipNdpAdvertisementCount,
+			Value: strconv.Itoa(c.NdpAdvertisementCount),
+		})
+	}
+	if c.EnableARP && c.ProxyARP {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  vipProxyArp,
+			Value: strconv.FormatBool(c.ProxyARP),
+		})
+	}
+	if c.EnableARP && c.ManageARPSysctls {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  vipManageARPSysctls,
+			Value: strconv.FormatBool(c.ManageARPSysctls),
+		})
+	}
+	if c.MonitorCarrier {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  vipMonitorCarrier,
+			Value: strconv.FormatBool(c.MonitorCarrier),
+		})
+	}
+	if c.MonitorNodeHealth {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  vipMonitorNodeHealth,
+			Value: strconv.FormatBool(c.MonitorNodeHealth),
+		})
+	}
+	if c.EnableARP && c.AnnouncementRateLimit != 0 {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  vipAnnouncementRateLimit,
+			Value: strconv.Itoa(c.AnnouncementRateLimit),
+		})
+	}
+
 	if c.DNSMode != "" {
 		// build environment variables
 		dnsModeSelector := []corev1.EnvVar{
@@ -229,6 +328,66 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 			},
 		}
 		newEnvironment = append(newEnvironment, svc...)
+		if c.ServicesWorkers > 1 {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcWorkers,
+				Value: fmt.Sprintf("%d", c.ServicesWorkers),
+			})
+		}
+		if c.ServicesLabelSelector != "" {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcLabelSelector,
+				Value: c.ServicesLabelSelector,
+			})
+		}
+		if c.ServicesIgnoreLabelSelector != "" {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcIgnoreLabelSelector,
+				Value: c.ServicesIgnoreLabelSelector,
+			})
+		}
+		if c.ServicesIgnoreAnnotations != "" {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcIgnoreAnnotations,
+				Value: c.ServicesIgnoreAnnotations,
+			})
+		}
+		if c.ServicesIgnoreNamespaces != "" {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcIgnoreNamespaces,
+				Value: c.ServicesIgnoreNamespaces,
+			})
+		}
+		if c.ServicesDriftInterval != 0 {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcDriftInterval,
+				Value: fmt.Sprintf("%d", c.ServicesDriftInterval),
+			})
+		}
+		if c.ServicesMinReadyEndpoints != 0 {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcMinReadyEndpoints,
+				Value: fmt.Sprintf("%d", c.ServicesMinReadyEndpoints),
+			})
+		}
+		if c.EnableServicesExternalIPs {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcExternalIPs,
+				Value: strconv.FormatBool(c.EnableServicesExternalIPs),
+			})
+		}
+		if c.EnableServicesFinalizer {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcFinalizer,
+				Value: strconv.FormatBool(c.EnableServicesFinalizer),
+			})
+		}
+		if c.ServicesAvoidUnschedulable {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  svcAvoidUnschedulable,
+				Value: strconv.FormatBool(c.ServicesAvoidUnschedulable),
+			})
+		}
 		if c.EnableServicesElection {
 			svcElection := []corev1.EnvVar{
 				{
@@ -237,6 +396,60 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 				},
 			}
 			newEnvironment = append(newEnvironment, svcElection...)
+			if c.ServicesElectionPool != "" {
+				newEnvironment = append(newEnvironment, corev1.EnvVar{
+					Name:  svcElectionPool,
+					Value: c.ServicesElectionPool,
+				})
+			}
+			if c.ServicesElectionShards != 0 {
+				newEnvironment = append(newEnvironment, corev1.EnvVar{
+					Name:  svcElectionShards,
+					Value: fmt.Sprintf("%d", c.ServicesElectionShards),
+				})
+			}
+			if c.ServicesLeaseDuration != 0 {
+				newEnvironment = append(newEnvironment, []corev1.EnvVar{
+					{
+						Name:  svcLeaseDuration,
+						Value: fmt.Sprintf("%d", c.ServicesLeaseDuration),
+					},
+					{
+						Name:  svcRenewDeadline,
+						Value: fmt.Sprintf("%d", c.ServicesRenewDeadline),
+					},
+					{
+						Name:  svcRetryPeriod,
+						Value: fmt.Sprintf("%d", c.ServicesRetryPeriod),
+					},
+				}...)
+			}
+			if c.EnableServicesSpread {
+				newEnvironment = append(newEnvironment, []corev1.EnvVar{
+					{
+						Name:  svcSpread,
+						Value: strconv.FormatBool(c.EnableServicesSpread),
+					},
+					{
+						Name:  svcRebalanceInterval,
+						Value: fmt.Sprintf("%d", c.ServicesRebalanceInterval),
+					},
+					{
+						Name:  svcRebalanceMaxMoves,
+						Value: fmt.Sprintf("%d", c.ServicesRebalanceMaxMoves),
+					},
+					{
+						Name:  svcRebalanceOnJoin,
+						Value: strconv.FormatBool(c.ServicesRebalanceOnJoin),
+					},
+				}...)
+			}
+			if c.EnableServicesTopology {
+				newEnvironment = append(newEnvironment, corev1.EnvVar{
+					Name:  svcTopology,
+					Value: strconv.FormatBool(c.EnableServicesTopology),
+				})
+			}
 		}
 		if c.LoadBalancerClassOnly {
 			lbClassOnlyVar := []corev1.EnvVar{
@@ -247,6 +460,18 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 			}
 			newEnvironment = append(newEnvironment, lbClassOnlyVar...)
 		}
+		if c.LoadBalancerClassName != "" && c.LoadBalancerClassName != DefaultLoadBalancerClass {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  lbClassName,
+				Value: c.LoadBalancerClassName,
+			})
+		}
+		if c.LoadBalancerClassAdoptUnclassed {
+			newEnvironment = append(newEnvironment, corev1.EnvVar{
+				Name:  lbClassAdoptUnclassed,
+				Value: strconv.FormatBool(c.LoadBalancerClassAdoptUnclassed),
+			})
+		}
 		if c.EnableServiceSecurity {
 			EnableServiceSecurityVar := []corev1.EnvVar{
 				{
@@ -301,6 +526,41 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 		newEnvironment = append(newEnvironment, leaderElection...)
 	}
 
+	// If the priority of the nodes is used in the elections
+	if c.EnableLeaderPriority {
+		leaderPriority := []corev1.EnvVar{
+			{
+				Name:  vipLeaderPriority,
+				Value: strconv.FormatBool(c.EnableLeaderPriority),
+			},
+			{
+				Name:  vipLeaderPreemption,
+				Value: strconv.FormatBool(c.LeaderPreemption),
+			},
+		}
+		if c.LeaderFailbackDelay != 0 {
+			leaderPriority = append(leaderPriority, corev1.EnvVar{
+				Name:  vipLeaderFailbackDelay,
+				Value: fmt.Sprintf("%d", c.LeaderFailbackDelay),
+			})
+		}
+		newEnvironment = append(newEnvironment, leaderPriority...)
+	}
+
+	// If nodes are excluded from the elections
+	if c.ElectionExcludeSelector != "" {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  vipElectionExcludeSelector,
+			Value: c.ElectionExcludeSelector,
+		})
+	}
+	if c.ElectionExcludeTaints != "" {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  vipElectionExcludeTaints,
+			Value: c.ElectionExcludeTaints,
+		})
+	}
+
 	// If we're enabling node labeling on leader election
 	if c.EnableNodeLabeling {
 		EnableNodeLabeling := []corev1.EnvVar{
@@ -377,6 +637,36 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 				Value: strconv.FormatBool(c.EnableRoutingTable),
 			},
 		}
+		if c.RoutingVRF != "" {
+			routingtable = append(routingtable, corev1.EnvVar{
+				Name:  vipRoutingVRF,
+				Value: c.RoutingVRF,
+			})
+		}
+		if c.RoutingTableECMP {
+			routingtable = append(routingtable, corev1.EnvVar{
+				Name:  vipRoutingTableECMP,
+				Value: strconv.FormatBool(c.RoutingTableECMP),
+			})
+		}
+		if c.RoutingBlackholeGracePeriod != 0 {
+			routingtable = append(routingtable, corev1.EnvVar{
+				Name:  vipRoutingBlackholeGracePeriod,
+				Value: strconv.Itoa(c.RoutingBlackholeGracePeriod),
+			})
+		}
+		if c.RoutingSource != "" {
+			routingtable = append(routingtable, corev1.EnvVar{
+				Name:  vipRoutingSource,
+				Value: c.RoutingSource,
+			})
+		}
+		if c.RoutingMetric != 0 {
+			routingtable = append(routingtable, corev1.EnvVar{
+				Name:  vipRoutingMetric,
+				Value: strconv.Itoa(c.RoutingMetric),
+			})
+		}
 		newEnvironment = append(newEnvironment, routingtable...)
 	}
 
@@ -415,6 +705,15 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 			},
 		}
 
+		// Detect if the next-hop advertised to the bgp peer should be overridden
+		if c.BGPPeerConfig.NextHop != "" {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpPeerNextHop,
+				Value: c.BGPPeerConfig.NextHop,
+			},
+			)
+		}
+
 		// Detect if we should be using a source interface for speaking to a bgp peer
 		if c.BGPConfig.SourceIF != "" {
 			bgpConfig = append(bgpConfig, corev1.EnvVar{
@@ -433,6 +732,212 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 			)
 		}
 
+		// Detect if the source address should be selected automatically for each bgp peer
+		if c.BGPConfig.SourceAuto {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpSourceAuto,
+				Value: strconv.FormatBool(c.BGPConfig.SourceAuto),
+			},
+			)
+		}
+
+		// Detect if the gobgp API should be exposed for debugging
+		if c.BGPConfig.GRPCAddress != "" {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpGRPCAddress,
+				Value: c.BGPConfig.GRPCAddress,
+			},
+			)
+		}
+
+		// Detect if we should be overriding the next-hop for advertised addresses
+		if c.BGPConfig.IPv4NextHop != "" {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpIPv4NextHop,
+				Value: c.BGPConfig.IPv4NextHop,
+			},
+			)
+		}
+
+		if c.BGPConfig.IPv6NextHop != "" {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpIPv6NextHop,
+				Value: c.BGPConfig.IPv6NextHop,
+			},
+			)
+		}
+
+		// Detect if we should be advertising aggregate prefixes
+		if len(c.BGPConfig.Aggregates) != 0 {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpAggregates,
+				Value: strings.Join(c.BGPConfig.Aggregates, ","),
+			},
+			)
+			if c.BGPConfig.SuppressAggregated {
+				bgpConfig = append(bgpConfig, corev1.EnvVar{
+					Name:  bgpSuppressAggregated,
+					Value: strconv.FormatBool(c.BGPConfig.SuppressAggregated),
+				},
+				)
+			}
+		}
+
+		// Detect if the number of advertised prefixes should be limited
+		if c.BGPConfig.MaxPrefixes != 0 {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpMaxPrefixes,
+				Value: fmt.Sprintf("%d", c.BGPConfig.MaxPrefixes),
+			},
+			)
+		}
+
+		// Detect if new VIPs should be ramped from a worse MED
+		if c.BGPConfig.MEDRamp.Start != 0 {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpMEDRampStart,
+				Value: fmt.Sprintf("%d", c.BGPConfig.MEDRamp.Start),
+			},
+			)
+			if c.BGPConfig.MEDRamp.Steps != 0 {
+				bgpConfig = append(bgpConfig, corev1.EnvVar{
+					Name:  bgpMEDRampSteps,
+					Value: fmt.Sprintf("%d", c.BGPConfig.MEDRamp.Steps),
+				},
+				)
+			}
+			if c.BGPConfig.MEDRamp.Interval != 0 {
+				bgpConfig = append(bgpConfig, corev1.EnvVar{
+					Name:  bgpMEDRampInterval,
+					Value: fmt.Sprintf("%d", c.BGPConfig.MEDRamp.Interval),
+				},
+				)
+			}
+		}
+
+		// Detect if graceful restart should be advertised to bgp peers
+		if c.BGPConfig.GracefulRestart.Enabled {
+			bgpConfig = append(bgpConfig, []corev1.EnvVar{
+				{
+					Name:  bgpGracefulRestart,
+					Value: strconv.FormatBool(c.BGPConfig.GracefulRestart.Enabled),
+				},
+				{
+					Name:  bgpGracefulRestartTime,
+					Value: fmt.Sprintf("%d", c.BGPConfig.GracefulRestart.RestartTime),
+				},
+			}...)
+			if c.BGPConfig.GracefulRestart.LongLived {
+				bgpConfig = append(bgpConfig, []corev1.EnvVar{
+					{
+						Name:  bgpLongLivedGracefulRestart,
+						Value: strconv.FormatBool(c.BGPConfig.GracefulRestart.LongLived),
+					},
+					{
+						Name:  bgpLongLivedGracefulRestartTime,
+						Value: fmt.Sprintf("%d", c.BGPConfig.GracefulRestart.LongLivedRestartTime),
+					},
+				}...)
+			}
+		}
+
+		// Detect if the ADD-PATH capability should be negotiated with bgp peers
+		if c.BGPConfig.AddPath.Receive {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpAddPathReceive,
+				Value: strconv.FormatBool(c.BGPConfig.AddPath.Receive),
+			},
+			)
+		}
+		if c.BGPConfig.AddPath.SendMax != 0 {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpAddPathSendMax,
+				Value: fmt.Sprintf("%d", c.BGPConfig.AddPath.SendMax),
+			},
+			)
+		}
+
+		// Detect if the bgp peer password should be read from a Secret
+		if c.BGPPeerConfig.PasswordSecret != "" {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpPeerPasswordSecret,
+				Value: c.BGPPeerConfig.PasswordSecret,
+			},
+			)
+		}
+
+		// Detect if we should be using BFD to detect failed bgp peers
+		if c.BGPPeerConfig.BFD.Enabled {
+			bgpConfig = append(bgpConfig, []corev1.EnvVar{
+				{
+					Name:  bgpBFD,
+					Value: strconv.FormatBool(c.BGPPeerConfig.BFD.Enabled),
+				},
+				{
+					Name:  bgpBFDInterval,
+					Value: fmt.Sprintf("%d", c.BGPPeerConfig.BFD.Interval),
+				},
+				{
+					Name:  bgpBFDMultiplier,
+					Value: fmt.Sprintf("%d", c.BGPPeerConfig.BFD.Multiplier),
+				},
+			}...)
+		}
+
+		if c.BGPPeersFromNode {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpPeersFromNode,
+				Value: strconv.FormatBool(c.BGPPeersFromNode),
+			},
+			)
+		}
+
+		if c.BGPPeersConfigMap != "" {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpPeersConfigMap,
+				Value: c.BGPPeersConfigMap,
+			},
+			)
+		}
+
+		if c.BGPNodeCondition {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpNodeCondition,
+				Value: strconv.FormatBool(c.BGPNodeCondition),
+			},
+			)
+		}
+
+		if c.BGPConfig.Backend != "" {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpBackend,
+				Value: c.BGPConfig.Backend,
+			},
+			)
+			if c.BGPConfig.VtyshPath != "" {
+				bgpConfig = append(bgpConfig, corev1.EnvVar{
+					Name:  bgpVtyshPath,
+					Value: c.BGPConfig.VtyshPath,
+				},
+				)
+			}
+		}
+
+		if c.BGPDrainOnCordon {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpDrainOnCordon,
+				Value: strconv.FormatBool(c.BGPDrainOnCordon),
+			},
+			)
+			if c.BGPConfig.DrainPrepend != 0 {
+				bgpConfig = append(bgpConfig, corev1.EnvVar{
+					Name:  bgpDrainPrepend,
+					Value: fmt.Sprintf("%d", c.BGPConfig.DrainPrepend),
+				},
+				)
+			}
+		}
+
 		var peers string
 		if len(c.BGPPeers) != 0 {
 			for x := range c.BGPPeers {
@@ -449,6 +954,61 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 			)
 		}
 
+		if len(c.BGPDynamicNeighbors) != 0 {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpDynamicNeighbors,
+				Value: strings.Join(c.BGPDynamicNeighbors, ","),
+			},
+			)
+		}
+
+		if len(c.BGPExportPolicies) != 0 {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpExportPolicies,
+				Value: strings.Join(c.BGPExportPolicies, ","),
+			},
+			)
+		}
+
+		if len(c.BGPPeerTimers) != 0 {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpPeerTimers,
+				Value: strings.Join(c.BGPPeerTimers, ","),
+			},
+			)
+		}
+
+		if len(c.BGPPeerGroups) != 0 {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpPeerGroups,
+				Value: strings.Join(c.BGPPeerGroups, ","),
+			},
+			)
+		}
+
+		if c.BGPConfig.AutoAS {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpASAuto,
+				Value: strconv.FormatBool(c.BGPConfig.AutoAS),
+			},
+			)
+		}
+
+		if c.BGPConfig.Confederation.Identifier != 0 {
+			bgpConfig = append(bgpConfig, corev1.EnvVar{
+				Name:  bgpConfederationID,
+				Value: fmt.Sprintf("%d", c.BGPConfig.Confederation.Identifier),
+			},
+			)
+			if len(c.BGPConfederationMembers) != 0 {
+				bgpConfig = append(bgpConfig, corev1.EnvVar{
+					Name:  bgpConfederationMembers,
+					Value: strings.Join(c.BGPConfederationMembers, ","),
+				},
+				)
+			}
+		}
+
 		newEnvironment = append(newEnvironment, bgpConfig...)
 
 	}
@@ -500,6 +1060,13 @@ func generatePodSpec(c *Config, imageVersion string, inCluster bool) *corev1.Pod
 		})
 	}
 
+	if c.EndpointsProvider != "" {
+		newEnvironment = append(newEnvironment, corev1.EnvVar{
+			Name:  endpointsProvider,
+			Value: c.EndpointsProvider,
+		})
+	}
+
 	if c.DisableServiceUpdates {
 		// Disable service updates
 		disServiceUpdates := []corev1.EnvVar{
@@ -626,8 +1193,9 @@ func GeneratePodManifestFromConfig(c *Config, imageVersion string, inCluster boo
 func GenerateDaemonsetManifestFromConfig(c *Config, imageVersion string, inCluster, taint bool) string {
 	// Determine where the pod should be deployed
 	var namespace string
-	if c.ServiceNamespace != "" {
-		namespace = c.ServiceNamespace
+	// The pod lives in the namespace of its services, unless it watches more than one
+	if namespaces := c.ServiceNamespaces(); len(namespaces) == 1 && namespaces[0] != "" {
+		namespace = namespaces[0]
 	} else {
 		namespace = metav1.NamespaceSystem
 	}
@@ -1,6 +1,9 @@
 package kubevip
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func TestParseEnvironment(t *testing.T) {
 
@@ -21,3 +24,16 @@ func TestParseEnvironment(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateCRSecrets(t *testing.T) {
+	// The passwords of the BGP peers can be read (and rotated) from Secrets
+	for _, rule := range GenerateCR().Rules {
+		if slices.Contains(rule.Resources, "secrets") {
+			if !slices.Contains(rule.Verbs, "get") || !slices.Contains(rule.Verbs, "watch") {
+				t.Errorf("secrets verbs = %v, want get and watch", rule.Verbs)
+			}
+			return
+		}
+	}
+	t.Error("the cluster role has no rule for secrets")
+}
@@ -2,25 +2,72 @@ package kubevip
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
 )
 
 func (c *Config) CheckInterface() error {
-	if c.Interface != "" {
-		if err := isValidInterface(c.Interface); err != nil {
-			return fmt.Errorf("%s is not valid interface, reason: %w", c.Interface, err)
+	// Both interfaces can be a comma separated list in order to announce on multiple interfaces
+	for _, ifaces := range []string{c.Interface, c.ServicesInterface} {
+		for _, iface := range strings.Split(ifaces, ",") {
+			if iface = strings.TrimSpace(iface); iface == "" {
+				continue
+			}
+			if err := isValidInterface(iface); err != nil {
+				return fmt.Errorf("%s is not valid interface, reason: %w", iface, err)
+			}
 		}
 	}
 
-	if c.ServicesInterface != "" {
-		if err := isValidInterface(c.ServicesInterface); err != nil {
-			return fmt.Errorf("%s is not valid interface, reason: %w", c.ServicesInterface, err)
+	return nil
+}
+
+// Mode returns how the VIPs are advertised, the later modes take precedence as they do when starting
+func (c *Config) Mode() string {
+	mode := ""
+	if c.EnableARP {
+		mode = "arp"
+	}
+	if c.EnableBGP {
+		mode = "bgp"
+	}
+	if c.EnableWireguard {
+		mode = "wireguard"
+	}
+	if c.EnableRoutingTable {
+		mode = "routingtable"
+	}
+	return mode
+}
+
+// ServiceNamespaces returns the namespaces whose services are watched, from the comma separated list. An empty
+// namespace (v1.NamespaceAll) is returned when every namespace is watched.
+func (c *Config) ServiceNamespaces() []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(c.ServiceNamespace, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" && !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
 		}
 	}
+	if len(namespaces) == 0 {
+		return []string{""}
+	}
+	return namespaces
+}
 
-	return nil
+// LoadBalancerClasses returns the load balancer classes of the services that are load balanced, from the comma
+// separated list
+func (c *Config) LoadBalancerClasses() []string {
+	var classes []string
+	for _, class := range strings.Split(c.LoadBalancerClassName, ",") {
+		if class = strings.TrimSpace(class); class != "" && !slices.Contains(classes, class) {
+			classes = append(classes, class)
+		}
+	}
+	return classes
 }
 
 func isValidInterface(iface string) error {
@@ -0,0 +1,38 @@
+package kubevip
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestServiceNamespaces(t *testing.T) {
+	tests := map[string][]string{
+		"":                    {""},
+		"tenant-a":            {"tenant-a"},
+		"tenant-a, tenant-b,": {"tenant-a", "tenant-b"},
+		"tenant-a,tenant-a":   {"tenant-a"},
+	}
+	for namespace, want := range tests {
+		c := &Config{ServiceNamespace: namespace}
+		if got := c.ServiceNamespaces(); !reflect.DeepEqual(got, want) {
+			t.Errorf("ServiceNamespaces(%q) = %v, want %v", namespace, got, want)
+		}
+	}
+}
+
+func TestLoadBalancerClasses(t *testing.T) {
+	tests := []struct {
+		classes string
+		want    []string
+	}{
+		{"", nil},
+		{DefaultLoadBalancerClass, []string{DefaultLoadBalancerClass}},
+		{"example.com/internal, example.com/external,example.com/internal", []string{"example.com/internal", "example.com/external"}},
+	}
+	for _, tt := range tests {
+		c := &Config{LoadBalancerClassName: tt.classes}
+		if got := c.LoadBalancerClasses(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LoadBalancerClasses(%q) = %v, want %v", tt.classes, got, tt.want)
+		}
+	}
+}
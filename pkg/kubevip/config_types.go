@@ -4,14 +4,52 @@ import (
 	"github.com/kube-vip/kube-vip/pkg/bgp"
 )
 
+// DefaultLoadBalancerClass is the load balancer class of the services that kube-vip load balances by default
+const DefaultLoadBalancerClass = "kube-vip.io/kube-vip-class"
+
+// The backends of the egress rules
+const (
+	EgressBackendIPTables = "iptables"
+	EgressBackendNFTables = "nftables"
+)
+
+// The providers of the endpoints of the services
+const (
+	// EndpointsProviderAuto uses the EndpointSlices when the API server serves them (and they can be listed),
+	// the Endpoints otherwise
+	EndpointsProviderAuto = "auto"
+	// EndpointsProviderEndpointSlices uses the EndpointSlices
+	EndpointsProviderEndpointSlices = "endpointslices"
+	// EndpointsProviderEndpoints uses the Endpoints
+	EndpointsProviderEndpoints = "endpoints"
+	// EndpointsProviderHybrid uses the EndpointSlices of the services that have them, and the Endpoints of any other
+	// service, whilst migrating from Endpoints
+	EndpointsProviderHybrid = "hybrid"
+)
+
 // Config defines all of the settings for the Kube-Vip Pod
 type Config struct {
 	// Logging, settings
 	Logging int `yaml:"logging"`
 
+	// Version, of kube-vip that is running, it is recorded on the leases that this node holds
+	Version string `yaml:"-"`
+
 	// EnableARP, will use ARP to advertise the VIP address
 	EnableARP bool `yaml:"enableARP"`
 
+	// ProxyARP, will answer ARP requests for the VIP address in user space instead of adding it to the interface
+	ProxyARP bool `yaml:"proxyARP"`
+
+	// ManageARPSysctls, will set the arp_ignore/arp_announce/proxy_ndp sysctls of the VIP interfaces, and restore them when stopping
+	ManageARPSysctls bool `yaml:"manageArpSysctls"`
+
+	// MonitorCarrier, will relinquish leadership of the VIPs whilst their interface has no carrier
+	MonitorCarrier bool `yaml:"monitorCarrier"`
+
+	// MonitorNodeHealth, will relinquish leadership of the VIPs whilst the node is NotReady or under pressure
+	MonitorNodeHealth bool `yaml:"monitorNodeHealth"`
+
 	// EnableBGP, will use BGP to advertise the VIP address
 	EnableBGP bool `yaml:"enableBGP"`
 
@@ -33,18 +71,75 @@ type Config struct {
 	// EnableServices, will enable the services functionality (used for hybrid behaviour)
 	EnableServices bool `yaml:"enableServices"`
 
+	// ServicesWorkers, is the number of services that are processed in parallel
+	ServicesWorkers int `yaml:"servicesWorkers"`
+
+	// ServicesDriftInterval, is how often (in seconds) the addresses, routes and BGP paths of the services are
+	// checked and repaired if they have been removed out-of-band (and the addresses of deleted services are
+	// removed), 0 disables the checks
+	ServicesDriftInterval int `yaml:"servicesDriftInterval"`
+
+	// ServicesMinReadyEndpoints, is the number of ready endpoints that a service requires before it is announced
+	// (unless it has the kube-vip.io/min-ready-endpoints annotation), the endpoints are counted on the node for the
+	// Local traffic policy and across the cluster otherwise. It applies when the endpoints of the services are
+	// watched, with servicesElection or with BGP and the routing table without leader election.
+	ServicesMinReadyEndpoints int `yaml:"servicesMinReadyEndpoints"`
+
+	// EnableServicesExternalIPs, will also announce the addresses in spec.externalIPs of the services
+	EnableServicesExternalIPs bool `yaml:"enableServicesExternalIPs"`
+
+	// EnableServicesFinalizer, will add a finalizer to the services, so that they aren't removed until their
+	// addresses, routes and lease have been cleaned up
+	EnableServicesFinalizer bool `yaml:"enableServicesFinalizer"`
+
 	// EnableServicesElection, will enable leaderElection per service
 	EnableServicesElection bool `yaml:"enableServicesElection"`
 
+	// ServicesAvoidUnschedulable, will relinquish the services elections whilst the node is cordoned or tainted for
+	// draining, so that the VIPs move off the node before its pods are evicted
+	ServicesAvoidUnschedulable bool `yaml:"servicesAvoidUnschedulable"`
+
+	// ServicesElectionPool, will elect a leader per pool (subnet) of services instead of per service, e.g. "24" or "24,64"
+	ServicesElectionPool string `yaml:"servicesElectionPool"`
+
+	// ServicesElectionShards, will elect a leader per shard of services (by a hash of the service) instead of per service, unless there is a pool
+	ServicesElectionShards int `yaml:"servicesElectionShards"`
+
+	// ServicesLeaseDuration, ServicesRenewDeadline and ServicesRetryPeriod are the timings (in seconds) of the
+	// services elections, when they are zero the timings of the leader election are used
+	ServicesLeaseDuration int `yaml:"servicesLeaseDuration"`
+	ServicesRenewDeadline int `yaml:"servicesRenewDeadline"`
+	ServicesRetryPeriod   int `yaml:"servicesRetryPeriod"`
+
+	// EnableServicesSpread, will bias the services elections so that the services are spread across the nodes
+	EnableServicesSpread bool `yaml:"enableServicesSpread"`
+
+	// ServicesRebalanceInterval, the seconds between rebalancing the services across the nodes (zero disables it)
+	ServicesRebalanceInterval int `yaml:"servicesRebalanceInterval"`
+
+	// ServicesRebalanceMaxMoves, the maximum number of services that are moved to another node at every rebalance
+	ServicesRebalanceMaxMoves int `yaml:"servicesRebalanceMaxMoves"`
+
+	// ServicesRebalanceOnJoin, will rebalance the spread services when a node joins, so that it takes a share of them
+	ServicesRebalanceOnJoin bool `yaml:"servicesRebalanceOnJoin"`
+
+	// EnableServicesTopology, will prefer the nodes with ready local endpoints (or in the zone of most endpoints) in the services elections
+	EnableServicesTopology bool `yaml:"enableServicesTopology"`
+
 	// EnableNodeLabeling, will enable node labeling as it becomes leader
 	EnableNodeLabeling bool `yaml:"enableNodeLabeling"`
 
 	// LoadBalancerClassOnly, will enable load balancing only for services with LoadBalancerClass set to "kube-vip.io/kube-vip-class"
 	LoadBalancerClassOnly bool `yaml:"lbClassOnly"`
 
-	// LoadBalancerClassName, will limit the load balancing services to services with LoadBalancerClass set to this value
+	// LoadBalancerClassName, will limit the load balancing services to services with LoadBalancerClass set to this value,
+	// or to one of the values of a comma separated list
 	LoadBalancerClassName string `yaml:"lbClassName"`
 
+	// LoadBalancerClassAdoptUnclassed, will also load balance the services without a LoadBalancerClass when
+	// the legacy handling is disabled
+	LoadBalancerClassAdoptUnclassed bool `yaml:"lbClassAdoptUnclassed"`
+
 	// LoadBalancerClassLegacyHandling, will enable legacy loadbalancer class handling which does not force service loadbalancer class and kube-vip's loadbalancer class to be the same.
 	LoadBalancerClassLegacyHandling bool `yaml:"lbClassNameLegacyHandling"`
 
@@ -54,10 +149,22 @@ type Config struct {
 	// ArpBroadcastRate, defines how often kube-vip will update the network about updates to the network
 	ArpBroadcastRate int64 `yaml:"arpBroadcastRate"`
 
+	// ArpBurstCount, defines how many gratuitous ARPs are sent in a burst when a VIP is first announced (e.g. on failover)
+	ArpBurstCount int `yaml:"arpBurstCount"`
+
+	// ArpBurstInterval, defines the time in milliseconds between the gratuitous ARPs of a burst
+	ArpBurstInterval int64 `yaml:"arpBurstInterval"`
+
+	// NdpAdvertisementCount, defines how many unsolicited neighbor advertisements are sent in a burst when an IPv6 VIP is first announced
+	NdpAdvertisementCount int `yaml:"ndpAdvertisementCount"`
+
+	// AnnouncementRateLimit, defines the maximum number of gratuitous ARPs and neighbor advertisements per second across all VIPs (0 is unlimited)
+	AnnouncementRateLimit int `yaml:"announcementRateLimit"`
+
 	// Annotations will define if we're going to wait and lookup configuration from Kubernetes node annotations
 	Annotations string
 
-	// LeaderElectionType defines the backend to run the leader election: kubernetes or etcd. Defaults to kubernetes.
+	// LeaderElectionType defines the backend to run the leader election: kubernetes, etcd or vrrp. Defaults to kubernetes.
 	// Etcd doesn't support load balancer mode (EnableLoadBalancer=true) or any other feature that depends on the kube-api server.
 	LeaderElectionType string `yaml:"leaderElectionType"`
 
@@ -67,6 +174,12 @@ type Config struct {
 	// Etcd defines all the settings for the etcd client.
 	Etcd Etcd
 
+	// VRRP defines all the settings for the VRRP leader election.
+	VRRP VRRP
+
+	// Raft defines all the settings for the Raft leader election.
+	Raft Raft
+
 	// AddPeersAsBackends, this will automatically add RAFT peers as backends to a loadbalancer
 	AddPeersAsBackends bool `yaml:"addPeersAsBackends"`
 
@@ -88,9 +201,20 @@ type Config struct {
 	// Namespace will define which namespace the control plane pods will run in
 	Namespace string `yaml:"namespace"`
 
-	// Namespace will define which namespace the control plane pods will run in
+	// ServiceNamespace will define which namespaces (a comma separated list) the services are watched in
 	ServiceNamespace string `yaml:"serviceNamespace"`
 
+	// ServicesLabelSelector will restrict the services that are watched to those that match it, so that the
+	// services can be split between multiple deployments
+	ServicesLabelSelector string `yaml:"servicesLabelSelector"`
+
+	// ServicesIgnoreLabelSelector, ServicesIgnoreAnnotations and ServicesIgnoreNamespaces are the services that
+	// are ignored (as with the kube-vip.io/ignore annotation), so that they can be handled by another load balancer
+	// controller. The annotations and namespaces are comma separated lists, an annotation is a key or a key=value pair.
+	ServicesIgnoreLabelSelector string `yaml:"servicesIgnoreLabelSelector"`
+	ServicesIgnoreAnnotations   string `yaml:"servicesIgnoreAnnotations"`
+	ServicesIgnoreNamespaces    string `yaml:"servicesIgnoreNamespaces"`
+
 	// use DDNS to allocate IP when Address is set to a DNS Name
 	DDNS bool `yaml:"ddns"`
 
@@ -109,6 +233,12 @@ type Config struct {
 	// ServicesInterface is the network interface to bind to for services (optional)
 	ServicesInterface string `yaml:"servicesInterface,omitempty"`
 
+	// ManagedInterfaceType will create a dummy or macvlan interface for the VIPs, instead of adding them to Interface
+	ManagedInterfaceType string `yaml:"managedInterfaceType,omitempty"`
+
+	// ManagedInterfaceName is the name of the managed interface (default: kube-vip0)
+	ManagedInterfaceName string `yaml:"managedInterfaceName,omitempty"`
+
 	// EnableLoadBalancer, provides the flexibility to make the load-balancer optional
 	EnableLoadBalancer bool `yaml:"enableLoadBalancer"`
 
@@ -127,6 +257,21 @@ type Config struct {
 	// Routing Protocol, value that will be used as protocol when creating rutes
 	RoutingProtocol int `yaml:"routingProtocol"`
 
+	// Routing Metric, the priority of the routes so they can be preferred over (or by) the routes of other agents
+	RoutingMetric int `yaml:"routingMetric"`
+
+	// Routing Source, the source address hint (an IPv4 and/or IPv6 address) of the routes on multi-homed nodes
+	RoutingSource string `yaml:"routingSource"`
+
+	// Routing Table ECMP, the routes have the ready local endpoints as their next-hops
+	RoutingTableECMP bool `yaml:"routingTableECMP"`
+
+	// Routing Blackhole Grace Period, the seconds that a blackhole route is kept for a withdrawn VIP (zero disables them)
+	RoutingBlackholeGracePeriod int `yaml:"routingBlackholeGracePeriod"`
+
+	// Routing VRF, the VRF that the routes (and the addresses on interfaces managed by kube-vip) are installed in
+	RoutingVRF string `yaml:"routingVRF"`
+
 	// Clean routing table of redundant routes on start
 	CleanRoutingTable bool `yaml:"cleanRoutingTable"`
 
@@ -135,6 +280,36 @@ type Config struct {
 	BGPPeerConfig bgp.Peer
 	BGPPeers      []string
 
+	// BGPDynamicNeighbors are prefixes (prefix:AS:password) that inbound BGP sessions are accepted from
+	BGPDynamicNeighbors []string
+
+	// BGPExportPolicies restrict the VIPs that are advertised to a peer (peer=prefix|community)
+	BGPExportPolicies []string
+
+	// BGPPeerTimers override the bgp timers for a peer (peer=holdtime:keepalive)
+	BGPPeerTimers []string
+
+	// BGPPeerGroups are named groups of bgp peers (group=peer|peer) that services can be advertised to
+	BGPPeerGroups []string
+
+	// BGPConfederationMembers are the other member AS of the bgp confederation
+	BGPConfederationMembers []string
+
+	// BGPPeersFromNode will discover the BGP peers from the annotations of the node kube-vip is running on
+	BGPPeersFromNode bool `yaml:"bgpPeersFromNode"`
+
+	// BGPPeersConfigMap is a ConfigMap (in the kube-vip namespace) that holds the BGP peers, changes are applied without a restart
+	BGPPeersConfigMap string `yaml:"bgpPeersConfigMap"`
+
+	// BGPNodeCondition will publish the health of the BGP sessions as a condition on the node
+	BGPNodeCondition bool `yaml:"bgpNodeCondition"`
+
+	// BGPDrainOnCordon will withdraw (or de-preference) the BGP advertisements whilst the node is cordoned or draining
+	BGPDrainOnCordon bool `yaml:"bgpDrainOnCordon"`
+
+	// BGPPathAttributes are the optional attributes (e.g. communities) attached to an advertised VIP
+	BGPPathAttributes bgp.PathAttributes `yaml:"bgpPathAttributes,omitempty"`
+
 	// EnableMetal, will use the metal API to update the EIP <-> VIP (if BGP is enabled then BGP will be used)
 	EnableMetal bool `yaml:"enableMetal"`
 
@@ -167,6 +342,10 @@ type Config struct {
 	// EgressWithNftables, this will use the iptables-nftables OVER iptables
 	EgressWithNftables bool
 
+	// EgressBackend, this is the backend of the egress rules, iptables (by default) or nftables which programs the
+	// rules over netlink rather than with the iptables binaries
+	EgressBackend string
+
 	// ServicesLeaseName, this will set the lease name for services leader in arp mode
 	ServicesLeaseName string `yaml:"servicesLeaseName"`
 
@@ -182,6 +361,10 @@ type Config struct {
 	// EnableEndpointSlices, if enabled, EndpointSlices will be used instead of Endpoints
 	EnableEndpointSlices bool `yaml:"enableEndpointSlices"`
 
+	// EndpointsProvider, the provider of the endpoints of the services (auto, endpointslices, endpoints or hybrid),
+	// auto unless EnableEndpointSlices is set
+	EndpointsProvider string `yaml:"endpointsProvider"`
+
 	// MirrorDestInterface is the network interface where all traffics that go through service interface
 	// will be mirrored to. If ServicesInterface is not set, fall back to Interface.
 	// + optional
@@ -213,6 +396,21 @@ type KubernetesLeaderElection struct {
 
 	// LeaseAnnotations - annotations which will be given to the lease object
 	LeaseAnnotations map[string]string
+
+	// EnableLeaderPriority - nodes with a higher priority (kube-vip.io/priority label) win the Kubernetes elections
+	EnableLeaderPriority bool `yaml:"enableLeaderPriority"`
+
+	// LeaderPreemption - a node with a higher priority takes the lease from a leader with a lower priority
+	LeaderPreemption bool `yaml:"leaderPreemption"`
+
+	// LeaderFailbackDelay - length of time (in seconds) a leader holds the lease before it can be preempted
+	LeaderFailbackDelay int `yaml:"leaderFailbackDelay"`
+
+	// ElectionExcludeSelector - nodes with labels matching this selector don't take part in the elections
+	ElectionExcludeSelector string `yaml:"electionExcludeSelector"`
+
+	// ElectionExcludeTaints - nodes with a taint with one of these (comma separated) keys don't take part in the elections
+	ElectionExcludeTaints string `yaml:"electionExcludeTaints"`
 }
 
 // Etcd defines all the settings for the etcd client.
@@ -223,6 +421,30 @@ type Etcd struct {
 	Endpoints      []string
 }
 
+// VRRP defines all the settings for the VRRP leader election.
+type VRRP struct {
+	// VirtualRouterID identifies the VRRP group, it must match the other routers in the group
+	VirtualRouterID int
+	// Priority of this node, the node with the highest priority becomes the master
+	Priority int
+	// AdvertisementInterval is the time in milliseconds between advertisements from the master
+	AdvertisementInterval int
+	// Preempt allows a node with a higher priority to take over from the current master
+	Preempt bool
+}
+
+// Raft defines all the settings for the Raft leader election.
+type Raft struct {
+	// Members of the election as comma separated name=host:port, including this node (by its node name)
+	Members string
+	// Key is the shared key that authenticates the messages between the members, a warning is logged without it
+	Key string
+	// HeartbeatInterval is the time in milliseconds between heartbeats from the leader
+	HeartbeatInterval int
+	// ElectionTimeout is the time in milliseconds without a heartbeat before a member starts an election
+	ElectionTimeout int
+}
+
 // LoadBalancer contains the configuration of a load balancing instance
 type LoadBalancer struct {
 	// Name of a LoadBalancer
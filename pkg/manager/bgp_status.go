@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kube-vip/kube-vip/pkg/bgp"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// bgpNodeCondition is the node condition that reports the health of the BGP sessions on that node
+	bgpNodeCondition v1.NodeConditionType = "KubeVipBGPPeersEstablished"
+
+	// bgpStatusInterval is how often the BGP sessions are checked for changes
+	bgpStatusInterval = 30 * time.Second
+)
+
+// bgpStatusCondition returns the node condition for the state of the BGP sessions
+func bgpStatusCondition(status []bgp.PeerStatus) v1.NodeCondition {
+	condition := v1.NodeCondition{
+		Type:   bgpNodeCondition,
+		Status: v1.ConditionTrue,
+		Reason: "AllPeersEstablished",
+	}
+
+	peers := make([]string, 0, len(status))
+	for _, peer := range status {
+		description := fmt.Sprintf("%s %s (%d prefixes)", peer.Address, peer.State, peer.AdvertisedPrefixes)
+		if !peer.Established() {
+			condition.Status = v1.ConditionFalse
+			condition.Reason = "PeersNotEstablished"
+			if !peer.LastDown.IsZero() {
+				description = fmt.Sprintf("%s %s (down since %s, %d notifications received)", peer.Address, peer.State,
+					peer.LastDown.UTC().Format(time.RFC3339), peer.NotificationsReceived)
+			}
+		}
+		peers = append(peers, description)
+	}
+	if len(peers) == 0 {
+		condition.Status = v1.ConditionFalse
+		condition.Reason = "NoPeers"
+	}
+	condition.Message = strings.Join(peers, ", ")
+	return condition
+}
+
+// bgpStatusReporter will publish the health of the BGP sessions as a condition on this node, so
+// that peering problems are visible with kubectl. The node is only updated when the health changes.
+func (sm *Manager) bgpStatusReporter(ctx context.Context) {
+	ticker := time.NewTicker(bgpStatusInterval)
+	defer ticker.Stop()
+
+	var last v1.NodeCondition
+	for {
+		status, err := sm.bgpServer.PeerStatus()
+		if err != nil {
+			log.Errorf("[BGP] unable to get peer status: %v", err)
+		} else {
+			condition := bgpStatusCondition(status)
+			if condition.Status != last.Status || condition.Reason != last.Reason || condition.Message != last.Message {
+				now := metav1.Now()
+				condition.LastHeartbeatTime = now
+				condition.LastTransitionTime = last.LastTransitionTime
+				if condition.Status != last.Status {
+					condition.LastTransitionTime = now
+				}
+				if err = sm.patchNodeCondition(ctx, condition); err != nil {
+					log.Errorf("[BGP] unable to update condition [%s] on node [%s]: %v", bgpNodeCondition, sm.config.NodeName, err)
+				} else {
+					last = condition
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// patchNodeCondition will add or replace a condition in the status of this node, conditions are
+// merged by their type so the conditions from the kubelet are left alone
+func (sm *Manager) patchNodeCondition(ctx context.Context, condition v1.NodeCondition) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []v1.NodeCondition{condition},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = sm.clientSet.CoreV1().Nodes().PatchStatus(ctx, sm.config.NodeName, patch)
+	return err
+}
@@ -21,6 +21,9 @@ func initClusterManager(sm *Manager) (*cluster.Manager, error) {
 			return nil, err
 		}
 		m.EtcdClient = client
+	case "vrrp", "raft":
+		// The election doesn't need a client, the Kubernetes client is still used by the control plane load balancer
+		m.KubernetesClient = sm.clientSet
 	default:
 		return nil, errors.Errorf("invalid LeaderElectionMode %s not supported", sm.config.LeaderElectionType)
 	}
@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kube-vip/kube-vip/pkg/bgp"
+)
+
+// repairDrift periodically checks that the addresses, routes and BGP paths of the active services are still
+// configured on the node and repairs any that have been removed out-of-band, until stop is closed. The services
+// don't use IPVS (it is only used by the control plane load balancer), so there are no IPVS entries to check.
+// The addresses that were left behind for services that no longer exist are also removed.
+func (sm *Manager) repairDrift(interval time.Duration, stop <-chan struct{}) {
+	log.Infof("(svcs) checking the services for drift every [%s]", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			sm.repairServices()
+			sm.reconcileAddresses(context.Background())
+		}
+	}
+}
+
+// repairServices repairs the services once, a failure is logged and the service is checked again next time
+func (sm *Manager) repairServices() {
+	sm.mutex.Lock()
+	instances := append([]*Instance(nil), sm.serviceInstances...)
+	sm.mutex.Unlock()
+
+	for _, instance := range instances {
+		for _, c := range instance.clusters {
+			for _, network := range c.Network {
+				repaired, err := network.RepairDrift()
+				for _, kind := range repaired {
+					log.Warnf("(svcs) the %s of [%s] for service [%s] had been removed, it has been repaired", kind, network.IP(), instance.UID)
+					sm.countDriftRepairs.With(prometheus.Labels{"kind": kind}).Inc()
+				}
+				if err != nil {
+					log.Errorf("(svcs) unable to repair [%s] for service [%s]: %v", network.IP(), instance.UID, err)
+				}
+			}
+		}
+	}
+
+	if reconciler, ok := sm.bgpServer.(bgp.Reconciler); ok {
+		restored, err := reconciler.ReconcileHosts()
+		for _, prefix := range restored {
+			log.Warnf("[BGP] path for [%s] had been removed, it has been repaired", prefix)
+			sm.countDriftRepairs.With(prometheus.Labels{"kind": "bgp"}).Inc()
+		}
+		if err != nil {
+			log.Errorf("[BGP] unable to repair the advertised paths: %v", err)
+		}
+	}
+}
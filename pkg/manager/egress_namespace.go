@@ -0,0 +1,312 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kube-vip/kube-vip/pkg/vip"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceEgress is the egress of the pods of the namespaces that are annotated with an egress service. A pod can
+// only be re-written by the node it runs on, as only its traffic passes through that node, so the pods of this node
+// are watched (along with the namespaces) and those of a service whose VIP is on this node egress with the VIP. When
+// the VIP is only on the node that holds it (with leader or service election), the pods on the other nodes can't
+// egress with it, as the replies to the VIP would reach the wrong node, so they are skipped with a warning.
+type namespaceEgress struct {
+	pods       cache.SharedIndexInformer
+	namespaces cache.SharedIndexInformer
+
+	mutex sync.Mutex
+	// services are the egress services of this node, by the namespace/name of the service
+	services map[string]*namespaceEgressService
+}
+
+// namespaceEgressService is a service whose VIPs the pods of its namespace egress with
+type namespaceEgressService struct {
+	namespace        string
+	name             string
+	vips             []string
+	destinationCIDRs string
+	destinationPorts string
+
+	// activeEndpoints are re-written by the egress of the service itself
+	activeEndpoints []string
+
+	// pods are the addresses of the pods whose egress has been configured
+	pods map[string]struct{}
+}
+
+// podEgressService returns the name of the service a pod egresses with, the annotation of the pod overrides that of
+// its namespace and an empty annotation opts the pod out of the egress of its namespace
+func podEgressService(pod *v1.Pod, namespace *v1.Namespace) (string, bool) {
+	if service, exists := pod.Annotations[egressService]; exists {
+		return service, service != ""
+	}
+	if namespace == nil {
+		return "", false
+	}
+	service := namespace.Annotations[egressService]
+	return service, service != ""
+}
+
+// remoteEgressPods returns the names of the pods (on other nodes than node) that egress with the service
+func remoteEgressPods(pods []v1.Pod, namespace *v1.Namespace, service, node string) []string {
+	var names []string
+	for i := range pods {
+		if pods[i].Spec.NodeName == node || len(egressPodIPs(&pods[i])) == 0 {
+			continue
+		}
+		if name, ok := podEgressService(&pods[i], namespace); ok && name == service {
+			names = append(names, pods[i].Name)
+		}
+	}
+	return names
+}
+
+// egressPodIPs returns the addresses of a pod that can egress with a VIP, the pods that share the network of the node
+// or have finished have none
+func egressPodIPs(pod *v1.Pod) []string {
+	if pod.Spec.HostNetwork || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return nil
+	}
+	var addresses []string
+	for _, podIP := range pod.Status.PodIPs {
+		addresses = append(addresses, podIP.IP)
+	}
+	if len(addresses) == 0 && pod.Status.PodIP != "" {
+		addresses = append(addresses, pod.Status.PodIP)
+	}
+	return addresses
+}
+
+// namespaceEgress returns the namespace egress, starting the informers of the pods of the node and of the namespaces
+// (until shutdown) when it is first used
+func (sm *Manager) namespaceEgress() (*namespaceEgress, error) {
+	sm.namespaceEgressOnce.Do(func() {
+		if sm.config.NodeName == "" {
+			sm.namespaceEgressErr = fmt.Errorf("the name of the node is required for the egress of namespaces")
+			return
+		}
+		podOptions := []informers.SharedInformerOption{informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", sm.config.NodeName).String()
+		})}
+		var namespaceOptions []informers.SharedInformerOption
+		// A single namespace is watched on its own, otherwise every namespace is watched
+		if namespaces := sm.config.ServiceNamespaces(); len(namespaces) == 1 && namespaces[0] != v1.NamespaceAll {
+			podOptions = append(podOptions, informers.WithNamespace(namespaces[0]))
+			namespaceOptions = append(namespaceOptions, informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector("metadata.name", namespaces[0]).String()
+			}))
+		}
+		c := &namespaceEgress{
+			pods:       informers.NewSharedInformerFactoryWithOptions(sm.clientSet, 0, podOptions...).Core().V1().Pods().Informer(),
+			namespaces: informers.NewSharedInformerFactoryWithOptions(sm.clientSet, 0, namespaceOptions...).Core().V1().Namespaces().Informer(),
+			services:   make(map[string]*namespaceEgressService),
+		}
+		// Any change of a pod or of a namespace is reconciled with the egress services of its namespace
+		handler := func(namespace func(metav1.Object) string) cache.ResourceEventHandlerFuncs {
+			reconcile := func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				if object, ok := obj.(metav1.Object); ok {
+					sm.reconcileNamespaceEgress(c, namespace(object))
+				}
+			}
+			return cache.ResourceEventHandlerFuncs{
+				AddFunc:    reconcile,
+				UpdateFunc: func(_, obj interface{}) { reconcile(obj) },
+				DeleteFunc: reconcile,
+			}
+		}
+		if _, err := c.pods.AddEventHandler(handler(metav1.Object.GetNamespace)); err != nil {
+			sm.namespaceEgressErr = fmt.Errorf("unable to watch the pods: %w", err)
+			return
+		}
+		if _, err := c.namespaces.AddEventHandler(handler(metav1.Object.GetName)); err != nil {
+			sm.namespaceEgressErr = fmt.Errorf("unable to watch the namespaces: %w", err)
+			return
+		}
+		log.Infof("[egress] starting the informers of the pods of node [%s] and of the namespaces", sm.config.NodeName)
+		go c.pods.Run(sm.shutdownChan)
+		go c.namespaces.Run(sm.shutdownChan)
+		sm.namespaceEgressCache = c
+	})
+	return sm.namespaceEgressCache, sm.namespaceEgressErr
+}
+
+// addNamespaceEgress configures the egress of the pods (of this node) that egress with the service
+func (sm *Manager) addNamespaceEgress(ctx context.Context, svc *v1.Service, vips []string) {
+	c, err := sm.namespaceEgress()
+	if err != nil {
+		log.Errorf("[egress] unable to configure the egress of namespace [%s]: %v", svc.Namespace, err)
+		return
+	}
+	s := &namespaceEgressService{
+		namespace:        svc.Namespace,
+		name:             svc.Name,
+		vips:             vips,
+		destinationCIDRs: svc.Annotations[egressDestinationCIDRs],
+		destinationPorts: svc.Annotations[egressDestinationPorts],
+		pods:             make(map[string]struct{}),
+	}
+	for _, annotation := range []string{activeEndpoint, activeEndpointIPv6} {
+		if svc.Annotations[annotation] != "" {
+			s.activeEndpoints = append(s.activeEndpoints, svc.Annotations[annotation])
+		}
+	}
+
+	c.mutex.Lock()
+	key := svc.Namespace + "/" + svc.Name
+	if existing := c.services[key]; existing != nil {
+		sm.teardownNamespaceEgress(existing)
+	}
+	c.services[key] = s
+	c.mutex.Unlock()
+
+	// The pods that are already known are configured now, those that aren't when they are added to the cache
+	sm.reconcileNamespaceEgress(c, svc.Namespace)
+
+	// Every node has the VIP when it is advertised without an election, otherwise only this node does
+	if (sm.config.EnableRoutingTable || sm.config.EnableBGP) && !sm.config.EnableLeaderElection && !sm.config.EnableServicesElection {
+		return
+	}
+	sm.warnRemoteNamespaceEgress(ctx, c, svc)
+}
+
+// warnRemoteNamespaceEgress warns about the pods on the other nodes that egress with the service, as only the pods of
+// the node that holds its VIP can egress with it
+func (sm *Manager) warnRemoteNamespaceEgress(ctx context.Context, c *namespaceEgress, svc *v1.Service) {
+	pods, err := sm.clientSet.CoreV1().Pods(svc.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermNotEqualSelector("spec.nodeName", sm.config.NodeName).String(),
+	})
+	if err != nil {
+		log.Warnf("[egress] unable to list the pods of namespace [%s] on the other nodes: %v", svc.Namespace, err)
+		return
+	}
+	var ns *v1.Namespace
+	if obj, exists, err := c.namespaces.GetIndexer().GetByKey(svc.Namespace); err == nil && exists {
+		ns, _ = obj.(*v1.Namespace)
+	}
+	if names := remoteEgressPods(pods.Items, ns, svc.Name, sm.config.NodeName); len(names) > 0 {
+		log.Warnf("[egress] the pods [%s] of namespace [%s] are on other nodes than [%s], which holds the VIP of service [%s], and don't egress with it",
+			strings.Join(names, ","), svc.Namespace, sm.config.NodeName, svc.Name)
+	}
+}
+
+// deleteNamespaceEgress tears down the egress of the pods that egress with the service
+func (sm *Manager) deleteNamespaceEgress(svc *v1.Service) {
+	c, err := sm.namespaceEgress()
+	if err != nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := svc.Namespace + "/" + svc.Name
+	if s := c.services[key]; s != nil {
+		log.Infof("[egress] tearing down the egress of namespace [%s] with service [%s]", s.namespace, s.name)
+		sm.teardownNamespaceEgress(s)
+		delete(c.services, key)
+	}
+}
+
+// reconcileNamespaceEgress configures the egress of the pods of the namespace with the service they egress with, and
+// tears down the egress of those that no longer do
+func (sm *Manager) reconcileNamespaceEgress(c *namespaceEgress, namespace string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var ns *v1.Namespace
+	if obj, exists, err := c.namespaces.GetIndexer().GetByKey(namespace); err == nil && exists {
+		ns, _ = obj.(*v1.Namespace)
+	}
+	pods, err := c.pods.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	if err != nil {
+		log.Errorf("[egress] unable to list the pods of namespace [%s]: %v", namespace, err)
+		return
+	}
+	// The service that each address of the pods egresses with
+	wanted := make(map[string]string)
+	for _, obj := range pods {
+		pod, ok := obj.(*v1.Pod)
+		if !ok {
+			continue
+		}
+		service, ok := podEgressService(pod, ns)
+		if !ok {
+			continue
+		}
+		for _, podIP := range egressPodIPs(pod) {
+			wanted[podIP] = service
+		}
+	}
+
+	for _, s := range c.services {
+		if s.namespace != namespace {
+			continue
+		}
+		for podIP := range s.pods {
+			if wanted[podIP] != s.name {
+				log.Infof("[egress] removing the egress of pod [%s] with service [%s/%s]", podIP, s.namespace, s.name)
+				sm.teardownPodEgress(s, podIP)
+				delete(s.pods, podIP)
+			}
+		}
+		for podIP, service := range wanted {
+			if service != s.name || slices.Contains(s.activeEndpoints, podIP) {
+				continue
+			}
+			if _, configured := s.pods[podIP]; configured {
+				continue
+			}
+			log.Infof("[egress] configuring the egress of pod [%s] with service [%s/%s]", podIP, s.namespace, s.name)
+			if err := sm.configurePodEgress(s, podIP); err != nil {
+				log.Errorf("[egress] unable to configure the egress of pod [%s]: %v", podIP, err)
+				continue
+			}
+			s.pods[podIP] = struct{}{}
+		}
+	}
+}
+
+// configurePodEgress configures the egress of a pod with the VIP of the service of its IP family
+func (sm *Manager) configurePodEgress(s *namespaceEgressService, podIP string) error {
+	for _, serviceIP := range s.vips {
+		if vip.IsIPv4(serviceIP) != vip.IsIPv4(podIP) {
+			continue
+		}
+		if err := sm.configureEgress(serviceIP, podIP, s.destinationCIDRs, s.destinationPorts, s.namespace, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// teardownPodEgress tears down the egress of a pod with the VIP of the service of its IP family
+func (sm *Manager) teardownPodEgress(s *namespaceEgressService, podIP string) {
+	for _, serviceIP := range s.vips {
+		if vip.IsIPv4(serviceIP) != vip.IsIPv4(podIP) {
+			continue
+		}
+		if err := sm.TeardownEgress(podIP, serviceIP, s.destinationCIDRs, s.destinationPorts, s.namespace); err != nil {
+			log.Errorf("[egress] unable to tear down the egress of pod [%s]: %v", podIP, err)
+		}
+	}
+}
+
+// teardownNamespaceEgress tears down the egress of every pod that egresses with the service
+func (sm *Manager) teardownNamespaceEgress(s *namespaceEgressService) {
+	for podIP := range s.pods {
+		sm.teardownPodEgress(s, podIP)
+		delete(s.pods, podIP)
+	}
+}
@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_podEgressService(t *testing.T) {
+	annotated := func(annotations map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: annotations}
+	}
+	namespace := &v1.Namespace{ObjectMeta: annotated(map[string]string{egressService: "egress"})}
+	tests := []struct {
+		name      string
+		pod       *v1.Pod
+		namespace *v1.Namespace
+		want      string
+		wantOk    bool
+	}{
+		{
+			name:      "the pod egresses with the service of its namespace",
+			pod:       &v1.Pod{ObjectMeta: annotated(nil)},
+			namespace: namespace,
+			want:      "egress",
+			wantOk:    true,
+		},
+		{
+			name:      "the pod overrides the service of its namespace",
+			pod:       &v1.Pod{ObjectMeta: annotated(map[string]string{egressService: "other"})},
+			namespace: namespace,
+			want:      "other",
+			wantOk:    true,
+		},
+		{
+			name:      "the pod opts out of the egress of its namespace",
+			pod:       &v1.Pod{ObjectMeta: annotated(map[string]string{egressService: ""})},
+			namespace: namespace,
+		},
+		{
+			name:   "the pod egresses with its service without a namespace annotation",
+			pod:    &v1.Pod{ObjectMeta: annotated(map[string]string{egressService: "other"})},
+			want:   "other",
+			wantOk: true,
+		},
+		{
+			name:      "neither the pod nor its namespace are annotated",
+			pod:       &v1.Pod{ObjectMeta: annotated(nil)},
+			namespace: &v1.Namespace{ObjectMeta: annotated(nil)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := podEgressService(tt.pod, tt.namespace)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("podEgressService() = %q, %t, want %q, %t", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_remoteEgressPods(t *testing.T) {
+	pod := func(name, node, service string) v1.Pod {
+		p := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"}, Spec: v1.PodSpec{NodeName: node},
+			Status: v1.PodStatus{PodIP: "10.0.0.1"}}
+		if service != "" {
+			p.Annotations = map[string]string{egressService: service}
+		}
+		return p
+	}
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: map[string]string{egressService: "egress"}}}
+	hostNetwork := pod("host", "node-b", "")
+	hostNetwork.Spec.HostNetwork = true
+	pods := []v1.Pod{pod("local", "node-a", ""), pod("remote", "node-b", ""), pod("other", "node-b", "other"), hostNetwork}
+
+	got := remoteEgressPods(pods, namespace, "egress", "node-a")
+	if len(got) != 1 || got[0] != "remote" {
+		t.Errorf("remoteEgressPods() = %v, want [remote]", got)
+	}
+}
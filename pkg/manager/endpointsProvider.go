@@ -0,0 +1,75 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// selectEndpointsProvider resolves the provider of the endpoints of the services, the EndpointSlices are used
+// automatically when they can be listed, as older clusters (or roles) don't have them
+func (sm *Manager) selectEndpointsProvider(ctx context.Context) error {
+	provider := sm.config.EndpointsProvider
+	if provider == "" {
+		provider = kubevip.EndpointsProviderAuto
+		if sm.config.EnableEndpointSlices {
+			provider = kubevip.EndpointsProviderEndpointSlices
+		}
+	}
+
+	switch provider {
+	case kubevip.EndpointsProviderEndpointSlices, kubevip.EndpointsProviderEndpoints, kubevip.EndpointsProviderHybrid:
+	case kubevip.EndpointsProviderAuto:
+		namespace := v1.NamespaceAll
+		if namespaces := sm.config.ServiceNamespaces(); len(namespaces) == 1 {
+			namespace = namespaces[0]
+		}
+		provider = kubevip.EndpointsProviderEndpointSlices
+		if _, err := sm.clientSet.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+			log.Warnf("[endpoints] unable to list the endpointslices, the endpoints are used instead: %v", err)
+			provider = kubevip.EndpointsProviderEndpoints
+		}
+	default:
+		return fmt.Errorf("unknown endpoints provider [%s], expected auto, endpointslices, endpoints or hybrid", provider)
+	}
+
+	sm.config.EndpointsProvider = provider
+	sm.config.EnableEndpointSlices = provider != kubevip.EndpointsProviderEndpoints
+	log.Infof("[endpoints] the endpoints of the services are provided by [%s]", provider)
+	return nil
+}
+
+// newEndpointsProvider returns the provider of the endpoints of a service, in the hybrid mode the Endpoints are
+// used for a service that doesn't have any EndpointSlices when its endpoints start being watched
+func (sm *Manager) newEndpointsProvider(ctx context.Context, service *v1.Service) epProvider {
+	switch sm.config.EndpointsProvider {
+	case kubevip.EndpointsProviderEndpoints:
+		return &endpointsProvider{label: "endpoints"}
+	case kubevip.EndpointsProviderHybrid:
+		slices, err := sm.endpointSlices()
+		if err == nil {
+			var found bool
+			if found, err = slices.hasSlices(ctx, service); err == nil && !found {
+				log.Infof("[endpoints] service %s/%s doesn't have any endpointslices, its endpoints are used", service.Namespace, service.Name)
+				return &endpointsProvider{label: "endpoints"}
+			}
+		}
+		if err != nil {
+			log.Errorf("[endpoints] unable to find the endpointslices of service %s/%s: %v", service.Namespace, service.Name, err)
+		}
+	}
+	return &endpointslicesProvider{label: "endpointslices"}
+}
+
+// serviceEndpointsProvider returns the provider of the endpoint watcher of a service, or a new provider (resolved
+// with the context) when its endpoints aren't watched
+func (sm *Manager) serviceEndpointsProvider(ctx context.Context, service *v1.Service) epProvider {
+	if provider, ok := sm.endpointsProviders.Load(string(service.UID)); ok {
+		return provider.(epProvider)
+	}
+	return sm.newEndpointsProvider(ctx, service)
+}
@@ -0,0 +1,29 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceEndpointsProvider(t *testing.T) {
+	// In the hybrid mode a new provider waits for the endpointslices informer, the provider of the endpoint watcher
+	// is used without it (there is no client to start the informer with)
+	sm := &Manager{config: &kubevip.Config{EndpointsProvider: kubevip.EndpointsProviderHybrid}}
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test", UID: "uid"}}
+	watched := &endpointsProvider{label: "endpoints"}
+	sm.endpointsProviders.Store(string(svc.UID), watched)
+
+	if got := sm.serviceEndpointsProvider(context.Background(), svc); got != watched {
+		t.Errorf("serviceEndpointsProvider() = %v, want the provider of the endpoint watcher", got)
+	}
+
+	sm.config.EndpointsProvider = kubevip.EndpointsProviderEndpoints
+	sm.endpointsProviders.Delete(string(svc.UID))
+	if got := sm.serviceEndpointsProvider(context.Background(), svc); got == watched || got.getLabel() != "endpoints" {
+		t.Errorf("serviceEndpointsProvider() = %v, want a new endpoints provider", got)
+	}
+}
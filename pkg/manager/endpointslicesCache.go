@@ -0,0 +1,200 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// endpointSliceServiceIndex indexes the EndpointSlices by the namespace/name of their service
+const endpointSliceServiceIndex = "service"
+
+// endpointSliceCache is an EndpointSlice informer that is shared by the endpoint watchers of every service, so that
+// there is a single watch of the API server rather than one per service. The events of the slices are passed on to
+// the watchers of their service.
+type endpointSliceCache struct {
+	informer cache.SharedIndexInformer
+
+	mutex    sync.Mutex
+	watchers map[string]map[*sliceWatcher]struct{}
+}
+
+// endpointSliceService returns the namespace/name of the service of an EndpointSlice
+func endpointSliceService(slice *discoveryv1.EndpointSlice) string {
+	service := slice.Labels[discoveryv1.LabelServiceName]
+	if service == "" {
+		return ""
+	}
+	return slice.Namespace + "/" + service
+}
+
+// endpointSlices returns the EndpointSlice cache, starting its informer (until shutdown) when it is first used
+func (sm *Manager) endpointSlices() (*endpointSliceCache, error) {
+	sm.endpointSlicesOnce.Do(func() {
+		// A single namespace is watched on its own, otherwise every namespace is watched
+		var options []informers.SharedInformerOption
+		if namespaces := sm.config.ServiceNamespaces(); len(namespaces) == 1 && namespaces[0] != v1.NamespaceAll {
+			options = append(options, informers.WithNamespace(namespaces[0]))
+		}
+		factory := informers.NewSharedInformerFactoryWithOptions(sm.clientSet, 0, options...)
+		c := &endpointSliceCache{
+			informer: factory.Discovery().V1().EndpointSlices().Informer(),
+			watchers: make(map[string]map[*sliceWatcher]struct{}),
+		}
+		if err := c.informer.AddIndexers(cache.Indexers{endpointSliceServiceIndex: func(obj interface{}) ([]string, error) {
+			if slice, ok := obj.(*discoveryv1.EndpointSlice); ok {
+				if service := endpointSliceService(slice); service != "" {
+					return []string{service}, nil
+				}
+			}
+			return nil, nil
+		}}); err != nil {
+			sm.endpointSlicesErr = fmt.Errorf("unable to index the endpointslices: %w", err)
+			return
+		}
+		if _, err := c.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { c.notify(watch.Added, obj) },
+			UpdateFunc: func(_, obj interface{}) { c.notify(watch.Modified, obj) },
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				c.notify(watch.Deleted, obj)
+			},
+		}); err != nil {
+			sm.endpointSlicesErr = fmt.Errorf("unable to watch the endpointslices: %w", err)
+			return
+		}
+		log.Infof("[endpointslices] starting the shared endpointslices informer")
+		go c.informer.Run(sm.shutdownChan)
+		sm.endpointSlicesCache = c
+	})
+	return sm.endpointSlicesCache, sm.endpointSlicesErr
+}
+
+// notify passes the event of a slice on to the watchers of its service
+func (c *endpointSliceCache) notify(eventType watch.EventType, obj interface{}) {
+	slice, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for w := range c.watchers[endpointSliceService(slice)] {
+		w.push(watch.Event{Type: eventType, Object: slice})
+	}
+}
+
+// hasSlices returns whether a service has any EndpointSlices
+func (c *endpointSliceCache) hasSlices(ctx context.Context, service *v1.Service) (bool, error) {
+	if !cache.WaitForCacheSync(ctx.Done(), c.informer.HasSynced) {
+		return false, fmt.Errorf("the endpointslices informer hasn't synced: %w", ctx.Err())
+	}
+	slices, err := c.informer.GetIndexer().ByIndex(endpointSliceServiceIndex, service.Namespace+"/"+service.Name)
+	return len(slices) != 0, err
+}
+
+// watch returns a watch of the EndpointSlices of a service, which starts with the slices that are already known
+func (c *endpointSliceCache) watch(ctx context.Context, service *v1.Service) (watch.Interface, error) {
+	if !cache.WaitForCacheSync(ctx.Done(), c.informer.HasSynced) {
+		return nil, fmt.Errorf("the endpointslices informer hasn't synced: %w", ctx.Err())
+	}
+	key := service.Namespace + "/" + service.Name
+	w := &sliceWatcher{
+		result: make(chan watch.Event),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	w.stop = func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		delete(c.watchers[key], w)
+		if len(c.watchers[key]) == 0 {
+			delete(c.watchers, key)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	slices, err := c.informer.GetIndexer().ByIndex(endpointSliceServiceIndex, key)
+	if err != nil {
+		return nil, err
+	}
+	for _, slice := range slices {
+		w.push(watch.Event{Type: watch.Added, Object: slice.(*discoveryv1.EndpointSlice)})
+	}
+	if c.watchers[key] == nil {
+		c.watchers[key] = make(map[*sliceWatcher]struct{})
+	}
+	c.watchers[key][w] = struct{}{}
+	go w.run()
+	return w, nil
+}
+
+// sliceWatcher is the watch of the EndpointSlices of a service, the events are queued so that a slow watcher
+// doesn't hold up the informer
+type sliceWatcher struct {
+	mutex  sync.Mutex
+	queue  []watch.Event
+	notify chan struct{}
+
+	result   chan watch.Event
+	done     chan struct{}
+	stop     func()
+	stopOnce sync.Once
+}
+
+func (w *sliceWatcher) push(event watch.Event) {
+	w.mutex.Lock()
+	w.queue = append(w.queue, event)
+	w.mutex.Unlock()
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers the queued events until the watch is stopped
+func (w *sliceWatcher) run() {
+	defer close(w.result)
+	for {
+		w.mutex.Lock()
+		if len(w.queue) == 0 {
+			w.mutex.Unlock()
+			select {
+			case <-w.notify:
+				continue
+			case <-w.done:
+				return
+			}
+		}
+		event := w.queue[0]
+		w.queue = w.queue[1:]
+		w.mutex.Unlock()
+		select {
+		case w.result <- event:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Stop stops the watch, and closes its result channel
+func (w *sliceWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		w.stop()
+		close(w.done)
+	})
+}
+
+// ResultChan returns the events of the EndpointSlices of the service
+func (w *sliceWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
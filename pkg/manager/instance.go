@@ -3,11 +3,14 @@ package manager
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
 	v1 "k8s.io/api/core/v1"
 
+	"github.com/kube-vip/kube-vip/pkg/bgp"
 	"github.com/kube-vip/kube-vip/pkg/cluster"
 	"github.com/kube-vip/kube-vip/pkg/kubevip"
 	"github.com/kube-vip/kube-vip/pkg/vip"
@@ -29,22 +32,60 @@ type Instance struct {
 	dhcpHostname        string
 	dhcpClient          *vip.DHCPClient
 
+	// Service uses a VLAN sub-interface, which is only removed with the service if kube-vip created it
+	vlanInterface string
+	vlanCreated   bool
+
 	// Kubernetes service mapping
 	VIPs []string
 	Port int32
 	UID  string
 	Type string
 
+	// unavailableVIPs are the addresses (of a dual-stack service) that couldn't be added
+	unavailableVIPs []string
+
+	// statusStop stops the refreshing of the status of a service whose addresses are names
+	statusStop chan struct{}
+
 	serviceSnapshot *v1.Service
 }
 
-func NewInstance(svc *v1.Service, config *kubevip.Config) (*Instance, error) {
-	instanceAddresses := fetchServiceAddresses(svc)
+// parseVIPCIDR parses the prefix length of the IPv4 and (optionally) IPv6 addresses of a service, e.g.
+// "32", "/24" or "24,64". An IPv6 address is added with a /128 if only the IPv4 prefix is set.
+func parseVIPCIDR(cidr string) (string, string, error) {
+	prefixes := strings.Split(cidr, ",")
+	if len(prefixes) > 2 {
+		return "", "", fmt.Errorf("vip cidr [%s] should be [ipv4 prefix],[ipv6 prefix]", cidr)
+	}
+	v4, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(prefixes[0]), "/"))
+	if err != nil || v4 < 0 || v4 > 32 {
+		return "", "", fmt.Errorf("invalid IPv4 prefix [%s]", prefixes[0])
+	}
+	v6 := 128
+	if len(prefixes) == 2 {
+		v6, err = strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(prefixes[1]), "/"))
+		if err != nil || v6 < 0 || v6 > 128 {
+			return "", "", fmt.Errorf("invalid IPv6 prefix [%s]", prefixes[1])
+		}
+	}
+	return fmt.Sprintf("/%d", v4), fmt.Sprintf("/%d", v6), nil
+}
+
+func NewInstance(svc *v1.Service, config *kubevip.Config, host *vip.Host) (_ *Instance, err error) {
+	instanceAddresses := fetchServiceAddresses(svc, config)
 	instanceUID := string(svc.UID)
 
 	// Detect if we're using a specific interface for services
 	var svcInterface string
-	svcInterface = svc.Annotations[serviceInterface] // If the service has a specific interface defined, then use it
+	svcInterface = fetchServiceInterface(svc) // If the service has a specific interface defined, then use it
+
+	// An interface that overrides the global one is checked, as a node may not be attached to every L2 domain
+	for _, iface := range vip.GetInterfaces(svcInterface) {
+		if _, err := netlink.LinkByName(iface); err != nil {
+			return nil, fmt.Errorf("interface [%s] of %s/%s isn't on this node: %w", iface, svc.Namespace, svc.Name, err)
+		}
+	}
 
 	// If it is still blank then use the
 	if svcInterface == "" {
@@ -54,23 +95,173 @@ func NewInstance(svc *v1.Service, config *kubevip.Config) (*Instance, error) {
 			svcInterface = config.Interface
 		}
 	}
+
+	// Create the VLAN sub-interface on the (first) interface, the addresses are added to it instead
+	var vlanInterface string
+	var vlanCreated bool
+	if vlan := svc.Annotations[vlanID]; vlan != "" {
+		id, err := strconv.Atoi(vlan)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", vlanID, svc.Namespace, svc.Name, err)
+		}
+		parent := svcInterface
+		if interfaces := vip.GetInterfaces(svcInterface); len(interfaces) > 0 {
+			parent = interfaces[0]
+		}
+		vlanInterface, vlanCreated, err = vip.EnsureVLANInterface(parent, id)
+		if err != nil {
+			return nil, fmt.Errorf("error creating VLAN interface for %s/%s: %w", svc.Namespace, svc.Name, err)
+		}
+		svcInterface = vlanInterface
+	}
+	// A sub-interface that was created for the service is removed again if the service can't be added
+	defer func() {
+		if err != nil && vlanCreated {
+			if deleteErr := vip.DeleteManagedInterface(vlanInterface); deleteErr != nil {
+				log.Errorf("unable to delete VLAN interface [%s]: %v", vlanInterface, deleteErr)
+			}
+		}
+	}()
+
+	// Parse any BGP communities that should be attached to the advertised addresses
+	var bgpAttributes bgp.PathAttributes
+	if communities := svc.Annotations[bgpCommunities]; communities != "" {
+		var err error
+		bgpAttributes, err = bgp.ParseCommunities(communities)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", bgpCommunities, svc.Namespace, svc.Name, err)
+		}
+	}
+
+	// Parse the number of times the AS path should be prepended, used to de-preference the addresses
+	if prepend := svc.Annotations[bgpPrependCount]; prepend != "" {
+		count, err := strconv.ParseUint(prepend, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", bgpPrependCount, svc.Namespace, svc.Name, err)
+		}
+		if count > bgp.MaxASPathPrepend {
+			return nil, fmt.Errorf("annotation [%s] for %s/%s is [%d], the maximum is [%d]", bgpPrependCount, svc.Namespace, svc.Name, count, bgp.MaxASPathPrepend)
+		}
+		bgpAttributes.ASPathPrepend = uint32(count)
+	}
+
+	// Parse the MED and local preference, used to steer traffic towards preferred nodes or sites
+	if med := svc.Annotations[bgpMED]; med != "" {
+		value, err := strconv.ParseUint(med, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", bgpMED, svc.Namespace, svc.Name, err)
+		}
+		v := uint32(value)
+		bgpAttributes.MED = &v
+	}
+	if localPref := svc.Annotations[bgpLocalPref]; localPref != "" {
+		value, err := strconv.ParseUint(localPref, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", bgpLocalPref, svc.Namespace, svc.Name, err)
+		}
+		v := uint32(value)
+		bgpAttributes.LocalPref = &v
+	}
+
+	// Parse the peers (or peer groups) that the addresses are only advertised to, e.g. internal services
+	if peers := svc.Annotations[bgpPeers]; peers != "" {
+		for _, peer := range strings.Split(peers, ",") {
+			if peer = strings.TrimSpace(peer); peer != "" {
+				bgpAttributes.Peers = append(bgpAttributes.Peers, peer)
+			}
+		}
+	}
+
+	// Parse the size and cadence of the gratuitous ARP burst, these override the global configuration
+	burstCount, burstInterval := config.ArpBurstCount, config.ArpBurstInterval
+	if count := svc.Annotations[arpBurstCount]; count != "" {
+		value, err := strconv.Atoi(count)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", arpBurstCount, svc.Namespace, svc.Name, err)
+		}
+		burstCount = value
+	}
+	if interval := svc.Annotations[arpBurstInterval]; interval != "" {
+		value, err := strconv.ParseInt(interval, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", arpBurstInterval, svc.Namespace, svc.Name, err)
+		}
+		burstInterval = value
+	}
+	ndpCount := config.NdpAdvertisementCount
+	if count := svc.Annotations[ndpAdvertisementCount]; count != "" {
+		value, err := strconv.Atoi(count)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", ndpAdvertisementCount, svc.Namespace, svc.Name, err)
+		}
+		ndpCount = value
+	}
+
+	// Parse the prefix length that the addresses are added with, e.g. /32 for a host route or the prefix of
+	// the interface subnet for on-link behaviour
+	subnetIPv4, subnetIPv6 := config.VIPSubnet, config.VIPSubnet
+	if cidr := svc.Annotations[vipCIDR]; cidr != "" {
+		var err error
+		subnetIPv4, subnetIPv6, err = parseVIPCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", vipCIDR, svc.Namespace, svc.Name, err)
+		}
+	}
+
+	// Parse the routing table that the routes are installed in, e.g. for policy routing per tenant
+	tableID := config.RoutingTableID
+	if table := svc.Annotations[routeTable]; table != "" {
+		value, err := strconv.ParseUint(table, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", routeTable, svc.Namespace, svc.Name, err)
+		}
+		tableID = int(value)
+	}
+	// The routes of the service can instead be installed in a VRF, along with its VLAN sub-interface
+	if vrf := svc.Annotations[routeVRF]; vrf != "" {
+		if svc.Annotations[routeTable] != "" {
+			return nil, fmt.Errorf("annotations [%s] and [%s] for %s/%s can't be used together", routeTable, routeVRF, svc.Namespace, svc.Name)
+		}
+		var err error
+		tableID, err = vip.VRFTable(vrf)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing annotation [%s] for %s/%s: %w", routeVRF, svc.Namespace, svc.Name, err)
+		}
+		if vlanInterface != "" {
+			if err = vip.EnsureVRFMember(vlanInterface, vrf); err != nil {
+				return nil, fmt.Errorf("error adding VLAN interface for %s/%s to VRF: %w", svc.Namespace, svc.Name, err)
+			}
+		}
+	}
+
 	var newVips []*kubevip.Config
 
 	for _, address := range instanceAddresses {
+		subnet := subnetIPv4
+		if vip.IsIPv6(address) {
+			subnet = subnetIPv6
+		}
 		// Generate new Virtual IP configuration
 		newVips = append(newVips, &kubevip.Config{
 			VIP:                    address,
 			Interface:              svcInterface,
 			SingleNode:             true,
 			EnableARP:              config.EnableARP,
+			ProxyARP:               config.ProxyARP,
 			EnableBGP:              config.EnableBGP,
+			BGPPathAttributes:      bgpAttributes,
 			VIPCIDR:                config.VIPCIDR,
-			VIPSubnet:              config.VIPSubnet,
+			VIPSubnet:              subnet,
 			EnableRoutingTable:     config.EnableRoutingTable,
-			RoutingTableID:         config.RoutingTableID,
+			RoutingTableID:         tableID,
 			RoutingTableType:       config.RoutingTableType,
 			RoutingProtocol:        config.RoutingProtocol,
+			RoutingMetric:          config.RoutingMetric,
+			RoutingSource:          config.RoutingSource,
 			ArpBroadcastRate:       config.ArpBroadcastRate,
+			ArpBurstCount:          burstCount,
+			ArpBurstInterval:       burstInterval,
+			NdpAdvertisementCount:  ndpCount,
 			EnableServiceSecurity:  config.EnableServiceSecurity,
 			DNSMode:                config.DNSMode,
 			DisableServiceUpdates:  config.DisableServiceUpdates,
@@ -85,6 +276,8 @@ func NewInstance(svc *v1.Service, config *kubevip.Config) (*Instance, error) {
 	instance := &Instance{
 		UID:             instanceUID,
 		VIPs:            instanceAddresses,
+		vlanInterface:   vlanInterface,
+		vlanCreated:     vlanCreated,
 		serviceSnapshot: svc,
 	}
 	if len(svc.Spec.Ports) > 0 {
@@ -129,12 +322,25 @@ func NewInstance(svc *v1.Service, config *kubevip.Config) (*Instance, error) {
 			instance.dhcpInterfaceIP = ip
 		}
 	}
-	for _, vipConfig := range instance.vipConfigs {
-		c, err := cluster.InitCluster(vipConfig, false)
+	// The address of each family is tracked separately, a service that only prefers dual-stack is still added
+	// if the address of one of its families can't be
+	var vipConfigs []*kubevip.Config
+	var addresses []string
+	for x, vipConfig := range instance.vipConfigs {
+		c, err := cluster.InitCluster(vipConfig, host, false)
 		if err != nil {
+			if isDualStack(svc) && !requiresDualStack(svc) && len(instance.vipConfigs) > 1 {
+				log.Warnf("(svcs) unable to add VIP [%s] for [%s/%s], continuing with the other addresses: %v", vipConfig.VIP, svc.Namespace, svc.Name, err)
+				instance.unavailableVIPs = append(instance.unavailableVIPs, vipConfig.VIP)
+				continue
+			}
 			log.Errorf("Failed to add Service %s/%s", svc.Namespace, svc.Name)
 			return nil, err
 		}
+		vipConfigs = append(vipConfigs, vipConfig)
+		if x < len(instance.VIPs) {
+			addresses = append(addresses, instance.VIPs[x])
+		}
 
 		for i := range c.Network {
 			c.Network[i].SetServicePorts(svc)
@@ -144,15 +350,35 @@ func NewInstance(svc *v1.Service, config *kubevip.Config) (*Instance, error) {
 		log.Infof("(svcs) adding VIP [%s] via %s for [%s/%s]", vipConfig.VIP, vipConfig.Interface, svc.Namespace, svc.Name)
 
 	}
+	if len(vipConfigs) == 0 {
+		return nil, fmt.Errorf("unable to add any of the VIPs for %s/%s", svc.Namespace, svc.Name)
+	}
+	instance.vipConfigs = vipConfigs
+	instance.VIPs = addresses
 
 	return instance, nil
 }
 
+// hasHostname checks if any of the addresses of the instance are names, that are resolved with DNS
+func (i *Instance) hasHostname() bool {
+	for _, c := range i.vipConfigs {
+		if !vip.IsIP(c.VIP) {
+			return true
+		}
+	}
+	return false
+}
+
 func (i *Instance) startDHCP() error {
 	if len(i.vipConfigs) != 1 {
 		return fmt.Errorf("DHCP requires exactly 1 VIP config, got: %v", len(i.vipConfigs))
 	}
-	parent, err := netlink.LinkByName(i.vipConfigs[0].Interface)
+	// The macvlan is created on the first interface when a VIP is announced on multiple interfaces
+	parentInterface := i.vipConfigs[0].Interface
+	if interfaces := vip.GetInterfaces(parentInterface); len(interfaces) > 0 {
+		parentInterface = interfaces[0]
+	}
+	parent, err := netlink.LinkByName(parentInterface)
 	if err != nil {
 		return fmt.Errorf("error finding VIP Interface, for building DHCP Link : %v", err)
 	}
@@ -0,0 +1,19 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/kube-vip/kube-vip/pkg/k8s"
+	log "github.com/sirupsen/logrus"
+)
+
+// annotateLease records this node, and how it advertises the VIPs, on a lease that it has started holding.
+// It runs in the background so that it doesn't hold up the announcement of the VIPs.
+func (sm *Manager) annotateLease(ctx context.Context, namespace, lease, iface string) {
+	go func() {
+		annotations := k8s.HolderAnnotations(ctx, sm.clientSet, sm.config.NodeName, iface, sm.config.Mode(), sm.config.Version)
+		if err := k8s.AnnotateLease(ctx, sm.clientSet, namespace, lease, sm.config.NodeName, annotations); err != nil && ctx.Err() == nil {
+			log.Warnf("unable to record the holder of lease [%s/%s]: %v", namespace, lease, err)
+		}
+	}()
+}
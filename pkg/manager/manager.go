@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -8,38 +9,70 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/kamhlos/upnp"
 	"github.com/kube-vip/kube-vip/pkg/bgp"
 	"github.com/kube-vip/kube-vip/pkg/k8s"
 	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	"github.com/kube-vip/kube-vip/pkg/sysctl"
 	"github.com/kube-vip/kube-vip/pkg/trafficmirror"
 	"github.com/kube-vip/kube-vip/pkg/utils"
+	"github.com/kube-vip/kube-vip/pkg/vip"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 const plunderLock = "plndr-svcs-lock"
 
 // Manager degines the manager of the load-balancing services
 type Manager struct {
-	clientSet *kubernetes.Clientset
+	clientSet kubernetes.Interface
 	configMap string
 	config    *kubevip.Config
 
+	// host is the state that the VIPs of the manager share on the host
+	host *vip.Host
+
 	// Manager services
 	// service bool
 
 	// Keeps track of all running instances
 	serviceInstances []*Instance
 
+	// services keeps track of the services that are being handled
+	services *serviceTracker
+
+	// endpointSlicesCache is the EndpointSlice informer that is shared by the endpoint watchers of the services
+	endpointSlicesOnce  sync.Once
+	endpointSlicesCache *endpointSliceCache
+	endpointSlicesErr   error
+
+	// namespaceEgressCache is the egress of the pods of the namespaces with an egress service
+	namespaceEgressOnce  sync.Once
+	namespaceEgressCache *namespaceEgress
+	namespaceEgressErr   error
+
+	// endpointsProviders are the providers of the endpoint watchers, by the UID of their service
+	endpointsProviders sync.Map
+
+	// configuredLocalRoutes keeps track of the services whose routes (or BGP paths) have been configured on the node
+	configuredLocalRoutes sync.Map
+
 	// Additional functionality
 	upnp *upnp.Upnp
 
 	// BGP Manager, this is a singleton that manages all BGP advertisements
-	bgpServer *bgp.Server
+	bgpServer bgp.Backend
+
+	// bgpSecretWatchers stop the watchers of the Secrets of the BGP peer passwords, by the namespace/name of the Secret
+	bgpSecretWatchers map[string]context.CancelFunc
+	bgpSecretsMutex   sync.Mutex
 
 	// This channel is used to catch an OS signal and trigger a shutdown
 	signalChan chan os.Signal
@@ -51,33 +84,58 @@ type Manager struct {
 	// from the service watcher
 	countServiceWatchEvent *prometheus.CounterVec
 
+	// This is a prometheus counter of the number of times that a service has been retried, as it failed
+	countServiceRetries prometheus.Counter
+
+	// This is a prometheus counter of the number of times that the election of a service has been restarted, as it stopped
+	countServiceRestarts *prometheus.CounterVec
+
+	// This is a prometheus counter of the addresses, routes and BGP paths that have been repaired, by kind
+	countDriftRepairs *prometheus.CounterVec
+
+	// This is a prometheus counter of the modifications of services that were skipped, as nothing that kube-vip acts upon changed
+	countServiceSkippedEvents prometheus.Counter
+
+	// This is a prometheus gauge of the total, ready and local endpoints of the services whose endpoints are watched
+	serviceEndpointsGauge *prometheus.GaugeVec
+
+	// failedServices are the services that have failed in the background, they are restarted when retried
+	failedServices sync.Map
+
 	// This is a prometheus gauge indicating the state of the sessions.
 	// 1 means "ESTABLISHED", 0 means "NOT ESTABLISHED"
 	bgpSessionInfoGauge *prometheus.GaugeVec
 
+	// This is a prometheus counter of the number of times an established session has gone down
+	bgpSessionFlapCounter *prometheus.CounterVec
+
+	// This is a prometheus counter of the number of addresses that weren't advertised because the
+	// maximum number of BGP prefixes were already advertised
+	bgpMaxPrefixesCounter prometheus.Counter
+
+	// eventRecorder records Kubernetes events against services
+	eventRecorder record.EventRecorder
+
 	// This mutex is to protect calls from various goroutines
 	mutex sync.Mutex
-}
 
-// New will create a new managing object
-func New(configMap string, config *kubevip.Config) (*Manager, error) {
+	// poolElections are the leader elections of the service pools, when services are elected per pool
+	poolElections map[string]*poolElection
+	poolMutex     sync.Mutex
 
-	// Instance identity should be the same as k8s node name to ensure better compatibility.
-	// By default k8s sets node name to `hostname -s`,
-	// so if node name is not provided in the config,
-	// we set it to hostname as a fallback.
-	// This mimics legacy behavior and should work on old kube-vip installations.
-	if config.NodeName == "" {
-		log.Warning("Node name is missing from the config, fall back to hostname")
-		hostname, err := os.Hostname()
-		if err != nil {
-			return nil, fmt.Errorf("could not get hostname: %v", err)
-		}
-		config.NodeName = hostname
-	}
-	log.Infof("Using node name [%v]", config.NodeName)
+	// spreadElections are the services elections that this node leads, they are released to spread the
+	// services across the nodes or to fail them over
+	spreadElections map[string]*spreadElection
+	spreadMutex     sync.Mutex
+
+	// elections are the running services elections, so that their leases can be released on shutdown
+	elections sync.WaitGroup
+}
 
-	var clientset *kubernetes.Clientset
+// New will create a new managing object, with a Kubernetes client from the admin or user kubeconfig, or from
+// the in-cluster configuration
+func New(configMap string, config *kubevip.Config) (*Manager, error) {
+	var clientset kubernetes.Interface
 	var err error
 
 	adminConfigPath := "/etc/kubernetes/admin.conf"
@@ -121,6 +179,27 @@ func New(configMap string, config *kubevip.Config) (*Manager, error) {
 		log.Debug("Using external Kubernetes configuration from incluster config.")
 	}
 
+	return NewWithClientset(configMap, config, clientset)
+}
+
+// NewWithClientset will create a new managing object that uses the Kubernetes client, so that the manager can be
+// embedded by a program that already has one. The client can be nil for the etcd leader election.
+func NewWithClientset(configMap string, config *kubevip.Config, clientset kubernetes.Interface) (*Manager, error) {
+	// Instance identity should be the same as k8s node name to ensure better compatibility.
+	// By default k8s sets node name to `hostname -s`,
+	// so if node name is not provided in the config,
+	// we set it to hostname as a fallback.
+	// This mimics legacy behavior and should work on old kube-vip installations.
+	if config.NodeName == "" {
+		log.Warning("Node name is missing from the config, fall back to hostname")
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("could not get hostname: %v", err)
+		}
+		config.NodeName = hostname
+	}
+	log.Infof("Using node name [%v]", config.NodeName)
+
 	// Flip this to something else
 	// if config.DetectControlPlane {
 	// 	log.Info("[k8s client] flipping to internal service account")
@@ -138,40 +217,142 @@ func New(configMap string, config *kubevip.Config) (*Manager, error) {
 	// 	}
 	// }
 
+	// Events can only be recorded when we have a Kubernetes client
+	var eventRecorder record.EventRecorder
+	if clientset != nil {
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+		eventRecorder = broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "kube-vip", Host: config.NodeName})
+	}
+
 	return &Manager{
-		clientSet: clientset,
-		configMap: configMap,
-		config:    config,
+		// The gratuitous ARPs and neighbor advertisements are dampened when many addresses are announced at once, and
+		// withdrawn routes can be replaced by a blackhole whilst the upstream routers converge
+		host:          vip.NewHost(config.AnnouncementRateLimit, time.Duration(config.RoutingBlackholeGracePeriod)*time.Second),
+		clientSet:     clientset,
+		configMap:     configMap,
+		config:        config,
+		eventRecorder: eventRecorder,
+		services:      newServiceTracker(),
 		countServiceWatchEvent: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: "kube_vip",
 			Subsystem: "manager",
 			Name:      "all_services_events",
 			Help:      "Count all events fired by the service watcher categorised by event type",
 		}, []string{"type"}),
+		countServiceRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "kube_vip",
+			Subsystem: "manager",
+			Name:      "services_retries",
+			Help:      "Count the retries of the services that have failed to be processed",
+		}),
+		countServiceRestarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kube_vip",
+			Subsystem: "manager",
+			Name:      "services_election_restarts",
+			Help:      "Count the restarts (with a backoff) of the elections of the services that have stopped, by service",
+		}, []string{"service"}),
+		countDriftRepairs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kube_vip",
+			Subsystem: "manager",
+			Name:      "drift_repairs",
+			Help:      "Count the addresses, routes and BGP paths of the services that were removed out-of-band and have been repaired, by kind",
+		}, []string{"kind"}),
+		countServiceSkippedEvents: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "kube_vip",
+			Subsystem: "manager",
+			Name:      "services_skipped_events",
+			Help:      "Count the modifications of services that were skipped, as none of the fields that kube-vip acts upon changed",
+		}),
+		serviceEndpointsGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "kube_vip",
+			Subsystem: "manager",
+			Name:      "services_endpoints",
+			Help:      "The number of endpoints of the services whose endpoints are watched, by service and scope (total, ready or local)",
+		}, []string{"service", "scope"}),
 		bgpSessionInfoGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: "kube_vip",
 			Subsystem: "manager",
 			Name:      "bgp_session_info",
 			Help:      "Display state of session by setting metric for label value with current state to 1",
 		}, []string{"state", "peer"}),
+		bgpSessionFlapCounter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kube_vip",
+			Subsystem: "manager",
+			Name:      "bgp_session_flaps",
+			Help:      "Count the number of times an established session with a peer has gone down",
+		}, []string{"peer"}),
+		bgpMaxPrefixesCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "kube_vip",
+			Subsystem: "manager",
+			Name:      "bgp_max_prefixes_exceeded",
+			Help:      "Count the number of addresses that weren't advertised as the maximum number of BGP prefixes were advertised",
+		}),
 	}, nil
 }
 
-// Start will begin the Manager, which will start services and watch the configmap
+// Start will begin the Manager, which will start services and watch the configmap, until kube-vip is interrupted
+// or terminated
 func (sm *Manager) Start() error {
-	// listen for interrupts or the Linux SIGTERM signal and cancel
+	// listen for interrupts or the Linux SIGTERM signal (sent from Kubernetes) and cancel
 	// our context, which the leader election code will observe and
 	// step down
-	sm.signalChan = make(chan os.Signal, 1)
-	// Add Notification for Userland interrupt
-	signal.Notify(sm.signalChan, syscall.SIGINT)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return sm.Run(ctx)
+}
 
-	// Add Notification for SIGTERM (sent from Kubernetes)
-	signal.Notify(sm.signalChan, syscall.SIGTERM)
+// Run will begin the Manager, which will start services and watch the configmap, until the context is cancelled.
+// The addresses, routes and leases are then released before it returns.
+func (sm *Manager) Run(ctx context.Context) error {
+	sm.signalChan = make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			select {
+			case sm.signalChan <- syscall.SIGTERM:
+			case <-stopped:
+			}
+		case <-stopped:
+		}
+	}()
 
 	// All watchers and other goroutines should have an additional goroutine that blocks on this, to shut things down
 	sm.shutdownChan = make(chan struct{})
 
+	// The endpoints of the services are provided by the EndpointSlices, unless the cluster doesn't have them
+	if err := sm.selectEndpointsProvider(ctx); err != nil {
+		return err
+	}
+
+	// If a managed interface is used then the VIPs are added to it instead, it is removed once we stop
+	if sm.config.ManagedInterfaceType != "" {
+		managedInterface, err := sm.startManagedInterface()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := vip.DeleteManagedInterface(managedInterface); err != nil {
+				log.Errorf("unable to delete managed interface [%s]: %v", managedInterface, err)
+			}
+		}()
+	}
+
+	// The routes (and the addresses) of the VIPs are installed in the VRF
+	if sm.config.RoutingVRF != "" {
+		if err := sm.startVRF(); err != nil {
+			return err
+		}
+	}
+
+	// The ARP sysctls of the VIP interfaces are restored to their original values once we stop
+	if sm.config.ManageARPSysctls {
+		saved := sm.startARPSysctls()
+		defer saved.Restore()
+	}
+
 	// If BGP is enabled then we start a server instance that will broadcast VIPs
 	if sm.config.EnableBGP {
 
@@ -228,11 +409,92 @@ func (sm *Manager) parseAnnotations() error {
 	return nil
 }
 
+// startManagedInterface creates the managed interface and points the configuration at it
+func (sm *Manager) startManagedInterface() (string, error) {
+	name := sm.config.ManagedInterfaceName
+	if name == "" {
+		name = vip.DefaultManagedInterface
+	}
+
+	// Gratuitous ARPs can't be sent from a dummy interface
+	if sm.config.ManagedInterfaceType == vip.InterfaceDummy && sm.config.EnableARP {
+		return "", fmt.Errorf("a %s interface can't be used with ARP, use %s instead", vip.InterfaceDummy, vip.InterfaceMacvlan)
+	}
+
+	// The macvlan is created on top of the (first) configured interface
+	var parent string
+	if interfaces := vip.GetInterfaces(sm.config.Interface); len(interfaces) > 0 {
+		parent = interfaces[0]
+	}
+	if err := vip.EnsureManagedInterface(sm.config.ManagedInterfaceType, name, parent); err != nil {
+		return "", err
+	}
+
+	log.Infof("VIPs will be added to the %s interface [%s] instead of [%s]", sm.config.ManagedInterfaceType, name, sm.config.Interface)
+	sm.config.Interface = name
+	return name, nil
+}
+
+// startVRF points the routing table at the table of the VRF, and adds the managed interface to it
+func (sm *Manager) startVRF() error {
+	table, err := vip.VRFTable(sm.config.RoutingVRF)
+	if err != nil {
+		return err
+	}
+	log.Infof("routes will be installed in table [%d] of VRF [%s]", table, sm.config.RoutingVRF)
+	sm.config.RoutingTableID = table
+
+	for _, iface := range vip.GetInterfaces(sm.config.Interface) {
+		if sm.config.ManagedInterfaceType != "" {
+			if err = vip.EnsureVRFMember(iface, sm.config.RoutingVRF); err != nil {
+				return err
+			}
+			continue
+		}
+		if member, err := vip.InVRF(iface, sm.config.RoutingVRF); err != nil {
+			return err
+		} else if !member {
+			log.Warnf("interface [%s] isn't in VRF [%s], the addresses of the VIPs on it won't be in the VRF", iface, sm.config.RoutingVRF)
+		}
+	}
+	return nil
+}
+
+// arpSysctls are set on the VIP interfaces to avoid ARP flux, only the interface that has the address
+// answers (arp_ignore) and the VIPs aren't used as the source of its ARP requests (arp_announce)
+var arpSysctls = map[string]string{
+	"/proc/sys/net/ipv4/conf/%s/arp_ignore":   "1",
+	"/proc/sys/net/ipv4/conf/%s/arp_announce": "2",
+	"/proc/sys/net/ipv6/conf/%s/proxy_ndp":    "1",
+}
+
+// startARPSysctls sets the ARP sysctls on the VIP (and services) interfaces
+func (sm *Manager) startARPSysctls() sysctl.Saved {
+	saved := sysctl.Saved{}
+	interfaces := vip.GetInterfaces(sm.config.Interface)
+	if sm.config.ServicesInterface != "" {
+		interfaces = append(interfaces, vip.GetInterfaces(sm.config.ServicesInterface)...)
+	}
+	for _, iface := range interfaces {
+		for path, value := range arpSysctls {
+			// IPv6 may be disabled, which isn't fatal
+			if err := saved.SetAndSave(fmt.Sprintf(path, iface), value); err != nil {
+				log.Warnf("unable to set sysctl for interface [%s]: %v", iface, err)
+			}
+		}
+	}
+	return saved
+}
+
 func (sm *Manager) serviceInterface() string {
 	svcIf := sm.config.Interface
 	if sm.config.ServicesInterface != "" {
 		svcIf = sm.config.ServicesInterface
 	}
+	// Traffic is only mirrored from the first interface of a list
+	if interfaces := vip.GetInterfaces(svcIf); len(interfaces) > 0 {
+		svcIf = interfaces[0]
+	}
 	return svcIf
 }
 
@@ -15,7 +15,7 @@ import (
 
 	"github.com/kube-vip/kube-vip/pkg/cluster"
 	"github.com/kube-vip/kube-vip/pkg/iptables"
-	"github.com/kube-vip/kube-vip/pkg/vip"
+	"github.com/kube-vip/kube-vip/pkg/k8s"
 )
 
 // Start will begin the Manager, which will start services and watch the configmap
@@ -36,6 +36,15 @@ func (sm *Manager) startARP(id string) error {
 		if sm.config.EnableControlPlane {
 			cpCluster.Stop()
 		}
+		// The services are withdrawn before the services lease is released, so that the next leader
+		// doesn't announce them at the same time
+		if sm.config.EnableServices && !sm.config.EnableServicesElection {
+			for _, instance := range sm.serviceInstances {
+				for _, cluster := range instance.clusters {
+					cluster.Stop()
+				}
+			}
+		}
 		// Close all go routines
 		close(sm.shutdownChan)
 		// Cancel the context, which will in turn cancel the leadership
@@ -43,7 +52,7 @@ func (sm *Manager) startARP(id string) error {
 	}()
 
 	if sm.config.EnableControlPlane {
-		cpCluster, err = cluster.InitCluster(sm.config, false)
+		cpCluster, err = cluster.InitCluster(sm.config, sm.host, false)
 		if err != nil {
 			return err
 		}
@@ -96,13 +105,15 @@ func (sm *Manager) startARP(id string) error {
 		}
 	}
 
-	// This will tidy any dangling kube-vip iptables rules
+	// This will tidy any dangling kube-vip iptables rules, of both IP families
 	if os.Getenv("EGRESS_CLEAN") != "" {
-		i, err := vip.CreateIptablesClient(sm.config.EgressWithNftables, sm.config.ServiceNamespace, iptables.ProtocolIPv4)
-		if err != nil {
-			log.Warnf("(egress) Unable to clean any dangling egress rules [%v]", err)
-			log.Warn("(egress) Can be ignored in non iptables release of kube-vip")
-		} else {
+		for _, protocol := range []iptables.Protocol{iptables.ProtocolIPv4, iptables.ProtocolIPv6} {
+			i, err := sm.egressClient(sm.config.ServiceNamespace, protocol)
+			if err != nil {
+				log.Warnf("(egress) Unable to clean any dangling egress rules [%v]", err)
+				log.Warn("(egress) Can be ignored in non iptables release of kube-vip")
+				continue
+			}
 			log.Info("(egress) Cleaning any dangling kube-vip egress rules")
 			cleanErr := i.CleanIPtables()
 			if cleanErr != nil {
@@ -120,6 +131,21 @@ func (sm *Manager) startARP(id string) error {
 			return err
 		}
 	} else {
+		// An excluded node relinquishes leadership (which restarts kube-vip) and only rejoins once it is included
+		if err := k8s.WaitForNodeIncluded(ctx, sm.clientSet, sm.config.NodeName, sm.config.ElectionExcludeSelector, sm.config.ElectionExcludeTaints); err != nil {
+			return nil
+		}
+		electionCtx, cancelIncluded := k8s.NodeIncludedContext(ctx, sm.clientSet, sm.config.NodeName, sm.config.ElectionExcludeSelector, sm.config.ElectionExcludeTaints)
+		defer cancelIncluded()
+		// Likewise an unhealthy node only rejoins once healthy
+		if sm.config.MonitorNodeHealth {
+			if err := k8s.WaitForNodeHealthy(ctx, sm.clientSet, id); err != nil {
+				return nil
+			}
+			var cancelHealth context.CancelFunc
+			electionCtx, cancelHealth = k8s.NodeHealthContext(electionCtx, sm.clientSet, id)
+			defer cancelHealth()
+		}
 
 		log.Infof("beginning services leadership, namespace [%s], lock name [%s], id [%s]", ns, sm.config.ServicesLeaseName, id)
 		// we use the Lease lock type since edits to Leases are less common
@@ -135,8 +161,10 @@ func (sm *Manager) startARP(id string) error {
 			},
 		}
 
+		// Losing the leadership (or being unable to watch the services) stops the manager, which returns the error
+		leadership := make(chan error, 1)
 		// start the leader election code loop
-		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		leaderelection.RunOrDie(electionCtx, leaderelection.LeaderElectionConfig{
 			Lock: lock,
 			// IMPORTANT: you MUST ensure that any code you have that
 			// is protected by the lease must terminate **before**
@@ -150,9 +178,13 @@ func (sm *Manager) startARP(id string) error {
 			RetryPeriod:     time.Duration(sm.config.RetryPeriod) * time.Second,
 			Callbacks: leaderelection.LeaderCallbacks{
 				OnStartedLeading: func(ctx context.Context) {
-					err = sm.servicesWatcher(ctx, sm.syncServices)
-					if err != nil {
-						log.Fatal(err)
+					sm.annotateLease(ctx, ns, sm.config.ServicesLeaseName, sm.serviceInterface())
+					if err := sm.servicesWatcher(ctx, sm.syncServices); err != nil && ctx.Err() == nil {
+						select {
+						case leadership <- err:
+						default:
+						}
+						cancel()
 					}
 				},
 				OnStoppedLeading: func() {
@@ -164,7 +196,14 @@ func (sm *Manager) startARP(id string) error {
 						}
 					}
 
-					log.Fatal("lost leadership, restarting kube-vip")
+					// The manager stops, rather than rejoining the election, unless it is already shutting down
+					if ctx.Err() == nil {
+						select {
+						case leadership <- cluster.ErrLeadershipLost:
+						default:
+						}
+						cancel()
+					}
 				},
 				OnNewLeader: func(identity string) {
 					// we're notified when new leader elected
@@ -179,6 +218,11 @@ func (sm *Manager) startARP(id string) error {
 				},
 			},
 		})
+		select {
+		case err := <-leadership:
+			return err
+		default:
+		}
 	}
 	return nil
 }
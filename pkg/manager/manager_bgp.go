@@ -3,7 +3,9 @@ package manager
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
+	"strings"
 	"syscall"
 
 	"github.com/kube-vip/kube-vip/pkg/bgp"
@@ -13,6 +15,8 @@ import (
 	"github.com/packethost/packngo"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Start will begin the Manager, which will start services and watch the configmap
@@ -50,12 +54,76 @@ func (sm *Manager) startBGP() error {
 		}
 	}
 
+	// The peers of FRR are configured in FRR, so kube-vip only advertises the VIPs
+	if sm.config.BGPConfig.Backend == bgp.BackendFRR {
+		if sm.config.BGPPeersFromNode || sm.config.BGPPeersConfigMap != "" || sm.config.BGPDrainOnCordon {
+			return fmt.Errorf("BGP peer discovery and draining aren't supported with the [%s] backend", bgp.BackendFRR)
+		}
+	}
+
+	// If the peers are configured per node, then find them from the node annotations
+	if sm.config.BGPPeersFromNode {
+		node, err := sm.clientSet.CoreV1().Nodes().Get(context.Background(), sm.config.NodeName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get node [%s] for BGP peers: %w", sm.config.NodeName, err)
+		}
+		peers, err := sm.parseNodePeers(node)
+		if err != nil {
+			return err
+		}
+		sm.config.BGPConfig.Peers = peers
+	}
+
+	// If the peers are configured in a ConfigMap, then find them from the ConfigMap
+	if sm.config.BGPPeersConfigMap != "" {
+		if sm.config.BGPPeersFromNode {
+			return fmt.Errorf("BGP peers can't be discovered from both the node and configmap [%s]", sm.config.BGPPeersConfigMap)
+		}
+		cm, err := sm.clientSet.CoreV1().ConfigMaps(sm.config.Namespace).Get(context.Background(), sm.config.BGPPeersConfigMap, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get configmap [%s/%s] for BGP peers: %w", sm.config.Namespace, sm.config.BGPPeersConfigMap, err)
+		}
+		peers, err := sm.parseConfigMapPeers(cm)
+		if err != nil {
+			return err
+		}
+		sm.config.BGPConfig.Peers = peers
+	}
+
+	// The AS is derived from the node address when it is assigned automatically and there is no routerID
+	if sm.config.BGPConfig.AutoAS && sm.config.BGPConfig.RouterID == "" {
+		node, err := sm.clientSet.CoreV1().Nodes().Get(context.Background(), sm.config.NodeName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get node [%s] to assign the BGP AS: %w", sm.config.NodeName, err)
+		}
+		// The routerID is also set from this address, so it needs to be IPv4
+		for _, address := range node.Status.Addresses {
+			if ip := net.ParseIP(address.Address); address.Type == v1.NodeInternalIP && ip != nil && ip.To4() != nil {
+				sm.config.BGPConfig.RouterID = address.Address
+				break
+			}
+		}
+	}
+
+	// Any peer passwords that are stored in Secrets need to be found before the peers are added
+	if err = sm.resolveBGPPasswords(context.Background(), sm.config.BGPConfig.Peers); err != nil {
+		return err
+	}
+
 	log.Info("Starting the BGP server to advertise VIP routes to BGP peers")
-	sm.bgpServer, err = bgp.NewBGPServer(&sm.config.BGPConfig, func(p *api.WatchEventResponse_PeerEvent) {
+	// established tracks the peers with an established session, so that flaps can be counted
+	established := map[string]bool{}
+	sm.bgpServer, err = bgp.NewBackend(&sm.config.BGPConfig, func(p *api.WatchEventResponse_PeerEvent) {
 		ipaddr := p.GetPeer().GetState().GetNeighborAddress()
 		port := uint64(179)
 		peerDescription := fmt.Sprintf("%s:%d", ipaddr, port)
 
+		state := p.GetPeer().GetState().GetSessionState()
+		if established[ipaddr] && state != api.PeerState_ESTABLISHED {
+			sm.bgpSessionFlapCounter.With(prometheus.Labels{"peer": peerDescription}).Inc()
+		}
+		established[ipaddr] = state == api.PeerState_ESTABLISHED
+
 		for stateName, stateValue := range api.PeerState_SessionState_value {
 			metricValue := 0.0
 			if stateValue == int32(p.GetPeer().GetState().GetSessionState().Number()) {
@@ -71,12 +139,52 @@ func (sm *Manager) startBGP() error {
 	if err != nil {
 		return err
 	}
+	if server, err := sm.gobgpServer(); err == nil {
+		server.SetMaxPrefixesHandler(sm.bgpMaxPrefixesExceeded)
+	}
 
 	// use a Go context so we can tell the leaderelection code when we
 	// want to step down
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Watch the Secrets holding peer passwords so that they can be rotated
+	if err = sm.syncBGPSecretWatchers(ctx, sm.config.BGPConfig.Peers); err != nil {
+		return err
+	}
+
+	// Watch this node for changes to its BGP peers
+	if sm.config.BGPPeersFromNode {
+		go func() {
+			if err := sm.nodePeersWatcher(ctx); err != nil {
+				log.Errorf("[BGP] node peers watcher error: %v", err)
+			}
+		}()
+	}
+
+	// Watch this node so that advertisements are withdrawn whilst it is draining
+	if sm.config.BGPDrainOnCordon {
+		go func() {
+			if err := sm.nodeDrainWatcher(ctx); err != nil {
+				log.Errorf("[BGP] node drain watcher error: %v", err)
+			}
+		}()
+	}
+
+	// Publish the health of the BGP sessions as a node condition
+	if sm.config.BGPNodeCondition {
+		go sm.bgpStatusReporter(ctx)
+	}
+
+	// Watch the peers ConfigMap so that peers can be changed without a restart
+	if sm.config.BGPPeersConfigMap != "" {
+		go func() {
+			if err := sm.configMapPeersWatcher(ctx); err != nil {
+				log.Errorf("[BGP] configmap peers watcher error: %v", err)
+			}
+		}()
+	}
+
 	// Defer a function to check if the bgpServer has been created and if so attempt to close it
 	defer func() {
 		if sm.bgpServer != nil {
@@ -98,7 +206,7 @@ func (sm *Manager) startBGP() error {
 	}()
 
 	if sm.config.EnableControlPlane {
-		cpCluster, err = cluster.InitCluster(sm.config, false)
+		cpCluster, err = cluster.InitCluster(sm.config, sm.host, false)
 		if err != nil {
 			return err
 		}
@@ -139,3 +247,36 @@ func (sm *Manager) startBGP() error {
 
 	return nil
 }
+
+// bgpMaxPrefixesExceeded will count an address that wasn't advertised because the maximum number of
+// prefixes were already advertised, and record an event against the service that the address belongs to
+func (sm *Manager) bgpMaxPrefixesExceeded(address string) {
+	sm.bgpMaxPrefixesCounter.Inc()
+
+	// This is called whilst the address is being added, so the service is found in the background as
+	// the caller may hold the manager mutex
+	go func() {
+		sm.mutex.Lock()
+		defer sm.mutex.Unlock()
+
+		ip, _, _ := strings.Cut(address, "/")
+		for _, instance := range sm.serviceInstances {
+			for _, vip := range instance.VIPs {
+				if vip == ip && sm.eventRecorder != nil && instance.serviceSnapshot != nil {
+					sm.eventRecorder.Eventf(instance.serviceSnapshot, v1.EventTypeWarning, "BGPMaxPrefixesExceeded",
+						"Address [%s] isn't advertised over BGP as the maximum of [%d] prefixes are advertised", ip, sm.config.BGPConfig.MaxPrefixes)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// gobgpServer returns the embedded gobgp server, the peers can only be managed with this backend
+func (sm *Manager) gobgpServer() (*bgp.Server, error) {
+	server, ok := sm.bgpServer.(*bgp.Server)
+	if !ok {
+		return nil, fmt.Errorf("the BGP peers can't be managed with the [%s] backend", sm.config.BGPConfig.Backend)
+	}
+	return server, nil
+}
@@ -3,11 +3,16 @@ package manager
 import (
 	"context"
 	"fmt"
+	"net"
+	"slices"
 	"time"
 
+	"github.com/kube-vip/kube-vip/pkg/cluster"
 	"github.com/kube-vip/kube-vip/pkg/vip"
 	log "github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
@@ -22,7 +27,17 @@ func (sm *Manager) startTableMode(id string) error {
 	// want to step down
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	log.Infof("all routing table entries will exist in table [%d] with protocol [%d]", sm.config.RoutingTableID, sm.config.RoutingProtocol)
+
+	// The routing protocol marks the routes that are owned (and garbage collected) by kube-vip
+	if err = vip.ValidateRoutingProtocol(sm.config.RoutingProtocol); err != nil {
+		return err
+	}
+	log.Infof("routing table entries will exist in table [%d] (unless set by the %s annotation) with protocol [%d]", sm.config.RoutingTableID, routeTable, sm.config.RoutingProtocol)
+
+	// Remove the routes that were left behind by a previous run, for services that have since been deleted
+	if err = sm.reconcileRoutes(ctx); err != nil {
+		log.Errorf("error reconciling routes: %v", err)
+	}
 
 	if sm.config.CleanRoutingTable {
 		go func() {
@@ -72,6 +87,8 @@ func (sm *Manager) startTableMode(id string) error {
 				Identity: id,
 			},
 		}
+		// Losing the leadership (or being unable to watch the services) stops the manager, which returns the error
+		leadership := make(chan error, 1)
 		// start the leader election code loop
 		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
 			Lock: lock,
@@ -87,9 +104,13 @@ func (sm *Manager) startTableMode(id string) error {
 			RetryPeriod:     time.Duration(sm.config.RetryPeriod) * time.Second,
 			Callbacks: leaderelection.LeaderCallbacks{
 				OnStartedLeading: func(ctx context.Context) {
-					err = sm.servicesWatcher(ctx, sm.syncServices)
-					if err != nil {
-						log.Fatal(err)
+					sm.annotateLease(ctx, ns, plunderLock, sm.serviceInterface())
+					if err := sm.servicesWatcher(ctx, sm.syncServices); err != nil && ctx.Err() == nil {
+						select {
+						case leadership <- err:
+						default:
+						}
+						cancel()
 					}
 				},
 				OnStoppedLeading: func() {
@@ -101,7 +122,14 @@ func (sm *Manager) startTableMode(id string) error {
 						}
 					}
 
-					log.Fatal("lost leadership, restarting kube-vip")
+					// The manager stops, rather than rejoining the election, unless it is already shutting down
+					if ctx.Err() == nil {
+						select {
+						case leadership <- cluster.ErrLeadershipLost:
+						default:
+						}
+						cancel()
+					}
 				},
 				OnNewLeader: func(identity string) {
 					// we're notified when new leader elected
@@ -113,6 +141,11 @@ func (sm *Manager) startTableMode(id string) error {
 				},
 			},
 		})
+		select {
+		case err := <-leadership:
+			return err
+		default:
+		}
 	} else {
 		log.Infof("beginning watching services without leader election")
 		err = sm.servicesWatcher(ctx, sm.syncServices)
@@ -123,11 +156,89 @@ func (sm *Manager) startTableMode(id string) error {
 	return nil
 }
 
-func (sm *Manager) cleanRoutes() error {
-	routes, err := vip.ListRoutes(sm.config.RoutingTableID, sm.config.RoutingProtocol)
+// reconcileRoutes deletes the routes (with the routing protocol of kube-vip), in any table, whose address doesn't belong
+// to a LoadBalancer service, so that a crashed pod doesn't leave behind routes that blackhole traffic. Any blackhole
+// routes that were left behind are also deleted. The services in all of the namespaces are listed, as they may be
+// split between multiple deployments of kube-vip, and the routes of the services of the other namespaces are left to
+// their deployment. Deployments on the same host that have their own control plane VIP should use their own routing
+// protocol.
+func (sm *Manager) reconcileRoutes(ctx context.Context) error {
+	services, err := sm.clientSet.CoreV1().Services(v1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing services: %w", err)
+	}
+
+	// The addresses of this deployment, including the control plane VIP, and of the other deployments
+	owned, others := make(map[string]bool), make(map[string]bool)
+	keep := func(addresses map[string]bool, address string) {
+		if ip := net.ParseIP(address); ip != nil {
+			addresses[ip.String()] = true
+		}
+	}
+	for _, address := range vip.GetIPs(sm.config.VIP) {
+		keep(owned, address)
+	}
+	namespaces := sm.config.ServiceNamespaces()
+	for i := range services.Items {
+		service := &services.Items[i]
+		if service.Spec.Type != v1.ServiceTypeLoadBalancer {
+			continue
+		}
+		addresses := owned
+		if !slices.Contains(namespaces, v1.NamespaceAll) && !slices.Contains(namespaces, service.Namespace) {
+			addresses = others
+		}
+		for _, address := range allServiceAddresses(service) {
+			keep(addresses, address)
+		}
+	}
+
+	routes, err := vip.ListRoutes(unix.RT_TABLE_UNSPEC, sm.config.RoutingProtocol)
 	if err != nil {
 		return fmt.Errorf("error getting routes: %w", err)
 	}
+	for _, route := range staleRoutes(routes, owned, others) {
+		if err = netlink.RouteDel(&route); err != nil {
+			log.Errorf("[route] error deleting stale route: %v", route)
+			continue
+		}
+		log.Infof("[route] deleted stale route for [%s] from table [%d]", route.Dst, route.Table)
+	}
+	return nil
+}
+
+// staleRoutes returns the routes whose address isn't owned by this deployment, nor by another deployment, along with
+// the blackholes of this deployment which have outlived their grace period
+func staleRoutes(routes []netlink.Route, owned, others map[string]bool) []netlink.Route {
+	var stale []netlink.Route
+	for i := range routes {
+		if routes[i].Dst == nil || others[routes[i].Dst.IP.String()] {
+			continue
+		}
+		if routes[i].Type != unix.RTN_BLACKHOLE && owned[routes[i].Dst.IP.String()] {
+			continue
+		}
+		stale = append(stale, routes[i])
+	}
+	return stale
+}
+
+func (sm *Manager) cleanRoutes() error {
+	// Services can install their routes in their own table, so each of those tables is also checked
+	tables := map[int]bool{sm.config.RoutingTableID: true}
+	for _, instance := range sm.serviceInstances {
+		for _, vipConfig := range instance.vipConfigs {
+			tables[vipConfig.RoutingTableID] = true
+		}
+	}
+	var routes []netlink.Route
+	for table := range tables {
+		tableRoutes, err := vip.ListRoutes(table, sm.config.RoutingProtocol)
+		if err != nil {
+			return fmt.Errorf("error getting routes: %w", err)
+		}
+		routes = append(routes, tableRoutes...)
+	}
 
 	for i := range routes {
 		found := false
@@ -135,14 +246,14 @@ func (sm *Manager) cleanRoutes() error {
 			for _, cluster := range instance.clusters {
 				for n := range cluster.Network {
 					r := cluster.Network[n].PrepareRoute()
-					if r.Dst.String() == routes[i].Dst.String() {
+					if r.Dst.String() == routes[i].Dst.String() && r.Table == routes[i].Table {
 						found = true
 					}
 				}
 			}
 		}
 		if !found {
-			err = netlink.RouteDel(&(routes[i]))
+			err := netlink.RouteDel(&(routes[i]))
 			if err != nil {
 				log.Errorf("[route] error deleting route: %v", routes[i])
 			}
@@ -158,7 +269,7 @@ func (sm *Manager) countRouteReferences(route *netlink.Route) int {
 		for _, cluster := range instance.clusters {
 			for n := range cluster.Network {
 				r := cluster.Network[n].PrepareRoute()
-				if r.Dst.String() == route.Dst.String() {
+				if r.Dst.String() == route.Dst.String() && r.Table == route.Table {
 					cnt++
 				}
 			}
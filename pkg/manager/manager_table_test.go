@@ -0,0 +1,34 @@
+package manager
+
+import (
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+func TestStaleRoutes(t *testing.T) {
+	route := func(address string, table, routeType int) netlink.Route {
+		return netlink.Route{Dst: &net.IPNet{IP: net.ParseIP(address), Mask: net.CIDRMask(32, 32)}, Table: table, Type: routeType}
+	}
+	routes := []netlink.Route{
+		route("192.0.2.1", 254, unix.RTN_UNICAST),
+		route("192.0.2.2", 100, unix.RTN_UNICAST),
+		route("192.0.2.3", 254, unix.RTN_BLACKHOLE),
+		route("192.0.2.4", 200, unix.RTN_UNICAST),
+		route("192.0.2.5", 254, unix.RTN_BLACKHOLE),
+	}
+	owned := map[string]bool{"192.0.2.1": true, "192.0.2.3": true}
+	others := map[string]bool{"192.0.2.4": true, "192.0.2.5": true}
+
+	stale := staleRoutes(routes, owned, others)
+	var got []string
+	for _, route := range stale {
+		got = append(got, route.Dst.IP.String())
+	}
+	// The route of a deleted service in its own table, and the blackhole of this deployment
+	if len(got) != 2 || got[0] != "192.0.2.2" || got[1] != "192.0.2.3" {
+		t.Errorf("staleRoutes() = %v, want [192.0.2.2 192.0.2.3]", got)
+	}
+}
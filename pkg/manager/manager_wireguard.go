@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/kamhlos/upnp"
+	"github.com/kube-vip/kube-vip/pkg/cluster"
 	"github.com/kube-vip/kube-vip/pkg/wireguard"
 	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -93,6 +94,8 @@ func (sm *Manager) startWireguard(id string) error {
 			},
 		}
 
+		// Losing the leadership (or being unable to watch the services) stops the manager, which returns the error
+		leadership := make(chan error, 1)
 		// start the leader election code loop
 		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
 			Lock: lock,
@@ -108,9 +111,13 @@ func (sm *Manager) startWireguard(id string) error {
 			RetryPeriod:     time.Duration(sm.config.RetryPeriod) * time.Second,
 			Callbacks: leaderelection.LeaderCallbacks{
 				OnStartedLeading: func(ctx context.Context) {
-					err = sm.servicesWatcher(ctx, sm.syncServices)
-					if err != nil {
-						log.Fatal(err)
+					sm.annotateLease(ctx, ns, plunderLock, sm.serviceInterface())
+					if err := sm.servicesWatcher(ctx, sm.syncServices); err != nil && ctx.Err() == nil {
+						select {
+						case leadership <- err:
+						default:
+						}
+						cancel()
 					}
 				},
 				OnStoppedLeading: func() {
@@ -122,7 +129,14 @@ func (sm *Manager) startWireguard(id string) error {
 						}
 					}
 
-					log.Fatal("lost leadership, restarting kube-vip")
+					// The manager stops, rather than rejoining the election, unless it is already shutting down
+					if ctx.Err() == nil {
+						select {
+						case leadership <- cluster.ErrLeadershipLost:
+						default:
+						}
+						cancel()
+					}
 				},
 				OnNewLeader: func(identity string) {
 					// we're notified when new leader elected
@@ -134,6 +148,11 @@ func (sm *Manager) startWireguard(id string) error {
 				},
 			},
 		})
+		select {
+		case err := <-leadership:
+			return err
+		default:
+		}
 	}
 	return nil
 }
@@ -24,7 +24,7 @@ type patchStringLabel struct {
 
 // applyNodeLabel add/remove node label `kube-vip.io/has-ip=<VIP-Address>` to/from
 // the node where the virtual IP was added to/removed from.
-func applyNodeLabel(clientSet *kubernetes.Clientset, address, id, identity string) {
+func applyNodeLabel(clientSet kubernetes.Interface, address, id, identity string) {
 	ctx := context.Background()
 	node, err := clientSet.CoreV1().Nodes().Get(ctx, id, metav1.GetOptions{})
 	if err != nil {
@@ -50,7 +50,7 @@ func applyNodeLabel(clientSet *kubernetes.Clientset, address, id, identity strin
 }
 
 // applyPatchLabels add/remove node labels
-func applyPatchLabels(ctx context.Context, clientSet *kubernetes.Clientset,
+func applyPatchLabels(ctx context.Context, clientSet kubernetes.Interface,
 	name, operation, path, value string) {
 	patchLabels := []patchStringLabel{{
 		Op:    operation,
@@ -1,8 +1,48 @@
 package manager
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kube-vip/kube-vip/pkg/vip"
+)
 
 // PrometheusCollector defines a service watch event counter.
 func (sm *Manager) PrometheusCollector() []prometheus.Collector {
-	return []prometheus.Collector{sm.countServiceWatchEvent, sm.bgpSessionInfoGauge}
+	collectors := []prometheus.Collector{sm.countServiceWatchEvent, sm.countServiceRetries, sm.countServiceRestarts, sm.countDriftRepairs, sm.countServiceSkippedEvents, sm.serviceEndpointsGauge, sm.bgpSessionInfoGauge, sm.bgpSessionFlapCounter, sm.bgpMaxPrefixesCounter, &bgpPrefixesCollector{sm: sm}}
+	collectors = append(collectors, vip.RouteCollectors()...)
+	return append(collectors, vip.AnnouncementCollectors()...)
+}
+
+var bgpAdvertisedPrefixesDesc = prometheus.NewDesc(
+	"kube_vip_manager_bgp_advertised_prefixes",
+	"The number of prefixes that are advertised to a BGP peer",
+	[]string{"peer"}, nil,
+)
+
+// bgpPrefixesCollector reads the number of advertised prefixes from the BGP server when it is scraped
+type bgpPrefixesCollector struct {
+	sm *Manager
+}
+
+// Describe implements prometheus.Collector
+func (c *bgpPrefixesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bgpAdvertisedPrefixesDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *bgpPrefixesCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.sm.bgpServer == nil {
+		return
+	}
+	status, err := c.sm.bgpServer.PeerStatus()
+	if err != nil {
+		log.Errorf("[BGP] unable to get advertised prefixes: %v", err)
+		return
+	}
+	for _, peer := range status {
+		ch <- prometheus.MustNewConstMetric(bgpAdvertisedPrefixesDesc, prometheus.GaugeValue, float64(peer.AdvertisedPrefixes), fmt.Sprintf("%s:%d", peer.Address, 179))
+	}
 }
@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/kube-vip/kube-vip/pkg/vip"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// serviceLeasePrefix is the prefix of the lease of a services election
+const serviceLeasePrefix = "kubevip-"
+
+// reconciledServices returns the services whose leases are kept. With a label selector the services of the other
+// deployments aren't in the cache, so every service is listed to keep what the others have configured.
+func (sm *Manager) reconciledServices(ctx context.Context, lister corelisters.ServiceLister) ([]*v1.Service, error) {
+	if sm.config.ServicesLabelSelector == "" {
+		return lister.List(labels.Everything())
+	}
+	list, err := sm.listServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*v1.Service, 0, len(list))
+	for i := range list {
+		services = append(services, &list[i])
+	}
+	return services, nil
+}
+
+// listServices lists the services in each of the namespaces that are watched, from the API server
+func (sm *Manager) listServices(ctx context.Context) ([]v1.Service, error) {
+	var services []v1.Service
+	for _, namespace := range sm.config.ServiceNamespaces() {
+		list, err := sm.clientSet.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, list.Items...)
+	}
+	return services, nil
+}
+
+// reconcileServices removes what a previous run left behind for services that no longer exist, before the
+// services are processed: the addresses of the services and the leases of the services elections.
+// The routes of deleted services are removed when the routing table mode starts.
+func (sm *Manager) reconcileServices(ctx context.Context, services []*v1.Service) {
+	var loadBalancers []*v1.Service
+	for _, service := range services {
+		if service.Spec.Type == v1.ServiceTypeLoadBalancer {
+			loadBalancers = append(loadBalancers, service)
+		}
+	}
+	sm.reconcileAddresses(ctx)
+	if sm.config.EnableServicesElection {
+		sm.reconcileLeases(ctx, loadBalancers)
+	}
+}
+
+// reconcileAddresses deletes the addresses that kube-vip has added (and labelled) for services that no longer exist,
+// on any interface. Only the IPv4 addresses can be labelled, so the IPv6 addresses of deleted services aren't found.
+// The addresses of the control plane VIP, of the node and of every service are kept, the services in all of the
+// namespaces are listed as they may be split between multiple deployments of kube-vip.
+func (sm *Manager) reconcileAddresses(ctx context.Context) {
+	services, err := sm.clientSet.CoreV1().Services(v1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("(svcs) unable to list the services, not reconciling the VIPs: %v", err)
+		return
+	}
+	node, err := sm.clientSet.CoreV1().Nodes().Get(ctx, sm.config.NodeName, metav1.GetOptions{})
+	if err != nil {
+		log.Warnf("(svcs) unable to find the addresses of node [%s], not reconciling the VIPs: %v", sm.config.NodeName, err)
+		return
+	}
+
+	keep := make(map[string]bool)
+	keepAddress := func(address string) {
+		if ip := net.ParseIP(address); ip != nil {
+			keep[ip.String()] = true
+		}
+	}
+	for _, address := range vip.GetIPs(sm.config.VIP) {
+		keepAddress(address)
+	}
+	for _, address := range node.Status.Addresses {
+		keepAddress(address.Address)
+	}
+	for i := range services.Items {
+		for _, address := range allServiceAddresses(&services.Items[i]) {
+			keepAddress(address)
+		}
+	}
+	// The services that are configured (e.g. with an address from DHCP) may not have been updated yet
+	sm.mutex.Lock()
+	for _, instance := range sm.serviceInstances {
+		for _, c := range instance.vipConfigs {
+			keepAddress(c.VIP)
+		}
+	}
+	sm.mutex.Unlock()
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		log.Warnf("(svcs) unable to list the interfaces, not reconciling the VIPs: %v", err)
+		return
+	}
+	for _, link := range links {
+		addresses, err := netlink.AddrList(link, netlink.FAMILY_V4)
+		if err != nil {
+			log.Warnf("(svcs) unable to list the addresses of interface [%s]: %v", link.Attrs().Name, err)
+			continue
+		}
+		for i := range addresses {
+			address := addresses[i]
+			if keep[address.IP.String()] || !vip.IsServiceAddress(link, address) {
+				continue
+			}
+			if err := netlink.AddrDel(link, &address); err != nil {
+				log.Errorf("(svcs) unable to delete stale address [%s] from [%s]: %v", address.IPNet, link.Attrs().Name, err)
+				continue
+			}
+			log.Infof("(svcs) deleted stale address [%s] from [%s], it doesn't belong to a service", address.IPNet, link.Attrs().Name)
+		}
+	}
+}
+
+// allServiceAddresses returns every address that a service may be announced with, whichever way it was allocated
+func allServiceAddresses(s *v1.Service) []string {
+	addresses := vip.GetIPs(s.Annotations[loadbalancerIPAnnotation])
+	for _, ingress := range s.Status.LoadBalancer.Ingress {
+		addresses = append(addresses, ingress.IP)
+	}
+	addresses = append(addresses, s.Spec.LoadBalancerIP)
+	return append(addresses, s.Spec.ExternalIPs...)
+}
+
+// reconcileLeases deletes the leases of the services elections that this node holds for services that no
+// longer exist, the leases of the pools and of the spreading of services aren't a single service's
+func (sm *Manager) reconcileLeases(ctx context.Context, services []*v1.Service) {
+	existing := make(map[string]bool)
+	for _, service := range services {
+		existing[service.Namespace+"/"+service.Name] = true
+	}
+	var leases []coordinationv1.Lease
+	for _, namespace := range sm.config.ServiceNamespaces() {
+		list, err := sm.clientSet.CoordinationV1().Leases(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Warnf("(svcs) unable to list the leases of the services elections: %v", err)
+			return
+		}
+		leases = append(leases, list.Items...)
+	}
+	for i := range leases {
+		lease := &leases[i]
+		name, found := strings.CutPrefix(lease.Name, serviceLeasePrefix)
+		if !found || strings.HasPrefix(lease.Name, spreadLeasePrefix) || strings.HasPrefix(lease.Name, poolLeaseName("")) {
+			continue
+		}
+		if existing[lease.Namespace+"/"+name] || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != sm.config.NodeName {
+			continue
+		}
+		if err := sm.clientSet.CoordinationV1().Leases(lease.Namespace).Delete(ctx, lease.Name, metav1.DeleteOptions{}); err != nil {
+			log.Warnf("(svcs) unable to delete the lease [%s/%s] of a deleted service: %v", lease.Namespace, lease.Name, err)
+			continue
+		}
+		log.Infof("(svcs) deleted the lease [%s/%s] of a deleted service", lease.Namespace, lease.Name)
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"strings"
 
 	"github.com/kube-vip/kube-vip/pkg/iptables"
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
 	"github.com/kube-vip/kube-vip/pkg/vip"
+	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -18,7 +21,12 @@ const (
 	defaultServiceCIDR = "10.96.0.0/12"
 )
 
-func (sm *Manager) iptablesCheck() error {
+// iptablesCheck checks that the iptables modules of the IP families of the addresses are loaded
+func (sm *Manager) iptablesCheck(addresses []string) error {
+	// The nftables backend doesn't use the iptables tables
+	if sm.config.EgressBackend == kubevip.EgressBackendNFTables {
+		return nil
+	}
 	file, err := os.Open("/proc/modules")
 	if err != nil {
 		return err
@@ -26,65 +34,120 @@ func (sm *Manager) iptablesCheck() error {
 	defer file.Close()
 	scanner := bufio.NewScanner(file)
 	scanner.Split(bufio.ScanLines)
-	var nat, filter, mangle bool
+	modules := make(map[string]bool)
 	for scanner.Scan() {
-		line := strings.Fields(scanner.Text())
-		switch line[0] {
-		case "iptable_filter":
-			filter = true
-		case "iptable_nat":
-			nat = true
-		case "iptable_mangle":
-			mangle = true
+		if line := strings.Fields(scanner.Text()); len(line) != 0 {
+			modules[line[0]] = true
 		}
 	}
 
-	if !filter || !nat || !mangle {
-		return fmt.Errorf("missing iptables modules -> nat [%t] -> filter [%t] mangle -> [%t]", nat, filter, mangle)
+	prefixes := make(map[string]bool)
+	for _, address := range addresses {
+		if vip.IsIPv6(address) {
+			prefixes["ip6table_"] = true
+		} else {
+			prefixes["iptable_"] = true
+		}
+	}
+	for prefix := range prefixes {
+		nat, filter, mangle := modules[prefix+"nat"], modules[prefix+"filter"], modules[prefix+"mangle"]
+		if !filter || !nat || !mangle {
+			return fmt.Errorf("missing %s modules -> nat [%t] -> filter [%t] mangle -> [%t]", strings.TrimSuffix(prefix, "_"), nat, filter, mangle)
+		}
 	}
 	return nil
 }
 
+// egressClient returns the client of the egress rules of the IP family, with the configured backend
+func (sm *Manager) egressClient(namespace string, protocol iptables.Protocol) (vip.EgressRules, error) {
+	switch sm.config.EgressBackend {
+	case "", kubevip.EgressBackendIPTables:
+		return vip.CreateIptablesClient(sm.config.EgressWithNftables, namespace, protocol)
+	case kubevip.EgressBackendNFTables:
+		return vip.CreateNftablesClient(namespace, protocol)
+	default:
+		return nil, fmt.Errorf("unknown egress backend [%s], expected iptables or nftables", sm.config.EgressBackend)
+	}
+}
+
+// getSameFamilyCidr returns the CIDR of the comma separated list that contains the IP, otherwise the first CIDR of
+// the same IP family
 func getSameFamilyCidr(source, ip string) string {
 	cidrs := strings.Split(source, ",")
+	address := net.ParseIP(ip)
 	for _, cidr := range cidrs {
-		source = cidr
-		if vip.IsIPv4(cidr) == vip.IsIPv4(ip) {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(address) {
+			return cidr
+		}
+	}
+	for _, cidr := range cidrs {
+		if vip.IsIPv4(cidrAddress(cidr)) == vip.IsIPv4(ip) {
 			return cidr
 		}
 	}
 	// return to the default behaviour of setting the CIDR to the first one (or only one)
-	return source
+	return cidrs[0]
 }
 
-func (sm *Manager) configureEgress(vipIP, podIP, destinationPorts, namespace string) error {
+// egressDestinations returns the destinations of the egress of the VIP that are of its IP family, and whether the
+// egress is restricted to destinations at all (when it is, none of its family means that nothing egresses with it)
+func egressDestinations(vipIP, destinationCIDRs, destinationPorts string) ([]vip.EgressDestination, bool, error) {
+	destinations, err := vip.ParseEgressDestinations(destinationCIDRs, destinationPorts)
+	if err != nil {
+		return nil, false, fmt.Errorf("error parsing the egress destinations of [%s]: %w", vipIP, err)
+	}
+	var family []vip.EgressDestination
+	for _, destination := range destinations {
+		if destination.Subnet == "" || vip.IsIPv4(cidrAddress(destination.Subnet)) == vip.IsIPv4(vipIP) {
+			family = append(family, destination)
+		}
+	}
+	return family, len(destinations) != 0, nil
+}
+
+// cidrAddress returns the address of a CIDR (or the address itself)
+func cidrAddress(cidr string) string {
+	address, _, _ := strings.Cut(cidr, "/")
+	return address
+}
+
+// configureEgress configures the egress of the pod with the VIP, when the pod replaces the active endpoint of the
+// service the rules that an earlier endpoint left behind are removed
+func (sm *Manager) configureEgress(vipIP, podIP, destinationCIDRs, destinationPorts, namespace string, replaceEndpoint bool) error {
 	// serviceCIDR, podCIDR, err := sm.AutoDiscoverCIDRs()
 	// if err != nil {
 	// 	serviceCIDR = "10.96.0.0/12"
 	// 	podCIDR = "10.0.0.0/16"
 	// }
 
+	// The pod can only egress with a VIP of its own IP family
+	if vip.IsIPv4(podIP) != vip.IsIPv4(vipIP) {
+		log.Debugf("[egress] skipping the egress of [%s] => [%s], as they aren't of the same IP family", podIP, vipIP)
+		return nil
+	}
+
 	var podCidr, serviceCidr string
 
+	// There are no default IPv6 pod and service CIDRs, the egress of an IPv6 VIP requires that they are configured
 	if sm.config.EgressPodCidr != "" {
 		podCidr = getSameFamilyCidr(sm.config.EgressPodCidr, podIP)
-	} else {
-		// There's no default IPv6 pod CIDR, therefore we silently back off if CIDR s not specified.
-		if !vip.IsIPv4(podIP) {
-			return nil
-		}
+	} else if vip.IsIPv4(podIP) {
 		podCidr = defaultPodCIDR
 	}
-
 	if sm.config.EgressServiceCidr != "" {
 		serviceCidr = getSameFamilyCidr(sm.config.EgressServiceCidr, vipIP)
-	} else {
-		// There's no default IPv6 service CIDR, therefore we silently back off if CIDR s not specified.
-		if !vip.IsIPv4(vipIP) {
-			return nil
-		}
+	} else if vip.IsIPv4(vipIP) {
 		serviceCidr = defaultServiceCIDR
 	}
+	if vip.IsIPv4(cidrAddress(podCidr)) != vip.IsIPv4(podIP) || vip.IsIPv4(cidrAddress(serviceCidr)) != vip.IsIPv4(vipIP) {
+		log.Warnf("[egress] unable to configure the egress of [%s] => [%s], the pod and service CIDRs of its IP family aren't configured (egress_podcidr and egress_servicecidr)", podIP, vipIP)
+		return nil
+	}
+
+	destinations, restricted, err := egressDestinations(vipIP, destinationCIDRs, destinationPorts)
+	if err != nil {
+		return err
+	}
 
 	protocol := iptables.ProtocolIPv4
 
@@ -92,7 +155,7 @@ func (sm *Manager) configureEgress(vipIP, podIP, destinationPorts, namespace str
 		protocol = iptables.ProtocolIPv6
 	}
 
-	i, err := vip.CreateIptablesClient(sm.config.EgressWithNftables, namespace, protocol)
+	i, err := sm.egressClient(namespace, protocol)
 	if err != nil {
 		return fmt.Errorf("error Creating iptables client [%s]", err)
 	}
@@ -117,9 +180,9 @@ func (sm *Manager) configureEgress(vipIP, podIP, destinationPorts, namespace str
 		return fmt.Errorf("error adding rules to mangle chain [%s], error [%s]", vip.MangleChainName, err)
 	}
 
-	mask := "/32"
-	if !vip.IsIPv4(podIP) {
-		mask = "/128"
+	mask, err := vip.GetFullMask(podIP)
+	if err != nil {
+		return err
 	}
 
 	err = i.AppendReturnRulesForMarking(vip.MangleChainName, podIP+mask)
@@ -132,38 +195,30 @@ func (sm *Manager) configureEgress(vipIP, podIP, destinationPorts, namespace str
 		return fmt.Errorf("error adding prerouting mangle chain [%s], error [%s]", vip.MangleChainName, err)
 	}
 
-	if destinationPorts != "" {
-
-		fixedPorts := strings.Split(destinationPorts, ",")
-
-		for _, fixedPort := range fixedPorts {
-			var proto, port string
-
-			data := strings.Split(fixedPort, ":")
-			if len(data) == 0 {
-				continue
-			} else if len(data) == 1 {
-				proto = "tcp"
-				port = data[0]
-			} else {
-				proto = data[0]
-				port = data[1]
-			}
-
-			err = i.InsertSourceNatForDestinationPort(vipIP, podIP, port, proto)
-			if err != nil {
-				return fmt.Errorf("error adding snat rules to nat chain [%s], error [%s]", vip.MangleChainName, err)
-			}
-
+	if replaceEndpoint {
+		err = i.DeleteSourceNatForOtherPods(vipIP, podIP)
+		if err != nil {
+			return fmt.Errorf("error removing stale snat rules from nat chain [%s], error [%s]", vip.MangleChainName, err)
 		}
-	} else {
+	}
+
+	if !restricted {
 		err = i.InsertSourceNat(vipIP, podIP)
 		if err != nil {
 			return fmt.Errorf("error adding snat rules to nat chain [%s], error [%s]", vip.MangleChainName, err)
 		}
+	} else if len(destinations) == 0 {
+		log.Warnf("[egress] none of the egress destinations of [%s] => [%s] are of its IP family", podIP, vipIP)
+		return nil
+	}
+	for _, destination := range destinations {
+		err = i.InsertSourceNatForDestination(vipIP, podIP, destination)
+		if err != nil {
+			return fmt.Errorf("error adding snat rules to nat chain [%s], error [%s]", vip.MangleChainName, err)
+		}
 	}
 	//_ = i.DumpChain(vip.MangleChainName)
-	err = vip.DeleteExistingSessions(podIP, false, destinationPorts, "")
+	err = vip.DeleteEgressSessions(podIP, destinations)
 	if err != nil {
 		return err
 	}
@@ -191,13 +246,18 @@ func (sm *Manager) AutoDiscoverCIDRs() (serviceCIDR, podCIDR string, err error)
 	return
 }
 
-func (sm *Manager) TeardownEgress(podIP, vipIP, destinationPorts, namespace string) error {
+func (sm *Manager) TeardownEgress(podIP, vipIP, destinationCIDRs, destinationPorts, namespace string) error {
+	destinations, restricted, err := egressDestinations(vipIP, destinationCIDRs, destinationPorts)
+	if err != nil {
+		return err
+	}
+
 	protocol := iptables.ProtocolIPv4
 	if vip.IsIPv6(podIP) {
 		protocol = iptables.ProtocolIPv6
 	}
 
-	i, err := vip.CreateIptablesClient(sm.config.EgressWithNftables, namespace, protocol)
+	i, err := sm.egressClient(namespace, protocol)
 	if err != nil {
 		return fmt.Errorf("error Creating iptables client [%s]", err)
 	}
@@ -209,36 +269,22 @@ func (sm *Manager) TeardownEgress(podIP, vipIP, destinationPorts, namespace stri
 	}
 
 	// Clear up SNAT rules
-	if destinationPorts != "" {
-		fixedPorts := strings.Split(destinationPorts, ",")
-
-		for _, fixedPort := range fixedPorts {
-			var proto, port string
-
-			data := strings.Split(fixedPort, ":")
-			if len(data) == 0 {
-				continue
-			} else if len(data) == 1 {
-				proto = "tcp"
-				port = data[0]
-			} else {
-				proto = data[0]
-				port = data[1]
-			}
-
-			err = i.DeleteSourceNatForDestinationPort(podIP, vipIP, port, proto)
-			if err != nil {
-				return fmt.Errorf("error changing iptables rules for egress [%s]", err)
-			}
-
-		}
-	} else {
+	if !restricted {
 		err = i.DeleteSourceNat(podIP, vipIP)
 		if err != nil {
 			return fmt.Errorf("error changing iptables rules for egress [%s]", err)
 		}
 	}
-	err = vip.DeleteExistingSessions(podIP, false, destinationPorts, "")
+	for _, destination := range destinations {
+		err = i.DeleteSourceNatForDestination(podIP, vipIP, destination)
+		if err != nil {
+			return fmt.Errorf("error changing iptables rules for egress [%s]", err)
+		}
+	}
+	if restricted && len(destinations) == 0 {
+		return nil
+	}
+	err = vip.DeleteEgressSessions(podIP, destinations)
 	if err != nil {
 		return fmt.Errorf("error changing iptables rules for egress [%s]", err)
 	}
@@ -36,6 +36,22 @@ func Test_getSameFamilyCidr(t *testing.T) {
 			},
 			want: "10.96.0.0/16",
 		},
+		{
+			name: "This returns the IPv4 cidr from dual-stack",
+			args: args{
+				source: "10.0.0.0/16,fd00:10::/56",
+				ip:     "172.16.0.1",
+			},
+			want: "10.0.0.0/16",
+		},
+		{
+			name: "This returns the IPv6 cidr from dual-stack",
+			args: args{
+				source: "10.0.0.0/16,fd00:10::/56",
+				ip:     "fd00:20::1",
+			},
+			want: "fd00:10::/56",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -15,55 +15,97 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
 
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
 	"github.com/kube-vip/kube-vip/pkg/vip"
 )
 
+// serviceStatusRefresh is how often the status of a service whose addresses are names is refreshed
+const serviceStatusRefresh = 10 * time.Second
+
+// The annotations of the services are kebab-case, as are the egress and conntrack ones. The camelCase ones
+// (requestedIP, vipHost, loadbalancerIPs, loadbalancerHostname and serviceInterface) predate that convention and
+// keep their names, as renaming them would break the services that already use them.
 const (
 	hwAddrKey                = "kube-vip.io/hwaddr"
 	requestedIP              = "kube-vip.io/requestedIP"
 	vipHost                  = "kube-vip.io/vipHost"
 	egress                   = "kube-vip.io/egress"
 	egressDestinationPorts   = "kube-vip.io/egress-destination-ports"
+	egressDestinationCIDRs   = "kube-vip.io/egress-destination-cidrs"
 	egressSourcePorts        = "kube-vip.io/egress-source-ports"
+	egressService            = "kube-vip.io/egress-service"
 	activeEndpoint           = "kube-vip.io/active-endpoint"
 	activeEndpointIPv6       = "kube-vip.io/active-endpoint-ipv6"
 	flushContrack            = "kube-vip.io/flush-conntrack"
 	loadbalancerIPAnnotation = "kube-vip.io/loadbalancerIPs"
 	loadbalancerHostname     = "kube-vip.io/loadbalancerHostname"
 	serviceInterface         = "kube-vip.io/serviceInterface"
+	interfaceOverride        = "kube-vip.io/interface"
+	bgpCommunities           = "kube-vip.io/bgp-communities"
+	bgpPrependCount          = "kube-vip.io/bgp-prepend-count"
+	bgpMED                   = "kube-vip.io/bgp-med"
+	bgpLocalPref             = "kube-vip.io/bgp-local-pref"
+	bgpMinReadyEndpoints     = "kube-vip.io/bgp-min-ready-endpoints"
+	minReadyEndpoints        = "kube-vip.io/min-ready-endpoints"
+	localFallback            = "kube-vip.io/local-fallback"
+	bgpPeers                 = "kube-vip.io/bgp-peers"
+	arpBurstCount            = "kube-vip.io/arp-burst-count"
+	arpBurstInterval         = "kube-vip.io/arp-burst-interval"
+	ndpAdvertisementCount    = "kube-vip.io/ndp-advertisement-count"
+	vipCIDR                  = "kube-vip.io/vip-cidr"
+	vlanID                   = "kube-vip.io/vlan"
+	routeTable               = "kube-vip.io/route-table"
+	routeVRF                 = "kube-vip.io/vrf"
+	leaseDuration            = "kube-vip.io/lease-duration"
+	leaseRenewDeadline       = "kube-vip.io/lease-renew-deadline"
+	leaseRetryPeriod         = "kube-vip.io/lease-retry-period"
+	sharingKey               = "kube-vip.io/sharing-key"
+	ipMode                   = "kube-vip.io/ip-mode"
+	pause                    = "kube-vip.io/pause"
 )
 
-func (sm *Manager) syncServices(_ context.Context, svc *v1.Service, wg *sync.WaitGroup) error {
+func (sm *Manager) syncServices(ctx context.Context, svc *v1.Service, wg *sync.WaitGroup) error {
 	defer wg.Done()
 
 	log.Debugf("[STARTING] Service Sync")
 
 	// Iterate through the synchronising services
 	foundInstance := false
-	newServiceAddresses := fetchServiceAddresses(svc)
+	var found *Instance
+	newServiceAddresses := fetchServiceAddresses(svc, sm.config)
 	newServiceUID := string(svc.UID)
 
 	ingressIPs := []string{}
 
 	for _, ingress := range svc.Status.LoadBalancer.Ingress {
 		ingressIPs = append(ingressIPs, ingress.IP)
+		// The addresses that are names are published as the hostname of their ingresses
+		if ingress.Hostname != "" {
+			ingressIPs = append(ingressIPs, ingress.Hostname)
+		}
 	}
 
 	shouldBreake := false
 
-	for x := range sm.serviceInstances {
+	// The services can be synchronised in parallel, so look through a copy of the instances
+	sm.mutex.Lock()
+	serviceInstances := append([]*Instance(nil), sm.serviceInstances...)
+	sm.mutex.Unlock()
+
+	for x := range serviceInstances {
 		if shouldBreake {
 			break
 		}
 		for _, newServiceAddress := range newServiceAddresses {
-			log.Debugf("isDHCP: %t, newServiceAddress: %s", sm.serviceInstances[x].isDHCP, newServiceAddress)
-			if sm.serviceInstances[x].UID == newServiceUID {
+			log.Debugf("isDHCP: %t, newServiceAddress: %s", serviceInstances[x].isDHCP, newServiceAddress)
+			if serviceInstances[x].UID == newServiceUID {
 				// If the found instance's DHCP configuration doesn't match the new service, delete it.
-				if (sm.serviceInstances[x].isDHCP && newServiceAddress != "0.0.0.0") ||
-					(!sm.serviceInstances[x].isDHCP && newServiceAddress == "0.0.0.0") ||
-					(!sm.serviceInstances[x].isDHCP && len(svc.Status.LoadBalancer.Ingress) > 0 && !slices.Contains(ingressIPs, newServiceAddress)) ||
+				if (serviceInstances[x].isDHCP && newServiceAddress != "0.0.0.0") ||
+					(!serviceInstances[x].isDHCP && newServiceAddress == "0.0.0.0") ||
+					(!serviceInstances[x].isDHCP && len(svc.Status.LoadBalancer.Ingress) > 0 && !slices.Contains(ingressIPs, newServiceAddress) &&
+						!slices.Contains(serviceInstances[x].unavailableVIPs, newServiceAddress)) ||
 					(len(svc.Status.LoadBalancer.Ingress) > 0 && !comparePortsAndPortStatuses(svc)) ||
-					(sm.serviceInstances[x].isDHCP && len(svc.Status.LoadBalancer.Ingress) > 0 && !slices.Contains(ingressIPs, sm.serviceInstances[x].dhcpInterfaceIP)) {
+					(serviceInstances[x].isDHCP && len(svc.Status.LoadBalancer.Ingress) > 0 && !slices.Contains(ingressIPs, serviceInstances[x].dhcpInterfaceIP)) {
 					if err := sm.deleteService(newServiceUID); err != nil {
 						return err
 					}
@@ -71,13 +113,35 @@ func (sm *Manager) syncServices(_ context.Context, svc *v1.Service, wg *sync.Wai
 					break
 				}
 				foundInstance = true
+				found = serviceInstances[x]
 			}
 		}
 	}
 
+	// The pods of the namespace no longer egress with a service whose egress annotation has been removed
+	if foundInstance && !shouldBreake && found.serviceSnapshot != nil &&
+		found.serviceSnapshot.Annotations[egress] == "true" && svc.Annotations[egress] != "true" {
+		sm.deleteNamespaceEgress(svc)
+	}
+
 	// This instance wasn't found, we need to add it to the manager
 	if !foundInstance && len(newServiceAddresses) > 0 {
-		if err := sm.addService(svc); err != nil {
+		if missing := missingFamilies(svc, newServiceAddresses); len(missing) != 0 {
+			if requiresDualStack(svc) {
+				if sm.eventRecorder != nil {
+					sm.eventRecorder.Eventf(svc, v1.EventTypeWarning, "DualStackAddressMissing", "The service requires dual-stack, but has no %s address", missing[0])
+				}
+				return fmt.Errorf("service [%s/%s] requires dual-stack, but has no %s address", svc.Namespace, svc.Name, missing[0])
+			}
+			log.Warnf("(svcs) service [%s/%s] prefers dual-stack, but only has addresses [%s]", svc.Namespace, svc.Name, strings.Join(newServiceAddresses, ","))
+		}
+		if conflict := sharingConflict(svc, newServiceAddresses, serviceInstances); conflict != "" {
+			if sm.eventRecorder != nil {
+				sm.eventRecorder.Eventf(svc, v1.EventTypeWarning, "SharedAddressConflict", "Unable to share the address: %s", conflict)
+			}
+			return fmt.Errorf("service [%s/%s] is unable to share its address: %s", svc.Namespace, svc.Name, conflict)
+		}
+		if err := sm.addService(ctx, svc); err != nil {
 			return err
 		}
 	}
@@ -98,10 +162,10 @@ func comparePortsAndPortStatuses(svc *v1.Service) bool {
 	return true
 }
 
-func (sm *Manager) addService(svc *v1.Service) error {
+func (sm *Manager) addService(ctx context.Context, svc *v1.Service) error {
 	startTime := time.Now()
 
-	newService, err := NewInstance(svc, sm.config)
+	newService, err := NewInstance(svc, sm.config, sm.host)
 	if err != nil {
 		return err
 	}
@@ -128,7 +192,9 @@ func (sm *Manager) addService(svc *v1.Service) error {
 		}()
 	}
 
+	sm.mutex.Lock()
 	sm.serviceInstances = append(sm.serviceInstances, newService)
+	sm.mutex.Unlock()
 
 	if !sm.config.DisableServiceUpdates {
 		log.Debugf("(svcs) will update [%s/%s]", newService.serviceSnapshot.Namespace, newService.serviceSnapshot.Name)
@@ -139,9 +205,20 @@ func (sm *Manager) addService(svc *v1.Service) error {
 			}
 			return err
 		}
+		// The addresses of a name can change, so its status is kept up to date
+		if newService.hasHostname() {
+			newService.statusStop = make(chan struct{})
+			go sm.refreshStatus(newService)
+		}
+	}
+
+	if sm.config.EnableServicesFinalizer {
+		if err := sm.updateFinalizer(svc, true); err != nil {
+			log.Warnf("(svcs) unable to add the finalizer to [%s/%s]: %v", svc.Namespace, svc.Name, err)
+		}
 	}
 
-	serviceIPs := fetchServiceAddresses(svc)
+	serviceIPs := fetchServiceAddresses(svc, sm.config)
 
 	// Check if we need to flush any conntrack connections (due to some dangling conntrack connections)
 	if svc.Annotations[flushContrack] == "true" {
@@ -164,7 +241,7 @@ func (sm *Manager) addService(svc *v1.Service) error {
 		log.Debugf("Enabling egress for the service [%s]", svc.Name)
 		if svc.Annotations[activeEndpoint] != "" {
 			// We will need to modify the iptables rules
-			err = sm.iptablesCheck()
+			err = sm.iptablesCheck(serviceIPs)
 			if err != nil {
 				log.Errorf("Error configuring egress for loadbalancer [%s]", err)
 			}
@@ -174,26 +251,22 @@ func (sm *Manager) addService(svc *v1.Service) error {
 				if sm.config.EnableEndpointSlices && vip.IsIPv6(serviceIP) {
 					podIPs = svc.Annotations[activeEndpointIPv6]
 				}
-				err = sm.configureEgress(serviceIP, podIPs, svc.Annotations[egressDestinationPorts], svc.Namespace)
+				err = sm.configureEgress(serviceIP, podIPs, svc.Annotations[egressDestinationCIDRs], svc.Annotations[egressDestinationPorts], svc.Namespace, true)
 				if err != nil {
 					errList = append(errList, err)
 					log.Errorf("Error configuring egress for loadbalancer [%s]", err)
 				}
 			}
 			if len(errList) == 0 {
-				var provider epProvider
-				if !sm.config.EnableEndpointSlices {
-					provider = &endpointsProvider{label: "endpoints"}
-				} else {
-					provider = &endpointslicesProvider{label: "endpointslices"}
-				}
-				err = provider.updateServiceAnnotation(svc.Annotations[activeEndpoint], svc.Annotations[activeEndpointIPv6], svc, sm)
+				err = sm.serviceEndpointsProvider(ctx, svc).updateServiceAnnotation(svc.Annotations[activeEndpoint], svc.Annotations[activeEndpointIPv6], svc, sm)
 				if err != nil {
 					log.Errorf("error configuring egress annotation for loadbalancer [%s]", err)
 				}
 
 			}
 		}
+		// The pods of the namespace that egress with the service
+		sm.addNamespaceEgress(ctx, svc, serviceIPs)
 	}
 
 	finishTime := time.Since(startTime)
@@ -227,6 +300,9 @@ func (sm *Manager) deleteService(uid string) error {
 		// return fmt.Errorf("unable to find/stop service [%s]", uid)
 		return nil
 	}
+	if serviceInstance.statusStop != nil {
+		close(serviceInstance.statusStop)
+	}
 	shared := false
 	vipSet := make(map[string]interface{})
 	for x := range updatedInstances {
@@ -255,7 +331,21 @@ func (sm *Manager) deleteService(uid string) error {
 				return fmt.Errorf("error deleting DHCP Link : %v", err)
 			}
 		}
-		// TODO: Implement dual-stack loadbalancer support if BGP is enabled
+		// The VLAN sub-interface that kube-vip created is removed once the last service on it has been deleted, until
+		// then it is handed over to another service on it
+		if serviceInstance.vlanInterface != "" && serviceInstance.vlanCreated {
+			var sharedWith *Instance
+			for x := range updatedInstances {
+				if updatedInstances[x].vlanInterface == serviceInstance.vlanInterface {
+					sharedWith = updatedInstances[x]
+				}
+			}
+			if sharedWith != nil {
+				sharedWith.vlanCreated = true
+			} else if err := vip.DeleteManagedInterface(serviceInstance.vlanInterface); err != nil {
+				log.Errorf("error deleting VLAN interface [%s]: %v", serviceInstance.vlanInterface, err)
+			}
+		}
 		for i := range serviceInstance.vipConfigs {
 			if serviceInstance.vipConfigs[i].EnableBGP {
 				cidrVip := fmt.Sprintf("%s/%s", serviceInstance.vipConfigs[i].VIP, serviceInstance.vipConfigs[i].VIPCIDR)
@@ -269,11 +359,20 @@ func (sm *Manager) deleteService(uid string) error {
 
 		// We will need to tear down the egress
 		if serviceInstance.serviceSnapshot.Annotations[egress] == "true" {
+			sm.deleteNamespaceEgress(serviceInstance.serviceSnapshot)
 			if serviceInstance.serviceSnapshot.Annotations[activeEndpoint] != "" {
 				log.Infof("service [%s] has an egress re-write enabled", serviceInstance.serviceSnapshot.Name)
-				err := sm.TeardownEgress(serviceInstance.serviceSnapshot.Annotations[activeEndpoint], serviceInstance.serviceSnapshot.Spec.LoadBalancerIP, serviceInstance.serviceSnapshot.Annotations[egressDestinationPorts], serviceInstance.serviceSnapshot.Namespace)
-				if err != nil {
-					log.Errorf("%v", err)
+				// The egress of each address is torn down, with the endpoint of the same family
+				for _, serviceIP := range serviceInstance.VIPs {
+					podIPs := serviceInstance.serviceSnapshot.Annotations[activeEndpoint]
+					if sm.config.EnableEndpointSlices && vip.IsIPv6(serviceIP) {
+						podIPs = serviceInstance.serviceSnapshot.Annotations[activeEndpointIPv6]
+					}
+					err := sm.TeardownEgress(podIPs, serviceIP, serviceInstance.serviceSnapshot.Annotations[egressDestinationCIDRs],
+						serviceInstance.serviceSnapshot.Annotations[egressDestinationPorts], serviceInstance.serviceSnapshot.Namespace)
+					if err != nil {
+						log.Errorf("%v", err)
+					}
 				}
 			}
 		}
@@ -290,9 +389,12 @@ func (sm *Manager) deleteService(uid string) error {
 func (sm *Manager) upnpMap(s *Instance) {
 	// If upnp is enabled then update the gateway/router with the address
 	// TODO - work out if we need to mapping.Reclaim()
-	// TODO - check if this implementation for dualstack is correct
 	if sm.upnp != nil {
 		for _, vip := range s.VIPs {
+			// An internet gateway device only maps ports to IPv4 addresses
+			if addressFamily(vip) != v1.IPv4Protocol {
+				continue
+			}
 			log.Infof("[UPNP] Adding map to [%s:%d - %s]", vip, s.Port, s.serviceSnapshot.Name)
 			if err := sm.upnp.AddPortMapping(int(s.Port), int(s.Port), 0, vip, strings.ToUpper(s.Type), s.serviceSnapshot.Name); err == nil {
 				log.Infof("service should be accessible externally on port [%d]", s.Port)
@@ -304,6 +406,40 @@ func (sm *Manager) upnpMap(s *Instance) {
 	}
 }
 
+// serviceIPMode returns how the addresses of a service behave, so that kube-proxy knows whether it can deliver
+// traffic for them itself. In every mode the traffic reaches the node with the address as its destination (VIP),
+// the annotation can set Proxy for a load balancer in front of kube-vip that rewrites the destination.
+func serviceIPMode(svc *v1.Service) *v1.LoadBalancerIPMode {
+	mode := v1.LoadBalancerIPModeVIP
+	if value := svc.Annotations[ipMode]; value != "" {
+		switch {
+		case strings.EqualFold(value, string(v1.LoadBalancerIPModeVIP)):
+		case strings.EqualFold(value, string(v1.LoadBalancerIPModeProxy)):
+			mode = v1.LoadBalancerIPModeProxy
+		default:
+			log.Warnf("(svcs) annotation [%s] for %s/%s is [%s], it must be VIP or Proxy", ipMode, svc.Namespace, svc.Name, value)
+		}
+	}
+	return &mode
+}
+
+// refreshStatus periodically updates the status of a service whose addresses are names, as the addresses that the
+// names resolve to can change, until the service is deleted
+func (sm *Manager) refreshStatus(i *Instance) {
+	ticker := time.NewTicker(serviceStatusRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.statusStop:
+			return
+		case <-ticker.C:
+			if err := sm.updateStatus(i); err != nil {
+				log.Warnf("(svcs) unable to refresh the status of [%s/%s]: %v", i.serviceSnapshot.Namespace, i.serviceSnapshot.Name, err)
+			}
+		}
+	}
+}
+
 func (sm *Manager) updateStatus(i *Instance) error {
 	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		// Retrieve the latest version of Deployment before attempting update
@@ -345,6 +481,7 @@ func (sm *Manager) updateStatus(i *Instance) error {
 		}
 
 		ingresses := []v1.LoadBalancerIngress{}
+		mode := serviceIPMode(i.serviceSnapshot)
 
 		for _, c := range i.vipConfigs {
 			if !vip.IsIP(c.VIP) {
@@ -352,19 +489,27 @@ func (sm *Manager) updateStatus(i *Instance) error {
 				if err != nil {
 					return err
 				}
+				// The name is published along with its addresses, kube-proxy only handles the traffic to the addresses
 				for _, ip := range ips {
 					i := v1.LoadBalancerIngress{
-						IP:    ip,
-						Ports: ports,
+						IP:       ip,
+						Hostname: c.VIP,
+						IPMode:   mode,
+						Ports:    ports,
 					}
 					ingresses = append(ingresses, i)
 				}
 			} else {
-				i := v1.LoadBalancerIngress{
-					IP:    c.VIP,
-					Ports: ports,
+				ingress := v1.LoadBalancerIngress{
+					IP:     c.VIP,
+					IPMode: mode,
+					Ports:  ports,
 				}
-				ingresses = append(ingresses, i)
+				// An address from DHCP is registered in DNS (DDNS) with the hostname of the lease
+				if i.isDHCP && i.dhcpHostname != "" {
+					ingress.Hostname = i.dhcpHostname
+				}
+				ingresses = append(ingresses, ingress)
 			}
 		}
 		if !cmp.Equal(currentService.Status.LoadBalancer.Ingress, ingresses) {
@@ -385,9 +530,39 @@ func (sm *Manager) updateStatus(i *Instance) error {
 	return nil
 }
 
+// fetchServiceInterface returns the interfaces that the service is bound and announced on, when they override the
+// global interface. The older kube-vip.io/serviceInterface annotation is still honoured.
+func fetchServiceInterface(s *v1.Service) string {
+	if iface := s.Annotations[interfaceOverride]; iface != "" {
+		return iface
+	}
+	return s.Annotations[serviceInterface]
+}
+
+// servicePaused returns whether a service has been paused for maintenance, its addresses are kept in its status
+// but aren't announced
+func servicePaused(s *v1.Service) bool {
+	return s.Annotations[pause] == "true"
+}
+
 // fetchServiceAddresses tries to get the addresses from annotations
-// kube-vip.io/loadbalancerIPs, then from spec.loadbalancerIP
-func fetchServiceAddresses(s *v1.Service) []string {
+// kube-vip.io/loadbalancerIPs, then from spec.loadbalancerIP. Only the
+// addresses of the IP families of the service are returned, followed by
+// its spec.externalIPs when they are also announced.
+func fetchServiceAddresses(s *v1.Service, c *kubevip.Config) []string {
+	addresses := fetchAllServiceAddresses(s)
+	if c != nil && c.EnableServicesExternalIPs && len(addresses) > 0 {
+		for _, address := range s.Spec.ExternalIPs {
+			// The external IPs are also published in the status, so they may already have been found
+			if address = strings.TrimSpace(address); address != "" && !slices.Contains(addresses, address) {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return serviceFamilyAddresses(s, addresses)
+}
+
+func fetchAllServiceAddresses(s *v1.Service) []string {
 	annotationAvailable := false
 	if s.Annotations != nil {
 		if v, annotationAvailable := s.Annotations[loadbalancerIPAnnotation]; annotationAvailable {
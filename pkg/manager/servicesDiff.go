@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// serviceChanges returns the fields that kube-vip acts upon which differ between the last processed version of a
+// service and the current one. A modification that changes none of them (e.g. a status update of another controller,
+// or of the managed fields) doesn't need to be processed again.
+func serviceChanges(last, svc *v1.Service, c *kubevip.Config) []string {
+	var changes []string
+	if last.Spec.Type != svc.Spec.Type || !equality.Semantic.DeepEqual(last.Spec.LoadBalancerClass, svc.Spec.LoadBalancerClass) {
+		changes = append(changes, "type")
+	}
+	if !slices.Equal(fetchServiceAddresses(last, c), fetchServiceAddresses(svc, c)) ||
+		!slices.Equal(statusAddresses(last), statusAddresses(svc)) {
+		changes = append(changes, "addresses")
+	}
+	if !equality.Semantic.DeepEqual(last.Spec.IPFamilies, svc.Spec.IPFamilies) ||
+		!equality.Semantic.DeepEqual(last.Spec.IPFamilyPolicy, svc.Spec.IPFamilyPolicy) {
+		changes = append(changes, "ipFamilies")
+	}
+	if !equality.Semantic.DeepEqual(last.Spec.Ports, svc.Spec.Ports) {
+		changes = append(changes, "ports")
+	}
+	if last.Spec.ExternalTrafficPolicy != svc.Spec.ExternalTrafficPolicy || !maps.Equal(last.Spec.Selector, svc.Spec.Selector) {
+		changes = append(changes, "trafficPolicy")
+	}
+	if !maps.Equal(kubevipAnnotations(last), kubevipAnnotations(svc)) {
+		changes = append(changes, "annotations")
+	}
+	if serviceIgnored(last, c) != serviceIgnored(svc, c) {
+		changes = append(changes, "ignored")
+	}
+	return changes
+}
+
+// statusAddresses returns the addresses that are published in the status of a service
+func statusAddresses(svc *v1.Service) []string {
+	var addresses []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			addresses = append(addresses, ingress.IP)
+		}
+	}
+	return addresses
+}
+
+// kubevipAnnotations returns the kube-vip.io annotations of a service
+func kubevipAnnotations(svc *v1.Service) map[string]string {
+	annotations := map[string]string{}
+	for k, v := range svc.Annotations {
+		if strings.HasPrefix(k, "kube-vip.io/") {
+			annotations[k] = v
+		}
+	}
+	return annotations
+}
@@ -0,0 +1,57 @@
+package manager
+
+import (
+	"slices"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceChanges(t *testing.T) {
+	last := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "web",
+			ResourceVersion: "1",
+			Annotations:     map[string]string{loadbalancerIPAnnotation: "192.168.0.10", "example.com/owner": "team"},
+		},
+		Spec: v1.ServiceSpec{
+			Type:                  v1.ServiceTypeLoadBalancer,
+			Ports:                 []v1.ServicePort{{Port: 80}},
+			ExternalTrafficPolicy: v1.ServiceExternalTrafficPolicyTypeCluster,
+			Selector:              map[string]string{"app": "web"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*v1.Service)
+		want   []string
+	}{
+		{"resource version", func(s *v1.Service) { s.ResourceVersion = "2" }, nil},
+		{"other annotation", func(s *v1.Service) { s.Annotations["example.com/owner"] = "other" }, nil},
+		{"labels", func(s *v1.Service) { s.Labels = map[string]string{"tier": "frontend"} }, nil},
+		{"ingress hostname", func(s *v1.Service) {
+			s.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{Hostname: "web.example.com"}}
+		}, nil},
+		{"address", func(s *v1.Service) { s.Annotations[loadbalancerIPAnnotation] = "192.168.0.11" }, []string{"addresses", "annotations"}},
+		{"ingress address", func(s *v1.Service) {
+			s.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "192.168.0.10"}}
+		}, []string{"addresses"}},
+		{"ports", func(s *v1.Service) { s.Spec.Ports[0].Port = 443 }, []string{"ports"}},
+		{"traffic policy", func(s *v1.Service) {
+			s.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeLocal
+		}, []string{"trafficPolicy"}},
+		{"kube-vip annotation", func(s *v1.Service) { s.Annotations[egress] = "true" }, []string{"annotations"}},
+		{"type", func(s *v1.Service) { s.Spec.Type = v1.ServiceTypeNodePort }, []string{"type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := last.DeepCopy()
+			tt.modify(svc)
+			if got := serviceChanges(last, svc, nil); !slices.Equal(got, tt.want) {
+				t.Errorf("serviceChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package manager
+
+import (
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/kube-vip/kube-vip/pkg/vip"
+)
+
+// addressFamily returns the IP family of an address
+func addressFamily(address string) v1.IPFamily {
+	if vip.IsIPv6(address) {
+		return v1.IPv6Protocol
+	}
+	return v1.IPv4Protocol
+}
+
+// serviceFamilyAddresses keeps the addresses of the IP families of a service, ordered by its families (the primary
+// family first), as kube-proxy ignores the addresses of any other family. Names (that are resolved with DNS) are
+// kept, the addresses are returned as they are if the families of the service aren't known.
+func serviceFamilyAddresses(s *v1.Service, addresses []string) []string {
+	if len(s.Spec.IPFamilies) == 0 {
+		return addresses
+	}
+	kept := make([]string, 0, len(addresses))
+	for _, family := range s.Spec.IPFamilies {
+		for _, address := range addresses {
+			if vip.IsIP(address) && addressFamily(address) == family {
+				kept = append(kept, address)
+			}
+		}
+	}
+	for _, address := range addresses {
+		if !vip.IsIP(address) {
+			kept = append(kept, address)
+		}
+	}
+	return kept
+}
+
+// isDualStack checks if a service prefers (or requires) an address of each IP family
+func isDualStack(s *v1.Service) bool {
+	return s.Spec.IPFamilyPolicy != nil && (*s.Spec.IPFamilyPolicy == v1.IPFamilyPolicyPreferDualStack ||
+		*s.Spec.IPFamilyPolicy == v1.IPFamilyPolicyRequireDualStack)
+}
+
+// requiresDualStack checks if a service must have an address of each IP family
+func requiresDualStack(s *v1.Service) bool {
+	return s.Spec.IPFamilyPolicy != nil && *s.Spec.IPFamilyPolicy == v1.IPFamilyPolicyRequireDualStack
+}
+
+// missingFamilies returns the IP families of a dual-stack service that it doesn't have an address of
+func missingFamilies(s *v1.Service, addresses []string) []v1.IPFamily {
+	if !isDualStack(s) {
+		return nil
+	}
+	families := s.Spec.IPFamilies
+	if len(families) == 0 {
+		families = []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}
+	}
+	var missing []v1.IPFamily
+	for _, family := range families {
+		found := false
+		for _, address := range addresses {
+			if !vip.IsIP(address) || addressFamily(address) == family {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, family)
+		}
+	}
+	return missing
+}
+
+// endpointFamilies returns the IP families of the endpoints, IPv4 first
+func endpointFamilies(endpoints []string) []v1.IPFamily {
+	var families []v1.IPFamily
+	for _, family := range []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol} {
+		for _, endpoint := range endpoints {
+			if addressFamily(endpoint) == family {
+				families = append(families, family)
+				break
+			}
+		}
+	}
+	return families
+}
+
+// announceFamilies returns the IP families of the addresses of a service that are announced, a dual-stack service
+// is only announced for the families that it has endpoints of, any other service for each of its families
+func announceFamilies(s *v1.Service, endpoints []string) []v1.IPFamily {
+	if !isDualStack(s) {
+		return []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}
+	}
+	return endpointFamilies(endpoints)
+}
+
+// familyEndpoint returns the endpoint of an IP family, the preferred endpoint when it is of that family
+func familyEndpoint(family v1.IPFamily, preferred string, endpoints []string) string {
+	if preferred != "" && addressFamily(preferred) == family {
+		return preferred
+	}
+	for _, endpoint := range endpoints {
+		if addressFamily(endpoint) == family {
+			return endpoint
+		}
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestServiceFamilyAddresses(t *testing.T) {
+	preferDualStack := v1.IPFamilyPolicyPreferDualStack
+	tests := []struct {
+		name      string
+		families  []v1.IPFamily
+		policy    *v1.IPFamilyPolicy
+		addresses []string
+		want      []string
+		missing   []v1.IPFamily
+	}{
+		{"unknown families", nil, nil, []string{"fd00::10", "192.168.0.10"}, []string{"fd00::10", "192.168.0.10"}, nil},
+		{"single stack", []v1.IPFamily{v1.IPv4Protocol}, nil, []string{"fd00::10", "192.168.0.10"}, []string{"192.168.0.10"}, nil},
+		{"dual-stack", []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}, &preferDualStack, []string{"fd00::10", "192.168.0.10"}, []string{"192.168.0.10", "fd00::10"}, nil},
+		{"dual-stack missing", []v1.IPFamily{v1.IPv6Protocol, v1.IPv4Protocol}, &preferDualStack, []string{"192.168.0.10"}, []string{"192.168.0.10"}, []v1.IPFamily{v1.IPv6Protocol}},
+		{"name", []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}, &preferDualStack, []string{"vip.example.com"}, []string{"vip.example.com"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &v1.Service{Spec: v1.ServiceSpec{IPFamilies: tt.families, IPFamilyPolicy: tt.policy}}
+			got := serviceFamilyAddresses(svc, tt.addresses)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("serviceFamilyAddresses() = %v, want %v", got, tt.want)
+			}
+			if missing := missingFamilies(svc, got); !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("missingFamilies() = %v, want %v", missing, tt.missing)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// failoverAnnotation forces the node that leads the election of a service to release it, and to withdraw the
+// announcements of the service, so that the VIP moves to another node
+const failoverAnnotation = "kube-vip.io/failover"
+
+// failoverService fails the service over to another node if this node leads its election. The annotation is
+// removed before the election is released, so that the node that takes over doesn't fail it over again.
+func (sm *Manager) failoverService(svc *v1.Service) {
+	if svc.Annotations[failoverAnnotation] != "true" {
+		return
+	}
+	failover := sm.serviceFailover(svc)
+	if failover == nil {
+		return
+	}
+	log.Infof("(svc election) service [%s/%s] has been asked to fail over, releasing its election", svc.Namespace, svc.Name)
+	if err := sm.clearFailover(svc); err != nil {
+		log.Errorf("(svc election) service [%s/%s] will not fail over: %v", svc.Namespace, svc.Name, err)
+		return
+	}
+	failover()
+}
+
+// serviceFailover returns the function that fails over the election of the service (or its pool), or nil if
+// this node doesn't lead it
+func (sm *Manager) serviceFailover(svc *v1.Service) func() {
+	if pool := sm.servicePool(svc); pool != "" {
+		sm.poolMutex.Lock()
+		defer sm.poolMutex.Unlock()
+		if election, exists := sm.poolElections[pool]; exists && election.leading {
+			return election.failover
+		}
+		return nil
+	}
+	sm.spreadMutex.Lock()
+	defer sm.spreadMutex.Unlock()
+	if election, exists := sm.spreadElections[string(svc.UID)]; exists {
+		return election.failover
+	}
+	return nil
+}
+
+// clearFailover removes the failover annotation from the service
+func (sm *Manager) clearFailover(svc *v1.Service) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		currentService, err := sm.clientSet.CoreV1().Services(svc.Namespace).Get(context.TODO(), svc.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if _, exists := currentService.Annotations[failoverAnnotation]; !exists {
+			return fmt.Errorf("the failover annotation has already been removed")
+		}
+		currentServiceCopy := currentService.DeepCopy()
+		delete(currentServiceCopy.Annotations, failoverAnnotation)
+		_, err = sm.clientSet.CoreV1().Services(currentService.Namespace).Update(context.TODO(), currentServiceCopy, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// serviceFinalizer holds the deletion of a service until kube-vip has removed its addresses, routes and lease
+const serviceFinalizer = "kube-vip.io/cleanup"
+
+// finalizerGracePeriod is how long a node that didn't announce a service being deleted waits for the node that
+// did to remove the finalizer, before removing it itself (e.g. the service isn't announced by any node)
+const finalizerGracePeriod = 30 * time.Second
+
+// finalizeService removes the finalizer of a service that is being deleted, once this node has cleaned it up. The
+// finalizer is removed by the node that announced the service, any other node only removes it after a grace period.
+// The finalizer is removed even if it is no longer added, so that disabling it doesn't hold up the deletion.
+func (sm *Manager) finalizeService(svc *v1.Service, announced bool, requeue func(time.Duration)) error {
+	if !slices.Contains(svc.Finalizers, serviceFinalizer) {
+		return nil
+	}
+	if !announced {
+		if wait := finalizerGracePeriod - time.Since(svc.DeletionTimestamp.Time); wait > 0 {
+			requeue(wait)
+			return nil
+		}
+	}
+	if sm.config.EnableServicesElection {
+		sm.deleteServiceLease(svc)
+	}
+	return sm.updateFinalizer(svc, false)
+}
+
+// serviceAnnounced checks if this node announces the addresses of a service
+func (sm *Manager) serviceAnnounced(svc *v1.Service) bool {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	return sm.findServiceInstance(svc) != nil
+}
+
+// deleteServiceLease deletes the lease of the election of a service being deleted, unless another node holds it
+func (sm *Manager) deleteServiceLease(svc *v1.Service) {
+	if sm.servicePool(svc) != "" {
+		return
+	}
+	name := serviceLeasePrefix + svc.Name
+	lease, err := sm.clientSet.CoordinationV1().Leases(svc.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Warnf("(svcs) unable to find the lease [%s/%s] of a deleted service: %v", svc.Namespace, name, err)
+		}
+		return
+	}
+	if holder := lease.Spec.HolderIdentity; holder != nil && *holder != "" && *holder != sm.config.NodeName {
+		return
+	}
+	if err = sm.clientSet.CoordinationV1().Leases(svc.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		log.Warnf("(svcs) unable to delete the lease [%s/%s] of a deleted service: %v", svc.Namespace, name, err)
+	}
+}
+
+// updateFinalizer adds (or removes) the finalizer of a service
+func (sm *Manager) updateFinalizer(svc *v1.Service, add bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		currentService, err := sm.clientSet.CoreV1().Services(svc.Namespace).Get(context.TODO(), svc.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		// A service that has been re-created isn't touched
+		if currentService.UID != svc.UID || slices.Contains(currentService.Finalizers, serviceFinalizer) == add {
+			return nil
+		}
+		currentServiceCopy := currentService.DeepCopy()
+		if add {
+			currentServiceCopy.Finalizers = append(currentServiceCopy.Finalizers, serviceFinalizer)
+		} else {
+			currentServiceCopy.Finalizers = slices.DeleteFunc(currentServiceCopy.Finalizers, func(finalizer string) bool {
+				return finalizer == serviceFinalizer
+			})
+		}
+		_, err = sm.clientSet.CoreV1().Services(currentService.Namespace).Update(context.TODO(), currentServiceCopy, metav1.UpdateOptions{})
+		return err
+	})
+}
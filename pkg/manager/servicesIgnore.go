@@ -0,0 +1,54 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ignoreAnnotation is the service annotation that always makes kube-vip ignore a service
+const ignoreAnnotation = "kube-vip.io/ignore"
+
+// serviceIgnored returns why a service is ignored, or an empty string if it isn't. A service is ignored by the ignore
+// annotation, or by the ignore criteria of the configuration so that kube-vip can run alongside other load balancer
+// controllers: its namespace, its labels matching a selector or one of its annotations. The annotations are a
+// comma separated list of keys (that match any value) or key=value pairs.
+func serviceIgnored(svc *v1.Service, c *kubevip.Config) string {
+	if svc.Annotations[ignoreAnnotation] == "true" {
+		return fmt.Sprintf("annotation [%s]", ignoreAnnotation)
+	}
+	if c == nil {
+		return ""
+	}
+	if c.ServicesIgnoreNamespaces != "" && slices.Contains(splitList(c.ServicesIgnoreNamespaces), svc.Namespace) {
+		return fmt.Sprintf("namespace [%s]", svc.Namespace)
+	}
+	if c.ServicesIgnoreLabelSelector != "" {
+		// The selector has been validated when the services watcher was started
+		if s, err := labels.Parse(c.ServicesIgnoreLabelSelector); err == nil && s.Matches(labels.Set(svc.Labels)) {
+			return fmt.Sprintf("labels matching [%s]", c.ServicesIgnoreLabelSelector)
+		}
+	}
+	for _, annotation := range splitList(c.ServicesIgnoreAnnotations) {
+		key, value, withValue := strings.Cut(annotation, "=")
+		if v, found := svc.Annotations[key]; found && (!withValue || v == value) {
+			return fmt.Sprintf("annotation [%s]", annotation)
+		}
+	}
+	return ""
+}
+
+// splitList returns the trimmed, non-empty elements of a comma separated list
+func splitList(list string) []string {
+	var elements []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
@@ -0,0 +1,42 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceIgnored(t *testing.T) {
+	config := &kubevip.Config{
+		ServicesIgnoreLabelSelector: "lb=other",
+		ServicesIgnoreAnnotations:   "metallb.universe.tf/address-pool, example.com/lb=external",
+		ServicesIgnoreNamespaces:    "kube-system,legacy",
+	}
+	service := func(namespace string, labels, annotations map[string]string) *v1.Service {
+		return &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace, Labels: labels, Annotations: annotations}}
+	}
+
+	tests := []struct {
+		name    string
+		svc     *v1.Service
+		config  *kubevip.Config
+		ignored bool
+	}{
+		{"not ignored", service("default", map[string]string{"lb": "kube-vip"}, map[string]string{"example.com/lb": "internal"}), config, false},
+		{"ignore annotation", service("default", nil, map[string]string{ignoreAnnotation: "true"}), nil, true},
+		{"namespace", service("legacy", nil, nil), config, true},
+		{"labels", service("default", map[string]string{"lb": "other"}, nil), config, true},
+		{"annotation key", service("default", nil, map[string]string{"metallb.universe.tf/address-pool": "pool"}), config, true},
+		{"annotation value", service("default", nil, map[string]string{"example.com/lb": "external"}), config, true},
+		{"no criteria", service("legacy", map[string]string{"lb": "other"}, nil), &kubevip.Config{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serviceIgnored(tt.svc, tt.config); (got != "") != tt.ignored {
+				t.Errorf("serviceIgnored() = %q, want ignored %t", got, tt.ignored)
+			}
+		})
+	}
+}
@@ -3,9 +3,14 @@ package manager
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/kube-vip/kube-vip/pkg/k8s"
+	"github.com/kube-vip/kube-vip/pkg/priority"
+	"github.com/kube-vip/kube-vip/pkg/vip"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,6 +20,10 @@ import (
 
 // The startServicesWatchForLeaderElection function will start a services watcher, the
 func (sm *Manager) startServicesWatchForLeaderElection(ctx context.Context) error {
+	if sm.config.EnableServicesSpread {
+		go sm.spreadServices(ctx)
+	}
+
 	err := sm.servicesWatcher(ctx, sm.StartServicesLeaderElection)
 	if err != nil {
 		return err
@@ -28,14 +37,182 @@ func (sm *Manager) startServicesWatchForLeaderElection(ctx context.Context) erro
 			cluster.Stop()
 		}
 	}
+	// The addresses have been withdrawn, so the leases can be released to the other nodes
+	sm.releaseServicesElections()
 
 	log.Infof("Shutting down kube-Vip")
 
 	return nil
 }
 
+// releaseServicesElections cancels the services elections so that their leases are released, and the other
+// nodes take over straight away rather than once the leases have expired
+func (sm *Manager) releaseServicesElections() {
+	sm.cancelServices()
+	sm.poolMutex.Lock()
+	for _, election := range sm.poolElections {
+		election.cancel()
+	}
+	sm.poolMutex.Unlock()
+
+	released := make(chan struct{})
+	go func() {
+		sm.elections.Wait()
+		close(released)
+	}()
+	timeout := sm.serviceLeaseTimings(nil).renewDeadline
+	select {
+	case <-released:
+		log.Infof("(svc election) the leases of the services elections have been released")
+	case <-time.After(timeout):
+		log.Warnf("(svc election) the services elections haven't stopped within %s, their leases will expire instead", timeout)
+	}
+}
+
+// serviceElectionInterface is the (first) interface that the addresses of the service are announced on
+func (sm *Manager) serviceElectionInterface(service *v1.Service) string {
+	iface := fetchServiceInterface(service)
+	if iface == "" {
+		iface = sm.serviceInterface()
+	}
+	if interfaces := vip.GetInterfaces(iface); len(interfaces) > 0 {
+		iface = interfaces[0]
+	}
+	return iface
+}
+
+// electionContext returns a context for an election that is cancelled if the interface loses carrier, the
+// node becomes unhealthy, it is cordoned or it is excluded from the elections, when any of them is monitored
+// it first waits for the interface to have carrier and the node to be healthy, schedulable and included
+func (sm *Manager) electionContext(ctx context.Context, iface string) (context.Context, context.CancelFunc, error) {
+	electionCtx, cancel := context.WithCancel(ctx)
+	if sm.config.MonitorCarrier {
+		cancel()
+		if err := sm.host.WaitForCarrier(ctx, iface); err != nil {
+			return nil, nil, err
+		}
+		electionCtx, cancel = sm.host.CarrierContext(ctx, iface)
+	}
+	if sm.config.MonitorNodeHealth {
+		if err := k8s.WaitForNodeHealthy(electionCtx, sm.clientSet, sm.config.NodeName); err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		healthCtx, cancelHealth := k8s.NodeHealthContext(electionCtx, sm.clientSet, sm.config.NodeName)
+		cancelCarrier := cancel
+		electionCtx, cancel = healthCtx, func() {
+			cancelHealth()
+			cancelCarrier()
+		}
+	}
+	// A cordoned node doesn't lead any services, whatever their traffic policy, so that they have moved before it is drained
+	if sm.config.ServicesAvoidUnschedulable {
+		if err := k8s.WaitForNodeSchedulable(electionCtx, sm.clientSet, sm.config.NodeName); err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		schedulableCtx, cancelSchedulable := k8s.NodeSchedulableContext(electionCtx, sm.clientSet, sm.config.NodeName)
+		cancelPrevious := cancel
+		electionCtx, cancel = schedulableCtx, func() {
+			cancelSchedulable()
+			cancelPrevious()
+		}
+	}
+	// An excluded node doesn't lead any services, until it is no longer excluded
+	if err := k8s.WaitForNodeIncluded(electionCtx, sm.clientSet, sm.config.NodeName, sm.config.ElectionExcludeSelector, sm.config.ElectionExcludeTaints); err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	includedCtx, cancelIncluded := k8s.NodeIncludedContext(electionCtx, sm.clientSet, sm.config.NodeName, sm.config.ElectionExcludeSelector, sm.config.ElectionExcludeTaints)
+	cancelPrevious := cancel
+	electionCtx, cancel = includedCtx, func() {
+		cancelIncluded()
+		cancelPrevious()
+	}
+	return electionCtx, cancel, nil
+}
+
+// runElection runs a services election, giving precedence to the nodes with a higher priority if enabled
+func (sm *Manager) runElection(ctx context.Context, config leaderelection.LeaderElectionConfig) {
+	if !sm.config.EnableLeaderPriority {
+		leaderelection.RunOrDie(ctx, config)
+		return
+	}
+	priority.RunOrDie(ctx, sm.clientSet, sm.config.LeaderPreemption, time.Duration(sm.config.LeaderFailbackDelay)*time.Second, config)
+}
+
+// leaseTimings are the lease duration, renew deadline and retry period of an election
+type leaseTimings struct {
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+}
+
+// parseLeaseTiming parses the timing of an annotation, either as a duration (e.g. 500ms) or in seconds
+func parseLeaseTiming(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// serviceLeaseTimings returns the timings of the election of the service (or of a pool if the service is nil),
+// the annotations of the service override the timings of the services elections
+func (sm *Manager) serviceLeaseTimings(service *v1.Service) leaseTimings {
+	timings := leaseTimings{
+		leaseDuration: time.Duration(sm.config.LeaseDuration) * time.Second,
+		renewDeadline: time.Duration(sm.config.RenewDeadline) * time.Second,
+		retryPeriod:   time.Duration(sm.config.RetryPeriod) * time.Second,
+	}
+	if sm.config.ServicesLeaseDuration != 0 {
+		timings = leaseTimings{
+			leaseDuration: time.Duration(sm.config.ServicesLeaseDuration) * time.Second,
+			renewDeadline: time.Duration(sm.config.ServicesRenewDeadline) * time.Second,
+			retryPeriod:   time.Duration(sm.config.ServicesRetryPeriod) * time.Second,
+		}
+	}
+	if service == nil {
+		return timings
+	}
+
+	overridden := timings
+	for annotation, timing := range map[string]*time.Duration{
+		leaseDuration:      &overridden.leaseDuration,
+		leaseRenewDeadline: &overridden.renewDeadline,
+		leaseRetryPeriod:   &overridden.retryPeriod,
+	} {
+		value := service.Annotations[annotation]
+		if value == "" {
+			continue
+		}
+		parsed, err := parseLeaseTiming(value)
+		if err != nil {
+			log.Errorf("(svc election) error parsing annotation [%s] for %s/%s: %v", annotation, service.Namespace, service.Name, err)
+			return timings
+		}
+		*timing = parsed
+	}
+	// The same constraints as the leader election, otherwise it would refuse to start
+	if overridden.leaseDuration <= overridden.renewDeadline ||
+		overridden.renewDeadline <= time.Duration(leaderelection.JitterFactor*float64(overridden.retryPeriod)) ||
+		overridden.retryPeriod <= 0 {
+		log.Errorf("(svc election) lease timings of %s/%s should be lease duration > renew deadline > %.1f * retry period, using the defaults",
+			service.Namespace, service.Name, leaderelection.JitterFactor)
+		return timings
+	}
+	return overridden
+}
+
 // The startServicesWatchForLeaderElection function will start a services watcher, the
 func (sm *Manager) StartServicesLeaderElection(ctx context.Context, service *v1.Service, wg *sync.WaitGroup) error {
+	// Services can share an election with the other services of their pool
+	if pool := sm.servicePool(service); pool != "" {
+		return sm.startPoolLeaderElection(ctx, pool, service, wg)
+	}
+
+	sm.elections.Add(1)
+	defer sm.elections.Done()
+
 	serviceLease := fmt.Sprintf("kubevip-%s", service.Name)
 	log.Infof("(svc election) service [%s], namespace [%s], lock name [%s], host id [%s]", service.Name, service.Namespace, serviceLease, sm.config.NodeName)
 	// we use the Lease lock type since edits to Leases are less common
@@ -51,52 +228,103 @@ func (sm *Manager) StartServicesLeaderElection(ctx context.Context, service *v1.
 		},
 	}
 
-	activeService[string(service.UID)] = true
-	// start the leader election code loop
-	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
-		Lock: lock,
-		// IMPORTANT: you MUST ensure that any code you have that
-		// is protected by the lease must terminate **before**
-		// you call cancel. Otherwise, you could have a background
-		// loop still running and another process could
-		// get elected before your background loop finished, violating
-		// the stated goal of the lease.
-		ReleaseOnCancel: true,
-		LeaseDuration:   time.Duration(sm.config.LeaseDuration) * time.Second,
-		RenewDeadline:   time.Duration(sm.config.RenewDeadline) * time.Second,
-		RetryPeriod:     time.Duration(sm.config.RetryPeriod) * time.Second,
-		Callbacks: leaderelection.LeaderCallbacks{
-			OnStartedLeading: func(ctx context.Context) {
-				// Mark this service as active (as we've started leading)
-				// we run this in background as it's blocking
-				wg.Add(1)
-				go func() {
-					if err := sm.syncServices(ctx, service, wg); err != nil {
-						log.Errorln(err)
+	electionInterface := sm.serviceElectionInterface(service)
+	timings := sm.serviceLeaseTimings(service)
+	for {
+		// Whilst the interface has no carrier or the node is unhealthy or cordoned this node doesn't take part in the election
+		carrierCtx, cancelElection, err := sm.electionContext(ctx, electionInterface)
+		if err != nil {
+			if ctx.Err() == nil {
+				// The interface lost carrier whilst waiting for the node to be healthy
+				continue
+			}
+			break
+		}
+		// Leadership can be released to rebalance the services across the nodes, or to fail the service over
+		electionCtx, release := context.WithCancel(carrierCtx)
+		var failedOver atomic.Bool
+		failover := func() {
+			failedOver.Store(true)
+			release()
+		}
+		if sm.config.EnableServicesSpread {
+			sm.waitForSpread(electionCtx, timings.retryPeriod)
+		}
+		if sm.config.EnableServicesTopology {
+			sm.waitForTopology(electionCtx, service, timings.retryPeriod)
+		}
+		sm.setServiceActive(string(service.UID), true)
+		// start the leader election code loop
+		sm.runElection(electionCtx, leaderelection.LeaderElectionConfig{
+			Lock: lock,
+			// IMPORTANT: you MUST ensure that any code you have that
+			// is protected by the lease must terminate **before**
+			// you call cancel. Otherwise, you could have a background
+			// loop still running and another process could
+			// get elected before your background loop finished, violating
+			// the stated goal of the lease.
+			ReleaseOnCancel: true,
+			LeaseDuration:   timings.leaseDuration,
+			RenewDeadline:   timings.renewDeadline,
+			RetryPeriod:     timings.retryPeriod,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					// Mark this service as active (as we've started leading)
+					// we run this in background as it's blocking
+					sm.spreadLeading(string(service.UID), service.Name, release, failover)
+					sm.annotateLease(ctx, service.Namespace, serviceLease, electionInterface)
+					wg.Add(1)
+					go func() {
+						if err := sm.syncServices(ctx, service, wg); err != nil {
+							log.Errorln(err)
+						}
+					}()
+				},
+				OnStoppedLeading: func() {
+					// we can do cleanup here
+					log.Infof("(svc election) service [%s] leader lost: [%s]", service.Name, sm.config.NodeName)
+					sm.spreadStopped(string(service.UID))
+					if sm.serviceActive(string(service.UID)) {
+						if err := sm.deleteService(string(service.UID)); err != nil {
+							log.Errorln(err)
+						}
 					}
-				}()
-			},
-			OnStoppedLeading: func() {
-				// we can do cleanup here
-				log.Infof("(svc election) service [%s] leader lost: [%s]", service.Name, sm.config.NodeName)
-				if activeService[string(service.UID)] {
-					if err := sm.deleteService(string(service.UID)); err != nil {
-						log.Errorln(err)
+					// Mark this service is inactive
+					sm.setServiceActive(string(service.UID), false)
+				},
+				OnNewLeader: func(identity string) {
+					// we're notified when new leader elected
+					if identity == sm.config.NodeName {
+						// I just got the lock
+						return
 					}
-				}
-				// Mark this service is inactive
-				activeService[string(service.UID)] = false
+					log.Infof("(svc election) new leader elected: %s", identity)
+				},
 			},
-			OnNewLeader: func(identity string) {
-				// we're notified when new leader elected
-				if identity == sm.config.NodeName {
-					// I just got the lock
-					return
-				}
-				log.Infof("(svc election) new leader elected: %s", identity)
-			},
-		},
-	})
+		})
+		released := electionCtx.Err() != nil && carrierCtx.Err() == nil
+		carrierLost := carrierCtx.Err() != nil && ctx.Err() == nil
+		release()
+		cancelElection()
+		if released && failedOver.Load() {
+			// Sit out for a lease so that another node takes over the service
+			log.Infof("(svc election) service [%s] has failed over, rejoining the election in %s", service.Name, timings.leaseDuration)
+			select {
+			case <-time.After(timings.leaseDuration):
+				continue
+			case <-ctx.Done():
+			}
+			break
+		}
+		if released {
+			log.Infof("(svc election) service [%s] has been released to rebalance the services, rejoining the election", service.Name)
+			continue
+		}
+		if !carrierLost {
+			break
+		}
+		log.Warnf("(svc election) service [%s] has relinquished leadership as [%s] has lost carrier or the node is unhealthy or excluded", service.Name, electionInterface)
+	}
 	log.Infof("(svc election) for service [%s] stopping", service.Name)
 	return nil
 }
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceLeaseTimings(t *testing.T) {
+	config := &kubevip.Config{
+		KubernetesLeaderElection: kubevip.KubernetesLeaderElection{
+			LeaseDuration: 5,
+			RenewDeadline: 3,
+			RetryPeriod:   1,
+		},
+	}
+	defaults := leaseTimings{5 * time.Second, 3 * time.Second, time.Second}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        leaseTimings
+	}{
+		{"defaults", nil, defaults},
+		{"durations", map[string]string{leaseDuration: "2s", leaseRenewDeadline: "1s", leaseRetryPeriod: "500ms"},
+			leaseTimings{2 * time.Second, time.Second, 500 * time.Millisecond}},
+		{"seconds", map[string]string{leaseDuration: "30", leaseRenewDeadline: "20", leaseRetryPeriod: "4"},
+			leaseTimings{30 * time.Second, 20 * time.Second, 4 * time.Second}},
+		{"invalid", map[string]string{leaseDuration: "soon"}, defaults},
+		{"renew deadline longer than lease", map[string]string{leaseRenewDeadline: "10s"}, defaults},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := &Manager{config: config}
+			svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: tt.annotations}}
+			if got := sm.serviceLeaseTimings(svc); got != tt.want {
+				t.Errorf("serviceLeaseTimings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcessLoad(t *testing.T) {
+	loads := map[string]int{"a": 5, "b": 2, "c": 3}
+	for node, want := range map[string]int{"a": 3, "b": 0, "c": 1} {
+		if got := excessLoad(loads, node); got != want {
+			t.Errorf("excess load of node [%s] = %d, want %d", node, got, want)
+		}
+	}
+}
+
+func TestMajorityZone(t *testing.T) {
+	zones := map[string]string{"a": "zone-1", "b": "zone-2", "c": "zone-2", "d": ""}
+	tests := []struct {
+		nodes map[string]int
+		want  string
+	}{
+		{map[string]int{"a": 3, "b": 1, "c": 1}, "zone-1"},
+		{map[string]int{"a": 1, "b": 1, "c": 1}, "zone-2"},
+		{map[string]int{"a": 1, "b": 1}, "zone-1"},
+		{map[string]int{"d": 4}, ""},
+	}
+	for _, tt := range tests {
+		if got := majorityZone(tt.nodes, zones); got != tt.want {
+			t.Errorf("majorityZone(%v) = [%s], want [%s]", tt.nodes, got, tt.want)
+		}
+	}
+}
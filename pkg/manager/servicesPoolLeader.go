@@ -0,0 +1,233 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// defaultPoolPrefixIPv6 is the size of an IPv6 pool, when only the size of an IPv4 pool is configured
+const defaultPoolPrefixIPv6 = 64
+
+// poolElection is a single leader election for all of the services with an address in the pool,
+// the leader announces all of them
+type poolElection struct {
+	services  map[string]*v1.Service
+	leading   bool
+	leaderCtx context.Context
+	cancel    context.CancelFunc
+	// failover releases the election whilst leading, so that the pool moves to another node
+	failover func()
+}
+
+// parsePoolPrefixes parses the size of the IPv4 and (optionally) IPv6 pools, e.g. "24" or "24,64"
+func parsePoolPrefixes(pool string) (int, int, error) {
+	sizes := strings.Split(pool, ",")
+	if len(sizes) > 2 {
+		return 0, 0, fmt.Errorf("services election pool [%s] should be [ipv4 prefix],[ipv6 prefix]", pool)
+	}
+	v4, err := strconv.Atoi(strings.TrimSpace(sizes[0]))
+	if err != nil || v4 < 0 || v4 > 32 {
+		return 0, 0, fmt.Errorf("invalid IPv4 services election pool prefix [%s]", sizes[0])
+	}
+	v6 := defaultPoolPrefixIPv6
+	if len(sizes) == 2 {
+		v6, err = strconv.Atoi(strings.TrimSpace(sizes[1]))
+		if err != nil || v6 < 0 || v6 > 128 {
+			return 0, 0, fmt.Errorf("invalid IPv6 services election pool prefix [%s]", sizes[1])
+		}
+	}
+	return v4, v6, nil
+}
+
+// serviceShard returns the shard of the service, from a hash of its namespace and name, so that a service
+// stays in the same shard when it is recreated
+func serviceShard(service *v1.Service, shards int) string {
+	h := fnv.New32a()
+	h.Write([]byte(service.Namespace + "/" + service.Name))
+	return fmt.Sprintf("shard-%d", h.Sum32()%uint32(shards))
+}
+
+// servicePool returns the pool (subnet) of the first address of the service, or its shard when the services
+// are sharded, or an empty string if the service has its own election. Services with a local traffic policy
+// are always elected individually, as the leader must have a local endpoint.
+func (sm *Manager) servicePool(service *v1.Service) string {
+	if service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
+		return ""
+	}
+	if sm.config.ServicesElectionPool == "" {
+		if sm.config.ServicesElectionShards > 0 {
+			return serviceShard(service, sm.config.ServicesElectionShards)
+		}
+		return ""
+	}
+	v4, v6, err := parsePoolPrefixes(sm.config.ServicesElectionPool)
+	if err != nil {
+		log.Errorf("(svc election) %v, using an election per service", err)
+		return ""
+	}
+
+	addresses := fetchServiceAddresses(service, sm.config)
+	if len(addresses) == 0 {
+		return ""
+	}
+	ip := net.ParseIP(addresses[0])
+	if ip == nil {
+		return ""
+	}
+	mask := net.CIDRMask(v6, 128)
+	if ip.To4() != nil {
+		ip = ip.To4()
+		mask = net.CIDRMask(v4, 32)
+	}
+	pool := net.IPNet{IP: ip.Mask(mask), Mask: mask}
+	return pool.String()
+}
+
+// poolLeaseName converts a pool into a valid name for a lease
+func poolLeaseName(pool string) string {
+	return "kubevip-pool-" + strings.NewReplacer(".", "-", ":", "-", "/", "-").Replace(pool)
+}
+
+// startPoolLeaderElection adds the service to the election of its pool, starting the election if
+// this is the first service in the pool. It blocks until the context of the service is cancelled.
+func (sm *Manager) startPoolLeaderElection(ctx context.Context, pool string, service *v1.Service, wg *sync.WaitGroup) error {
+	uid := string(service.UID)
+
+	sm.poolMutex.Lock()
+	if sm.poolElections == nil {
+		sm.poolElections = make(map[string]*poolElection)
+	}
+	election, exists := sm.poolElections[pool]
+	if !exists {
+		electionCtx, cancel := context.WithCancel(context.Background())
+		election = &poolElection{services: make(map[string]*v1.Service), cancel: cancel}
+		sm.poolElections[pool] = election
+		sm.elections.Add(1)
+		go sm.runPoolLeaderElection(electionCtx, pool, election, wg)
+	}
+	election.services[uid] = service
+	// The pool is already led by this node, so the service can be announced straight away
+	if election.leading {
+		wg.Add(1)
+		go func(leaderCtx context.Context) {
+			if err := sm.syncServices(leaderCtx, service, wg); err != nil {
+				log.Errorln(err)
+			}
+		}(election.leaderCtx)
+	}
+	sm.poolMutex.Unlock()
+
+	sm.setServiceActive(uid, true)
+	log.Infof("(svc election) service [%s/%s] has joined the election for pool [%s]", service.Namespace, service.Name, pool)
+
+	<-ctx.Done()
+
+	// The service has been removed, stop the election once the pool is empty
+	sm.poolMutex.Lock()
+	delete(election.services, uid)
+	if len(election.services) == 0 {
+		election.cancel()
+		delete(sm.poolElections, pool)
+	}
+	sm.poolMutex.Unlock()
+
+	log.Infof("(svc election) for service [%s] in pool [%s] stopping", service.Name, pool)
+	return nil
+}
+
+// runPoolLeaderElection runs the election of a pool until the last service has left the pool
+func (sm *Manager) runPoolLeaderElection(ctx context.Context, pool string, election *poolElection, wg *sync.WaitGroup) {
+	defer sm.elections.Done()
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      poolLeaseName(pool),
+			Namespace: sm.config.Namespace,
+		},
+		Client: sm.clientSet.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: sm.config.NodeName,
+		},
+	}
+	log.Infof("(svc election) pool [%s], namespace [%s], lock name [%s], host id [%s]", pool, sm.config.Namespace, lock.LeaseMeta.Name, sm.config.NodeName)
+	timings := sm.serviceLeaseTimings(nil)
+	// Unlike the election of a service, the services of the pool are still active when leadership is
+	// lost, so we rejoin the election until the pool is empty
+	for ctx.Err() == nil {
+		// Whilst the interface has no carrier or the node is unhealthy or excluded this node doesn't take part in the election
+		carrierCtx, cancelElection, err := sm.electionContext(ctx, sm.serviceInterface())
+		if err != nil {
+			continue
+		}
+		electionCtx, release := context.WithCancel(carrierCtx)
+		var failedOver atomic.Bool
+		sm.runElection(electionCtx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			ReleaseOnCancel: true,
+			LeaseDuration:   timings.leaseDuration,
+			RenewDeadline:   timings.renewDeadline,
+			RetryPeriod:     timings.retryPeriod,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					sm.poolMutex.Lock()
+					defer sm.poolMutex.Unlock()
+					election.leading = true
+					election.leaderCtx = ctx
+					election.failover = func() {
+						failedOver.Store(true)
+						release()
+					}
+					log.Infof("(svc election) pool [%s] leader acquired, announcing %d services", pool, len(election.services))
+					sm.annotateLease(ctx, sm.config.Namespace, lock.LeaseMeta.Name, sm.serviceInterface())
+					for _, service := range election.services {
+						wg.Add(1)
+						go func(service *v1.Service) {
+							if err := sm.syncServices(ctx, service, wg); err != nil {
+								log.Errorln(err)
+							}
+						}(service)
+					}
+				},
+				OnStoppedLeading: func() {
+					sm.poolMutex.Lock()
+					defer sm.poolMutex.Unlock()
+					election.leading = false
+					log.Infof("(svc election) pool [%s] leader lost: [%s]", pool, sm.config.NodeName)
+					for uid := range election.services {
+						if err := sm.deleteService(uid); err != nil {
+							log.Errorln(err)
+						}
+					}
+				},
+				OnNewLeader: func(identity string) {
+					if identity == sm.config.NodeName {
+						return
+					}
+					log.Infof("(svc election) new leader elected for pool [%s]: %s", pool, identity)
+				},
+			},
+		})
+		release()
+		cancelElection()
+		if failedOver.Load() && ctx.Err() == nil {
+			// Sit out for a lease so that another node takes over the pool
+			log.Infof("(svc election) pool [%s] has failed over, rejoining the election in %s", pool, timings.leaseDuration)
+			select {
+			case <-time.After(timings.leaseDuration):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
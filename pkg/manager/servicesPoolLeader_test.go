@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServicePool(t *testing.T) {
+	tests := []struct {
+		name    string
+		pool    string
+		address string
+		policy  v1.ServiceExternalTrafficPolicyType
+		want    string
+	}{
+		{"disabled", "", "192.168.0.10", v1.ServiceExternalTrafficPolicyTypeCluster, ""},
+		{"ipv4", "24", "192.168.0.10", v1.ServiceExternalTrafficPolicyTypeCluster, "192.168.0.0/24"},
+		{"ipv6 default", "24", "fd00::1:10", v1.ServiceExternalTrafficPolicyTypeCluster, "fd00::/64"},
+		{"ipv6", "24,112", "fd00::1:10", v1.ServiceExternalTrafficPolicyTypeCluster, "fd00::1:0/112"},
+		{"local traffic policy", "24", "192.168.0.10", v1.ServiceExternalTrafficPolicyTypeLocal, ""},
+		{"invalid", "33", "192.168.0.10", v1.ServiceExternalTrafficPolicyTypeCluster, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := &Manager{config: &kubevip.Config{ServicesElectionPool: tt.pool}}
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec: v1.ServiceSpec{
+					LoadBalancerIP:        tt.address,
+					ExternalTrafficPolicy: tt.policy,
+				},
+			}
+			if got := sm.servicePool(svc); got != tt.want {
+				t.Errorf("servicePool() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceShard(t *testing.T) {
+	sm := &Manager{config: &kubevip.Config{ServicesElectionShards: 16}}
+	shards := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("svc-%d", i)}}
+		shard := sm.servicePool(svc)
+		if shard != sm.servicePool(svc.DeepCopy()) {
+			t.Fatalf("service [%s] should always be in the same shard", svc.Name)
+		}
+		shards[shard] = true
+	}
+	if len(shards) > 16 || len(shards) < 2 {
+		t.Errorf("services should be spread across at most 16 shards, got %d", len(shards))
+	}
+
+	local := &v1.Service{Spec: v1.ServiceSpec{ExternalTrafficPolicy: v1.ServiceExternalTrafficPolicyTypeLocal}}
+	if got := sm.servicePool(local); got != "" {
+		t.Errorf("service with a local traffic policy should have its own election, got [%s]", got)
+	}
+}
+
+func TestPoolLeaseName(t *testing.T) {
+	if got := poolLeaseName("192.168.0.0/24"); got != "kubevip-pool-192-168-0-0-24" {
+		t.Errorf("poolLeaseName() = %q", got)
+	}
+}
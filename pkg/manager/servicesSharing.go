@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// sharingConflict returns why a service can't share its addresses with the services that are already configured,
+// or an empty string if it can. Services that share an address must have the same sharing key (services without a
+// key can still share an address with each other), must not declare the same ports and must have compatible
+// traffic policies, as the address can only follow the endpoints of one of them.
+func sharingConflict(svc *v1.Service, addresses []string, instances []*Instance) string {
+	for _, instance := range instances {
+		other := instance.serviceSnapshot
+		if other == nil || instance.UID == string(svc.UID) || instance.isDHCP {
+			continue
+		}
+		shared := ""
+		for _, address := range addresses {
+			if slices.Contains(instance.VIPs, address) {
+				shared = address
+				break
+			}
+		}
+		if shared == "" {
+			continue
+		}
+
+		name := other.Namespace + "/" + other.Name
+		if key := other.Annotations[sharingKey]; svc.Annotations[sharingKey] != key {
+			return fmt.Sprintf("address [%s] is used by service [%s], which has the sharing key [%s]", shared, name, key)
+		}
+		for _, port := range svc.Spec.Ports {
+			if servicePortUsed(other, port) {
+				return fmt.Sprintf("port [%d/%s] of address [%s] is also used by service [%s]", port.Port, servicePortProtocol(port), shared, name)
+			}
+		}
+		local := svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal
+		otherLocal := other.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal
+		if local != otherLocal {
+			return fmt.Sprintf("address [%s] is used by service [%s], which has a different external traffic policy", shared, name)
+		}
+		if local && !equality.Semantic.DeepEqual(svc.Spec.Selector, other.Spec.Selector) {
+			return fmt.Sprintf("address [%s] is used by service [%s], services with a local external traffic policy must select the same pods", shared, name)
+		}
+	}
+	return ""
+}
+
+// servicePortUsed checks if a service already declares a port (with the same protocol)
+func servicePortUsed(svc *v1.Service, port v1.ServicePort) bool {
+	for _, used := range svc.Spec.Ports {
+		if used.Port == port.Port && servicePortProtocol(used) == servicePortProtocol(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// servicePortProtocol returns the protocol of a port, which defaults to TCP
+func servicePortProtocol(port v1.ServicePort) v1.Protocol {
+	if port.Protocol == "" {
+		return v1.ProtocolTCP
+	}
+	return port.Protocol
+}
@@ -0,0 +1,51 @@
+package manager
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSharingConflict(t *testing.T) {
+	service := func(uid, key string, port int32, policy v1.ServiceExternalTrafficPolicyType, app string) *v1.Service {
+		svc := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: uid, Namespace: "default", UID: types.UID("uid-" + uid)},
+			Spec: v1.ServiceSpec{
+				Ports:                 []v1.ServicePort{{Port: port}},
+				ExternalTrafficPolicy: policy,
+				Selector:              map[string]string{"app": app},
+			},
+		}
+		if key != "" {
+			svc.Annotations = map[string]string{sharingKey: key}
+		}
+		return svc
+	}
+	existing := service("web", "shared", 80, v1.ServiceExternalTrafficPolicyTypeLocal, "web")
+	instances := []*Instance{{UID: string(existing.UID), VIPs: []string{"192.168.0.10"}, serviceSnapshot: existing}}
+
+	tests := []struct {
+		name     string
+		svc      *v1.Service
+		address  string
+		conflict bool
+	}{
+		{"different address", service("api", "", 80, v1.ServiceExternalTrafficPolicyTypeCluster, "api"), "192.168.0.11", false},
+		{"same service", existing, "192.168.0.10", false},
+		{"shared", service("tls", "shared", 443, v1.ServiceExternalTrafficPolicyTypeLocal, "web"), "192.168.0.10", false},
+		{"different key", service("tls", "other", 443, v1.ServiceExternalTrafficPolicyTypeLocal, "web"), "192.168.0.10", true},
+		{"no key", service("tls", "", 443, v1.ServiceExternalTrafficPolicyTypeLocal, "web"), "192.168.0.10", true},
+		{"overlapping port", service("tls", "shared", 80, v1.ServiceExternalTrafficPolicyTypeLocal, "web"), "192.168.0.10", true},
+		{"different policy", service("tls", "shared", 443, v1.ServiceExternalTrafficPolicyTypeCluster, "web"), "192.168.0.10", true},
+		{"different pods", service("tls", "shared", 443, v1.ServiceExternalTrafficPolicyTypeLocal, "api"), "192.168.0.10", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sharingConflict(tt.svc, []string{tt.address}, instances); (got != "") != tt.conflict {
+				t.Errorf("sharingConflict() = %q, want a conflict %t", got, tt.conflict)
+			}
+		})
+	}
+}
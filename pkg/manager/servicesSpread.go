@@ -0,0 +1,250 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// spreadLeasePrefix is the prefix of the lease of every node, that advertises how many services elections
+	// that node is leading
+	spreadLeasePrefix = "kubevip-spread-"
+
+	// spreadLoadAnnotation is the number of services elections led by the holder of a spread lease
+	spreadLoadAnnotation = "kube-vip.io/services-led"
+)
+
+// spreadElection is a services election that this node leads, when spreading services it can be released
+// so that it moves to a node that leads fewer, and it can be failed over to another node
+type spreadElection struct {
+	service  string
+	release  context.CancelFunc
+	failover func()
+}
+
+// spreadLeading registers a services election that this node has started leading
+func (sm *Manager) spreadLeading(uid, service string, release context.CancelFunc, failover func()) {
+	sm.spreadMutex.Lock()
+	defer sm.spreadMutex.Unlock()
+	if sm.spreadElections == nil {
+		sm.spreadElections = make(map[string]*spreadElection)
+	}
+	sm.spreadElections[uid] = &spreadElection{service: service, release: release, failover: failover}
+}
+
+// spreadStopped removes a services election that this node no longer leads
+func (sm *Manager) spreadStopped(uid string) {
+	sm.spreadMutex.Lock()
+	defer sm.spreadMutex.Unlock()
+	delete(sm.spreadElections, uid)
+}
+
+// servicesLed returns the number of services elections that this node leads
+func (sm *Manager) servicesLed() int {
+	sm.spreadMutex.Lock()
+	defer sm.spreadMutex.Unlock()
+	return len(sm.spreadElections)
+}
+
+// spreadServices advertises the number of services elections that this node leads and, if enabled,
+// periodically rebalances them across the nodes (and when a node joins)
+func (sm *Manager) spreadServices(ctx context.Context) {
+	timings := sm.serviceLeaseTimings(nil)
+	advertise := time.NewTicker(timings.leaseDuration)
+	defer advertise.Stop()
+
+	var rebalance <-chan time.Time
+	if sm.config.ServicesRebalanceInterval > 0 {
+		ticker := time.NewTicker(time.Duration(sm.config.ServicesRebalanceInterval) * time.Second)
+		defer ticker.Stop()
+		rebalance = ticker.C
+	}
+
+	// The nodes that have been seen, so that a node that joins is handed a share of the services
+	var known map[string]bool
+	joined := false
+
+	sm.advertiseLoad(ctx, timings.leaseDuration)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-advertise.C:
+			sm.advertiseLoad(ctx, timings.leaseDuration)
+			if sm.config.ServicesRebalanceOnJoin {
+				known, joined = sm.rebalanceOnJoin(ctx, known, joined)
+			}
+		case <-rebalance:
+			sm.rebalanceServices(ctx)
+		}
+	}
+}
+
+// advertiseLoad renews the spread lease of this node with the number of services elections that it leads
+func (sm *Manager) advertiseLoad(ctx context.Context, duration time.Duration) {
+	leases := sm.clientSet.CoordinationV1().Leases(sm.config.Namespace)
+	name := spreadLeasePrefix + sm.config.NodeName
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(duration.Seconds())
+	load := strconv.Itoa(sm.servicesLed())
+
+	lease, err := leases.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   sm.config.Namespace,
+				Annotations: map[string]string{spreadLoadAnnotation: load},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &sm.config.NodeName,
+				LeaseDurationSeconds: &seconds,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+	} else if err == nil {
+		if lease.Annotations == nil {
+			lease.Annotations = make(map[string]string)
+		}
+		lease.Annotations[spreadLoadAnnotation] = load
+		lease.Spec.HolderIdentity = &sm.config.NodeName
+		lease.Spec.LeaseDurationSeconds = &seconds
+		lease.Spec.RenewTime = &now
+		_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		log.Warnf("(svc spread) unable to advertise the services led by [%s]: %v", sm.config.NodeName, err)
+	}
+}
+
+// serviceLoads returns the number of services elections led by every node that is spreading services, a
+// node is left out once it hasn't advertised for three lease durations
+func (sm *Manager) serviceLoads(ctx context.Context) (map[string]int, error) {
+	leases, err := sm.clientSet.CoordinationV1().Leases(sm.config.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the spread leases: %w", err)
+	}
+	loads := make(map[string]int)
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if !strings.HasPrefix(lease.Name, spreadLeasePrefix) || lease.Spec.HolderIdentity == nil ||
+			lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		if time.Since(lease.Spec.RenewTime.Time) > 3*time.Duration(*lease.Spec.LeaseDurationSeconds)*time.Second {
+			continue
+		}
+		load, err := strconv.Atoi(lease.Annotations[spreadLoadAnnotation])
+		if err != nil {
+			continue
+		}
+		loads[*lease.Spec.HolderIdentity] = load
+	}
+	// The advertisement of this node may be behind
+	loads[sm.config.NodeName] = sm.servicesLed()
+	return loads, nil
+}
+
+// excessLoad returns how many more services elections this node leads than the node that leads the least
+func excessLoad(loads map[string]int, node string) int {
+	least := loads[node]
+	for _, load := range loads {
+		if load < least {
+			least = load
+		}
+	}
+	return loads[node] - least
+}
+
+// waitForSpread delays joining a services election by a retry period for every services election that this
+// node leads more than the node that leads the least, so that the less loaded nodes win it
+func (sm *Manager) waitForSpread(ctx context.Context, retryPeriod time.Duration) {
+	loads, err := sm.serviceLoads(ctx)
+	if err != nil {
+		log.Warnf("(svc spread) %v", err)
+		return
+	}
+	excess := excessLoad(loads, sm.config.NodeName)
+	if excess <= 0 {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Duration(excess) * retryPeriod):
+	}
+}
+
+// rebalanceOnJoin rebalances the services when a node has joined, then keeps rebalancing them at every
+// advertisement until this node leads at most one more than the node that leads the least
+func (sm *Manager) rebalanceOnJoin(ctx context.Context, known map[string]bool, joined bool) (map[string]bool, bool) {
+	loads, err := sm.serviceLoads(ctx)
+	if err != nil {
+		log.Warnf("(svc spread) %v", err)
+		return known, joined
+	}
+	nodes := make(map[string]bool, len(loads))
+	for node := range loads {
+		nodes[node] = true
+		// The nodes that are seen first (when this node starts) haven't joined
+		if known != nil && !known[node] {
+			log.Infof("(svc spread) node [%s] has joined, rebalancing the services", node)
+			joined = true
+		}
+	}
+	if !joined || excessLoad(loads, sm.config.NodeName) < 2 {
+		return nodes, false
+	}
+	sm.releaseExcess(loads)
+	return nodes, true
+}
+
+// rebalanceServices releases services elections whilst this node leads at least two more than the node that
+// leads the least, at most the maximum number of moves at a time
+func (sm *Manager) rebalanceServices(ctx context.Context) {
+	loads, err := sm.serviceLoads(ctx)
+	if err != nil {
+		log.Warnf("(svc spread) %v", err)
+		return
+	}
+	sm.releaseExcess(loads)
+}
+
+// releaseExcess releases half of the services elections that this node leads more than the node that leads
+// the least, at most the maximum number of moves
+func (sm *Manager) releaseExcess(loads map[string]int) {
+	moves := excessLoad(loads, sm.config.NodeName) / 2
+	if moves > sm.config.ServicesRebalanceMaxMoves {
+		moves = sm.config.ServicesRebalanceMaxMoves
+	}
+	if moves <= 0 {
+		return
+	}
+
+	sm.spreadMutex.Lock()
+	uids := make([]string, 0, len(sm.spreadElections))
+	for uid := range sm.spreadElections {
+		uids = append(uids, uid)
+	}
+	// Release the same services first, so that repeated rebalancing is predictable
+	sort.Strings(uids)
+	released := make([]*spreadElection, 0, moves)
+	for _, uid := range uids[:moves] {
+		released = append(released, sm.spreadElections[uid])
+	}
+	sm.spreadMutex.Unlock()
+
+	for _, election := range released {
+		log.Infof("(svc spread) releasing service [%s] to rebalance the services across the nodes", election.service)
+		election.release()
+	}
+}
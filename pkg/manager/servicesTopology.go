@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// zoneLabel is the well-known label of the zone of a node
+const zoneLabel = "topology.kubernetes.io/zone"
+
+// endpointNodes returns the number of ready endpoints of the service on every node
+func endpointNodes(endpoints *v1.Endpoints) map[string]int {
+	nodes := make(map[string]int)
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			if address.NodeName != nil {
+				nodes[*address.NodeName]++
+			}
+		}
+	}
+	return nodes
+}
+
+// majorityZone returns the zone with the most endpoints, ties are broken by the name of the zone
+func majorityZone(nodes map[string]int, zones map[string]string) string {
+	counts := make(map[string]int)
+	for node, count := range nodes {
+		if zone := zones[node]; zone != "" {
+			counts[zone] += count
+		}
+	}
+	majority := ""
+	for zone, count := range counts {
+		if count > counts[majority] || (count == counts[majority] && zone < majority) {
+			majority = zone
+		}
+	}
+	return majority
+}
+
+// topologyPreference returns how far down this node is in the preference to lead the service, 0 if it has ready
+// local endpoints, 1 if it is in the zone with the most endpoints and 2 otherwise
+func (sm *Manager) topologyPreference(ctx context.Context, service *v1.Service) (int, error) {
+	endpoints, err := sm.clientSet.CoreV1().Endpoints(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	nodes := endpointNodes(endpoints)
+	if nodes[sm.config.NodeName] > 0 {
+		return 0, nil
+	}
+
+	zones := make(map[string]string)
+	for name := range nodes {
+		node, err := sm.clientSet.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		zones[name] = node.Labels[zoneLabel]
+	}
+	self, err := sm.clientSet.CoreV1().Nodes().Get(ctx, sm.config.NodeName, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	if zone := self.Labels[zoneLabel]; zone != "" && zone == majorityZone(nodes, zones) {
+		return 1, nil
+	}
+	return 2, nil
+}
+
+// waitForTopology delays joining the election of the service by two retry periods for every step down in the
+// topology preference, so that the nodes with ready local endpoints (and then the nodes in the same zone as
+// most of the endpoints) win it
+func (sm *Manager) waitForTopology(ctx context.Context, service *v1.Service, retryPeriod time.Duration) {
+	preference, err := sm.topologyPreference(ctx, service)
+	if err != nil {
+		log.Warnf("(svc election) unable to find the topology of the endpoints of %s/%s: %v", service.Namespace, service.Name, err)
+		return
+	}
+	if preference == 0 {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Duration(preference) * 2 * retryPeriod):
+	}
+}
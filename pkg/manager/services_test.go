@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDeleteServiceVLAN(t *testing.T) {
+	instance := func(uid, address, vlan string, created bool) *Instance {
+		return &Instance{
+			UID:             uid,
+			VIPs:            []string{address},
+			vipConfigs:      []*kubevip.Config{{VIP: address, VIPCIDR: "32", EnableBGP: true}},
+			vlanInterface:   vlan,
+			vlanCreated:     created,
+			serviceSnapshot: &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: uid, UID: types.UID(uid)}},
+		}
+	}
+
+	// The sub-interface that kube-vip created is handed over to the other service on it
+	backend := &withdrawnHosts{}
+	shared := instance("api", "192.0.2.2", "kube-vip-test.10", false)
+	sm := &Manager{bgpServer: backend, serviceInstances: []*Instance{instance("web", "192.0.2.1", "kube-vip-test.10", true), shared}}
+	if err := sm.deleteService("web"); err != nil {
+		t.Fatal(err)
+	}
+	if !shared.vlanCreated {
+		t.Error("the VLAN interface should be handed over to the service that shares it")
+	}
+
+	// A sub-interface that can't be deleted doesn't stop the service from being withdrawn
+	backend = &withdrawnHosts{}
+	sm = &Manager{bgpServer: backend, serviceInstances: []*Instance{instance("web", "192.0.2.1", "kube-vip-test-overlong.10", true)}}
+	if err := sm.deleteService("web"); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.deleted) != 1 || len(sm.serviceInstances) != 0 {
+		t.Errorf("withdrawn hosts = %v and %d instances remain, want the service to be withdrawn", backend.deleted, len(sm.serviceInstances))
+	}
+}
+
+func TestSyncServicesEgressRemoved(t *testing.T) {
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "web",
+		Annotations: map[string]string{loadbalancerIPAnnotation: "192.0.2.1", egress: "true"}}}
+	c := &namespaceEgress{services: map[string]*namespaceEgressService{
+		"default/web": {namespace: "default", name: "web", pods: map[string]struct{}{}},
+	}}
+	sm := &Manager{config: &kubevip.Config{}, namespaceEgressCache: c, serviceInstances: []*Instance{{UID: "web", serviceSnapshot: svc}}}
+	sm.namespaceEgressOnce.Do(func() {})
+
+	// The egress of the namespace is kept while the service egresses with its VIP
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := sm.syncServices(context.Background(), svc, &wg); err != nil {
+		t.Fatal(err)
+	}
+	if c.services["default/web"] == nil {
+		t.Fatal("the egress of the namespace should be kept while the service has egress enabled")
+	}
+
+	removed := svc.DeepCopy()
+	delete(removed.Annotations, egress)
+	wg.Add(1)
+	if err := sm.syncServices(context.Background(), removed, &wg); err != nil {
+		t.Fatal(err)
+	}
+	if c.services["default/web"] != nil {
+		t.Error("the egress of the namespace should be torn down once the egress annotation is removed")
+	}
+}
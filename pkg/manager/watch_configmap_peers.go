@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kube-vip/kube-vip/pkg/bgp"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// bgpPeersConfigMapKey is the key within the ConfigMap that holds the BGP peers, it uses the
+// same format as the bgp_peers environment variable (address:AS:password:multihop:bfd,...)
+const bgpPeersConfigMapKey = "bgp-peers"
+
+// parseConfigMapPeers will return the BGP peers that are defined in a ConfigMap
+func (sm *Manager) parseConfigMapPeers(cm *v1.ConfigMap) ([]bgp.Peer, error) {
+	data := cm.Data[bgpPeersConfigMapKey]
+	if data == "" {
+		return nil, fmt.Errorf("configmap [%s/%s] has no BGP peers defined in key [%s]", cm.Namespace, cm.Name, bgpPeersConfigMapKey)
+	}
+
+	peers, err := bgp.ParseBGPPeerConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing key [%s] in configmap [%s/%s]: %w", bgpPeersConfigMapKey, cm.Namespace, cm.Name, err)
+	}
+
+	sm.applyPeerDefaults(peers)
+	return peers, nil
+}
+
+// configMapPeersWatcher watches the peers ConfigMap and reconfigures the BGP server when it changes, the
+// sessions with unchanged peers are left alone
+func (sm *Manager) configMapPeersWatcher(ctx context.Context) error {
+	opts := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", sm.config.BGPPeersConfigMap).String(),
+	}
+
+	rw, err := watchtools.NewRetryWatcher("1", &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return sm.clientSet.CoreV1().ConfigMaps(sm.config.Namespace).Watch(ctx, opts)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating configmap peers watcher: %s", err.Error())
+	}
+
+	go func() {
+		<-ctx.Done()
+		log.Debug("[BGP] configmap peers watcher context cancelled")
+		rw.Stop()
+	}()
+
+	for event := range rw.ResultChan() {
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			cm, ok := event.Object.(*v1.ConfigMap)
+			if !ok {
+				return fmt.Errorf("unable to parse Kubernetes ConfigMap from API watcher")
+			}
+			peers, err := sm.parseConfigMapPeers(cm)
+			if err != nil {
+				log.Errorf("[BGP] %v", err)
+				continue
+			}
+			if err = sm.resolveBGPPasswords(ctx, peers); err != nil {
+				log.Errorf("[BGP] %v", err)
+				continue
+			}
+
+			server, err := sm.gobgpServer()
+			if err != nil {
+				return err
+			}
+			sm.mutex.Lock()
+			err = server.SyncPeers(peers)
+			sm.mutex.Unlock()
+			if err != nil {
+				log.Errorf("[BGP] unable to update peers from configmap [%s/%s]: %v", cm.Namespace, cm.Name, err)
+			}
+			// The Secrets of the peers that have been added are watched, so that their passwords can be rotated
+			if err = sm.syncBGPSecretWatchers(ctx, peers); err != nil {
+				log.Errorf("[BGP] %v", err)
+			}
+		case watch.Deleted:
+			log.Warnf("[BGP] peers configmap [%s/%s] has been deleted, peers will be left unchanged", sm.config.Namespace, sm.config.BGPPeersConfigMap)
+		case watch.Error:
+			log.Errorf("[BGP] error attempting to watch configmap [%s/%s]", sm.config.Namespace, sm.config.BGPPeersConfigMap)
+		}
+	}
+	log.Debug("[BGP] exiting configmap peers watcher")
+	return nil
+}
@@ -4,33 +4,47 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/jpillora/backoff"
 	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
-	discoveryv1 "k8s.io/api/discovery/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 	watchtools "k8s.io/client-go/tools/watch"
 	"k8s.io/client-go/util/retry"
 )
 
+const (
+	// electionRestartMin and electionRestartMax are the bounds of the backoff between the restarts of the election
+	// of a service that has failed, an election that ran for longer than the maximum restarts without a backoff
+	electionRestartMin = time.Second
+	electionRestartMax = time.Minute
+)
+
 type epProvider interface {
 	createRetryWatcher(context.Context, *Manager,
-		*v1.Service) (*watchtools.RetryWatcher, error)
+		*v1.Service) (watch.Interface, error)
 	getAllEndpoints() ([]string, error)
+	getTotalEndpoints() int
 	getLocalEndpoints(string, *kubevip.Config) ([]string, error)
+	getTargetPort(v1.ServicePort) (int32, error)
 	getLabel() string
 	updateServiceAnnotation(string, string, *v1.Service, *Manager) error
 	loadObject(runtime.Object, context.CancelFunc) error
-	getProtocol() string
+	unloadObject(runtime.Object) bool
 }
 
 type endpointsProvider struct {
@@ -39,7 +53,7 @@ type endpointsProvider struct {
 }
 
 func (ep *endpointsProvider) createRetryWatcher(ctx context.Context, sm *Manager,
-	service *v1.Service) (*watchtools.RetryWatcher, error) {
+	service *v1.Service) (watch.Interface, error) {
 	opts := metav1.ListOptions{
 		FieldSelector: fields.OneTermEqualSelector("metadata.name", service.Name).String(),
 	}
@@ -79,6 +93,15 @@ func (ep *endpointsProvider) getAllEndpoints() ([]string, error) {
 	return result, nil
 }
 
+// getTotalEndpoints returns the number of endpoints, whether they are ready or not
+func (ep *endpointsProvider) getTotalEndpoints() int {
+	total := 0
+	for _, subset := range ep.endpoints.Subsets {
+		total += len(subset.Addresses) + len(subset.NotReadyAddresses)
+	}
+	return total
+}
+
 func (ep *endpointsProvider) getLocalEndpoints(id string, _ *kubevip.Config) ([]string, error) {
 	var localEndpoints []string
 
@@ -103,6 +126,25 @@ func (ep *endpointsProvider) getLocalEndpoints(id string, _ *kubevip.Config) ([]
 	return localEndpoints, nil
 }
 
+// getTargetPort returns the port of the endpoints that a port of the service targets, the ports of the subsets are
+// named after the port of the service
+func (ep *endpointsProvider) getTargetPort(servicePort v1.ServicePort) (int32, error) {
+	return targetPort(servicePort, func(name string, protocol v1.Protocol) []int32 {
+		var ports []int32
+		for _, subset := range ep.endpoints.Subsets {
+			if len(subset.Addresses) == 0 {
+				continue
+			}
+			for _, port := range subset.Ports {
+				if port.Name == name && port.Protocol == protocol {
+					ports = append(ports, port.Port)
+				}
+			}
+		}
+		return ports
+	})
+}
+
 func (ep *endpointsProvider) updateServiceAnnotation(endpoint string, _ string, service *v1.Service, sm *Manager) error {
 	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		// Retrieve the latest version of Deployment before attempting update
@@ -138,18 +180,108 @@ func (ep *endpointsProvider) getLabel() string {
 	return ep.label
 }
 
-func (ep *endpointsProvider) getProtocol() string {
-	return ""
+// unloadObject reports the deletion of the endpoints, a service only has the one Endpoints object
+func (ep *endpointsProvider) unloadObject(_ runtime.Object) bool {
+	return false
+}
+
+// targetPort returns the port that a port of the service targets, a named target port is resolved from the ports
+// of the endpoints, which all need to resolve it to the same port
+func targetPort(servicePort v1.ServicePort, endpointPorts func(name string, protocol v1.Protocol) []int32) (int32, error) {
+	if servicePort.TargetPort.Type == intstr.Int {
+		// The target port defaults to the port of the service
+		if servicePort.TargetPort.IntVal == 0 {
+			return servicePort.Port, nil
+		}
+		return servicePort.TargetPort.IntVal, nil
+	}
+	protocol := servicePort.Protocol
+	if protocol == "" {
+		protocol = v1.ProtocolTCP
+	}
+	var resolved int32
+	for _, port := range endpointPorts(servicePort.Name, protocol) {
+		if resolved != 0 && port != resolved {
+			return 0, fmt.Errorf("target port [%s] resolves to ports [%d] and [%d] on the endpoints", servicePort.TargetPort.StrVal, resolved, port)
+		}
+		resolved = port
+	}
+	if resolved == 0 {
+		return 0, fmt.Errorf("target port [%s] isn't resolved by any of the endpoints", servicePort.TargetPort.StrVal)
+	}
+	return resolved, nil
+}
+
+// serviceMinReadyEndpoints returns the number of ready endpoints that a service requires before it is announced,
+// and whether they are counted on this node only. A service with the Local traffic policy only delivers traffic to
+// the endpoints on the node, the endpoints of any other service are counted across the cluster. The older
+// kube-vip.io/bgp-min-ready-endpoints annotation always counts the endpoints on the node.
+func serviceMinReadyEndpoints(service *v1.Service, c *kubevip.Config) (int, bool) {
+	local := service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal
+	annotation := minReadyEndpoints
+	value := service.Annotations[minReadyEndpoints]
+	if value == "" && service.Annotations[bgpMinReadyEndpoints] != "" {
+		annotation = bgpMinReadyEndpoints
+		value = service.Annotations[bgpMinReadyEndpoints]
+		local = true
+	}
+	if value == "" {
+		return c.ServicesMinReadyEndpoints, local
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		log.Warnf("ignoring invalid annotation [%s] for %s/%s: [%s]", annotation, service.Namespace, service.Name, value)
+		return c.ServicesMinReadyEndpoints, local
+	}
+	return count, local
+}
+
+// serviceLocalFallback returns whether a service with the Local traffic policy falls back to the endpoints of the
+// cluster whilst none of its endpoints are ready on this node, the node is then announced as long as any endpoint is
+// ready (the service proxy must forward the traffic to the other nodes) until a local endpoint is ready again.
+func serviceLocalFallback(service *v1.Service) bool {
+	return service.Annotations[localFallback] == "true"
+}
+
+// updateEndpointMetrics sets the gauges of the total, ready and local endpoints of the service
+func (sm *Manager) updateEndpointMetrics(provider epProvider, service *v1.Service, id string) error {
+	ready, err := provider.getAllEndpoints()
+	if err != nil {
+		return err
+	}
+	local, err := provider.getLocalEndpoints(id, sm.config)
+	if err != nil {
+		return err
+	}
+	key := service.Namespace + "/" + service.Name
+	sm.serviceEndpointsGauge.With(prometheus.Labels{"service": key, "scope": "total"}).Set(float64(provider.getTotalEndpoints()))
+	sm.serviceEndpointsGauge.With(prometheus.Labels{"service": key, "scope": "ready"}).Set(float64(len(ready)))
+	sm.serviceEndpointsGauge.With(prometheus.Labels{"service": key, "scope": "local"}).Set(float64(len(local)))
+	return nil
+}
+
+// deleteEndpointMetrics removes the endpoint gauges of a service that is no longer watched
+func (sm *Manager) deleteEndpointMetrics(service *v1.Service) {
+	for _, scope := range []string{"total", "ready", "local"} {
+		sm.serviceEndpointsGauge.Delete(prometheus.Labels{"service": service.Namespace + "/" + service.Name, "scope": scope})
+	}
 }
 
 func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Service, wg *sync.WaitGroup, provider epProvider) error {
 	log.Infof("[%s] watching for service [%s] in namespace [%s]", provider.getLabel(), service.Name, service.Namespace)
+	defer sm.deleteEndpointMetrics(service)
+	sm.endpointsProviders.Store(string(service.UID), provider)
+	defer sm.endpointsProviders.Delete(string(service.UID))
 	// Use a restartable watcher, as this should help in the event of etcd or timeout issues
 	leaderContext, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	var leaderElectionActive bool
 
+	minReady, localOnly := serviceMinReadyEndpoints(service, sm.config)
+	fallback := localOnly && serviceLocalFallback(service)
+	var fallenBack bool
+
 	rw, err := provider.createRetryWatcher(leaderContext, sm, service)
 	if err != nil {
 		cancel()
@@ -186,25 +318,33 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 	ch := rw.ResultChan()
 
 	var lastKnownGoodEndpoint string
+	var announcedFamilies []v1.IPFamily
 	for event := range ch {
-		activeEndpointAnnotation := activeEndpoint
+		// The endpoints of the service are only deleted once none of its objects remain, the deletion of one of
+		// its EndpointSlices (e.g. of an IP family) is an update of its endpoints
+		eventType := event.Type
+		if eventType == watch.Deleted && provider.unloadObject(event.Object) {
+			eventType = watch.Modified
+		}
 		// We need to inspect the event and get ResourceVersion out of it
-		switch event.Type {
+		switch eventType {
 
 		case watch.Added, watch.Modified:
 
-			if err = provider.loadObject(event.Object, cancel); err != nil {
-				return fmt.Errorf("[%s] error loading k8s object: %w", provider.getLabel(), err)
+			if event.Type != watch.Deleted {
+				if err = provider.loadObject(event.Object, cancel); err != nil {
+					return fmt.Errorf("[%s] error loading k8s object: %w", provider.getLabel(), err)
+				}
 			}
 
-			if sm.config.EnableEndpointSlices && provider.getProtocol() == string(discoveryv1.AddressTypeIPv6) {
-				activeEndpointAnnotation = activeEndpointIPv6
+			if err = sm.updateEndpointMetrics(provider, service, id); err != nil {
+				log.Errorf("[%s] error updating the endpoint metrics of service %s/%s: %v", provider.getLabel(), service.Namespace, service.Name, err)
 			}
 
 			// Build endpoints
 			var endpoints []string
-			if (sm.config.EnableBGP || sm.config.EnableRoutingTable) && !sm.config.EnableLeaderElection && !sm.config.EnableServicesElection &&
-				service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeCluster {
+			local := localOnly
+			if !localOnly {
 				if endpoints, err = provider.getAllEndpoints(); err != nil {
 					return fmt.Errorf("[%s] error getting all endpoints: %w", provider.getLabel(), err)
 				}
@@ -214,6 +354,39 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 				}
 			}
 
+			// Without any ready local endpoints the service may fall back to the endpoints of the cluster
+			if fallback && len(endpoints) == 0 {
+				var all []string
+				if all, err = provider.getAllEndpoints(); err != nil {
+					return fmt.Errorf("[%s] error getting all endpoints: %w", provider.getLabel(), err)
+				}
+				if len(all) != 0 {
+					endpoints = all
+					local = false
+				}
+			}
+			if fallback && fallenBack == local {
+				fallenBack = !local
+				if fallenBack {
+					log.Warnf("[%s] service %s/%s has no ready local endpoints, falling back to the [%d] ready endpoint(s) of the cluster",
+						provider.getLabel(), service.Namespace, service.Name, len(endpoints))
+				} else {
+					log.Infof("[%s] service %s/%s is restoring the local scope of its endpoints",
+						provider.getLabel(), service.Namespace, service.Name)
+				}
+			}
+
+			// The service VIP is only announced whilst there are enough ready endpoints to serve it
+			if len(endpoints) < minReady {
+				scope := "cluster"
+				if local {
+					scope = "local"
+				}
+				log.Infof("[%s] service %s/%s has [%d] ready %s endpoint(s), [%d] are required to announce it",
+					provider.getLabel(), service.Namespace, service.Name, len(endpoints), scope, minReady)
+				endpoints = nil
+			}
+
 			// Find out if we have any local endpoints
 			// if out endpoint is empty then populate it
 			// if not, go through the endpoints and see if ours still exists
@@ -233,8 +406,11 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 							stillExists = true
 						}
 					}
-					// If the last endpoint no longer exists, we cancel our leader Election
-					if !stillExists && leaderElectionActive {
+					// If the last endpoint no longer exists, we cancel our leader Election, unless the endpoints are
+					// counted across the cluster as the election then doesn't depend on any one of them
+					if !stillExists && leaderElectionActive && !local && sm.config.EnableServicesElection {
+						lastKnownGoodEndpoint = endpoints[0]
+					} else if !stillExists && leaderElectionActive {
 						if sm.config.EnableServicesElection || sm.config.EnableLeaderElection {
 							log.Warnf("[%s] existing [%s] has been removed, restarting leaderElection", provider.getLabel(), lastKnownGoodEndpoint)
 							// Stop the existing leaderElection
@@ -250,25 +426,48 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 					lastKnownGoodEndpoint = endpoints[0]
 				}
 
-				// Set the service accordingly
+				// Set the service accordingly, with the active endpoint of each IP family
 				if service.Annotations[egress] == "true" {
-					service.Annotations[activeEndpointAnnotation] = lastKnownGoodEndpoint
+					for _, family := range endpointFamilies(endpoints) {
+						annotation := activeEndpoint
+						if sm.config.EnableEndpointSlices && family == v1.IPv6Protocol {
+							annotation = activeEndpointIPv6
+						}
+						service.Annotations[annotation] = familyEndpoint(family, lastKnownGoodEndpoint, endpoints)
+					}
 				}
 
 				if !leaderElectionActive && sm.config.EnableServicesElection {
 					go func() {
 						leaderContext, cancel = context.WithCancel(context.Background())
 
-						// This is a blocking function, that will restart (in the event of failure)
+						// This is a blocking function, that will restart (in the event of failure) with a backoff, so that
+						// a service that keeps failing doesn't spin
+						restarts := backoff.Backoff{Factor: 2, Jitter: true, Min: electionRestartMin, Max: electionRestartMax}
 						for {
 							// if the context isn't cancelled restart
 							if leaderContext.Err() != context.Canceled {
 								leaderElectionActive = true
+								started := time.Now()
 								err := sm.StartServicesLeaderElection(leaderContext, service, wg)
 								if err != nil {
 									log.Error(err)
 								}
 								leaderElectionActive = false
+								if leaderContext.Err() != nil {
+									continue
+								}
+								if time.Since(started) > electionRestartMax {
+									restarts.Reset()
+								}
+								delay := restarts.Duration()
+								sm.countServiceRestarts.With(prometheus.Labels{"service": service.Namespace + "/" + service.Name}).Inc()
+								log.Warnf("(svc election) the election of service [%s/%s] has stopped, restarting it in %s (attempt %.0f)",
+									service.Namespace, service.Name, delay, restarts.Attempt())
+								select {
+								case <-time.After(delay):
+								case <-leaderContext.Done():
+								}
 							} else {
 								leaderElectionActive = false
 								break
@@ -277,10 +476,18 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 					}()
 				}
 
-				isRouteConfigured, err := isRouteConfigured(service.UID)
+				isRouteConfigured, err := sm.isRouteConfigured(service.UID)
 				if err != nil {
 					return fmt.Errorf("[%s] error while checking if route is configured: %w", provider.getLabel(), err)
 				}
+				// The addresses of a dual-stack service are only announced for the IP families that have endpoints,
+				// those of a family that no longer has any are withdrawn
+				families := announceFamilies(service, endpoints)
+				if !sm.config.EnableServicesElection && !sm.config.EnableLeaderElection && isRouteConfigured && !slices.Equal(families, announcedFamilies) {
+					sm.withdrawFamilies(service, families)
+					isRouteConfigured = false
+				}
+				announcedFamilies = families
 				// There are local endpoints available on the node
 				if !sm.config.EnableServicesElection && !sm.config.EnableLeaderElection && !isRouteConfigured {
 					// If routing table mode is enabled - routes should be added per node
@@ -288,6 +495,9 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 						if instance := sm.findServiceInstance(service); instance != nil {
 							for _, cluster := range instance.clusters {
 								for i := range cluster.Network {
+									if !slices.Contains(families, addressFamily(cluster.Network[i].IP())) {
+										continue
+									}
 									err := cluster.Network[i].AddRoute()
 									if err != nil {
 										if errors.Is(err, syscall.EEXIST) {
@@ -305,8 +515,8 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 										}
 									} else {
 										log.Infof("[%s] added route: %s, service: %s/%s, interface: %s, table: %d",
-											provider.getLabel(), cluster.Network[i].IP(), service.Namespace, service.Name, cluster.Network[i].Interface(), sm.config.RoutingTableID)
-										configuredLocalRoutes.Store(string(service.UID), true)
+											provider.getLabel(), cluster.Network[i].IP(), service.Namespace, service.Name, cluster.Network[i].Interface(), cluster.Network[i].PrepareRoute().Table)
+										sm.configuredLocalRoutes.Store(string(service.UID), true)
 										leaderElectionActive = true
 									}
 								}
@@ -317,17 +527,20 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 					// If BGP mode is enabled - hosts should be added per node
 					if sm.config.EnableBGP {
 						if instance := sm.findServiceInstance(service); instance != nil {
-							for _, cluster := range instance.clusters {
+							for x, cluster := range instance.clusters {
 								for i := range cluster.Network {
+									if !slices.Contains(families, addressFamily(cluster.Network[i].IP())) {
+										continue
+									}
 									address := fmt.Sprintf("%s/%s", cluster.Network[i].IP(), sm.config.VIPCIDR)
 									log.Debugf("[%s] attempting to advertise BGP service: %s", provider.getLabel(), address)
-									err := sm.bgpServer.AddHost(address)
+									err := sm.bgpServer.AddHostWithAttributes(address, &instance.vipConfigs[x].BGPPathAttributes)
 									if err != nil {
 										log.Errorf("[%s] error adding BGP host %s\n", err.Error(), provider.getLabel())
 									} else {
 										log.Infof("[%s] added BGP host: %s, service: %s/%s",
 											provider.getLabel(), address, service.Namespace, service.Name)
-										configuredLocalRoutes.Store(string(service.UID), true)
+										sm.configuredLocalRoutes.Store(string(service.UID), true)
 										leaderElectionActive = true
 									}
 								}
@@ -336,12 +549,13 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 					}
 				}
 			} else {
+				announcedFamilies = nil
 				// There are no local enpoints
 				if !sm.config.EnableServicesElection && !sm.config.EnableLeaderElection {
 					// If routing table mode is enabled - routes should be deleted
 					if sm.config.EnableRoutingTable {
 						if errs := sm.clearRoutes(service); len(errs) == 0 {
-							configuredLocalRoutes.Store(string(service.UID), false)
+							sm.configuredLocalRoutes.Store(string(service.UID), false)
 						}
 					}
 
@@ -357,7 +571,7 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 									} else {
 										log.Infof("[%s] deleted BGP host: %s, service: %s/%s",
 											provider.getLabel(), address, service.Namespace, service.Name)
-										configuredLocalRoutes.Store(string(service.UID), false)
+										sm.configuredLocalRoutes.Store(string(service.UID), false)
 										leaderElectionActive = false
 									}
 								}
@@ -376,6 +590,13 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 					leaderElectionActive = false
 				}
 			}
+			// The routes are balanced across the ready local endpoints
+			if sm.config.EnableRoutingTable && sm.config.RoutingTableECMP {
+				if err := sm.updateNextHops(provider, service, id); err != nil {
+					log.Errorf("[%s] error updating next-hops for service %s/%s: %v", provider.getLabel(), service.Namespace, service.Name, err)
+				}
+			}
+
 			log.Debugf("[%s watcher] service %s/%s: local endpoint(s) [%d], known good [%s], active election [%t]",
 				provider.getLabel(), service.Namespace, service.Name, len(endpoints), lastKnownGoodEndpoint, leaderElectionActive)
 
@@ -425,6 +646,33 @@ func (sm *Manager) watchEndpoint(ctx context.Context, id string, service *v1.Ser
 	return nil //nolint:govet
 }
 
+// updateNextHops sets the ready local endpoints as the next-hops of the routes of the service, of the IP family of
+// each route
+func (sm *Manager) updateNextHops(provider epProvider, service *v1.Service, id string) error {
+	endpoints, err := provider.getLocalEndpoints(id, sm.config)
+	if err != nil {
+		return err
+	}
+	instance := sm.findServiceInstance(service)
+	if instance == nil {
+		return nil
+	}
+	for _, cluster := range instance.clusters {
+		for i := range cluster.Network {
+			var hops []string
+			for _, endpoint := range endpoints {
+				if addressFamily(endpoint) == addressFamily(cluster.Network[i].IP()) {
+					hops = append(hops, endpoint)
+				}
+			}
+			if err = cluster.Network[i].SetNextHops(hops); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (sm *Manager) clearRoutes(service *v1.Service) []error {
 	errs := []error{}
 	if instance := sm.findServiceInstance(service); instance != nil {
@@ -439,7 +687,7 @@ func (sm *Manager) clearRoutes(service *v1.Service) []error {
 						errs = append(errs, err)
 					}
 					log.Debugf("deleted route: %s, service: %s/%s, interface: %s, table: %d",
-						cluster.Network[i].IP(), service.Namespace, service.Name, cluster.Network[i].Interface(), sm.config.RoutingTableID)
+						cluster.Network[i].IP(), service.Namespace, service.Name, cluster.Network[i].Interface(), cluster.Network[i].PrepareRoute().Table)
 				}
 			}
 		}
@@ -447,6 +695,38 @@ func (sm *Manager) clearRoutes(service *v1.Service) []error {
 	return errs
 }
 
+// withdrawFamilies withdraws the routes (or BGP paths) of the addresses of a service that aren't of the IP families
+func (sm *Manager) withdrawFamilies(service *v1.Service, families []v1.IPFamily) {
+	instance := sm.findServiceInstance(service)
+	if instance == nil {
+		return
+	}
+	for _, cluster := range instance.clusters {
+		for i := range cluster.Network {
+			if slices.Contains(families, addressFamily(cluster.Network[i].IP())) {
+				continue
+			}
+			if sm.config.EnableRoutingTable && sm.countRouteReferences(cluster.Network[i].PrepareRoute()) <= 1 {
+				if err := cluster.Network[i].DeleteRoute(); err != nil && !errors.Is(err, syscall.ESRCH) {
+					log.Errorf("failed to delete route for %s: %s", cluster.Network[i].IP(), err.Error())
+				} else {
+					log.Infof("[endpoint] deleted route: %s, service: %s/%s, as there are no endpoints of its family",
+						cluster.Network[i].IP(), service.Namespace, service.Name)
+				}
+			}
+			if sm.config.EnableBGP {
+				address := fmt.Sprintf("%s/%s", cluster.Network[i].IP(), sm.config.VIPCIDR)
+				if err := sm.bgpServer.DelHost(address); err != nil {
+					log.Errorf("[endpoint] error deleting BGP host %s\n", err.Error())
+				} else {
+					log.Infof("[endpoint] deleted BGP host: %s, service: %s/%s, as there are no endpoints of its family",
+						address, service.Namespace, service.Name)
+				}
+			}
+		}
+	}
+}
+
 func (sm *Manager) clearBGPHosts(service *v1.Service) {
 	if instance := sm.findServiceInstance(service); instance != nil {
 		for _, cluster := range instance.clusters {
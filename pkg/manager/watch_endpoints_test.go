@@ -0,0 +1,56 @@
+package manager
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestGetTargetPort(t *testing.T) {
+	http, metrics, protocol := "http", "metrics", v1.ProtocolTCP
+	port := func(number int32) *int32 { return &number }
+	endpoints := &endpointsProvider{label: "endpoints", endpoints: &v1.Endpoints{Subsets: []v1.EndpointSubset{{
+		Addresses: []v1.EndpointAddress{{IP: "10.0.0.1"}},
+		Ports:     []v1.EndpointPort{{Name: http, Port: 8080, Protocol: protocol}, {Name: metrics, Port: 9090, Protocol: protocol}},
+	}}}}
+	slice := func(name string, number int32) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Endpoints:  []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.1"}}},
+			Ports:      []discoveryv1.EndpointPort{{Name: &http, Port: port(number), Protocol: &protocol}, {Name: &metrics, Port: port(9090)}},
+		}
+	}
+	endpointslices := &endpointslicesProvider{label: "endpointslices", slices: map[string]*discoveryv1.EndpointSlice{
+		"web-ipv4": slice("web-ipv4", 8080), "web-ipv6": slice("web-ipv6", 8080),
+	}}
+	differing := &endpointslicesProvider{label: "endpointslices", slices: map[string]*discoveryv1.EndpointSlice{
+		"web-a": slice("web-a", 8080), "web-b": slice("web-b", 8081),
+	}}
+
+	tests := []struct {
+		name     string
+		provider epProvider
+		port     v1.ServicePort
+		want     int32
+		wantErr  bool
+	}{
+		{"numeric", endpoints, v1.ServicePort{Name: http, Port: 80, TargetPort: intstr.FromInt32(8888)}, 8888, false},
+		{"defaults to the port", endpoints, v1.ServicePort{Name: http, Port: 80}, 80, false},
+		{"named from the endpoints", endpoints, v1.ServicePort{Name: metrics, Port: 90, TargetPort: intstr.FromString("metrics")}, 9090, false},
+		{"named from the endpointslices", endpointslices, v1.ServicePort{Name: http, Port: 80, TargetPort: intstr.FromString("web")}, 8080, false},
+		{"other protocol", endpointslices, v1.ServicePort{Name: http, Port: 80, Protocol: v1.ProtocolUDP, TargetPort: intstr.FromString("web")}, 0, true},
+		{"not resolved", endpoints, v1.ServicePort{Name: "grpc", Port: 81, TargetPort: intstr.FromString("grpc")}, 0, true},
+		{"resolved differently", differing, v1.ServicePort{Name: http, Port: 80, TargetPort: intstr.FromString("web")}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.provider.getTargetPort(tt.port)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("getTargetPort() = %d, %v, want %d (error %t)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
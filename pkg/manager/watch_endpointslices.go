@@ -3,43 +3,39 @@ package manager
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/kube-vip/kube-vip/pkg/kubevip"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/tools/cache"
-	watchtools "k8s.io/client-go/tools/watch"
 	"k8s.io/client-go/util/retry"
 )
 
+// endpointslicesProvider merges the EndpointSlices of a service (there is a slice per IP family, and large services
+// are split across several slices) into the endpoints of the service
 type endpointslicesProvider struct {
 	label     string
 	endpoints *discoveryv1.EndpointSlice
+	slices    map[string]*discoveryv1.EndpointSlice
 }
 
+// createRetryWatcher watches the EndpointSlices of the service through the informer that is shared by every
+// service, rather than with a watch of its own
 func (ep *endpointslicesProvider) createRetryWatcher(ctx context.Context, sm *Manager,
-	service *v1.Service) (*watchtools.RetryWatcher, error) {
-	labelSelector := metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/service-name": service.Name}}
-
-	opts := metav1.ListOptions{
-		LabelSelector: labels.Set(labelSelector.MatchLabels).String(),
+	service *v1.Service) (watch.Interface, error) {
+	slices, err := sm.endpointSlices()
+	if err != nil {
+		return nil, fmt.Errorf("[%s] error creating endpointslices watcher: %w", ep.label, err)
 	}
-
-	rw, err := watchtools.NewRetryWatcher("1", &cache.ListWatch{
-		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-			return sm.clientSet.DiscoveryV1().EndpointSlices(service.Namespace).Watch(ctx, opts)
-		},
-	})
+	w, err := slices.watch(ctx, service)
 	if err != nil {
-		return nil, fmt.Errorf("[%s] error creating endpointslices watcher: %s", ep.label, err.Error())
+		return nil, fmt.Errorf("[%s] error creating endpointslices watcher: %w", ep.label, err)
 	}
-
-	return rw, nil
+	return w, nil
 }
 
 func (ep *endpointslicesProvider) loadObject(endpoints runtime.Object, cancel context.CancelFunc) error {
@@ -48,23 +44,101 @@ func (ep *endpointslicesProvider) loadObject(endpoints runtime.Object, cancel co
 		cancel()
 		return fmt.Errorf("[%s] error casting endpoints to v1.Endpoints struct", ep.label)
 	}
-	ep.endpoints = eps
+	if ep.slices == nil {
+		ep.slices = make(map[string]*discoveryv1.EndpointSlice)
+	}
+	ep.slices[eps.Name] = eps
+	ep.mergeSlices()
 	return nil
 }
 
+// unloadObject removes a deleted slice whilst the service has any other slice, the endpoints of its last slice are
+// kept so that they can be withdrawn once it is deleted
+func (ep *endpointslicesProvider) unloadObject(endpoints runtime.Object) bool {
+	eps, ok := endpoints.(*discoveryv1.EndpointSlice)
+	if _, found := ep.slices[eps.Name]; !ok || !found || len(ep.slices) == 1 {
+		return false
+	}
+	delete(ep.slices, eps.Name)
+	ep.mergeSlices()
+	return true
+}
+
+// mergeSlices sets the endpoints of every slice of the service, ordered by the name of the slices
+func (ep *endpointslicesProvider) mergeSlices() {
+	names := make([]string, 0, len(ep.slices))
+	for name := range ep.slices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	merged := &discoveryv1.EndpointSlice{}
+	for _, name := range names {
+		merged.Endpoints = append(merged.Endpoints, ep.slices[name].Endpoints...)
+	}
+	ep.endpoints = merged
+}
+
+// endpointConditions returns whether an endpoint is serving and whether it is terminating. An endpoint whose
+// conditions are unknown is serving, as the API defines them as ready when they aren't set.
+func endpointConditions(endpoint discoveryv1.Endpoint) (serving, terminating bool) {
+	serving = endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+	if endpoint.Conditions.Serving != nil {
+		serving = *endpoint.Conditions.Serving
+	}
+	terminating = endpoint.Conditions.Terminating != nil && *endpoint.Conditions.Terminating
+	return serving, terminating
+}
+
+// servingEndpoints returns the serving endpoints of the slice, those that are terminating are only returned
+// when there are no others, so that the service is still announced whilst its last endpoints drain their
+// connections (e.g. during a rollout) rather than being withdrawn as soon as they start terminating
+func servingEndpoints(endpoints []discoveryv1.Endpoint) []discoveryv1.Endpoint {
+	var ready, terminating []discoveryv1.Endpoint
+	for _, endpoint := range endpoints {
+		switch serving, isTerminating := endpointConditions(endpoint); {
+		case serving && !isTerminating:
+			ready = append(ready, endpoint)
+		case serving:
+			terminating = append(terminating, endpoint)
+		}
+	}
+	if len(ready) == 0 {
+		return terminating
+	}
+	return ready
+}
+
 func (ep *endpointslicesProvider) getAllEndpoints() ([]string, error) {
 	result := []string{}
-	for _, ep := range ep.endpoints.Endpoints {
+	for _, ep := range servingEndpoints(ep.endpoints.Endpoints) {
 		result = append(result, ep.Addresses...)
 	}
 	return result, nil
 }
 
+// getTotalEndpoints returns the number of endpoints, whatever their conditions
+func (ep *endpointslicesProvider) getTotalEndpoints() int {
+	total := 0
+	for _, endpoint := range ep.endpoints.Endpoints {
+		total += len(endpoint.Addresses)
+	}
+	return total
+}
+
 func (ep *endpointslicesProvider) getLocalEndpoints(id string, _ *kubevip.Config) ([]string, error) {
-	var localEndpoints []string
+	// Only the endpoints of this node are considered, so that it keeps advertising whilst its own endpoints
+	// are terminating even when there are ready endpoints on other nodes
+	var local []discoveryv1.Endpoint
 	for _, endpoint := range ep.endpoints.Endpoints {
-		if !*endpoint.Conditions.Serving {
-			continue
+		if (endpoint.NodeName != nil && id == *endpoint.NodeName) || (endpoint.NodeName == nil && endpoint.Hostname != nil && id == *endpoint.Hostname) {
+			local = append(local, endpoint)
+		}
+	}
+
+	var localEndpoints []string
+	for _, endpoint := range servingEndpoints(local) {
+		if _, terminating := endpointConditions(endpoint); terminating {
+			log.Debugf("[%s] endpoint [%v] is terminating, but still serving", ep.label, endpoint.Addresses)
 		}
 		for _, address := range endpoint.Addresses {
 			log.Debugf("[%s] processing endpoint [%s]", ep.label, address)
@@ -122,10 +196,32 @@ func (ep *endpointslicesProvider) updateServiceAnnotation(endpoint, endpointIPv6
 	return nil
 }
 
-func (ep *endpointslicesProvider) getLabel() string {
-	return ep.label
+// getTargetPort returns the port of the endpoints that a port of the service targets, the ports of the slices are
+// named after the port of the service
+func (ep *endpointslicesProvider) getTargetPort(servicePort v1.ServicePort) (int32, error) {
+	return targetPort(servicePort, func(name string, protocol v1.Protocol) []int32 {
+		var ports []int32
+		for _, slice := range ep.slices {
+			if len(servingEndpoints(slice.Endpoints)) == 0 {
+				continue
+			}
+			for _, port := range slice.Ports {
+				portName, portProtocol := "", v1.ProtocolTCP
+				if port.Name != nil {
+					portName = *port.Name
+				}
+				if port.Protocol != nil {
+					portProtocol = *port.Protocol
+				}
+				if port.Port != nil && portName == name && portProtocol == protocol {
+					ports = append(ports, *port.Port)
+				}
+			}
+		}
+		return ports
+	})
 }
 
-func (ep *endpointslicesProvider) getProtocol() string {
-	return string(ep.endpoints.AddressType)
+func (ep *endpointslicesProvider) getLabel() string {
+	return ep.label
 }
@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServingEndpoints(t *testing.T) {
+	node, yes, no := "node", true, false
+	endpoint := func(address string, ready, serving, terminating *bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  []string{address},
+			NodeName:   &node,
+			Conditions: discoveryv1.EndpointConditions{Ready: ready, Serving: serving, Terminating: terminating},
+		}
+	}
+	ready := endpoint("10.0.0.1", &yes, &yes, &no)
+	unknown := endpoint("10.0.0.2", nil, nil, nil)
+	draining := endpoint("10.0.0.3", &no, &yes, &yes)
+	terminated := endpoint("10.0.0.4", &no, &no, &yes)
+	notReady := endpoint("10.0.0.5", &no, nil, nil)
+
+	tests := []struct {
+		name      string
+		endpoints []discoveryv1.Endpoint
+		want      []string
+	}{
+		{"ready", []discoveryv1.Endpoint{ready, unknown, notReady}, []string{"10.0.0.1", "10.0.0.2"}},
+		{"ready over terminating", []discoveryv1.Endpoint{draining, ready}, []string{"10.0.0.1"}},
+		{"terminating but serving", []discoveryv1.Endpoint{draining, terminated, notReady}, []string{"10.0.0.3"}},
+		{"none serving", []discoveryv1.Endpoint{terminated, notReady}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := &endpointslicesProvider{label: "endpointslices", endpoints: &discoveryv1.EndpointSlice{Endpoints: tt.endpoints}}
+			got, err := ep.getLocalEndpoints("node", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getLocalEndpoints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeSlices(t *testing.T) {
+	node := "node"
+	slice := func(name string, addressType discoveryv1.AddressType, address string) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta:  metav1.ObjectMeta{Name: name},
+			AddressType: addressType,
+			Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{address}, NodeName: &node}},
+		}
+	}
+	ipv4 := slice("web-ipv4", discoveryv1.AddressTypeIPv4, "10.0.0.1")
+	ipv6 := slice("web-ipv6", discoveryv1.AddressTypeIPv6, "fd00::1")
+
+	ep := &endpointslicesProvider{label: "endpointslices"}
+	for _, s := range []*discoveryv1.EndpointSlice{ipv6, ipv4} {
+		if err := ep.loadObject(s, func() {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	local, _ := ep.getLocalEndpoints(node, nil)
+	if want := []string{"10.0.0.1", "fd00::1"}; !reflect.DeepEqual(local, want) {
+		t.Errorf("getLocalEndpoints() = %v, want %v", local, want)
+	}
+	if families := endpointFamilies(local); !reflect.DeepEqual(families, []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}) {
+		t.Errorf("endpointFamilies() = %v", families)
+	}
+
+	if !ep.unloadObject(ipv6) {
+		t.Fatal("unloadObject() of the IPv6 slice = false, want true")
+	}
+	local, _ = ep.getLocalEndpoints(node, nil)
+	if families := endpointFamilies(local); !reflect.DeepEqual(families, []v1.IPFamily{v1.IPv4Protocol}) {
+		t.Errorf("endpointFamilies() = %v, want [IPv4]", families)
+	}
+	if ep.unloadObject(ipv4) {
+		t.Error("unloadObject() of the last slice = true, want false")
+	}
+	if all, _ := ep.getAllEndpoints(); !reflect.DeepEqual(all, []string{"10.0.0.1"}) {
+		t.Errorf("getAllEndpoints() = %v, the endpoints of the last slice are kept", all)
+	}
+}
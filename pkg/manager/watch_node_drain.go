@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kube-vip/kube-vip/pkg/k8s"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// nodeDrainWatcher watches this node and withdraws (or de-preferences) the BGP advertisements whilst
+// it is cordoned, so that traffic has moved away before the pods are evicted
+func (sm *Manager) nodeDrainWatcher(ctx context.Context) error {
+	server, err := sm.gobgpServer()
+	if err != nil {
+		return err
+	}
+
+	opts := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", sm.config.NodeName).String(),
+	}
+
+	rw, err := watchtools.NewRetryWatcher("1", &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return sm.clientSet.CoreV1().Nodes().Watch(ctx, opts)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating node drain watcher: %s", err.Error())
+	}
+
+	go func() {
+		<-ctx.Done()
+		log.Debug("[BGP] node drain watcher context cancelled")
+		rw.Stop()
+	}()
+
+	for event := range rw.ResultChan() {
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			node, ok := event.Object.(*v1.Node)
+			if !ok {
+				return fmt.Errorf("unable to parse Kubernetes Node from API watcher")
+			}
+			draining := k8s.NodeDraining(node)
+			if draining {
+				log.Infof("[BGP] node [%s] is draining, withdrawing advertisements", node.Name)
+			}
+			if err = server.Drain(draining); err != nil {
+				log.Errorf("[BGP] unable to update advertisements for node [%s]: %v", node.Name, err)
+			}
+		case watch.Deleted:
+			log.Warnf("[BGP] node [%s] has been deleted", sm.config.NodeName)
+		case watch.Error:
+			log.Errorf("[BGP] error attempting to watch node [%s]", sm.config.NodeName)
+		}
+	}
+	log.Debug("[BGP] exiting node drain watcher")
+	return nil
+}
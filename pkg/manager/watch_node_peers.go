@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kube-vip/kube-vip/pkg/bgp"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// bgpPeersAnnotation is the node annotation that holds the BGP peers for that node, it uses
+// the same format as the bgp_peers environment variable (address:AS:password:multihop:bfd,...)
+const bgpPeersAnnotation = "kube-vip.io/bgp-peers"
+
+// parseNodePeers will return the BGP peers that are defined in the annotations of a node
+func (sm *Manager) parseNodePeers(node *v1.Node) ([]bgp.Peer, error) {
+	annotation := node.Annotations[bgpPeersAnnotation]
+	if annotation == "" {
+		return nil, fmt.Errorf("node [%s] has no BGP peers defined in annotation [%s]", node.Name, bgpPeersAnnotation)
+	}
+
+	peers, err := bgp.ParseBGPPeerConfig(annotation)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing annotation [%s] on node [%s]: %w", bgpPeersAnnotation, node.Name, err)
+	}
+
+	sm.applyPeerDefaults(peers)
+	return peers, nil
+}
+
+// applyPeerDefaults will use the global peer configuration (BFD, password Secret) as the default for every peer
+func (sm *Manager) applyPeerDefaults(peers []bgp.Peer) {
+	for x := range peers {
+		if peers[x].Password == "" {
+			peers[x].PasswordSecret = sm.config.BGPPeerConfig.PasswordSecret
+		}
+		peers[x].BFD.Enabled = peers[x].BFD.Enabled || sm.config.BGPPeerConfig.BFD.Enabled
+		peers[x].BFD.Interval = sm.config.BGPPeerConfig.BFD.Interval
+		peers[x].BFD.Multiplier = sm.config.BGPPeerConfig.BFD.Multiplier
+	}
+}
+
+// nodePeersWatcher watches this node and reconfigures the BGP server when its peers change
+func (sm *Manager) nodePeersWatcher(ctx context.Context) error {
+	opts := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", sm.config.NodeName).String(),
+	}
+
+	rw, err := watchtools.NewRetryWatcher("1", &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return sm.clientSet.CoreV1().Nodes().Watch(ctx, opts)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating node peers watcher: %s", err.Error())
+	}
+
+	go func() {
+		<-ctx.Done()
+		log.Debug("[BGP] node peers watcher context cancelled")
+		rw.Stop()
+	}()
+
+	for event := range rw.ResultChan() {
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			node, ok := event.Object.(*v1.Node)
+			if !ok {
+				return fmt.Errorf("unable to parse Kubernetes Node from API watcher")
+			}
+			peers, err := sm.parseNodePeers(node)
+			if err != nil {
+				log.Errorf("[BGP] %v", err)
+				continue
+			}
+			if err = sm.resolveBGPPasswords(ctx, peers); err != nil {
+				log.Errorf("[BGP] %v", err)
+				continue
+			}
+
+			server, err := sm.gobgpServer()
+			if err != nil {
+				return err
+			}
+			sm.mutex.Lock()
+			err = server.SyncPeers(peers)
+			sm.mutex.Unlock()
+			if err != nil {
+				log.Errorf("[BGP] unable to update peers from node [%s]: %v", node.Name, err)
+			}
+			// The Secrets of the peers that have been added are watched, so that their passwords can be rotated
+			if err = sm.syncBGPSecretWatchers(ctx, peers); err != nil {
+				log.Errorf("[BGP] %v", err)
+			}
+		case watch.Deleted:
+			log.Warnf("[BGP] node [%s] has been deleted", sm.config.NodeName)
+		case watch.Error:
+			log.Errorf("[BGP] error attempting to watch node [%s]", sm.config.NodeName)
+		}
+	}
+	log.Debug("[BGP] exiting node peers watcher")
+	return nil
+}
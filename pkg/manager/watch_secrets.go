@@ -0,0 +1,165 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kube-vip/kube-vip/pkg/bgp"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// secretRef references a single key within a Kubernetes Secret
+type secretRef struct {
+	namespace string
+	name      string
+	key       string
+}
+
+// parseSecretRef will parse a reference in the format namespace/name/key
+func parseSecretRef(ref string) (secretRef, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return secretRef{}, fmt.Errorf("secret reference format error (namespace/name/key) [%s]", ref)
+	}
+	return secretRef{namespace: parts[0], name: parts[1], key: parts[2]}, nil
+}
+
+// resolveBGPPasswords will populate the password of any BGP peer that references a Secret
+func (sm *Manager) resolveBGPPasswords(ctx context.Context, peers []bgp.Peer) error {
+	for x := range peers {
+		peer := &peers[x]
+		if peer.PasswordSecret == "" {
+			continue
+		}
+		ref, err := parseSecretRef(peer.PasswordSecret)
+		if err != nil {
+			return err
+		}
+		secret, err := sm.clientSet.CoreV1().Secrets(ref.namespace).Get(ctx, ref.name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get BGP password secret [%s/%s]: %w", ref.namespace, ref.name, err)
+		}
+		password, exists := secret.Data[ref.key]
+		if !exists {
+			return fmt.Errorf("key [%s] not found in BGP password secret [%s/%s]", ref.key, ref.namespace, ref.name)
+		}
+		peer.Password = string(password)
+	}
+	return nil
+}
+
+// syncBGPSecretWatchers watches every Secret that is referenced by the peers, as the peers are hot reloaded the
+// watchers of the Secrets that are no longer referenced are stopped
+func (sm *Manager) syncBGPSecretWatchers(ctx context.Context, peers []bgp.Peer) error {
+	referenced := map[string]secretRef{}
+	for _, peer := range peers {
+		if peer.PasswordSecret == "" {
+			continue
+		}
+		ref, err := parseSecretRef(peer.PasswordSecret)
+		if err != nil {
+			return err
+		}
+		referenced[ref.namespace+"/"+ref.name] = ref
+	}
+
+	sm.bgpSecretsMutex.Lock()
+	defer sm.bgpSecretsMutex.Unlock()
+	if sm.bgpSecretWatchers == nil {
+		sm.bgpSecretWatchers = make(map[string]context.CancelFunc)
+	}
+	for key, cancel := range sm.bgpSecretWatchers {
+		if _, exists := referenced[key]; !exists {
+			cancel()
+			delete(sm.bgpSecretWatchers, key)
+		}
+	}
+	for key, ref := range referenced {
+		if _, exists := sm.bgpSecretWatchers[key]; exists {
+			continue
+		}
+		secretCtx, cancel := context.WithCancel(ctx)
+		sm.bgpSecretWatchers[key] = cancel
+		go func(ref secretRef) {
+			if err := sm.bgpSecretWatcher(secretCtx, ref.namespace, ref.name); err != nil {
+				log.Errorf("[BGP] secret watcher [%s/%s] error: %v", ref.namespace, ref.name, err)
+			}
+		}(ref)
+	}
+	return nil
+}
+
+// bgpSecretWatcher watches a single Secret and updates the BGP peers when a referenced password has rotated
+func (sm *Manager) bgpSecretWatcher(ctx context.Context, namespace, name string) error {
+	opts := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	}
+
+	rw, err := watchtools.NewRetryWatcher("1", &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return sm.clientSet.CoreV1().Secrets(namespace).Watch(ctx, opts)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating secret watcher: %s", err.Error())
+	}
+
+	go func() {
+		<-ctx.Done()
+		log.Debugf("[BGP] secret watcher [%s/%s] context cancelled", namespace, name)
+		rw.Stop()
+	}()
+
+	for event := range rw.ResultChan() {
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			secret, ok := event.Object.(*v1.Secret)
+			if !ok {
+				return fmt.Errorf("unable to parse Kubernetes Secret from API watcher")
+			}
+			sm.updateBGPPasswords(secret)
+		case watch.Deleted:
+			log.Warnf("[BGP] password secret [%s/%s] has been deleted, peers will keep their existing password", namespace, name)
+		case watch.Error:
+			log.Errorf("[BGP] error attempting to watch secret [%s/%s]", namespace, name)
+		}
+	}
+	log.Debugf("[BGP] exiting secret watcher [%s/%s]", namespace, name)
+	return nil
+}
+
+// updateBGPPasswords will update any peer whose referenced password has changed, peers
+// with an unchanged password are left alone so their sessions aren't reset
+func (sm *Manager) updateBGPPasswords(secret *v1.Secret) {
+	server, err := sm.gobgpServer()
+	if err != nil {
+		return
+	}
+	server.RotatePasswords(func(peer bgp.Peer) (string, bool) {
+		return secretPassword(peer, secret)
+	})
+}
+
+// secretPassword returns the password of the peer from the Secret, if the peer references it
+func secretPassword(peer bgp.Peer, secret *v1.Secret) (string, bool) {
+	if peer.PasswordSecret == "" {
+		return "", false
+	}
+	ref, err := parseSecretRef(peer.PasswordSecret)
+	if err != nil || ref.namespace != secret.Namespace || ref.name != secret.Name {
+		return "", false
+	}
+	password, exists := secret.Data[ref.key]
+	if !exists {
+		log.Warnf("[BGP] key [%s] not found in password secret [%s/%s]", ref.key, ref.namespace, ref.name)
+		return "", false
+	}
+	return string(password), true
+}
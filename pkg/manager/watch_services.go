@@ -3,44 +3,139 @@ package manager
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/davecgh/go-spew/spew"
 	"github.com/kube-vip/kube-vip/pkg/vip"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
-	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// servicesResyncPeriod is how often every service is reconciled again
+	servicesResyncPeriod = 5 * time.Minute
+
+	// servicesRetryDelay is the delay before a service that has failed is first retried, the delay doubles
+	// with every failure up to the resync period
+	servicesRetryDelay = time.Second
 )
 
 // TODO: Fix the naming of these contexts
 
-// activeServiceLoadBalancer keeps track of services that already have a leaderElection in place
-var activeServiceLoadBalancer map[string]context.Context
+// serviceTracker keeps track of the services that are being handled, it is shared by the workers and the elections
+type serviceTracker struct {
+	mutex sync.Mutex
 
-// activeServiceLoadBalancer keeps track of services that already have a leaderElection in place
-var activeServiceLoadBalancerCancel map[string]func()
+	// contexts and cancels are the contexts of the handling of the services, and the functions that cancel them
+	contexts map[string]context.Context
+	cancels  map[string]func()
 
-// activeService keeps track of services that already have a leaderElection in place
-var activeService map[string]bool
+	// active keeps track of services that already have a leaderElection in place
+	active map[string]bool
 
-// watchedService keeps track of services that are already being watched
-var watchedService map[string]bool
+	// watched keeps track of services whose endpoints are already being watched
+	watched map[string]bool
 
-// watchedService keeps track of routes that has been configured on the node
-var configuredLocalRoutes sync.Map
+	// started keeps track of the services that are being handled by their namespace/name, so that a service
+	// that has been deleted and recreated (with a new UID) is released even if its deletion was missed
+	started map[string]*v1.Service
+}
+
+func newServiceTracker() *serviceTracker {
+	return &serviceTracker{
+		contexts: make(map[string]context.Context),
+		cancels:  make(map[string]func()),
+		active:   make(map[string]bool),
+		watched:  make(map[string]bool),
+		started:  make(map[string]*v1.Service),
+	}
+}
 
-func init() {
-	// Set up the caches for monitoring existing active or watched services
-	activeServiceLoadBalancerCancel = make(map[string]func())
-	activeServiceLoadBalancer = make(map[string]context.Context)
-	activeService = make(map[string]bool)
-	watchedService = make(map[string]bool)
+// serviceActive returns if the service is already being handled
+func (sm *Manager) serviceActive(uid string) bool {
+	sm.services.mutex.Lock()
+	defer sm.services.mutex.Unlock()
+	return sm.services.active[uid]
+}
+
+// setServiceActive records if the service is being handled
+func (sm *Manager) setServiceActive(uid string, active bool) {
+	sm.services.mutex.Lock()
+	defer sm.services.mutex.Unlock()
+	sm.services.active[uid] = active
+}
+
+// startedService returns the service with the namespace/name that is being handled, whatever its UID
+func (sm *Manager) startedService(key string) *v1.Service {
+	sm.services.mutex.Lock()
+	defer sm.services.mutex.Unlock()
+	return sm.services.started[key]
+}
+
+// setServiceStarted records if the service is being handled, by its namespace/name
+func (sm *Manager) setServiceStarted(svc *v1.Service, started bool) {
+	sm.services.mutex.Lock()
+	defer sm.services.mutex.Unlock()
+	key := svc.Namespace + "/" + svc.Name
+	if started {
+		sm.services.started[key] = svc
+	} else if current := sm.services.started[key]; current != nil && current.UID == svc.UID {
+		delete(sm.services.started, key)
+	}
+}
+
+// serviceWatched returns if the endpoints of the service are already being watched
+func (sm *Manager) serviceWatched(uid string) bool {
+	sm.services.mutex.Lock()
+	defer sm.services.mutex.Unlock()
+	return sm.services.watched[uid]
+}
+
+// setServiceWatched records if the endpoints of the service are being watched
+func (sm *Manager) setServiceWatched(uid string, watched bool) {
+	sm.services.mutex.Lock()
+	defer sm.services.mutex.Unlock()
+	sm.services.watched[uid] = watched
+}
+
+// newServiceContext returns the context of the handling of a service, which is cancelled once it is stopped
+func (sm *Manager) newServiceContext(uid string) context.Context {
+	sm.services.mutex.Lock()
+	defer sm.services.mutex.Unlock()
+	sm.services.contexts[uid], sm.services.cancels[uid] = context.WithCancel(context.TODO())
+	return sm.services.contexts[uid]
+}
+
+// cancelService stops the handling of a service
+func (sm *Manager) cancelService(uid string) {
+	sm.services.mutex.Lock()
+	defer sm.services.mutex.Unlock()
+	if cancel := sm.services.cancels[uid]; cancel != nil {
+		cancel()
+	}
+}
+
+// cancelServices stops the handling of every service
+func (sm *Manager) cancelServices() {
+	sm.services.mutex.Lock()
+	defer sm.services.mutex.Unlock()
+	for _, cancel := range sm.services.cancels {
+		if cancel != nil {
+			cancel()
+		}
+	}
 }
 
 // This function handles the watching of a services endpoints and updates a load balancers endpoint configurations accordingly
@@ -56,275 +151,521 @@ func (sm *Manager) servicesWatcher(ctx context.Context, serviceFunc func(context
 		// clean up traffic mirror related config
 		err := sm.stopTrafficMirroringIfEnabled()
 		if err != nil {
-			log.Fatal(err)
+			log.Errorf("unable to stop the traffic mirroring: %v", err)
 		}
 	}()
 
-	if sm.config.ServiceNamespace == "" {
-		// v1.NamespaceAll is actually "", but we'll stay with the const in case things change upstream
-		sm.config.ServiceNamespace = v1.NamespaceAll
+	namespaces := sm.config.ServiceNamespaces()
+	if len(namespaces) == 1 && namespaces[0] == v1.NamespaceAll {
 		log.Infof("(svcs) starting services watcher for all namespaces")
 	} else {
-		log.Infof("(svcs) starting services watcher for services in namespace [%s]", sm.config.ServiceNamespace)
+		log.Infof("(svcs) starting services watcher for services in namespaces [%s]", strings.Join(namespaces, ","))
+	}
+	if sm.config.ServicesLabelSelector != "" {
+		if _, err := labels.Parse(sm.config.ServicesLabelSelector); err != nil {
+			return fmt.Errorf("invalid services label selector [%s]: %w", sm.config.ServicesLabelSelector, err)
+		}
+		log.Infof("(svcs) only watching the services that match the label selector [%s]", sm.config.ServicesLabelSelector)
+	}
+	if sm.config.ServicesIgnoreLabelSelector != "" {
+		if _, err := labels.Parse(sm.config.ServicesIgnoreLabelSelector); err != nil {
+			return fmt.Errorf("invalid services ignore label selector [%s]: %w", sm.config.ServicesIgnoreLabelSelector, err)
+		}
 	}
 
-	// Use a restartable watcher, as this should help in the event of etcd or timeout issues
-	rw, err := watchtools.NewRetryWatcher("1", &cache.ListWatch{
-		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-			return sm.clientSet.CoreV1().Services(sm.config.ServiceNamespace).Watch(ctx, metav1.ListOptions{})
+	// A shared informer lists the services before watching them, so that no stale events are replayed, and
+	// the work queue retries the services that fail to be processed (or fail in the background) with a backoff.
+	// Each namespace has its own informer, they all share the work queue (and the workers).
+	rateLimiter := workqueue.NewItemExponentialFailureRateLimiter(servicesRetryDelay, servicesResyncPeriod)
+	queue := workqueue.NewRateLimitingQueueWithConfig(rateLimiter, workqueue.RateLimitingQueueConfig{Name: "services"})
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueueService(queue, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			enqueueService(queue, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			enqueueService(queue, obj)
 		},
-	})
-	if err != nil {
-		return fmt.Errorf("error creating services watcher: %s", err.Error())
 	}
+	var factories []informers.SharedInformerFactory
+	var synced []cache.InformerSynced
+	listers := make(serviceListers)
+	for _, namespace := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(sm.clientSet, servicesResyncPeriod, informers.WithNamespace(namespace),
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.LabelSelector = sm.config.ServicesLabelSelector
+			}))
+		informer := factory.Core().V1().Services()
+		if _, err := informer.Informer().AddEventHandler(handler); err != nil {
+			return fmt.Errorf("error creating services watcher: %s", err.Error())
+		}
+		factories = append(factories, factory)
+		synced = append(synced, informer.Informer().HasSynced)
+		listers[namespace] = informer.Lister()
+	}
+
+	stop := make(chan struct{})
 	exitFunction := make(chan struct{})
 	go func() {
 		select {
 		case <-sm.shutdownChan:
 			log.Debug("(svcs) shutdown called")
-			// Stop the retry watcher
-			rw.Stop()
-			return
+		case <-ctx.Done():
+			log.Debug("(svcs) context cancelled")
 		case <-exitFunction:
 			log.Debug("(svcs) function ending")
-			// Stop the retry watcher
-			rw.Stop()
-			return
 		}
+		// Stop the informer and the workers
+		close(stop)
+		queue.ShutDown()
 	}()
-	ch := rw.ResultChan()
-
-	// Used for tracking an active endpoint / pod
-	for event := range ch {
-		sm.countServiceWatchEvent.With(prometheus.Labels{"type": string(event.Type)}).Add(1)
-
-		// We need to inspect the event and get ResourceVersion out of it
-		switch event.Type {
-		case watch.Added, watch.Modified:
-			// log.Debugf("Endpoints for service [%s] have been Created or modified", s.service.ServiceName)
-			svc, ok := event.Object.(*v1.Service)
-			if !ok {
-				return fmt.Errorf("unable to parse Kubernetes services from API watcher")
-			}
+	defer close(exitFunction)
 
-			// We only care about LoadBalancer services
-			if svc.Spec.Type != v1.ServiceTypeLoadBalancer {
-				break
-			}
+	for _, factory := range factories {
+		factory.Start(stop)
+	}
+	if !cache.WaitForCacheSync(stop, synced...) {
+		log.Warnln("Stopping watching services before they were listed")
+		return nil
+	}
 
-			// Check if we ignore this service
-			if svc.Annotations["kube-vip.io/ignore"] == "true" {
-				log.Infof("(svcs) [%s] has an ignore annotation for kube-vip", svc.Name)
-				break
-			}
+	// Before the services are processed, remove what a previous run left behind for deleted services
+	services, err := sm.reconciledServices(ctx, listers)
+	if err != nil {
+		return fmt.Errorf("error listing services: %w", err)
+	}
+	sm.reconcileServices(ctx, services)
 
-			// Select loadbalancer class filtering function
-			lbClassFilterFunc := sm.lbClassFilter
-			if sm.config.LoadBalancerClassLegacyHandling {
-				lbClassFilterFunc = sm.lbClassFilterLegacy
-			}
+	if sm.config.ServicesDriftInterval > 0 {
+		go sm.repairDrift(time.Duration(sm.config.ServicesDriftInterval)*time.Second, stop)
+	}
 
-			// Check the loadBalancer class
-			if lbClassFilterFunc(svc) {
-				break
+	// The work queue never hands the same service to two workers, so each service is processed in order
+	workers := sm.config.ServicesWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	processed := &processedServices{services: make(map[string]*v1.Service)}
+	var workersWg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		workersWg.Add(1)
+		go func() {
+			defer workersWg.Done()
+			for sm.processNextService(queue, listers, processed, serviceFunc, &wg) {
 			}
+		}()
+	}
+	workersWg.Wait()
+	for _, factory := range factories {
+		factory.Shutdown()
+	}
+	log.Warnln("Stopping watching services for type: LoadBalancer in all namespaces")
+	return nil
+}
+
+// serviceListers are the listers of the informers that watch each namespace, keyed by the namespace (an informer
+// that watches every namespace is keyed by v1.NamespaceAll)
+type serviceListers map[string]corelisters.ServiceLister
+
+// List lists the services of every informer
+func (l serviceListers) List(selector labels.Selector) ([]*v1.Service, error) {
+	var services []*v1.Service
+	for _, lister := range l {
+		namespaced, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, namespaced...)
+	}
+	return services, nil
+}
+
+// Services returns the lister of the informer that watches a namespace
+func (l serviceListers) Services(namespace string) corelisters.ServiceNamespaceLister {
+	if lister, watched := l[namespace]; watched {
+		return lister.Services(namespace)
+	}
+	return l[v1.NamespaceAll].Services(namespace)
+}
+
+// processedServices are the last versions of the services that have been processed, so that an event can be
+// told apart from a resync and a service that has been deleted is still known
+type processedServices struct {
+	mutex    sync.Mutex
+	services map[string]*v1.Service
+}
+
+func (p *processedServices) get(key string) (*v1.Service, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	svc, exists := p.services[key]
+	return svc, exists
+}
+
+func (p *processedServices) set(key string, svc *v1.Service) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if svc == nil {
+		delete(p.services, key)
+		return
+	}
+	p.services[key] = svc
+}
+
+// enqueueService adds the key of the service to the queue, a deleted service is still queued by its key
+func enqueueService(queue workqueue.RateLimitingInterface, obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Errorf("(svcs) unable to find the key of service: %v", err)
+		return
+	}
+	queue.Add(key)
+}
 
-			svcAddresses := fetchServiceAddresses(svc)
+// processNextService processes the next service in the queue, retrying it with a backoff until it succeeds. It
+// returns false once the queue has been shut down.
+func (sm *Manager) processNextService(queue workqueue.RateLimitingInterface, lister corelisters.ServiceLister,
+	processed *processedServices, serviceFunc func(context.Context, *v1.Service, *sync.WaitGroup) error, wg *sync.WaitGroup) bool {
+	item, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(item)
+
+	key := item.(string)
+	retry := func(err error) {
+		sm.countServiceRetries.Inc()
+		log.Warnf("(svcs) error processing service [%s], retrying (attempt %d): %v", key, queue.NumRequeues(key)+1, err)
+		queue.AddRateLimited(key)
+	}
+	requeue := func(after time.Duration) {
+		queue.AddAfter(key, after)
+	}
+	if err := sm.syncServiceKey(key, lister, processed, serviceFunc, wg, retry, requeue); err != nil {
+		retry(err)
+		return true
+	}
+	queue.Forget(item)
+	return true
+}
+
+// restartService stops the handling of a service that has failed in the background, so that it is started again
+func (sm *Manager) restartService(uid string) {
+	sm.cancelService(uid)
+	if err := sm.deleteService(uid); err != nil {
+		log.Error(err)
+	}
+	sm.setServiceActive(uid, false)
+	sm.setServiceWatched(uid, false)
+}
+
+// syncServiceKey processes the current state of a service, compared to the last version that was processed
+func (sm *Manager) syncServiceKey(key string, lister corelisters.ServiceLister, processed *processedServices,
+	serviceFunc func(context.Context, *v1.Service, *sync.WaitGroup) error, wg *sync.WaitGroup, retry func(error), requeue func(time.Duration)) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	svc, err := lister.Services(namespace).Get(name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	last, known := processed.get(key)
+
+	// The service has been deleted, or deleted and recreated before the deletion was processed
+	if known && (svc == nil || svc.UID != last.UID) {
+		sm.countServiceWatchEvent.With(prometheus.Labels{"type": string(watch.Deleted)}).Add(1)
+		if err := sm.serviceDeleted(last); err != nil {
+			return err
+		}
+		processed.set(key, nil)
+		known = false
+	}
+	// A service that is still handled under a previous UID is released before the new one is programmed, as its
+	// deletion was missed (e.g. its last version was never processed successfully)
+	if started := sm.startedService(key); started != nil && (svc == nil || svc.UID != started.UID) {
+		log.Warnf("(svcs) [%s] has been deleted or recreated, releasing the previous service [%s]", key, started.UID)
+		sm.countServiceWatchEvent.With(prometheus.Labels{"type": string(watch.Deleted)}).Add(1)
+		if err := sm.serviceDeleted(started); err != nil {
+			return err
+		}
+	}
+	if svc == nil {
+		return nil
+	}
 
-			// We only care about LoadBalancer services that have been allocated an address
-			if len(svcAddresses) <= 0 {
-				break
+	// A service that is being deleted is cleaned up before its finalizer is removed, so that it doesn't disappear
+	// before its addresses, routes and lease have been removed
+	if svc.DeletionTimestamp != nil {
+		announced := sm.serviceAnnounced(svc)
+		if known {
+			sm.countServiceWatchEvent.With(prometheus.Labels{"type": string(watch.Deleted)}).Add(1)
+			if err := sm.serviceDeleted(svc); err != nil {
+				return err
 			}
+			processed.set(key, nil)
+		}
+		return sm.finalizeService(svc, announced, requeue)
+	}
 
-			// The modified event should only be triggered if the service has been modified (i.e. moved somewhere else)
-			if event.Type == watch.Modified {
-				for _, addr := range svcAddresses {
-					// log.Debugf("(svcs) Retreiving local addresses, to ensure that this modified address doesn't exist: %s", addr)
-					f, err := vip.GarbageCollect(sm.config.Interface, addr)
-					if err != nil {
-						log.Errorf("(svcs) cleaning existing address error: [%s]", err.Error())
-					}
-					if f {
-						log.Warnf("(svcs) already found existing address [%s] on adapter [%s]", addr, sm.config.Interface)
-					}
+	// A resync of a service that hasn't changed is reconciled, but isn't a modification
+	modified := known && last.ResourceVersion != svc.ResourceVersion
+	// A modification of fields that kube-vip doesn't act upon (e.g. its own status update) is skipped, unless the
+	// service has failed and has to be restarted
+	if _, failed := sm.failedServices.Load(string(svc.UID)); modified && !failed {
+		changes := serviceChanges(last, svc, sm.config)
+		if len(changes) == 0 {
+			log.Debugf("(svcs) [%s] has been modified, but nothing relevant has changed", key)
+			sm.countServiceSkippedEvents.Inc()
+			processed.set(key, svc)
+			return nil
+		}
+		log.Debugf("(svcs) [%s] has been modified %v", key, changes)
+	}
+	if !known {
+		sm.countServiceWatchEvent.With(prometheus.Labels{"type": string(watch.Added)}).Add(1)
+	} else if modified {
+		sm.countServiceWatchEvent.With(prometheus.Labels{"type": string(watch.Modified)}).Add(1)
+	}
+	if _, failed := sm.failedServices.LoadAndDelete(string(svc.UID)); failed {
+		sm.restartService(string(svc.UID))
+	}
+	// A service that fails in the background is restarted when it is retried
+	failed := func(err error) {
+		sm.failedServices.Store(string(svc.UID), true)
+		retry(err)
+	}
+	if err := sm.serviceAddedOrModified(svc, modified, serviceFunc, wg, failed); err != nil {
+		return err
+	}
+	processed.set(key, svc)
+	return nil
+}
+
+// serviceAddedOrModified starts the handling of a service that has been added, or reconciles one that has
+// been modified or resynced. An error from the handling that runs in the background is passed to failed.
+func (sm *Manager) serviceAddedOrModified(svc *v1.Service, modified bool, serviceFunc func(context.Context, *v1.Service, *sync.WaitGroup) error, wg *sync.WaitGroup, failed func(error)) error {
+	// We only care about LoadBalancer services
+	if svc.Spec.Type != v1.ServiceTypeLoadBalancer {
+		return nil
+	}
+
+	// Check if we ignore this service, a service that has become ignored is handed over e.g. to another controller
+	if reason := serviceIgnored(svc, sm.config); reason != "" {
+		log.Infof("(svcs) [%s] is ignored by kube-vip, because of its %s", svc.Name, reason)
+		if sm.serviceActive(string(svc.UID)) {
+			return sm.stopService(svc)
+		}
+		return nil
+	}
+
+	// Select loadbalancer class filtering function
+	lbClassFilterFunc := sm.lbClassFilter
+	if sm.config.LoadBalancerClassLegacyHandling {
+		lbClassFilterFunc = sm.lbClassFilterLegacy
+	}
+
+	// Check the loadBalancer class
+	if lbClassFilterFunc(svc) {
+		return nil
+	}
+
+	svcAddresses := fetchServiceAddresses(svc, sm.config)
+
+	// We only care about LoadBalancer services that have been allocated an address
+	if len(svcAddresses) <= 0 {
+		return nil
+	}
+
+	// A paused service is no longer announced, but keeps its addresses so that it comes back with them
+	if servicePaused(svc) {
+		if !sm.serviceActive(string(svc.UID)) {
+			return nil
+		}
+		if err := sm.stopService(svc); err != nil {
+			return err
+		}
+		log.Infof("(svcs) [%s/%s] has been paused, its addresses %v are no longer announced", svc.Namespace, svc.Name, svcAddresses)
+		if sm.eventRecorder != nil {
+			sm.eventRecorder.Eventf(svc, v1.EventTypeNormal, "Paused", "The addresses %v are no longer announced", svcAddresses)
+		}
+		return nil
+	}
+
+	// An operator can fail the service over to another node, e.g. to drain this node for maintenance
+	if modified && sm.config.EnableServicesElection {
+		sm.failoverService(svc)
+	}
+
+	// The modified event should only be triggered if the service has been modified (i.e. moved somewhere else)
+	if modified {
+		for _, addr := range svcAddresses {
+			// log.Debugf("(svcs) Retreiving local addresses, to ensure that this modified address doesn't exist: %s", addr)
+			for _, iface := range vip.GetInterfaces(sm.config.Interface) {
+				f, err := vip.GarbageCollect(iface, addr)
+				if err != nil {
+					log.Errorf("(svcs) cleaning existing address error: [%s]", err.Error())
+				}
+				if f {
+					log.Warnf("(svcs) already found existing address [%s] on adapter [%s]", addr, iface)
 				}
 			}
-			// Scenarios:
-			// 1.
-			if !activeService[string(svc.UID)] {
-				log.Debugf("(svcs) [%s] has been added/modified with addresses [%s]", svc.Name, fetchServiceAddresses(svc))
-
-				wg.Add(1)
-				activeServiceLoadBalancer[string(svc.UID)], activeServiceLoadBalancerCancel[string(svc.UID)] = context.WithCancel(context.TODO())
-				// Background the services election
-				// EnableServicesElection enabled
-				// watchEndpoint will do a ServicesElection by Service and understands local endpoints
-				//
-				// EnableRoutingTable enabled and EnableLeaderElection disabled
-				// watchEndpoint will also not do a leaderElection by service.
-				if sm.config.EnableServicesElection ||
-					((sm.config.EnableRoutingTable || sm.config.EnableBGP) && (!sm.config.EnableLeaderElection && !sm.config.EnableServicesElection)) {
-					if svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
-						// Start an endpoint watcher if we're not watching it already
-						if !watchedService[string(svc.UID)] {
-							// background the endpoint watcher
-							go func() {
-								if svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
-									// Add Endpoint or EndpointSlices watcher
-									wg.Add(1)
-									var provider epProvider
-									if !sm.config.EnableEndpointSlices {
-										provider = &endpointsProvider{label: "endpoints"}
-									} else {
-										provider = &endpointslicesProvider{label: "endpointslices"}
-									}
-									if err = sm.watchEndpoint(activeServiceLoadBalancer[string(svc.UID)], sm.config.NodeName, svc, &wg, provider); err != nil {
-										log.Error(err)
-									}
-									wg.Done()
-								}
-							}()
-
-							if (sm.config.EnableRoutingTable || sm.config.EnableBGP) && (!sm.config.EnableLeaderElection && !sm.config.EnableServicesElection) {
-								wg.Add(1)
-								go func() {
-									err = serviceFunc(activeServiceLoadBalancer[string(svc.UID)], svc, &wg)
-									if err != nil {
-										log.Error(err)
-									}
-									wg.Done()
-								}()
+		}
+	}
+	// Scenarios:
+	// 1.
+	if !sm.serviceActive(string(svc.UID)) {
+		log.Debugf("(svcs) [%s] has been added/modified with addresses [%s]", svc.Name, fetchServiceAddresses(svc, sm.config))
+
+		wg.Add(1)
+		serviceCtx := sm.newServiceContext(string(svc.UID))
+		// An error once the service has been stopped (e.g. deleted) isn't retried
+		backgroundFailed := func(err error) {
+			if serviceCtx.Err() != nil {
+				log.Error(err)
+				return
+			}
+			failed(err)
+		}
+		// Background the services election
+		// EnableServicesElection enabled
+		// watchEndpoint will do a ServicesElection by Service and understands local endpoints
+		//
+		// EnableRoutingTable enabled and EnableLeaderElection disabled
+		// watchEndpoint will also not do a leaderElection by service.
+		if sm.config.EnableServicesElection ||
+			((sm.config.EnableRoutingTable || sm.config.EnableBGP) && (!sm.config.EnableLeaderElection && !sm.config.EnableServicesElection)) {
+			// The election of a service that requires a minimum of ready endpoints is also started by its endpoint watcher
+			minReady, _ := serviceMinReadyEndpoints(svc, sm.config)
+			watchEndpoints := svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal ||
+				(sm.config.EnableServicesElection && minReady > 0)
+			if watchEndpoints {
+				// Start an endpoint watcher if we're not watching it already
+				if !sm.serviceWatched(string(svc.UID)) {
+					// background the endpoint watcher
+					go func() {
+						if watchEndpoints {
+							// Add Endpoint or EndpointSlices watcher
+							wg.Add(1)
+							provider := sm.newEndpointsProvider(serviceCtx, svc)
+							if err := sm.watchEndpoint(serviceCtx, sm.config.NodeName, svc, wg, provider); err != nil {
+								backgroundFailed(err)
 							}
-							// We're now watching this service
-							watchedService[string(svc.UID)] = true
+							wg.Done()
 						}
-					} else if (sm.config.EnableBGP || sm.config.EnableRoutingTable) && (!sm.config.EnableLeaderElection && !sm.config.EnableServicesElection) {
-						go func() {
-							if svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeCluster {
-								// Add Endpoint watcher
-								wg.Add(1)
-								var provider epProvider
-								if !sm.config.EnableEndpointSlices {
-									provider = &endpointsProvider{label: "endpoints"}
-								} else {
-									provider = &endpointslicesProvider{label: "endpointslices"}
-								}
-								if err = sm.watchEndpoint(activeServiceLoadBalancer[string(svc.UID)], sm.config.NodeName, svc, &wg, provider); err != nil {
-									log.Error(err)
-								}
-								wg.Done()
-							}
-						}()
+					}()
 
+					if (sm.config.EnableRoutingTable || sm.config.EnableBGP) && (!sm.config.EnableLeaderElection && !sm.config.EnableServicesElection) {
 						wg.Add(1)
 						go func() {
-							err = serviceFunc(activeServiceLoadBalancer[string(svc.UID)], svc, &wg)
+							err := serviceFunc(serviceCtx, svc, wg)
 							if err != nil {
-								log.Error(err)
+								backgroundFailed(err)
 							}
 							wg.Done()
 						}()
-					} else {
-						// Increment the waitGroup before the service Func is called (Done is completed in there)
+					}
+					// We're now watching this service
+					sm.setServiceWatched(string(svc.UID), true)
+				}
+			} else if (sm.config.EnableBGP || sm.config.EnableRoutingTable) && (!sm.config.EnableLeaderElection && !sm.config.EnableServicesElection) {
+				go func() {
+					if svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeCluster {
+						// Add Endpoint watcher
 						wg.Add(1)
-						go func() {
-							err = serviceFunc(activeServiceLoadBalancer[string(svc.UID)], svc, &wg)
-							if err != nil {
-								log.Error(err)
-							}
-							wg.Done()
-						}()
+						provider := sm.newEndpointsProvider(serviceCtx, svc)
+						if err := sm.watchEndpoint(serviceCtx, sm.config.NodeName, svc, wg, provider); err != nil {
+							backgroundFailed(err)
+						}
+						wg.Done()
+					}
+				}()
+
+				wg.Add(1)
+				go func() {
+					err := serviceFunc(serviceCtx, svc, wg)
+					if err != nil {
+						backgroundFailed(err)
 					}
-				} else {
-					// Increment the waitGroup before the service Func is called (Done is completed in there)
-					wg.Add(1)
-					err = serviceFunc(activeServiceLoadBalancer[string(svc.UID)], svc, &wg)
+					wg.Done()
+				}()
+			} else {
+				// Increment the waitGroup before the service Func is called (Done is completed in there)
+				wg.Add(1)
+				go func() {
+					err := serviceFunc(serviceCtx, svc, wg)
 					if err != nil {
-						log.Error(err)
+						backgroundFailed(err)
 					}
 					wg.Done()
-				}
-				activeService[string(svc.UID)] = true
+				}()
 			}
-		case watch.Deleted:
-			svc, ok := event.Object.(*v1.Service)
-			if !ok {
-				return fmt.Errorf("unable to parse Kubernetes services from API watcher")
+		} else {
+			// Increment the waitGroup before the service Func is called (Done is completed in there)
+			wg.Add(1)
+			err := serviceFunc(serviceCtx, svc, wg)
+			wg.Done()
+			if err != nil {
+				sm.cancelService(string(svc.UID))
+				return err
 			}
-			if activeService[string(svc.UID)] {
-
-				// We only care about LoadBalancer services
-				if svc.Spec.Type != v1.ServiceTypeLoadBalancer {
-					break
-				}
-
-				// We can ignore this service
-				if svc.Annotations["kube-vip.io/ignore"] == "true" {
-					log.Infof("(svcs) [%s] has an ignore annotation for kube-vip", svc.Name)
-					break
-				}
+		}
+		sm.setServiceActive(string(svc.UID), true)
+		sm.setServiceStarted(svc, true)
+	}
+	return nil
+}
 
-				isRouteConfigured, err := isRouteConfigured(svc.UID)
-				if err != nil {
-					return fmt.Errorf("error while checkig if route is configured: %w", err)
-				}
-				// If no leader election is enabled, delete routes here
-				if !sm.config.EnableLeaderElection && !sm.config.EnableServicesElection &&
-					sm.config.EnableRoutingTable && isRouteConfigured {
-					if errs := sm.clearRoutes(svc); len(errs) == 0 {
-						configuredLocalRoutes.Store(string(svc.UID), false)
-					}
-				}
+// serviceDeleted stops the handling of a service that has been deleted
+func (sm *Manager) serviceDeleted(svc *v1.Service) error {
+	if err := sm.stopService(svc); err != nil {
+		return err
+	}
+	log.Infof("(svcs) [%s/%s] has been deleted", svc.Namespace, svc.Name)
+	return nil
+}
 
-				// If this is an active service then and additional leaderElection will handle stopping
-				err = sm.deleteService(string(svc.UID))
-				if err != nil {
-					log.Error(err)
-				}
+// stopService withdraws the addresses, routes and BGP paths of a service and stops its handling
+func (sm *Manager) stopService(svc *v1.Service) error {
+	// The routes of the leader of the cluster are cleared whilst the instance still exists, its BGP paths are
+	// withdrawn along with the instance
+	if sm.config.EnableRoutingTable && !sm.config.EnableBGP && sm.config.EnableLeaderElection && !sm.config.EnableServicesElection {
+		sm.clearRoutes(svc)
+	}
 
-				// Calls the cancel function of the context
-				if activeServiceLoadBalancerCancel[string(svc.UID)] != nil {
-					activeServiceLoadBalancerCancel[string(svc.UID)]()
-				}
-				activeService[string(svc.UID)] = false
-				watchedService[string(svc.UID)] = false
-			}
+	if sm.serviceActive(string(svc.UID)) {
 
-			if (sm.config.EnableBGP || sm.config.EnableRoutingTable) && sm.config.EnableLeaderElection && !sm.config.EnableServicesElection {
-				if sm.config.EnableBGP {
-					instance := sm.findServiceInstance(svc)
-					for _, vip := range instance.vipConfigs {
-						vipCidr := fmt.Sprintf("%s/%s", vip.VIP, vip.VIPCIDR)
-						err = sm.bgpServer.DelHost(vipCidr)
-						if err != nil {
-							log.Errorf("error deleting host %s: %s", vipCidr, err.Error())
-						}
-					}
-				} else {
-					sm.clearRoutes(svc)
-				}
-			}
+		// We only care about LoadBalancer services
+		if svc.Spec.Type != v1.ServiceTypeLoadBalancer {
+			return nil
+		}
 
-			log.Infof("(svcs) [%s/%s] has been deleted", svc.Namespace, svc.Name)
-		case watch.Bookmark:
-			// Un-used
-		case watch.Error:
-			log.Error("Error attempting to watch Kubernetes services")
-
-			// This round trip allows us to handle unstructured status
-			errObject := apierrors.FromObject(event.Object)
-			statusErr, ok := errObject.(*apierrors.StatusError)
-			if !ok {
-				log.Errorf(spew.Sprintf("Received an error which is not *metav1.Status but %#+v", event.Object))
+		isRouteConfigured, err := sm.isRouteConfigured(svc.UID)
+		if err != nil {
+			return fmt.Errorf("error while checkig if route is configured: %w", err)
+		}
+		// If no leader election is enabled, delete routes here
+		if !sm.config.EnableLeaderElection && !sm.config.EnableServicesElection &&
+			sm.config.EnableRoutingTable && isRouteConfigured {
+			if errs := sm.clearRoutes(svc); len(errs) == 0 {
+				sm.configuredLocalRoutes.Store(string(svc.UID), false)
 			}
+		}
 
-			status := statusErr.ErrStatus
-			log.Errorf("services -> %v", status)
-		default:
+		// If this is an active service then and additional leaderElection will handle stopping
+		err = sm.deleteService(string(svc.UID))
+		if err != nil {
+			log.Error(err)
 		}
+
+		// Calls the cancel function of the context
+		sm.cancelService(string(svc.UID))
+		sm.setServiceActive(string(svc.UID), false)
+		sm.setServiceWatched(string(svc.UID), false)
 	}
-	close(exitFunction)
-	log.Warnln("Stopping watching services for type: LoadBalancer in all namespaces")
+	sm.setServiceStarted(svc, false)
 	return nil
 }
 
@@ -334,8 +675,8 @@ func (sm *Manager) lbClassFilterLegacy(svc *v1.Service) bool {
 		return true
 	}
 	if svc.Spec.LoadBalancerClass != nil {
-		// if this isn't nil then it has been configured, check if it the kube-vip loadBalancer class
-		if *svc.Spec.LoadBalancerClass != sm.config.LoadBalancerClassName {
+		// if this isn't nil then it has been configured, check if it is one of the kube-vip loadBalancer classes
+		if !slices.Contains(sm.config.LoadBalancerClasses(), *svc.Spec.LoadBalancerClass) {
 			log.Infof("(svcs) [%s] specified the loadBalancer class [%s], ignoring", svc.Name, *svc.Spec.LoadBalancerClass)
 			return true
 		}
@@ -352,23 +693,25 @@ func (sm *Manager) lbClassFilter(svc *v1.Service) bool {
 		log.Infof("(svcs) service is nil, ignoring")
 		return true
 	}
-	if svc.Spec.LoadBalancerClass == nil && sm.config.LoadBalancerClassName != "" {
-		log.Infof("(svcs) [%s] specified no loadBalancer class, expected [%s], ignoring", svc.Name, sm.config.LoadBalancerClassName)
-		return true
-	}
-	if svc.Spec.LoadBalancerClass == nil && sm.config.LoadBalancerClassName == "" {
+	classes := sm.config.LoadBalancerClasses()
+	if svc.Spec.LoadBalancerClass == nil {
+		// services without a class are adopted when there are no classes, or when asked to
+		if len(classes) != 0 && !sm.config.LoadBalancerClassAdoptUnclassed {
+			log.Infof("(svcs) [%s] specified no loadBalancer class, expected one of %v, ignoring", svc.Name, classes)
+			return true
+		}
 		return false
 	}
-	if *svc.Spec.LoadBalancerClass != sm.config.LoadBalancerClassName {
-		log.Infof("(svcs) [%s] specified loadBalancer class [%s], expected [%s], ignoring", svc.Name, *svc.Spec.LoadBalancerClass, sm.config.LoadBalancerClassName)
+	if !slices.Contains(classes, *svc.Spec.LoadBalancerClass) {
+		log.Infof("(svcs) [%s] specified loadBalancer class [%s], expected one of %v, ignoring", svc.Name, *svc.Spec.LoadBalancerClass, classes)
 		return true
 	}
 	return false
 }
 
-func isRouteConfigured(serviceUID types.UID) (bool, error) {
+func (sm *Manager) isRouteConfigured(serviceUID types.UID) (bool, error) {
 	isConfigured := false
-	value, ok := configuredLocalRoutes.Load(string(serviceUID))
+	value, ok := sm.configuredLocalRoutes.Load(string(serviceUID))
 	if ok {
 		isConfigured, ok = value.(bool)
 		if !ok {
@@ -0,0 +1,86 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kube-vip/kube-vip/pkg/bgp"
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// withdrawnHosts is a BGP backend that records the hosts that are withdrawn
+type withdrawnHosts struct {
+	bgp.Backend
+	deleted []string
+}
+
+func (b *withdrawnHosts) DelHost(addr string) error {
+	b.deleted = append(b.deleted, addr)
+	return nil
+}
+
+// clusterLeaderBGPManager returns a manager that advertises the service over BGP as the leader of the cluster (rather
+// than of the service), with the service already started
+func clusterLeaderBGPManager(svc *v1.Service) (*Manager, *withdrawnHosts) {
+	backend := &withdrawnHosts{}
+	sm := &Manager{
+		config:    &kubevip.Config{EnableBGP: true, KubernetesLeaderElection: kubevip.KubernetesLeaderElection{EnableLeaderElection: true}},
+		bgpServer: backend,
+		services:  newServiceTracker(),
+		serviceInstances: []*Instance{{
+			UID:             string(svc.UID),
+			VIPs:            []string{"192.0.2.1"},
+			vipConfigs:      []*kubevip.Config{{VIP: "192.0.2.1", VIPCIDR: "32", EnableBGP: true}},
+			serviceSnapshot: svc,
+		}},
+	}
+	sm.setServiceActive(string(svc.UID), true)
+	sm.setServiceStarted(svc, true)
+	return sm, backend
+}
+
+func loadBalancerService(annotations map[string]string) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid", Annotations: annotations},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer, LoadBalancerIP: "192.0.2.1"},
+	}
+}
+
+func TestPausedServiceWithdrawn(t *testing.T) {
+	svc := loadBalancerService(map[string]string{pause: "true"})
+	sm, backend := clusterLeaderBGPManager(svc)
+	serviceFunc := func(context.Context, *v1.Service, *sync.WaitGroup) error {
+		t.Fatal("a paused service shouldn't be started")
+		return nil
+	}
+	if err := sm.serviceAddedOrModified(svc, true, serviceFunc, &sync.WaitGroup{}, func(error) {}); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.deleted) != 1 || backend.deleted[0] != "192.0.2.1/32" {
+		t.Errorf("withdrawn hosts = %v, want [192.0.2.1/32]", backend.deleted)
+	}
+	if sm.serviceActive(string(svc.UID)) || len(sm.serviceInstances) != 0 {
+		t.Error("the paused service should no longer be handled")
+	}
+}
+
+func TestIgnoredServiceWithdrawn(t *testing.T) {
+	svc := loadBalancerService(map[string]string{ignoreAnnotation: "true"})
+	sm, backend := clusterLeaderBGPManager(svc)
+	serviceFunc := func(context.Context, *v1.Service, *sync.WaitGroup) error {
+		t.Fatal("an ignored service shouldn't be started")
+		return nil
+	}
+	if err := sm.serviceAddedOrModified(svc, true, serviceFunc, &sync.WaitGroup{}, func(error) {}); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.deleted) != 1 || backend.deleted[0] != "192.0.2.1/32" {
+		t.Errorf("withdrawn hosts = %v, want [192.0.2.1/32]", backend.deleted)
+	}
+	if sm.serviceActive(string(svc.UID)) || len(sm.serviceInstances) != 0 {
+		t.Error("the ignored service should no longer be handled")
+	}
+}
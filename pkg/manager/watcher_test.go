@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/kube-vip/kube-vip/pkg/bgp"
+	"github.com/kube-vip/kube-vip/pkg/kubevip"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -143,3 +144,29 @@ func Test_parseBgpAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestParseNodePeers(t *testing.T) {
+	sm := &Manager{config: &kubevip.Config{
+		BGPPeerConfig: bgp.Peer{BFD: bgp.BFDConfig{Interval: 100, Multiplier: 5}},
+	}}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: map[string]string{}},
+	}
+
+	_, err := sm.parseNodePeers(node)
+	if err == nil {
+		t.Fatal("Parsing node peers should return an error when no annotation exists")
+	}
+
+	node.Annotations[bgpPeersAnnotation] = "10.0.0.1:64000,10.0.0.2:64001:password:true:true"
+	peers, err := sm.parseNodePeers(node)
+	if err != nil {
+		t.Fatalf("Parsing node peers should return nil with a valid annotation [%v]", err)
+	}
+
+	bgpPeers := []bgp.Peer{
+		{Address: "10.0.0.1", AS: uint32(64000), BFD: bgp.BFDConfig{Interval: 100, Multiplier: 5}},
+		{Address: "10.0.0.2", AS: uint32(64001), Password: "password", MultiHop: true, BFD: bgp.BFDConfig{Enabled: true, Interval: 100, Multiplier: 5}},
+	}
+	assert.Equal(t, bgpPeers, peers, "node peers parsed incorrectly")
+}
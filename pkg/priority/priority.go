@@ -0,0 +1,400 @@
+// Package priority gives the nodes with a higher priority (the kube-vip.io/priority label) precedence in the
+// Kubernetes leader elections, the nodes with a lower priority only hold the lease as a fallback
+package priority
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// Label is the node label that holds the priority of the node, nodes without it have a priority of 0
+	Label = "kube-vip.io/priority"
+
+	// preemptAnnotation is set on the lease by a node with a higher priority than the holder, asking it
+	// to release the lease. The value is the node and the time of the request (node@RFC3339), the request
+	// is refreshed every lease duration so that the request of a node that has gone again is ignored
+	preemptAnnotation = "kube-vip.io/preempt"
+)
+
+// nodePriority returns the priority of the node from its label
+func nodePriority(node *v1.Node) int {
+	priority, err := strconv.Atoi(node.Labels[Label])
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+func nodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// caches are the informers of the nodes with a priority and of the leases (by namespace) that are shared by the
+// elections of a client, so that the elections don't poll the API server
+type caches struct {
+	client kubernetes.Interface
+	nodes  cache.SharedIndexInformer
+
+	mutex  sync.Mutex
+	leases map[string]cache.SharedIndexInformer
+}
+
+var (
+	sharedCachesMutex sync.Mutex
+	sharedCaches      = make(map[kubernetes.Interface]*caches)
+)
+
+// cachesFor returns the shared informers of the client, starting the informer of the nodes (which runs for the life
+// of the process) when they are first used
+func cachesFor(client kubernetes.Interface) *caches {
+	sharedCachesMutex.Lock()
+	defer sharedCachesMutex.Unlock()
+	if i, exists := sharedCaches[client]; exists {
+		return i
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.LabelSelector = Label
+	}))
+	i := &caches{
+		client: client,
+		nodes:  factory.Core().V1().Nodes().Informer(),
+		leases: make(map[string]cache.SharedIndexInformer),
+	}
+	go i.nodes.Run(make(chan struct{}))
+	sharedCaches[client] = i
+	return i
+}
+
+// leaseInformer returns the informer of the leases of the namespace, starting it when it is first used
+func (i *caches) leaseInformer(namespace string) cache.SharedIndexInformer {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if informer, exists := i.leases[namespace]; exists {
+		return informer
+	}
+	informer := informers.NewSharedInformerFactoryWithOptions(i.client, 0, informers.WithNamespace(namespace)).Coordination().V1().Leases().Informer()
+	go informer.Run(make(chan struct{}))
+	i.leases[namespace] = informer
+	return informer
+}
+
+// NodePriority returns the priority of the node, a node that can't be found has a priority of 0
+func NodePriority(ctx context.Context, client kubernetes.Interface, name string) int {
+	nodes := cachesFor(client).nodes
+	if !cache.WaitForCacheSync(ctx.Done(), nodes.HasSynced) {
+		return 0
+	}
+	obj, exists, err := nodes.GetStore().GetByKey(name)
+	if err != nil || !exists {
+		return 0
+	}
+	return nodePriority(obj.(*v1.Node))
+}
+
+// rank returns the number of (distinct) priorities of the ready nodes that are higher than the priority of this node
+func (e *election) rank(ctx context.Context) int {
+	nodes := cachesFor(e.client).nodes
+	if !cache.WaitForCacheSync(ctx.Done(), nodes.HasSynced) {
+		return 0
+	}
+	higher := make(map[int]bool)
+	for _, obj := range nodes.GetStore().List() {
+		node, ok := obj.(*v1.Node)
+		if !ok {
+			continue
+		}
+		if p := nodePriority(node); p > e.priority && nodeReady(node) {
+			higher[p] = true
+		}
+	}
+	return len(higher)
+}
+
+// lease returns a copy of the lease from the shared informer, or false if it doesn't exist (or isn't known yet)
+func (e *election) lease(ctx context.Context) (*coordinationv1.Lease, bool) {
+	leases := cachesFor(e.client).leaseInformer(e.namespace)
+	if !cache.WaitForCacheSync(ctx.Done(), leases.HasSynced) {
+		return nil, false
+	}
+	obj, exists, err := leases.GetStore().GetByKey(e.namespace + "/" + e.leaseName)
+	if err != nil || !exists {
+		return nil, false
+	}
+	lease, ok := obj.(*coordinationv1.Lease)
+	if !ok {
+		return nil, false
+	}
+	// The lease is shared with the other elections, so a copy is returned for it to be updated
+	return lease.DeepCopy(), true
+}
+
+// leaseHeld returns the holder of the lease, if it is held and hasn't expired
+func leaseHeld(lease *coordinationv1.Lease) (string, bool) {
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" || lease.Spec.RenewTime == nil {
+		return "", false
+	}
+	duration := time.Duration(0)
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	if time.Since(lease.Spec.RenewTime.Time) > duration {
+		return "", false
+	}
+	return *lease.Spec.HolderIdentity, true
+}
+
+// election is the state of a leader election that the priority is applied to
+type election struct {
+	client    kubernetes.Interface
+	preempt   bool
+	identity  string
+	leaseName string
+	namespace string
+	retry     time.Duration
+	duration  time.Duration
+
+	// failbackDelay is the minimum time that the leader holds the lease before a preemption is granted
+	failbackDelay time.Duration
+
+	priority int
+	delay    time.Duration
+	// stale is the holder of the lease when this node joined the election, client-go reports it as the
+	// leader until it has observed that the lease has expired
+	stale string
+}
+
+// RunOrDie runs the election, giving precedence to the nodes with a higher priority. This node only becomes
+// a candidate once it is its turn (see wait), it leaves the election again if another node is elected and,
+// with preemption, it releases the lease when a node with a higher priority asks for it. The stopped leading
+// callback is only called if this node was the leader. A leader only grants a preemption once it has held
+// the lease for the failback delay, so that a node that is crash-looping doesn't move the lease back and forth.
+func RunOrDie(ctx context.Context, client kubernetes.Interface, preempt bool, failbackDelay time.Duration, config leaderelection.LeaderElectionConfig) {
+	lock, ok := config.Lock.(*resourcelock.LeaseLock)
+	if !ok {
+		leaderelection.RunOrDie(ctx, config)
+		return
+	}
+	e := &election{
+		client:    client,
+		preempt:   preempt,
+		identity:  lock.Identity(),
+		leaseName: lock.LeaseMeta.Name,
+		namespace: lock.LeaseMeta.Namespace,
+		retry:     config.RetryPeriod,
+		duration:  config.LeaseDuration,
+
+		failbackDelay: failbackDelay,
+	}
+	callbacks := config.Callbacks
+
+	for ctx.Err() == nil {
+		if err := e.wait(ctx); err != nil {
+			return
+		}
+
+		electionCtx, cancelElection := context.WithCancel(ctx)
+		var leading atomic.Bool
+		config.Callbacks = leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				leading.Store(true)
+				go func() {
+					select {
+					case <-e.watchPreemption(ctx):
+						cancelElection()
+					case <-ctx.Done():
+					}
+				}()
+				callbacks.OnStartedLeading(ctx)
+			},
+			OnStoppedLeading: func() {
+				if leading.Load() {
+					callbacks.OnStoppedLeading()
+				}
+			},
+			OnNewLeader: func(identity string) {
+				if callbacks.OnNewLeader != nil {
+					callbacks.OnNewLeader(identity)
+				}
+				if !leading.Load() && e.withdraw(identity) {
+					log.Infof("(priority) [%s] leads lease [%s/%s], leaving the election until it is released", identity, e.namespace, e.leaseName)
+					cancelElection()
+				}
+			},
+		}
+		leaderelection.RunOrDie(electionCtx, config)
+		cancelElection()
+	}
+}
+
+// withdraw returns if this node should leave the election, now that another node has been elected
+func (e *election) withdraw(identity string) bool {
+	if identity == e.identity || identity == e.stale {
+		return false
+	}
+	if e.delay > 0 {
+		return true
+	}
+	// A node with the highest priority remains a candidate, unless it can preempt the new leader
+	return e.preempt && NodePriority(context.Background(), e.client, identity) < e.priority
+}
+
+// wait blocks until this node should become a candidate in the election. Whilst the lease is held by
+// another node it waits, once the lease has expired (or has been released) it waits for two retry periods
+// for each priority of the ready nodes that is higher than its own, so that those nodes acquire it first.
+// A node with the highest priority joins straight away, unless it is waiting to preempt the holder.
+func (e *election) wait(ctx context.Context) error {
+	e.priority = NodePriority(ctx, e.client, e.identity)
+	e.delay = time.Duration(e.rank(ctx)) * 2 * e.retry
+
+	var expired time.Time
+	var holder string
+	holderPriority := 0
+	var requested time.Time
+	for {
+		lease, exists := e.lease(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		current, held := "", false
+		if exists {
+			current, held = leaseHeld(lease)
+			if lease.Spec.HolderIdentity != nil {
+				e.stale = *lease.Spec.HolderIdentity
+			}
+		}
+		if held && current != holder {
+			holder, holderPriority = current, NodePriority(ctx, e.client, current)
+			requested = time.Time{}
+		}
+
+		switch {
+		case held && current == e.identity:
+			return nil
+		case held:
+			expired = time.Time{}
+			canPreempt := e.preempt && e.priority > holderPriority
+			if e.delay == 0 && !canPreempt {
+				return nil
+			}
+			if canPreempt && time.Since(requested) >= e.duration {
+				if e.requestPreemption(ctx, lease, current, requested.IsZero()) {
+					requested = time.Now()
+				}
+			}
+		default:
+			if expired.IsZero() {
+				expired = time.Now()
+			}
+			if time.Since(expired) >= e.delay {
+				if e.delay > 0 {
+					log.Infof("(priority) lease [%s/%s] has no leader, joining the election with priority [%d]", e.namespace, e.leaseName, e.priority)
+				}
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.retry):
+		}
+	}
+}
+
+// requestPreemption asks the holder of the lease to release it
+func (e *election) requestPreemption(ctx context.Context, lease *coordinationv1.Lease, holder string, first bool) bool {
+	if lease.Annotations == nil {
+		lease.Annotations = make(map[string]string)
+	}
+	lease.Annotations[preemptAnnotation] = fmt.Sprintf("%s@%s", e.identity, time.Now().UTC().Format(time.RFC3339))
+	if _, err := e.client.CoordinationV1().Leases(e.namespace).Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		// A conflict with the renewal of the lease is retried at the next period
+		log.Debugf("(priority) unable to request preemption of lease [%s/%s]: %v", e.namespace, e.leaseName, err)
+		return false
+	}
+	if first {
+		log.Infof("(priority) asking [%s] to release lease [%s/%s] to this node, as it has a higher priority", holder, e.namespace, e.leaseName)
+	}
+	return true
+}
+
+// parsePreemption returns the node that requested the preemption and when it was (last) requested
+func parsePreemption(value string) (string, time.Time, error) {
+	requester, at, found := strings.Cut(value, "@")
+	if !found {
+		return "", time.Time{}, fmt.Errorf("preemption request [%s] should be node@time", value)
+	}
+	requested, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return requester, requested, nil
+}
+
+// watchPreemption is run by the leader, the returned channel is closed when a node with a higher priority
+// asks for the lease (as seen by the shared informer of the leases)
+func (e *election) watchPreemption(ctx context.Context) <-chan struct{} {
+	preempted := make(chan struct{})
+	go func() {
+		started := time.Now()
+		deferred := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(e.retry):
+			}
+
+			lease, exists := e.lease(ctx)
+			if !exists {
+				continue
+			}
+			value := lease.Annotations[preemptAnnotation]
+			if value == "" {
+				continue
+			}
+			requester, requested, err := parsePreemption(value)
+			// A request that hasn't been refreshed is from a node that has gone again
+			current := err == nil && time.Since(requested) <= 2*e.duration
+			if current && requester != e.identity && NodePriority(ctx, e.client, requester) > e.priority {
+				if time.Since(started) < e.failbackDelay {
+					if !deferred {
+						log.Infof("(priority) [%s] asked for lease [%s/%s], it will be released once it has been held for %s", requester, e.namespace, e.leaseName, e.failbackDelay)
+						deferred = true
+					}
+					continue
+				}
+				log.Infof("(priority) releasing lease [%s/%s] to [%s], as it has a higher priority", e.namespace, e.leaseName, requester)
+				close(preempted)
+				return
+			}
+			// The request has been granted (or is stale), so it is removed
+			delete(lease.Annotations, preemptAnnotation)
+			if _, err = e.client.CoordinationV1().Leases(e.namespace).Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+				log.Debugf("(priority) unable to clear preemption of lease [%s/%s]: %v", e.namespace, e.leaseName, err)
+			}
+		}
+	}()
+	return preempted
+}
@@ -0,0 +1,100 @@
+package priority
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func node(name, priority string, ready v1.ConditionStatus) *v1.Node {
+	n := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: ready}},
+		},
+	}
+	if priority != "" {
+		n.Labels[Label] = priority
+	}
+	return n
+}
+
+func TestRank(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		node("a", "100", v1.ConditionTrue),
+		node("b", "100", v1.ConditionTrue),
+		node("c", "50", v1.ConditionTrue),
+		node("d", "200", v1.ConditionFalse),
+		node("e", "", v1.ConditionTrue),
+	)
+	for name, want := range map[string]int{"a": 0, "b": 0, "c": 1, "d": 0, "e": 2} {
+		e := &election{client: client, identity: name}
+		e.priority = NodePriority(context.Background(), client, name)
+		if got := e.rank(context.Background()); got != want {
+			t.Errorf("rank of node [%s] = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestLeaseHeld(t *testing.T) {
+	holder := "a"
+	seconds := int32(5)
+	lease := func(renewed time.Duration, identity *string) *coordinationv1.Lease {
+		return &coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       identity,
+			LeaseDurationSeconds: &seconds,
+			RenewTime:            &metav1.MicroTime{Time: time.Now().Add(-renewed)},
+		}}
+	}
+	if got, held := leaseHeld(lease(time.Second, &holder)); !held || got != holder {
+		t.Errorf("renewed lease should be held by [%s], got [%s] %v", holder, got, held)
+	}
+	if _, held := leaseHeld(lease(time.Minute, &holder)); held {
+		t.Error("expired lease should not be held")
+	}
+	if _, held := leaseHeld(lease(time.Second, nil)); held {
+		t.Error("released lease should not be held")
+	}
+}
+
+func TestParsePreemption(t *testing.T) {
+	requester, requested, err := parsePreemption("node-a@2024-01-02T03:04:05Z")
+	if err != nil || requester != "node-a" || !requested.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected request [%s] at [%s]: %v", requester, requested, err)
+	}
+	for _, value := range []string{"node-a", "node-a@yesterday"} {
+		if _, _, err := parsePreemption(value); err == nil {
+			t.Errorf("request [%s] should not parse", value)
+		}
+	}
+}
+
+func TestLease(t *testing.T) {
+	holder := "a"
+	client := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "plndr-cp-lock", Namespace: "kube-system"},
+		Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	e := &election{client: client, namespace: "kube-system", leaseName: "plndr-cp-lock"}
+	lease, exists := e.lease(ctx)
+	if !exists || *lease.Spec.HolderIdentity != holder {
+		t.Fatalf("lease() = %v, %v, want the lease held by [%s]", lease, exists, holder)
+	}
+	// The lease is a copy, so that the shared lease isn't modified by a preemption request
+	lease.Annotations = map[string]string{preemptAnnotation: "b@2024-01-02T03:04:05Z"}
+	if lease, _ = e.lease(ctx); lease.Annotations[preemptAnnotation] != "" {
+		t.Error("lease() should return a copy of the shared lease")
+	}
+
+	if _, exists := (&election{client: client, namespace: "kube-system", leaseName: "other"}).lease(ctx); exists {
+		t.Error("lease() should not find a lease that doesn't exist")
+	}
+}
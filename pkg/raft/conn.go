@@ -0,0 +1,107 @@
+package raft
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// maxMessageSize is larger than any message that is sent, with the names of the members included
+const maxMessageSize = 4096
+
+// maxMessageAge is how far from the clock of this member a signed message can have been sent, the clocks of the
+// members must agree within it
+const maxMessageAge = 10 * time.Second
+
+// conn sends and receives the messages between the members
+type conn interface {
+	Send(to string, m *message) error
+	Receive() (*message, error)
+	Close() error
+}
+
+// udpConn sends the messages as UDP datagrams, a lost message is recovered by the next heartbeat or election
+type udpConn struct {
+	pc    net.PacketConn
+	peers map[string]*net.UDPAddr
+	key   []byte
+
+	// lastSent is when the last message of each peer that was received was sent, an earlier message is a replay
+	lastSent map[string]int64
+}
+
+func newUDPConn(bind string, peers map[string]string, key []byte) (*udpConn, error) {
+	pc, err := net.ListenPacket("udp", bind)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for Raft messages on [%s]: %w", bind, err)
+	}
+	c := &udpConn{pc: pc, peers: make(map[string]*net.UDPAddr), key: key, lastSent: make(map[string]int64)}
+	for name, address := range peers {
+		addr, err := net.ResolveUDPAddr("udp", address)
+		if err != nil {
+			pc.Close()
+			return nil, fmt.Errorf("unable to resolve Raft member [%s] address [%s]: %w", name, address, err)
+		}
+		c.peers[name] = addr
+	}
+	return c, nil
+}
+
+func (c *udpConn) Send(to string, m *message) error {
+	addr, exists := c.peers[to]
+	if !exists {
+		return fmt.Errorf("unknown Raft member [%s]", to)
+	}
+	sent := *m
+	sent.Sent = time.Now().UnixNano()
+	b, err := sent.marshal(c.key)
+	if err != nil {
+		return err
+	}
+	_, err = c.pc.WriteTo(b, addr)
+	return err
+}
+
+func (c *udpConn) Receive() (*message, error) {
+	b := make([]byte, maxMessageSize)
+	for {
+		n, _, err := c.pc.ReadFrom(b)
+		if err != nil {
+			return nil, err
+		}
+		m, err := parseMessage(b[:n], c.key)
+		if err != nil {
+			// Messages that can't be parsed (or authenticated) are ignored
+			continue
+		}
+		if _, exists := c.peers[m.From]; !exists {
+			continue
+		}
+		if !c.fresh(m, time.Now()) {
+			continue
+		}
+		return m, nil
+	}
+}
+
+// fresh returns whether a message hasn't been received before, a signed message is only accepted when it was sent
+// recently and after the last message of its member (so a message that arrives out of order is dropped like a lost
+// one). The messages without a key aren't authenticated, so they can't be protected from a replay either.
+func (c *udpConn) fresh(m *message, now time.Time) bool {
+	if len(c.key) == 0 {
+		return true
+	}
+	age := now.Sub(time.Unix(0, m.Sent))
+	if age > maxMessageAge || age < -maxMessageAge {
+		return false
+	}
+	if m.Sent <= c.lastSent[m.From] {
+		return false
+	}
+	c.lastSent[m.From] = m.Sent
+	return true
+}
+
+func (c *udpConn) Close() error {
+	return c.pc.Close()
+}
@@ -0,0 +1,384 @@
+// Package raft elects a leader amongst the kube-vip members with the leader election of the Raft consensus
+// algorithm, without depending on the Kubernetes API (so the VIP can be elected before the API server exists)
+package raft
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LeaderElectionConfig allows to configure the Raft election.
+type LeaderElectionConfig struct {
+	// Name of this member, it must be one of the members
+	Name string
+
+	// Members are the addresses (host:port) of the members of the election by name, including this member
+	Members map[string]string
+
+	// Key is a shared key that authenticates the messages between the members, without it any host that can reach
+	// the members can take part in the election. The signed messages can't be replayed, but the clocks of the
+	// members must then agree within 10 seconds.
+	Key []byte
+
+	// HeartbeatInterval is the time between the heartbeats of the leader
+	HeartbeatInterval time.Duration
+
+	// ElectionTimeout is the (minimum) time without a heartbeat before a member starts an election, the leader
+	// steps down before then if it can't reach a majority of the members
+	ElectionTimeout time.Duration
+
+	// Callbacks are callbacks that are triggered during certain lifecycle
+	// events of the election
+	Callbacks LeaderCallbacks
+}
+
+// LeaderCallbacks are callbacks that are triggered during certain
+// lifecycle events of the election.
+type LeaderCallbacks struct {
+	// OnStartedLeading is called when this member becomes the leader.
+	OnStartedLeading func(context.Context)
+	// OnStoppedLeading is called when this member stops being the leader.
+	OnStoppedLeading func()
+	// OnNewLeader is called when the member observes a leader that is
+	// not the previously observed leader, with the name of the leader.
+	OnNewLeader func(identity string)
+}
+
+// ParseMembers parses the members of the election as comma separated name=host:port
+func ParseMembers(members string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, member := range strings.Split(members, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		name, address, found := strings.Cut(member, "=")
+		if !found || name == "" || address == "" {
+			return nil, fmt.Errorf("Raft member [%s] should be name=host:port", member)
+		}
+		parsed[name] = address
+	}
+	return parsed, nil
+}
+
+type state int
+
+const (
+	stateFollower state = iota
+	stateCandidate
+	stateLeader
+)
+
+type member struct {
+	config *LeaderElectionConfig
+	conn   conn
+	peers  []string
+
+	state    state
+	term     uint64
+	votedFor string
+	votes    map[string]bool
+	leader   string
+	timer    *time.Timer
+
+	// lastHeartbeat is when the leader was last heard from, whilst it is alive the votes of other candidates are
+	// ignored, so that a member that rejoins doesn't disrupt the leader
+	lastHeartbeat time.Time
+	// lastAck is when every peer last acknowledged the heartbeats of this member, whilst it is the leader
+	lastAck map[string]time.Time
+	// noVotesUntil, the term and vote aren't persisted so a member that has (re)started doesn't vote until
+	// any election that it could have voted in has finished
+	noVotesUntil time.Time
+
+	cancelLeading context.CancelFunc
+}
+
+// RunElectionOrDie behaves the same way as RunElection but panics if there is an error.
+func RunElectionOrDie(ctx context.Context, config *LeaderElectionConfig) {
+	if err := RunElection(ctx, config); err != nil {
+		panic(err)
+	}
+}
+
+// RunElection joins the Raft election with the other members.
+// RunElection blocks until ctx is cancelled or this member stops being the leader.
+func RunElection(ctx context.Context, config *LeaderElectionConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	peers := make(map[string]string)
+	for name, address := range config.Members {
+		if name != config.Name {
+			peers[name] = address
+		}
+	}
+	c, err := newUDPConn(config.Members[config.Name], peers, config.Key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if len(config.Key) == 0 {
+		log.Warnf("[Raft] no key has been configured, the messages of the election aren't authenticated and any host that can reach [%s] can take part in it",
+			config.Members[config.Name])
+	}
+	log.Infof("[Raft] joined the election as [%s] with %d members", config.Name, len(config.Members))
+	return newMember(config, c).run(ctx)
+}
+
+func (config *LeaderElectionConfig) validate() error {
+	if _, exists := config.Members[config.Name]; !exists {
+		return fmt.Errorf("[%s] isn't one of the Raft members", config.Name)
+	}
+	if config.HeartbeatInterval <= 0 || config.ElectionTimeout <= 2*config.HeartbeatInterval {
+		return fmt.Errorf("the Raft election timeout [%s] must be more than twice the heartbeat interval [%s]",
+			config.ElectionTimeout, config.HeartbeatInterval)
+	}
+	return nil
+}
+
+func newMember(config *LeaderElectionConfig, c conn) *member {
+	m := &member{config: config, conn: c, lastAck: make(map[string]time.Time)}
+	for name := range config.Members {
+		if name != config.Name {
+			m.peers = append(m.peers, name)
+		}
+	}
+	return m
+}
+
+func (m *member) run(ctx context.Context) error {
+	messages := make(chan *message)
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			msg, err := m.conn.Receive()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	m.noVotesUntil = time.Now().Add(2 * m.config.ElectionTimeout)
+	m.timer = time.NewTimer(2*m.config.ElectionTimeout + m.electionTimeout())
+	defer m.timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if m.state == stateLeader {
+				// Resign, so that a follower takes over without waiting for the election timeout
+				m.broadcast(resign)
+				m.stopLeading()
+			}
+			return nil
+		case err := <-errs:
+			if m.state == stateLeader {
+				m.stopLeading()
+			}
+			return fmt.Errorf("unable to receive Raft messages: %w", err)
+		case <-m.timer.C:
+			if m.state != stateLeader {
+				m.startElection(ctx)
+				continue
+			}
+			if !m.hasQuorum() {
+				log.Warnf("[Raft] [%s] can't reach a majority of the members, stepping down", m.config.Name)
+				m.becomeFollower()
+				m.stopLeading()
+				return nil
+			}
+			m.broadcast(heartbeat)
+			resetTimer(m.timer, m.config.HeartbeatInterval)
+		case msg := <-messages:
+			if m.handleMessage(ctx, msg) {
+				// We've stopped being the leader
+				return nil
+			}
+		}
+	}
+}
+
+// handleMessage processes a message from another member, it returns true if this member has stopped being the leader
+func (m *member) handleMessage(ctx context.Context, msg *message) bool {
+	// Whilst there is a leader, a candidate that has rejoined can't disrupt it with a higher term
+	if msg.Type == requestVote && m.leaderAlive() {
+		return false
+	}
+	if msg.Term > m.term {
+		wasLeader := m.state == stateLeader
+		m.term = msg.Term
+		m.votedFor = ""
+		m.becomeFollower()
+		if wasLeader {
+			log.Infof("[Raft] [%s] has a newer term [%d], stepping down", msg.From, msg.Term)
+			m.stopLeading()
+			return true
+		}
+	}
+
+	switch msg.Type {
+	case requestVote:
+		granted := msg.Term == m.term && (m.votedFor == "" || m.votedFor == msg.From) && time.Now().After(m.noVotesUntil)
+		if granted {
+			m.votedFor = msg.From
+			resetTimer(m.timer, m.electionTimeout())
+		}
+		m.send(msg.From, &message{Type: vote, Term: m.term, Granted: granted})
+	case vote:
+		if m.state == stateCandidate && msg.Term == m.term && msg.Granted {
+			m.votes[msg.From] = true
+			if m.isMajority(len(m.votes)) {
+				m.becomeLeader(ctx)
+			}
+		}
+	case heartbeat:
+		if msg.Term < m.term {
+			// Let the stale leader know about the newer term
+			m.send(msg.From, &message{Type: heartbeatAck, Term: m.term})
+			return false
+		}
+		if m.state == stateLeader {
+			return false
+		}
+		m.becomeFollower()
+		m.lastHeartbeat = time.Now()
+		m.observe(msg.From)
+		resetTimer(m.timer, m.electionTimeout())
+		m.send(msg.From, &message{Type: heartbeatAck, Term: m.term})
+	case heartbeatAck:
+		if m.state == stateLeader && msg.Term == m.term {
+			m.lastAck[msg.From] = time.Now()
+		}
+	case resign:
+		if msg.Term == m.term && msg.From == m.leader && m.state == stateFollower {
+			m.lastHeartbeat = time.Time{}
+			resetTimer(m.timer, time.Duration(rand.Int63n(int64(m.config.HeartbeatInterval)))) //nolint:gosec
+		}
+	}
+	return false
+}
+
+func (m *member) startElection(ctx context.Context) {
+	m.state = stateCandidate
+	m.term++
+	m.votedFor = m.config.Name
+	m.votes = map[string]bool{m.config.Name: true}
+	m.lastHeartbeat = time.Time{}
+	log.Debugf("[Raft] [%s] is starting an election for term [%d]", m.config.Name, m.term)
+	if m.isMajority(len(m.votes)) {
+		m.becomeLeader(ctx)
+		return
+	}
+	resetTimer(m.timer, m.electionTimeout())
+	m.broadcast(requestVote)
+}
+
+func (m *member) becomeLeader(ctx context.Context) {
+	log.Infof("[Raft] [%s] is becoming the leader for term [%d]", m.config.Name, m.term)
+	m.state = stateLeader
+	now := time.Now()
+	for _, peer := range m.peers {
+		m.lastAck[peer] = now
+	}
+	m.broadcast(heartbeat)
+	resetTimer(m.timer, m.config.HeartbeatInterval)
+	m.observe(m.config.Name)
+
+	var leadingCtx context.Context
+	leadingCtx, m.cancelLeading = context.WithCancel(ctx)
+	if m.config.Callbacks.OnStartedLeading != nil {
+		go m.config.Callbacks.OnStartedLeading(leadingCtx)
+	}
+}
+
+func (m *member) becomeFollower() {
+	if m.state != stateFollower {
+		m.state = stateFollower
+		resetTimer(m.timer, m.electionTimeout())
+	}
+}
+
+func (m *member) stopLeading() {
+	if m.cancelLeading != nil {
+		m.cancelLeading()
+	}
+	if m.config.Callbacks.OnStoppedLeading != nil {
+		m.config.Callbacks.OnStoppedLeading()
+	}
+}
+
+func (m *member) observe(leader string) {
+	if leader == m.leader {
+		return
+	}
+	m.leader = leader
+	if m.config.Callbacks.OnNewLeader != nil {
+		m.config.Callbacks.OnNewLeader(leader)
+	}
+}
+
+// leaderAlive returns if this member is the leader or has heard from the leader within the election timeout
+func (m *member) leaderAlive() bool {
+	return m.state == stateLeader || time.Since(m.lastHeartbeat) < m.config.ElectionTimeout
+}
+
+// hasQuorum returns if a majority of the members (including the leader) acknowledged a heartbeat recently
+// enough, the leader steps down a heartbeat before the followers could start an election
+func (m *member) hasQuorum() bool {
+	acks := 1
+	for _, peer := range m.peers {
+		if time.Since(m.lastAck[peer]) < m.config.ElectionTimeout-m.config.HeartbeatInterval {
+			acks++
+		}
+	}
+	return m.isMajority(acks)
+}
+
+func (m *member) isMajority(count int) bool {
+	return count > len(m.config.Members)/2
+}
+
+func (m *member) broadcast(t messageType) {
+	for _, peer := range m.peers {
+		m.send(peer, &message{Type: t, Term: m.term})
+	}
+}
+
+func (m *member) send(to string, msg *message) {
+	msg.From = m.config.Name
+	if err := m.conn.Send(to, msg); err != nil {
+		log.Debugf("[Raft] unable to send [%s] to [%s]: %v", msg.Type, to, err)
+	}
+}
+
+// electionTimeout returns a random timeout between the election timeout and twice the election timeout, so
+// that the members don't all start an election at the same time
+func (m *member) electionTimeout() time.Duration {
+	return m.config.ElectionTimeout + time.Duration(rand.Int63n(int64(m.config.ElectionTimeout))) //nolint:gosec
+}
+
+// resetTimer stops and drains the timer before resetting it
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,214 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// network is an in-memory network that connects the members of a test, members can be partitioned from it
+type network struct {
+	mutex       sync.Mutex
+	conns       map[string]*netConn
+	partitioned map[string]bool
+}
+
+type netConn struct {
+	network *network
+	name    string
+	inbox   chan *message
+	closed  chan struct{}
+	closing sync.Once
+}
+
+func (n *network) join(name string) *netConn {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	c := &netConn{network: n, name: name, inbox: make(chan *message, 100), closed: make(chan struct{})}
+	n.conns[name] = c
+	return c
+}
+
+func (n *network) partition(name string, partitioned bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.partitioned[name] = partitioned
+}
+
+func (c *netConn) Send(to string, m *message) error {
+	c.network.mutex.Lock()
+	defer c.network.mutex.Unlock()
+	peer, exists := c.network.conns[to]
+	if !exists || c.network.partitioned[to] || c.network.partitioned[c.name] {
+		return nil
+	}
+	copied := *m
+	select {
+	case peer.inbox <- &copied:
+	default:
+	}
+	return nil
+}
+
+func (c *netConn) Receive() (*message, error) {
+	select {
+	case m := <-c.inbox:
+		return m, nil
+	case <-c.closed:
+		return nil, errors.New("closed")
+	}
+}
+
+func (c *netConn) Close() error {
+	c.closing.Do(func() { close(c.closed) })
+	return nil
+}
+
+type testMember struct {
+	name    string
+	leading chan bool
+	cancel  context.CancelFunc
+}
+
+func startMember(n *network, name string, members map[string]string) *testMember {
+	tm := &testMember{name: name, leading: make(chan bool, 10)}
+	config := &LeaderElectionConfig{
+		Name:              name,
+		Members:           members,
+		HeartbeatInterval: 10 * time.Millisecond,
+		ElectionTimeout:   50 * time.Millisecond,
+		Callbacks: LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { tm.leading <- true },
+			OnStoppedLeading: func() { tm.leading <- false },
+		},
+	}
+	var ctx context.Context
+	ctx, tm.cancel = context.WithCancel(context.Background())
+	c := n.join(name)
+	go func() {
+		_ = newMember(config, c).run(ctx)
+		c.Close()
+	}()
+	return tm
+}
+
+// waitForLeader returns the member that starts leading
+func waitForLeader(t *testing.T, members []*testMember) *testMember {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		for _, tm := range members {
+			select {
+			case leading := <-tm.leading:
+				if leading {
+					return tm
+				}
+			default:
+			}
+		}
+		select {
+		case <-timeout:
+			t.Fatal("no member became the leader")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestElection(t *testing.T) {
+	n := &network{conns: make(map[string]*netConn), partitioned: make(map[string]bool)}
+	addresses := map[string]string{"a": "a:1", "b": "b:1", "c": "c:1"}
+	var members []*testMember
+	for name := range addresses {
+		members = append(members, startMember(n, name, addresses))
+	}
+	defer func() {
+		for _, tm := range members {
+			tm.cancel()
+		}
+	}()
+
+	leader := waitForLeader(t, members)
+
+	// A partitioned leader steps down and the others elect a new leader
+	n.partition(leader.name, true)
+	select {
+	case leading := <-leader.leading:
+		if leading {
+			t.Fatal("partitioned leader should stop leading")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("partitioned leader didn't step down")
+	}
+	var others []*testMember
+	for _, tm := range members {
+		if tm != leader {
+			others = append(others, tm)
+		}
+	}
+	next := waitForLeader(t, others)
+
+	// The old leader rejoins after the partition, then the leader that is shutting down resigns and the
+	// remaining member takes over
+	n.partition(leader.name, false)
+	rejoined := startMember(n, leader.name, addresses)
+	members = append(members, rejoined)
+	time.Sleep(150 * time.Millisecond)
+	next.cancel()
+	for _, tm := range others {
+		if tm != next {
+			waitForLeader(t, []*testMember{tm, rejoined})
+		}
+	}
+}
+
+func TestMessageKey(t *testing.T) {
+	key := []byte("secret")
+	b, err := (&message{Type: heartbeat, Term: 3, From: "a"}).marshal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := parseMessage(b, key)
+	if err != nil || m.Type != heartbeat || m.Term != 3 || m.From != "a" {
+		t.Fatalf("unexpected message %+v: %v", m, err)
+	}
+	if _, err := parseMessage(b, []byte("other")); err == nil {
+		t.Error("message signed with another key should be rejected")
+	}
+	if _, err := parseMessage(bytes.Replace(b, []byte(`"term":3`), []byte(`"term":4`), 1), key); err == nil {
+		t.Error("modified message should be rejected")
+	}
+}
+
+func TestMessageReplay(t *testing.T) {
+	c := &udpConn{key: []byte("secret"), lastSent: make(map[string]int64)}
+	now := time.Now()
+	m := &message{Type: heartbeat, Term: 3, From: "a", Sent: now.UnixNano()}
+	if !c.fresh(m, now) {
+		t.Fatal("a new message should be accepted")
+	}
+	if c.fresh(m, now) {
+		t.Error("a replayed message should be rejected")
+	}
+	if c.fresh(&message{Type: heartbeat, Term: 3, From: "a", Sent: now.Add(-time.Second).UnixNano()}, now) {
+		t.Error("a message sent before the last one should be rejected")
+	}
+	if c.fresh(&message{Type: heartbeat, Term: 3, From: "b", Sent: now.Add(-time.Minute).UnixNano()}, now) {
+		t.Error("a message sent before the window should be rejected")
+	}
+	if !c.fresh(&message{Type: heartbeat, Term: 3, From: "b", Sent: now.Add(time.Second).UnixNano()}, now) {
+		t.Error("a message of another member should be accepted")
+	}
+}
+
+func TestParseMembers(t *testing.T) {
+	members, err := ParseMembers("a=10.0.0.1:7947, b=10.0.0.2:7947")
+	if err != nil || len(members) != 2 || members["b"] != "10.0.0.2:7947" {
+		t.Fatalf("unexpected members %v: %v", members, err)
+	}
+	if _, err := ParseMembers("a"); err == nil {
+		t.Error("member without an address should be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+package raft
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+// messageType is the type of a message between the members of the election
+type messageType string
+
+const (
+	// requestVote is sent by a candidate to ask for the votes of the other members
+	requestVote messageType = "requestVote"
+	// vote is the answer to a request for a vote
+	vote messageType = "vote"
+	// heartbeat is sent by the leader to keep its leadership
+	heartbeat messageType = "heartbeat"
+	// heartbeatAck is the answer to a heartbeat, so that the leader knows it can reach a majority
+	heartbeatAck messageType = "heartbeatAck"
+	// resign is sent by a leader that is shutting down, so that an election starts straight away
+	resign messageType = "resign"
+)
+
+// message is a message between the members of the election, only leader election is implemented so there
+// isn't a log to replicate
+type message struct {
+	Type    messageType `json:"type"`
+	Term    uint64      `json:"term"`
+	From    string      `json:"from"`
+	Granted bool        `json:"granted,omitempty"`
+	// Sent is when the message was sent (in Unix nanoseconds), so that a signed message can't be replayed
+	Sent int64 `json:"sent,omitempty"`
+}
+
+// envelope authenticates a message with the shared key of the members
+type envelope struct {
+	Message json.RawMessage `json:"message"`
+	MAC     []byte          `json:"mac,omitempty"`
+}
+
+func mac(key, payload []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// marshal encodes the message, it is signed if there is a key
+func (m *message) marshal(key []byte) ([]byte, error) {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	e := envelope{Message: payload}
+	if len(key) > 0 {
+		e.MAC = mac(key, payload)
+	}
+	return json.Marshal(e)
+}
+
+// parseMessage decodes a message, if there is a key the message must be signed with it
+func parseMessage(b, key []byte) (*message, error) {
+	var e envelope
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	if len(key) > 0 && !hmac.Equal(e.MAC, mac(key, e.Message)) {
+		return nil, fmt.Errorf("message isn't signed with the key of the election")
+	}
+	var m message
+	if err := json.Unmarshal(e.Message, &m); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,53 @@
+package sysctl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ReadProcSys returns the value of a file in /proc/sys, without the trailing newline
+func ReadProcSys(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Saved holds the original values of the files in /proc/sys that have been changed
+type Saved map[string]string
+
+// SetAndSave writes the value to the file in /proc/sys, remembering the original value so that it can be
+// restored. The original of a file that is written more than once is kept.
+func (s Saved) SetAndSave(path, value string) error {
+	original, err := ReadProcSys(path)
+	if err != nil {
+		return err
+	}
+	if original == value {
+		return nil
+	}
+	if err = WriteProcSys(path, value); err != nil {
+		return err
+	}
+	if _, exists := s[path]; !exists {
+		s[path] = original
+	}
+	log.Infof("sysctl set %s to %s (was %s)", path, value, original)
+	return nil
+}
+
+// Restore writes the original values back
+func (s Saved) Restore() {
+	for path, original := range s {
+		if err := WriteProcSys(path, original); err != nil {
+			log.Errorf("unable to restore %s to %s: %v", path, original, err)
+			continue
+		}
+		log.Infof("sysctl restored %s to %s", path, original)
+		delete(s, path)
+	}
+}
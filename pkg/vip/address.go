@@ -1,9 +1,11 @@
 package vip
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -39,6 +41,9 @@ type Network interface {
 	SetServicePorts(service *v1.Service)
 	Interface() string
 	IsDADFAIL() bool
+	Restore() error
+	RepairDrift() ([]string, error)
+	SetNextHops(hops []string) error
 	IsDNS() bool
 	IsDDNS() bool
 	DDNSHostName() string
@@ -47,7 +52,8 @@ type Network interface {
 
 // network - This allows network configuration
 type network struct {
-	mu sync.Mutex
+	mu   sync.Mutex
+	host *Host
 
 	address        *netlink.Addr
 	link           netlink.Link
@@ -64,6 +70,13 @@ type network struct {
 	routeTable       int
 	routingTableType int
 	routingProtocol  int
+	routingMetric    int
+	routingSources   []net.IP
+	nextHops         []net.IP
+
+	// bound and routed are set whilst the address (or route) is configured, so that it can be restored
+	bound  bool
+	routed bool
 }
 
 func netlinkParse(addr string) (*netlink.Addr, error) {
@@ -75,9 +88,14 @@ func netlinkParse(addr string) (*netlink.Addr, error) {
 }
 
 // NewConfig will attempt to provide an interface to the kernel network configuration
-func NewConfig(address string, iface string, subnet string, isDDNS bool, tableID int, tableType int, routingProtocol int, dnsMode, forwardMethod, iptablesBackend string) ([]Network, error) {
+func NewConfig(host *Host, address string, iface string, subnet string, isDDNS bool, tableID int, tableType int, routingProtocol int, routingMetric int, routingSource, dnsMode, forwardMethod, iptablesBackend string) ([]Network, error) {
 	networks := []Network{}
 
+	routingSources, err := parseRoutingSources(routingSource)
+	if err != nil {
+		return networks, err
+	}
+
 	link, err := netlink.LinkByName(iface)
 	if err != nil {
 		return networks, errors.Wrapf(err, "could not get link for interface '%s'", iface)
@@ -85,10 +103,13 @@ func NewConfig(address string, iface string, subnet string, isDDNS bool, tableID
 
 	if IsIP(address) {
 		result := &network{
+			host:             host,
 			link:             link,
 			routeTable:       tableID,
 			routingTableType: tableType,
 			routingProtocol:  routingProtocol,
+			routingMetric:    routingMetric,
+			routingSources:   routingSources,
 			forwardMethod:    forwardMethod,
 			iptablesBackend:  iptablesBackend,
 		}
@@ -118,10 +139,13 @@ func NewConfig(address string, iface string, subnet string, isDDNS bool, tableID
 			// when leader starts, should do get IP from DHCP for the domain
 			if isDDNS {
 				result := &network{
+					host:             host,
 					link:             link,
 					routeTable:       tableID,
 					routingTableType: tableType,
 					routingProtocol:  routingProtocol,
+					routingMetric:    routingMetric,
+					routingSources:   routingSources,
 					forwardMethod:    forwardMethod,
 					iptablesBackend:  iptablesBackend,
 					isDDNS:           isDDNS,
@@ -136,10 +160,13 @@ func NewConfig(address string, iface string, subnet string, isDDNS bool, tableID
 
 		for _, ip := range ips {
 			result := &network{
+				host:             host,
 				link:             link,
 				routeTable:       tableID,
 				routingTableType: tableType,
 				routingProtocol:  routingProtocol,
+				routingMetric:    routingMetric,
+				routingSources:   routingSources,
 				forwardMethod:    forwardMethod,
 				iptablesBackend:  iptablesBackend,
 				isDDNS:           isDDNS,
@@ -161,6 +188,36 @@ func NewConfig(address string, iface string, subnet string, isDDNS bool, tableID
 	return networks, nil
 }
 
+// knownRoutingProtocols are the protocols of other routing daemons, that shouldn't be used by kube-vip
+var knownRoutingProtocols = map[int]string{
+	unix.RTPROT_RA:         "router advertisements",
+	unix.RTPROT_ZEBRA:      "zebra (FRR)",
+	unix.RTPROT_BIRD:       "bird",
+	unix.RTPROT_DHCP:       "dhcp",
+	unix.RTPROT_KEEPALIVED: "keepalived",
+	unix.RTPROT_BABEL:      "babel",
+	unix.RTPROT_BGP:        "bgp",
+	unix.RTPROT_ISIS:       "is-is",
+	unix.RTPROT_OSPF:       "ospf",
+	unix.RTPROT_RIP:        "rip",
+	unix.RTPROT_EIGRP:      "eigrp",
+}
+
+// ValidateRoutingProtocol checks the protocol that marks the routes that are owned by kube-vip, as the
+// routes with it are garbage collected it can't be one of the protocols of the kernel
+func ValidateRoutingProtocol(protocol int) error {
+	if protocol < 0 || protocol > 255 {
+		return fmt.Errorf("routing protocol [%d] should be between 0 and 255", protocol)
+	}
+	if protocol <= unix.RTPROT_STATIC {
+		return fmt.Errorf("routing protocol [%d] is reserved by the kernel, routes that kube-vip doesn't own would be garbage collected", protocol)
+	}
+	if name, known := knownRoutingProtocols[protocol]; known {
+		log.Warnf("routing protocol [%d] is also used by %s, its routes may be garbage collected by kube-vip", protocol, name)
+	}
+	return nil
+}
+
 // ListRoutes returns all routes from selected table with selected protocol
 func ListRoutes(table, protocol int) ([]netlink.Route, error) {
 	route := &netlink.Route{
@@ -195,24 +252,207 @@ func (configurator *network) PrepareRoute() *netlink.Route {
 	route := &netlink.Route{
 		Scope:     routeScope,
 		Dst:       configurator.address.IPNet,
-		LinkIndex: configurator.link.Attrs().Index,
+		LinkIndex: configurator.currentLink().Attrs().Index,
 		Table:     configurator.routeTable,
 		Type:      configurator.routingTableType,
 		Protocol:  netlink.RouteProtocol(configurator.routingProtocol),
+		Priority:  configurator.routingMetric,
+		Src:       configurator.routingSource(),
+	}
+	// With next-hops the kernel balances the traffic (ECMP) across them, instead of sending it to the interface
+	if hops := configurator.routeNextHops(); len(hops) > 0 {
+		route.LinkIndex = 0
+		for _, hop := range hops {
+			route.MultiPath = append(route.MultiPath, &netlink.NexthopInfo{Gw: hop})
+		}
 	}
 	return route
 }
 
+// routeNextHops returns the next-hops with the same family as the VIP
+func (configurator *network) routeNextHops() []net.IP {
+	configurator.mu.Lock()
+	defer configurator.mu.Unlock()
+
+	var hops []net.IP
+	for _, hop := range configurator.nextHops {
+		if (hop.To4() != nil) == (configurator.address.IP.To4() != nil) {
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// SetNextHops - Set the next-hops (e.g. the local endpoints) of the route, if the route is installed
+// it is updated
+func (configurator *network) SetNextHops(hops []string) error {
+	var nextHops []net.IP
+	for _, hop := range hops {
+		ip := net.ParseIP(hop)
+		if ip == nil {
+			return fmt.Errorf("could not parse next-hop '%s'", hop)
+		}
+		nextHops = append(nextHops, ip)
+	}
+	sort.Slice(nextHops, func(i, j int) bool { return bytes.Compare(nextHops[i], nextHops[j]) < 0 })
+
+	configurator.mu.Lock()
+	unchanged := len(nextHops) == len(configurator.nextHops)
+	for i := 0; unchanged && i < len(nextHops); i++ {
+		unchanged = nextHops[i].Equal(configurator.nextHops[i])
+	}
+	configurator.nextHops = nextHops
+	routed := configurator.routed
+	configurator.mu.Unlock()
+
+	if unchanged || !routed {
+		return nil
+	}
+	if err := netlink.RouteReplace(configurator.PrepareRoute()); err != nil {
+		return errors.Wrap(err, "could not update next-hops of route")
+	}
+	return nil
+}
+
+// parseRoutingSources parses the (comma separated) source addresses that are used as the src hint of the routes
+func parseRoutingSources(sources string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, source := range strings.Split(sources, ",") {
+		if source = strings.TrimSpace(source); source == "" {
+			continue
+		}
+		ip := net.ParseIP(source)
+		if ip == nil {
+			return nil, fmt.Errorf("could not parse routing source address '%s'", source)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// routingSource returns the source address with the same family as the VIP, if there is one
+func (configurator *network) routingSource() net.IP {
+	for _, ip := range configurator.routingSources {
+		if (ip.To4() != nil) == (configurator.address.IP.To4() != nil) {
+			return ip
+		}
+	}
+	return nil
+}
+
+// currentLink returns the link of the interface, looking it up again if the interface has been
+// re-created (e.g. by an SR-IOV VF reset or a bond rebuild) as it will have a new index
+func (configurator *network) currentLink() netlink.Link {
+	configurator.mu.Lock()
+	defer configurator.mu.Unlock()
+
+	name := configurator.link.Attrs().Name
+	link, err := netlink.LinkByName(name)
+	if err == nil && link.Attrs().Index != configurator.link.Attrs().Index {
+		log.Infof("interface [%s] has been re-created, index [%d] => [%d]", name, configurator.link.Attrs().Index, link.Attrs().Index)
+		configurator.link = link
+	}
+	return configurator.link
+}
+
+// setApplied records whether the address or the route is configured
+func (configurator *network) setApplied(field *bool, applied bool) {
+	configurator.mu.Lock()
+	defer configurator.mu.Unlock()
+	*field = applied
+}
+
 // AddRoute - Add an IP address to a route table
 func (configurator *network) AddRoute() error {
 	route := configurator.PrepareRoute()
-	return netlink.RouteAdd(route)
+	configurator.host.blackholes.remove(route)
+	err := netlink.RouteAdd(route)
+	if err == nil || errors.Is(err, unix.EEXIST) {
+		configurator.setApplied(&configurator.routed, true)
+		configurator.host.routes.add(configurator)
+	}
+	return err
 }
 
 // DeleteRoute - Delete an IP address from a route table
 func (configurator *network) DeleteRoute() error {
+	configurator.setApplied(&configurator.routed, false)
+	configurator.host.routes.remove(configurator)
 	route := configurator.PrepareRoute()
-	return netlink.RouteDel(route)
+	if err := netlink.RouteDel(route); err != nil {
+		return err
+	}
+	configurator.host.blackholes.add(route)
+	return nil
+}
+
+// Restore - Re-apply the address and the route to the interface, if they were configured before it was
+// re-created
+func (configurator *network) Restore() error {
+	configurator.mu.Lock()
+	bound, routed := configurator.bound, configurator.routed
+	configurator.mu.Unlock()
+
+	if routed {
+		if err := netlink.RouteReplace(configurator.PrepareRoute()); err != nil {
+			return errors.Wrap(err, "could not restore route")
+		}
+	}
+	if bound {
+		if err := configurator.AddIP(); err != nil {
+			return errors.Wrap(err, "could not restore ip")
+		}
+	}
+	return nil
+}
+
+// RepairDrift - Re-apply the address and the route if they are configured but have been removed from the
+// node out-of-band, what has been repaired ("address" or "route") is returned
+func (configurator *network) RepairDrift() ([]string, error) {
+	configurator.mu.Lock()
+	bound, routed := configurator.bound, configurator.routed
+	configurator.mu.Unlock()
+
+	var repaired []string
+	if bound {
+		set, err := configurator.IsSet()
+		if err != nil {
+			return repaired, err
+		}
+		if !set {
+			if err = configurator.AddIP(); err != nil {
+				return repaired, errors.Wrap(err, "could not repair ip")
+			}
+			repaired = append(repaired, "address")
+		}
+	}
+	if routed {
+		installed, err := configurator.routeInstalled()
+		if err != nil {
+			return repaired, err
+		}
+		if !installed {
+			if err = netlink.RouteReplace(configurator.PrepareRoute()); err != nil {
+				return repaired, errors.Wrap(err, "could not repair route")
+			}
+			repaired = append(repaired, "route")
+		}
+	}
+	return repaired, nil
+}
+
+// routeInstalled checks that the route table still has the route of the address with the protocol of kube-vip
+func (configurator *network) routeInstalled() (bool, error) {
+	routes, err := configurator.getRoutes()
+	if err != nil {
+		return false, err
+	}
+	for _, route := range *routes {
+		if int(route.Protocol) == configurator.routingProtocol {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // GetRoutes - Get an IP addresses from a route table
@@ -244,11 +484,40 @@ func (configurator *network) UpdateRoutes() (bool, error) {
 	return isUpdated, nil
 }
 
+// serviceAddressLabel is the suffix of the label (IFA_LABEL) of the IPv4 addresses that are added for services, so
+// that they can be told apart from the other addresses of an interface
+const serviceAddressLabel = ":kv"
+
+// addressLabel returns the label of an address that is added to an interface for a service, only IPv4 addresses
+// can be labelled and a label is limited to the size of an interface name
+func addressLabel(link netlink.Link, ip net.IP) string {
+	label := link.Attrs().Name + serviceAddressLabel
+	if ip.To4() == nil || len(label) >= unix.IFNAMSIZ {
+		return ""
+	}
+	return label
+}
+
+// IsServiceAddress checks if an address of an interface has been added (and labelled) by kube-vip for a service
+func IsServiceAddress(link netlink.Link, address netlink.Addr) bool {
+	return address.Label != "" && address.Label == addressLabel(link, address.IP)
+}
+
 // AddIP - Add an IP address to the interface
 func (configurator *network) AddIP() error {
-	if err := netlink.AddrReplace(configurator.link, configurator.address); err != nil {
+	configurator.mu.Lock()
+	isService := configurator.serviceName != ""
+	configurator.mu.Unlock()
+
+	link := configurator.currentLink()
+	address := *configurator.address
+	if isService {
+		address.Label = addressLabel(link, address.IP)
+	}
+	if err := netlink.AddrReplace(link, &address); err != nil {
 		return errors.Wrap(err, "could not add ip")
 	}
+	configurator.setApplied(&configurator.bound, true)
 
 	if os.Getenv("enable_service_security") == "true" && !configurator.ignoreSecurity {
 		if err := configurator.addIptablesRulesToLimitTrafficPorts(); err != nil {
@@ -400,12 +669,14 @@ func (configurator *network) DeleteIP() error {
 		return errors.Wrap(err, "ip check in DeleteIP failed")
 	}
 
+	configurator.setApplied(&configurator.bound, false)
+
 	// Nothing to delete
 	if !result {
 		return nil
 	}
 
-	if err = netlink.AddrDel(configurator.link, configurator.address); err != nil {
+	if err = netlink.AddrDel(configurator.currentLink(), configurator.address); err != nil {
 		return errors.Wrap(err, "could not delete ip")
 	}
 
@@ -493,7 +764,7 @@ func (configurator *network) IsDADFAIL() bool {
 	}
 
 	// Get all the address
-	addresses, err := netlink.AddrList(configurator.link, netlink.FAMILY_V6)
+	addresses, err := netlink.AddrList(configurator.currentLink(), netlink.FAMILY_V6)
 	if err != nil {
 		return false
 	}
@@ -520,7 +791,7 @@ func (configurator *network) IsSet() (result bool, err error) {
 		return false, nil
 	}
 
-	addresses, err = netlink.AddrList(configurator.link, 0)
+	addresses, err = netlink.AddrList(configurator.currentLink(), 0)
 	if err != nil {
 		err = errors.Wrap(err, "could not list addresses")
 
@@ -596,6 +867,9 @@ func (configurator *network) DDNSHostName() string {
 
 // Interface - return the Interface name
 func (configurator *network) Interface() string {
+	configurator.mu.Lock()
+	defer configurator.mu.Unlock()
+
 	return configurator.link.Attrs().Name
 }
 
@@ -0,0 +1,32 @@
+package vip
+
+import (
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestIsServiceAddress(t *testing.T) {
+	link := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "eth0"}}
+	long := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "enp0s31f6.1000"}}
+	address := func(ip, label string) netlink.Addr {
+		return netlink.Addr{IPNet: &net.IPNet{IP: net.ParseIP(ip), Mask: net.CIDRMask(32, 32)}, Label: label}
+	}
+
+	if label := addressLabel(link, net.ParseIP("192.168.0.10")); label != "eth0:kv" {
+		t.Errorf("addressLabel() = %q", label)
+	}
+	if label := addressLabel(link, net.ParseIP("fd00::10")); label != "" {
+		t.Errorf("addressLabel() = %q for an IPv6 address", label)
+	}
+	if label := addressLabel(long, net.ParseIP("192.168.0.10")); label != "" {
+		t.Errorf("addressLabel() = %q for a long interface name", label)
+	}
+	if !IsServiceAddress(link, address("192.168.0.10", "eth0:kv")) {
+		t.Errorf("a labelled address isn't a service address")
+	}
+	if IsServiceAddress(link, address("192.168.0.10", "eth0")) || IsServiceAddress(link, address("192.168.0.10", "")) {
+		t.Errorf("an unlabelled address is a service address")
+	}
+}
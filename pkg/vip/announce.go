@@ -0,0 +1,44 @@
+package vip
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	announcementARP = "arp"
+	announcementNDP = "ndp"
+)
+
+var (
+	announcementsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kube_vip",
+		Subsystem: "vip",
+		Name:      "announcements_sent",
+		Help:      "Count the gratuitous ARPs and unsolicited neighbor advertisements that are sent for an address",
+	}, []string{"address", "interface", "type"})
+
+	announcementsSuppressed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kube_vip",
+		Subsystem: "vip",
+		Name:      "announcements_suppressed",
+		Help:      "Count the gratuitous ARPs and unsolicited neighbor advertisements that weren't sent as the announcement rate limit was exceeded",
+	}, []string{"address", "interface", "type"})
+)
+
+// AnnouncementCollectors returns the metrics of the gratuitous ARPs and neighbor advertisements
+func AnnouncementCollectors() []prometheus.Collector {
+	return []prometheus.Collector{announcementsSent, announcementsSuppressed}
+}
+
+// allowAnnouncement checks the announcement against the rate limit of the host and records it, the
+// announcements are repeated so a dropped announcement is sent again at the next broadcast
+func (h *Host) allowAnnouncement(address, iface, announcementType string) bool {
+	if h.announcements != nil && !h.announcements.Allow() {
+		log.Debugf("suppressing %s announcement for [%s] on [%s], the announcement rate limit has been exceeded", announcementType, address, iface)
+		announcementsSuppressed.WithLabelValues(address, iface, announcementType).Inc()
+		return false
+	}
+	announcementsSent.WithLabelValues(address, iface, announcementType).Inc()
+	return true
+}
@@ -0,0 +1,29 @@
+package vip
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAllowAnnouncement(t *testing.T) {
+	address, iface := "192.168.0.100", "eth0"
+	host := NewHost(3, 0)
+	for i := 0; i < 5; i++ {
+		host.allowAnnouncement(address, iface, announcementARP)
+	}
+	if sent := testutil.ToFloat64(announcementsSent.WithLabelValues(address, iface, announcementARP)); sent != 3 {
+		t.Errorf("sent = %v, want 3", sent)
+	}
+	if suppressed := testutil.ToFloat64(announcementsSuppressed.WithLabelValues(address, iface, announcementARP)); suppressed != 2 {
+		t.Errorf("suppressed = %v, want 2", suppressed)
+	}
+
+	// Without a limit nothing is suppressed
+	host = NewHost(0, 0)
+	for i := 0; i < 5; i++ {
+		if !host.allowAnnouncement(address, iface, announcementNDP) {
+			t.Fatalf("announcement was suppressed without a rate limit")
+		}
+	}
+}
@@ -10,8 +10,13 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"syscall"
+	"time"
 	"unsafe"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
 )
 
 const (
@@ -155,7 +160,7 @@ func sendARP(iface *net.Interface, m *arpMessage) error {
 }
 
 // ARPSendGratuitous sends a gratuitous ARP message via the specified interface.
-func ARPSendGratuitous(address, ifaceName string) error {
+func (h *Host) ARPSendGratuitous(address, ifaceName string) error {
 	iface, err := net.InterfaceByName(ifaceName)
 	if err != nil {
 		return fmt.Errorf("failed to get interface %q: %v", ifaceName, err)
@@ -166,10 +171,171 @@ func ARPSendGratuitous(address, ifaceName string) error {
 		return fmt.Errorf("failed to parse address %s", ip)
 	}
 
+	if !h.allowAnnouncement(address, ifaceName, announcementARP) {
+		return nil
+	}
+
 	// This is a debug message, enable debugging to ensure that the gratuitous arp is repeating
 	m, err := gratuitousARP(ip, iface.HardwareAddr)
 	if err != nil {
 		return err
 	}
-	return sendARP(iface, m)
+	return sendARP(announceInterface(iface), m)
+}
+
+// announceInterface returns the interface that the gratuitous ARPs are sent out of, for an active-backup
+// bond this is the active slave so that the switches learn the address on the port that is forwarding.
+// The message still carries the address of the bond.
+func announceInterface(iface *net.Interface) *net.Interface {
+	link, err := netlink.LinkByIndex(iface.Index)
+	if err != nil {
+		return iface
+	}
+	bond, ok := link.(*netlink.Bond)
+	if !ok || bond.Mode != netlink.BOND_MODE_ACTIVE_BACKUP || bond.ActiveSlave <= 0 {
+		return iface
+	}
+	slave, err := net.InterfaceByIndex(bond.ActiveSlave)
+	if err != nil {
+		log.Warnf("unable to find the active slave [%d] of bond [%s]: %v", bond.ActiveSlave, iface.Name, err)
+		return iface
+	}
+	log.Debugf("sending gratuitous ARP for bond [%s] via active slave [%s]", iface.Name, slave.Name)
+	return slave
+}
+
+// ARPResponder answers the ARP requests for an address that isn't bound to an interface
+type ARPResponder struct {
+	iface  *net.Interface
+	fd     int
+	closed atomic.Bool
+}
+
+// NewARPResponder opens a packet socket on the interface to receive the ARP requests
+func NewARPResponder(ifaceName string) (*ARPResponder, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface %q: %v", ifaceName, err)
+	}
+	if len(iface.HardwareAddr) != hwLen {
+		return nil, fmt.Errorf("%q is not an Ethernet interface", ifaceName)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(htons(syscall.ETH_P_ARP)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get raw socket: %v", err)
+	}
+	ll := syscall.SockaddrLinklayer{
+		Protocol: htons(syscall.ETH_P_ARP),
+		Ifindex:  iface.Index,
+	}
+	if err := syscall.Bind(fd, &ll); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to bind: %v", err)
+	}
+	// A timeout allows the responder to notice that it has been closed
+	timeout := syscall.NsecToTimeval(time.Second.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to set receive timeout: %v", err)
+	}
+
+	return &ARPResponder{iface: iface, fd: fd}, nil
+}
+
+// Respond answers the ARP requests for the address until the responder is closed
+func (r *ARPResponder) Respond(address string) error {
+	ip := net.ParseIP(address).To4()
+	if ip == nil {
+		return fmt.Errorf("%q is not an IPv4 address", address)
+	}
+
+	b := make([]byte, 128)
+	for !r.closed.Load() {
+		n, _, err := syscall.Recvfrom(r.fd, b, 0)
+		if err != nil {
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+			if r.closed.Load() {
+				return nil
+			}
+			return fmt.Errorf("failed to receive ARP request: %v", err)
+		}
+		request, ok := parseARPRequest(b[:n])
+		if !ok || !ip.Equal(request.targetProtocolAddress) {
+			continue
+		}
+		// Ignore the gratuitous ARPs for the address, including our own
+		if ip.Equal(request.senderProtocolAddress) {
+			continue
+		}
+		if err := r.reply(ip, request); err != nil {
+			log.Warnf("failed to answer ARP request for [%s] from [%s]: %v", address, net.IP(request.senderProtocolAddress), err)
+		}
+	}
+	return nil
+}
+
+func (r *ARPResponder) reply(ip net.IP, request *arpMessage) error {
+	m := &arpMessage{
+		arpHeader: arpHeader{
+			1,           // Ethernet
+			0x0800,      // IPv4
+			hwLen,       // 48-bit MAC Address
+			net.IPv4len, // 32-bit IPv4 Address
+			opARPReply,  // ARP Reply
+		},
+		senderHardwareAddress: r.iface.HardwareAddr,
+		senderProtocolAddress: ip,
+		targetHardwareAddress: request.senderHardwareAddress,
+		targetProtocolAddress: request.senderProtocolAddress,
+	}
+	b, err := m.bytes()
+	if err != nil {
+		return err
+	}
+
+	ll := syscall.SockaddrLinklayer{
+		Protocol: htons(syscall.ETH_P_ARP),
+		Ifindex:  r.iface.Index,
+		Hatype:   m.hardwareType,
+		Halen:    m.hardwareAddressLength,
+	}
+	copy(ll.Addr[:], request.senderHardwareAddress)
+	return syscall.Sendto(r.fd, b, 0, &ll)
+}
+
+// Close stops the responder
+func (r *ARPResponder) Close() error {
+	if r.closed.Swap(true) {
+		return nil
+	}
+	return syscall.Close(r.fd)
+}
+
+// parseARPRequest decodes an Ethernet/IPv4 ARP request
+func parseARPRequest(b []byte) (*arpMessage, bool) {
+	const length = 8 + 2*hwLen + 2*net.IPv4len
+	if len(b) < length {
+		return nil, false
+	}
+	m := &arpMessage{
+		arpHeader: arpHeader{
+			hardwareType:          binary.BigEndian.Uint16(b[0:]),
+			protocolType:          binary.BigEndian.Uint16(b[2:]),
+			hardwareAddressLength: b[4],
+			protocolAddressLength: b[5],
+			opcode:                binary.BigEndian.Uint16(b[6:]),
+		},
+	}
+	if m.hardwareType != 1 || m.protocolType != 0x0800 || m.hardwareAddressLength != hwLen ||
+		m.protocolAddressLength != net.IPv4len || m.opcode != opARPRequest {
+		return nil, false
+	}
+	m.senderHardwareAddress = append([]byte{}, b[8:14]...)
+	m.senderProtocolAddress = append([]byte{}, b[14:18]...)
+	m.targetHardwareAddress = append([]byte{}, b[18:24]...)
+	m.targetProtocolAddress = append([]byte{}, b[24:28]...)
+	return m, true
 }
@@ -0,0 +1,45 @@
+//go:build linux
+// +build linux
+
+package vip
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseARPRequest(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	request := &arpMessage{
+		arpHeader:             arpHeader{1, 0x0800, hwLen, net.IPv4len, opARPRequest},
+		senderHardwareAddress: mac,
+		senderProtocolAddress: net.ParseIP("192.168.0.10").To4(),
+		targetHardwareAddress: make([]byte, hwLen),
+		targetProtocolAddress: net.ParseIP("192.168.0.100").To4(),
+	}
+	b, err := request.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, ok := parseARPRequest(b)
+	if !ok {
+		t.Fatalf("parseARPRequest() failed to parse a request")
+	}
+	if !net.IP(m.targetProtocolAddress).Equal(net.ParseIP("192.168.0.100")) || net.HardwareAddr(m.senderHardwareAddress).String() != mac.String() {
+		t.Errorf("parseARPRequest() = %+v", m)
+	}
+
+	// Replies and truncated messages are ignored
+	request.opcode = opARPReply
+	reply, err := request.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := parseARPRequest(reply); ok {
+		t.Errorf("parseARPRequest() parsed a reply")
+	}
+	if _, ok := parseARPRequest(b[:20]); ok {
+		t.Errorf("parseARPRequest() parsed a truncated request")
+	}
+}
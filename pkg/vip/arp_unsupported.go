@@ -6,6 +6,24 @@ package vip
 import "fmt"
 
 // ARPSendGratuitous is only supported on Linux, so return an error
-func ARPSendGratuitous(address, ifaceName string) error {
+func (h *Host) ARPSendGratuitous(address, ifaceName string) error {
 	return fmt.Errorf("Unsupported on this OS")
 }
+
+// ARPResponder is only supported on Linux
+type ARPResponder struct{}
+
+// NewARPResponder is only supported on Linux, so return an error
+func NewARPResponder(ifaceName string) (*ARPResponder, error) {
+	return nil, fmt.Errorf("Unsupported on this OS")
+}
+
+// Respond is only supported on Linux, so return an error
+func (r *ARPResponder) Respond(address string) error {
+	return fmt.Errorf("Unsupported on this OS")
+}
+
+// Close is only supported on Linux
+func (r *ARPResponder) Close() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package vip
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// blackholes are installed in place of the routes of the withdrawn VIPs, this prevents routing loops whilst the
+// upstream routers converge
+type blackholes struct {
+	// gracePeriod is how long a blackhole route is kept for a withdrawn VIP, zero disables them
+	gracePeriod time.Duration
+
+	mutex sync.Mutex
+	// timers remove the blackhole routes, by table and destination
+	timers map[string]*time.Timer
+}
+
+func newBlackholes(gracePeriod time.Duration) *blackholes {
+	return &blackholes{gracePeriod: gracePeriod, timers: make(map[string]*time.Timer)}
+}
+
+func blackholeKey(route *netlink.Route) string {
+	return fmt.Sprintf("%d/%s", route.Table, route.Dst)
+}
+
+// blackholeRoute is the blackhole equivalent of the route of a VIP
+func blackholeRoute(route *netlink.Route) *netlink.Route {
+	return &netlink.Route{
+		Dst:      route.Dst,
+		Table:    route.Table,
+		Type:     unix.RTN_BLACKHOLE,
+		Protocol: route.Protocol,
+		Priority: route.Priority,
+	}
+}
+
+// add installs a blackhole in place of the route that has been withdrawn, for the grace period
+func (b *blackholes) add(route *netlink.Route) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.gracePeriod <= 0 {
+		return
+	}
+
+	key := blackholeKey(route)
+	if timer, exists := b.timers[key]; exists {
+		timer.Stop()
+	}
+	blackhole := blackholeRoute(route)
+	if err := netlink.RouteReplace(blackhole); err != nil {
+		log.Warnf("[route] unable to add blackhole route for [%s]: %v", route.Dst, err)
+		return
+	}
+	log.Infof("[route] added blackhole route for [%s] in table [%d] for %s", route.Dst, route.Table, b.gracePeriod)
+	b.timers[key] = time.AfterFunc(b.gracePeriod, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.timers, key)
+		if err := netlink.RouteDel(blackhole); err != nil {
+			log.Warnf("[route] unable to delete blackhole route for [%s]: %v", route.Dst, err)
+			return
+		}
+		log.Infof("[route] deleted blackhole route for [%s] in table [%d]", route.Dst, route.Table)
+	})
+}
+
+// remove deletes the blackhole of the route before it is installed again
+func (b *blackholes) remove(route *netlink.Route) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	key := blackholeKey(route)
+	timer, exists := b.timers[key]
+	if !exists {
+		return
+	}
+	timer.Stop()
+	delete(b.timers, key)
+	if err := netlink.RouteDel(blackholeRoute(route)); err != nil {
+		log.Warnf("[route] unable to delete blackhole route for [%s]: %v", route.Dst, err)
+	}
+}
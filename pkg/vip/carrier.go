@@ -0,0 +1,196 @@
+package vip
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// carrierMonitor shares a single subscription to the netlink link events between all of the
+// elections that are waiting on the carrier of an interface, and the announcements of the VIPs
+type carrierMonitor struct {
+	once    sync.Once
+	mutex   sync.Mutex
+	carrier map[string]bool
+	// changed is closed (and replaced) when the carrier of the interface changes
+	changed map[string]chan struct{}
+	// flapped is closed (and replaced) when the interface, or the interface it is on top of (e.g. a bond
+	// below a VLAN), regains carrier or a bond fails over to another slave
+	flapped map[string]chan struct{}
+	// indexes is the index of an interface, which changes if the interface is re-created
+	indexes map[string]int
+	// parents is the index of the parent link of an interface
+	parents map[string]int
+	// activeSlaves is the active slave of a bond, by the index of the bond
+	activeSlaves map[int]int
+}
+
+func newCarrierMonitor() *carrierMonitor {
+	return &carrierMonitor{
+		carrier:      make(map[string]bool),
+		changed:      make(map[string]chan struct{}),
+		flapped:      make(map[string]chan struct{}),
+		indexes:      make(map[string]int),
+		parents:      make(map[string]int),
+		activeSlaves: make(map[int]int),
+	}
+}
+
+// hasCarrier is true when the link is administratively up and has a carrier (IFF_LOWER_UP)
+func hasCarrier(attrs *netlink.LinkAttrs) bool {
+	return attrs.Flags&net.FlagUp != 0 && attrs.RawFlags&unix.IFF_LOWER_UP != 0
+}
+
+func (m *carrierMonitor) start() {
+	updates := make(chan netlink.LinkUpdate)
+	if err := netlink.LinkSubscribe(updates, nil); err != nil {
+		log.Errorf("unable to subscribe to link events, carrier changes won't be detected: %v", err)
+		return
+	}
+	go func() {
+		for update := range updates {
+			attrs := update.Attrs()
+			carrier := hasCarrier(attrs)
+			if update.Header.Type == unix.RTM_DELLINK {
+				carrier = false
+			}
+
+			m.mutex.Lock()
+			flapped := false
+			if _, watched := m.carrier[attrs.Name]; watched {
+				// The interface may be removed and re-created (e.g. an SR-IOV VF reset or a bond rebuild), in
+				// which case it is re-announced (and its addresses and routes restored) once it has carrier
+				if update.Header.Type == unix.RTM_DELLINK {
+					log.Warnf("interface [%s] has been removed", attrs.Name)
+					delete(m.indexes, attrs.Name)
+				} else if index, seen := m.indexes[attrs.Name]; !seen || index != attrs.Index {
+					if seen {
+						log.Infof("interface [%s] has been re-created", attrs.Name)
+					}
+					m.indexes[attrs.Name] = attrs.Index
+					m.parents[attrs.Name] = attrs.ParentIndex
+				}
+			}
+			if previous, watched := m.carrier[attrs.Name]; watched && previous != carrier {
+				if carrier {
+					log.Infof("interface [%s] has regained carrier", attrs.Name)
+				} else {
+					log.Warnf("interface [%s] has lost carrier", attrs.Name)
+				}
+				m.carrier[attrs.Name] = carrier
+				close(m.changed[attrs.Name])
+				m.changed[attrs.Name] = make(chan struct{})
+				flapped = carrier
+			}
+			if bond, ok := update.Link.(*netlink.Bond); ok && bond.ActiveSlave > 0 {
+				if previous, seen := m.activeSlaves[attrs.Index]; seen && previous != bond.ActiveSlave {
+					log.Infof("bond [%s] has failed over to slave [%d]", attrs.Name, bond.ActiveSlave)
+					flapped = true
+				}
+				m.activeSlaves[attrs.Index] = bond.ActiveSlave
+			}
+			if flapped {
+				m.notifyFlapped(attrs)
+			}
+			m.mutex.Unlock()
+		}
+	}()
+}
+
+// notifyFlapped wakes the announcements on the link, and on any interface on top of it
+func (m *carrierMonitor) notifyFlapped(attrs *netlink.LinkAttrs) {
+	for iface, flapped := range m.flapped {
+		if iface == attrs.Name || m.parents[iface] == attrs.Index {
+			close(flapped)
+			m.flapped[iface] = make(chan struct{})
+		}
+	}
+}
+
+// register starts tracking the interface, the mutex must be held
+func (m *carrierMonitor) register(iface string) {
+	if _, watched := m.carrier[iface]; watched {
+		return
+	}
+	carrier := false
+	if link, err := netlink.LinkByName(iface); err == nil {
+		attrs := link.Attrs()
+		carrier = hasCarrier(attrs)
+		m.indexes[iface] = attrs.Index
+		m.parents[iface] = attrs.ParentIndex
+		if bond, ok := link.(*netlink.Bond); ok {
+			m.activeSlaves[attrs.Index] = bond.ActiveSlave
+		}
+		// The parent is also tracked, so that a flap or failover below the interface is detected
+		if attrs.ParentIndex > 0 {
+			if parent, err := netlink.LinkByIndex(attrs.ParentIndex); err == nil {
+				m.register(parent.Attrs().Name)
+			}
+		}
+	}
+	m.carrier[iface] = carrier
+	m.changed[iface] = make(chan struct{})
+	m.flapped[iface] = make(chan struct{})
+}
+
+// watch returns the carrier of the interface, and a channel that is closed when it changes
+func (m *carrierMonitor) watch(iface string) (bool, <-chan struct{}) {
+	m.once.Do(m.start)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.register(iface)
+	return m.carrier[iface], m.changed[iface]
+}
+
+// LinkFlapped returns a channel that is closed the next time the interface (or the interface below it)
+// regains carrier, or a bond below it fails over, after which the VIPs should be announced again. This
+// includes an interface that has been re-created and has come back up.
+func (h *Host) LinkFlapped(iface string) <-chan struct{} {
+	h.carriers.once.Do(h.carriers.start)
+
+	h.carriers.mutex.Lock()
+	defer h.carriers.mutex.Unlock()
+	h.carriers.register(iface)
+	return h.carriers.flapped[iface]
+}
+
+// WaitForCarrier blocks until the interface has carrier, or the context is cancelled
+func (h *Host) WaitForCarrier(ctx context.Context, iface string) error {
+	for {
+		carrier, changed := h.carriers.watch(iface)
+		if carrier {
+			return nil
+		}
+		log.Infof("waiting for interface [%s] to have carrier", iface)
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// CarrierContext returns a copy of the context that is cancelled when the interface loses carrier
+func (h *Host) CarrierContext(ctx context.Context, iface string) (context.Context, context.CancelFunc) {
+	carrierCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		for {
+			carrier, changed := h.carriers.watch(iface)
+			if !carrier {
+				cancel()
+				return
+			}
+			select {
+			case <-changed:
+			case <-carrierCtx.Done():
+				return
+			}
+		}
+	}()
+	return carrierCtx, cancel
+}
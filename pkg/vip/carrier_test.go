@@ -0,0 +1,27 @@
+package vip
+
+import (
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestLinkFlappedRegistersLink(t *testing.T) {
+	link, err := netlink.LinkByName("lo")
+	if err != nil {
+		t.Skipf("unable to find the loopback interface: %v", err)
+	}
+	host := NewHost(0, 0)
+	if flapped := host.LinkFlapped("lo"); flapped == nil {
+		t.Fatal("LinkFlapped() returned no channel")
+	}
+
+	host.carriers.mutex.Lock()
+	defer host.carriers.mutex.Unlock()
+	if index := host.carriers.indexes["lo"]; index != link.Attrs().Index {
+		t.Errorf("the index of [lo] = %d, want %d", index, link.Attrs().Index)
+	}
+	if _, watched := host.carriers.carrier["lo"]; !watched {
+		t.Error("[lo] isn't watched")
+	}
+}
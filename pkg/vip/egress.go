@@ -2,7 +2,7 @@ package vip
 
 import (
 	"fmt"
-	"strconv"
+	"slices"
 	"strings"
 
 	iptables "github.com/kube-vip/kube-vip/pkg/iptables"
@@ -29,6 +29,23 @@ import (
 const MangleChainName = "KUBE-VIP-EGRESS"
 const Comment = "a3ViZS12aXAK=kube-vip"
 
+// EgressRules are the rules that mark the egress packets of the pods and source nat them to the VIP, they are
+// managed by Egress with the iptables binaries or by NftablesEgress with nftables
+type EgressRules interface {
+	CheckMangleChain(name string) (bool, error)
+	CreateMangleChain(name string) error
+	AppendReturnRulesForDestinationSubnet(name, subnet string) error
+	AppendReturnRulesForMarking(name, subnet string) error
+	InsertMangeTableIntoPrerouting(name string) error
+	InsertSourceNat(vip, podIP string) error
+	InsertSourceNatForDestination(vip, podIP string, destination EgressDestination) error
+	DeleteMangleMarking(podIP, name string) error
+	DeleteSourceNat(podIP, vip string) error
+	DeleteSourceNatForDestination(podIP, vip string, destination EgressDestination) error
+	DeleteSourceNatForOtherPods(vip, podIP string) error
+	CleanIPtables() error
+}
+
 type Egress struct {
 	ipTablesClient *iptables.IPTables
 	comment        string
@@ -51,6 +68,14 @@ func CreateIptablesClient(nftables bool, namespace string, protocol iptables.Pro
 	return e, err
 }
 
+// hostPrefix returns the prefix of the address, of its IP family
+func hostPrefix(address string) string {
+	if IsIPv6(address) {
+		return address + "/128"
+	}
+	return address + "/32"
+}
+
 func (e *Egress) CheckMangleChain(name string) (bool, error) {
 	log.Infof("[egress] Checking for Chain [%s]", name)
 	return e.ipTablesClient.ChainExists("mangle", name)
@@ -78,23 +103,24 @@ func (e *Egress) DeleteMangleMarking(podIP, name string) error {
 func (e *Egress) DeleteSourceNat(podIP, vip string) error {
 	log.Infof("[egress] Removing source nat from [%s] => [%s]", podIP, vip)
 
-	exists, _ := e.ipTablesClient.Exists("nat", "POSTROUTING", "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment)
+	exists, _ := e.ipTablesClient.Exists("nat", "POSTROUTING", "-s", hostPrefix(podIP), "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment)
 
 	if !exists {
 		return fmt.Errorf("unable to find source Nat rule for [%s]", podIP)
 	}
-	return e.ipTablesClient.Delete("nat", "POSTROUTING", "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment)
+	return e.ipTablesClient.Delete("nat", "POSTROUTING", "-s", hostPrefix(podIP), "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment)
 }
 
-func (e *Egress) DeleteSourceNatForDestinationPort(podIP, vip, port, proto string) error {
-	log.Infof("[egress] Adding source nat from [%s] => [%s]", podIP, vip)
+func (e *Egress) DeleteSourceNatForDestination(podIP, vip string, destination EgressDestination) error {
+	log.Infof("[egress] Removing source nat from [%s] => [%s], to destination [%s]", podIP, vip, destination)
 
-	exists, _ := e.ipTablesClient.Exists("nat", "POSTROUTING", "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-p", proto, "--dport", port, "-m", "comment", "--comment", e.comment)
+	rule := e.sourceNatForDestination(podIP, vip, destination)
+	exists, _ := e.ipTablesClient.Exists("nat", "POSTROUTING", rule...)
 
 	if !exists {
-		return fmt.Errorf("unable to find source Nat rule for [%s], with destination port [%s]", podIP, port)
+		return fmt.Errorf("unable to find source Nat rule for [%s], to destination [%s]", podIP, destination)
 	}
-	return e.ipTablesClient.Delete("nat", "POSTROUTING", "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-p", proto, "--dport", port, "-m", "comment", "--comment", e.comment)
+	return e.ipTablesClient.Delete("nat", "POSTROUTING", rule...)
 }
 
 func (e *Egress) CreateMangleChain(name string) error {
@@ -137,149 +163,117 @@ func (e *Egress) InsertMangeTableIntoPrerouting(name string) error {
 
 func (e *Egress) InsertSourceNat(vip, podIP string) error {
 	log.Infof("[egress] Adding source nat from [%s] => [%s]", podIP, vip)
-	if exists, err := e.ipTablesClient.Exists("nat", "POSTROUTING", "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment); err != nil {
+	if exists, err := e.ipTablesClient.Exists("nat", "POSTROUTING", "-s", hostPrefix(podIP), "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment); err != nil {
 		return err
 	} else if exists {
-		if err2 := e.ipTablesClient.Delete("nat", "POSTROUTING", "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment); err2 != nil {
+		if err2 := e.ipTablesClient.Delete("nat", "POSTROUTING", "-s", hostPrefix(podIP), "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment); err2 != nil {
 			return err2
 		}
 	}
 
-	return e.ipTablesClient.Insert("nat", "POSTROUTING", 1, "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment)
+	return e.ipTablesClient.Insert("nat", "POSTROUTING", 1, "-s", hostPrefix(podIP), "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-m", "comment", "--comment", e.comment)
 }
 
-func (e *Egress) InsertSourceNatForDestinationPort(vip, podIP, port, proto string) error {
-	log.Infof("[egress] Adding source nat from [%s] => [%s], with destination port [%s]", podIP, vip, port)
+func (e *Egress) InsertSourceNatForDestination(vip, podIP string, destination EgressDestination) error {
+	log.Infof("[egress] Adding source nat from [%s] => [%s], to destination [%s]", podIP, vip, destination)
+	rule := e.sourceNatForDestination(podIP, vip, destination)
+	if exists, err := e.ipTablesClient.Exists("nat", "POSTROUTING", rule...); err != nil {
+		return err
+	} else if exists {
+		if err2 := e.ipTablesClient.Delete("nat", "POSTROUTING", rule...); err2 != nil {
+			return err2
+		}
+	}
+
+	return e.ipTablesClient.Insert("nat", "POSTROUTING", 1, rule...)
+}
+
+// DeleteSourceNatForOtherPods removes the source nat rules of the VIP of the other pods, which are left over from an
+// earlier endpoint of the service
+func (e *Egress) DeleteSourceNatForOtherPods(vip, podIP string) error {
 	natRules, err := e.ipTablesClient.List("nat", "POSTROUTING")
 	if err != nil {
 		return err
 	}
-	foundNatRules := e.findExistingVIP(natRules, vip)
-	log.Warnf("[egress] Cleaning [%d] existing postrouting nat rules for vip [%s]", len(foundNatRules), vip)
-	for x := range foundNatRules {
-		err = e.ipTablesClient.Delete("nat", "POSTROUTING", foundNatRules[x][2:]...)
+	staleNatRules := e.findOtherPods(natRules, vip, podIP)
+	log.Warnf("[egress] Cleaning [%d] existing postrouting nat rules for vip [%s]", len(staleNatRules), vip)
+	for x := range staleNatRules {
+		err = e.ipTablesClient.Delete("nat", "POSTROUTING", staleNatRules[x][2:]...)
 		if err != nil {
 			log.Errorf("[egress] Error removing rule [%v]", err)
 		}
 	}
+	return nil
+}
 
-	if exists, err := e.ipTablesClient.Exists("nat", "POSTROUTING", "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-p", proto, "--dport", port, "-m", "comment", "--comment", e.comment); err != nil {
-		return err
-	} else if exists {
-		if err2 := e.ipTablesClient.Delete("nat", "POSTROUTING", "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-p", proto, "--dport", port, "-m", "comment", "--comment", e.comment); err2 != nil {
-			return err2
-		}
+// sourceNatForDestination returns the rule that source nats the marked packets of the pod to the destination
+func (e *Egress) sourceNatForDestination(podIP, vip string, destination EgressDestination) []string {
+	rule := []string{"-s", hostPrefix(podIP), "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip}
+	if destination.Subnet != "" {
+		rule = append(rule, "-d", destination.Subnet)
 	}
-
-	return e.ipTablesClient.Insert("nat", "POSTROUTING", 1, "-s", podIP+"/32", "-m", "mark", "--mark", "64/64", "-j", "SNAT", "--to-source", vip, "-p", proto, "--dport", port, "-m", "comment", "--comment", e.comment)
+	if destination.Protocol != "" {
+		rule = append(rule, "-p", destination.Protocol)
+	}
+	if destination.Port != "" {
+		rule = append(rule, "--dport", strings.ReplaceAll(destination.Port, "-", ":"))
+	}
+	return append(rule, "-m", "comment", "--comment", e.comment)
 }
 
 func DeleteExistingSessions(sessionIP string, destination bool, destinationPorts, srcPorts string) error {
+	ports := destinationPorts
+	if destination {
+		ports = srcPorts
+	}
+	destinations, err := ParseEgressDestinations("", ports)
+	if err != nil {
+		return fmt.Errorf("[egress] error parsing annotaion [%s]: %w", ports, err)
+	}
+	return deleteSessions(sessionIP, destination, destinations)
+}
 
+// DeleteEgressSessions deletes the sessions of the pod to the destinations (or all of its sessions without
+// destinations), so that they are source nated with the egress rules
+func DeleteEgressSessions(podIP string, destinations []EgressDestination) error {
+	return deleteSessions(podIP, false, destinations)
+}
+
+func deleteSessions(sessionIP string, destination bool, destinations []EgressDestination) error {
 	nfct, err := ct.Open(&ct.Config{})
 	if err != nil {
 		log.Errorf("could not create nfct: %v", err)
 		return err
 	}
 	defer nfct.Close()
-	sessions, err := nfct.Dump(ct.Conntrack, ct.IPv4)
+	family := ct.IPv4
+	if IsIPv6(sessionIP) {
+		family = ct.IPv6
+	}
+	sessions, err := nfct.Dump(ct.Conntrack, family)
 	if err != nil {
 		log.Errorf("could not dump sessions: %v", err)
 		return err
 	}
-	destPortProtocol := make(map[uint16]uint8)
-	srcPortProtocol := make(map[uint16]uint8)
-
-	if destinationPorts != "" {
-		fixedPorts := strings.Split(destinationPorts, ",")
 
-		for _, fixedPort := range fixedPorts {
-
-			data := strings.Split(fixedPort, ":")
-			if len(data) == 0 {
-				continue
-			}
-			port, err := strconv.ParseUint(data[1], 10, 16)
-			if err != nil {
-				return fmt.Errorf("[egress] error parsing annotaion [%s]", destinationPorts)
-			}
-			switch data[0] {
-			case strings.ToLower("udp"):
-				destPortProtocol[uint16(port)] = ProtocolUDP
-			case strings.ToLower("tcp"):
-				destPortProtocol[uint16(port)] = ProtocolTCP
-			case strings.ToLower("sctp"):
-				destPortProtocol[uint16(port)] = ProtocolSCTP
-			default:
-				log.Errorf("[egress] annotation protocol [%s] isn't supported", data[0])
-			}
+	for _, session := range sessions {
+		if session.Origin == nil {
+			continue
 		}
-	}
-
-	if srcPorts != "" {
-		fixedPorts := strings.Split(srcPorts, ",")
-
-		for _, fixedPort := range fixedPorts {
-
-			data := strings.Split(fixedPort, ":")
-			if len(data) == 0 {
-				continue
-			}
-			port, err := strconv.ParseUint(data[1], 10, 16)
-			if err != nil {
-				return fmt.Errorf("[egress] error parsing annotaion [%s]", srcPorts)
-			}
-			switch data[0] {
-			case strings.ToLower("udp"):
-				srcPortProtocol[uint16(port)] = ProtocolUDP
-			case strings.ToLower("tcp"):
-				srcPortProtocol[uint16(port)] = ProtocolTCP
-			case strings.ToLower("sctp"):
-				srcPortProtocol[uint16(port)] = ProtocolSCTP
-			default:
-				log.Errorf("[egress] annotation protocol [%s] isn't supported", data[0])
-			}
+		// by default we only clear source (i.e. connections going from the vip (egress)), otherwise this will clear
+		// any "dangling" outbound connections.
+		address := session.Origin.Src
+		if destination {
+			address = session.Origin.Dst
 		}
-	}
-
-	// by default we only clear source (i.e. connections going from the vip (egress))
-	if !destination {
-		for _, session := range sessions {
-			//session.Origin.Proto
-			if session.Origin.Src.String() == sessionIP /*&& *session.Origin.Proto.DstPort == uint16(destinationPort)*/ {
-				if destinationPorts != "" {
-					proto := destPortProtocol[*session.Origin.Proto.DstPort]
-					if proto == *session.Origin.Proto.Number {
-						log.Infof("[egress] cleaning existing connection Source [%s] -> [%s:%d] proto: [%d] ", session.Origin.Src.String(), session.Origin.Dst.String(), *session.Origin.Proto.DstPort, *session.Origin.Proto.Number)
-						err = nfct.Delete(ct.Conntrack, ct.IPv4, session)
-					}
-				} else {
-					err = nfct.Delete(ct.Conntrack, ct.IPv4, session)
-				}
-				if err != nil {
-					log.Errorf("could not delete sessions: %v", err)
-				}
-			}
+		if address == nil || address.String() != sessionIP || !matchesSession(destinations, session) {
+			continue
 		}
-	} else {
-		// This will clear any "dangling" outbound connections.
-		for _, session := range sessions {
-			//fmt.Printf("Looking for [%s] found [%s]\n", podIP, session.Origin.Dst.String())
-
-			if session.Origin.Dst.String() == sessionIP /*&& *session.Origin.Proto.DstPort == uint16(destinationPort)*/ {
-				if srcPorts != "" {
-					proto := srcPortProtocol[*session.Origin.Proto.DstPort]
-					if proto == *session.Origin.Proto.Number {
-						log.Infof("[egress] cleaning existing connection Source [%s] -> [%s:%d] proto: [%d] ", session.Origin.Src.String(), session.Origin.Dst.String(), *session.Origin.Proto.DstPort, *session.Origin.Proto.Number)
-						err = nfct.Delete(ct.Conntrack, ct.IPv4, session)
-					}
-				} else {
-					err = nfct.Delete(ct.Conntrack, ct.IPv4, session)
-				}
-				if err != nil {
-					log.Errorf("could not delete sessions: %v", err)
-				}
-			}
+		if len(destinations) != 0 {
+			log.Infof("[egress] cleaning existing connection Source [%s] -> [%s]", session.Origin.Src, session.Origin.Dst)
+		}
+		if err = nfct.Delete(ct.Conntrack, family, session); err != nil {
+			log.Errorf("could not delete sessions: %v", err)
 		}
 	}
 
@@ -361,6 +355,17 @@ func (e *Egress) findRules(rules []string) [][]string {
 	return foundRules
 }
 
+// findOtherPods returns the rules of the VIP that aren't of the pod
+func (e *Egress) findOtherPods(rules []string, vip, podIP string) [][]string {
+	var foundRules [][]string
+	for _, rule := range e.findExistingVIP(rules, vip) {
+		if !slices.Contains(rule, hostPrefix(podIP)) {
+			foundRules = append(foundRules, rule)
+		}
+	}
+	return foundRules
+}
+
 func (e *Egress) findExistingVIP(rules []string, vip string) [][]string {
 	var foundRules [][]string
 
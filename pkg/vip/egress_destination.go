@@ -0,0 +1,167 @@
+package vip
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	ct "github.com/florianl/go-conntrack"
+)
+
+// EgressDestination restricts the source nat of the egress to the traffic going to a subnet, and/or with a protocol
+// and its destination ports. An empty field matches any traffic.
+type EgressDestination struct {
+	Subnet   string
+	Protocol string
+	// Port is a port or a range of ports (first-last), the protocol is required with a port
+	Port string
+}
+
+// ParseEgressDestinations returns the destinations of the comma separated subnets and the comma separated ports, each
+// subnet with each port. A port is [protocol:]port, [protocol:]first-last or a protocol (for any of its ports), the
+// protocol defaults to tcp. There are no destinations (any traffic) without subnets and ports.
+func ParseEgressDestinations(subnets, ports string) ([]EgressDestination, error) {
+	var ranges []EgressDestination
+	if ports != "" {
+		for _, port := range strings.Split(ports, ",") {
+			destination, err := parseEgressPort(strings.TrimSpace(port))
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, destination)
+		}
+	}
+	if subnets == "" {
+		return ranges, nil
+	}
+	if len(ranges) == 0 {
+		ranges = []EgressDestination{{}}
+	}
+	var destinations []EgressDestination
+	for _, subnet := range strings.Split(subnets, ",") {
+		subnet = strings.TrimSpace(subnet)
+		if !strings.Contains(subnet, "/") {
+			subnet = hostPrefix(subnet)
+		}
+		if _, _, err := net.ParseCIDR(subnet); err != nil {
+			return nil, fmt.Errorf("unable to parse the egress destination [%s]: %w", subnet, err)
+		}
+		for _, r := range ranges {
+			r.Subnet = subnet
+			destinations = append(destinations, r)
+		}
+	}
+	return destinations, nil
+}
+
+// String returns the destination as [subnet] [protocol[:port]]
+func (d EgressDestination) String() string {
+	var fields []string
+	if d.Subnet != "" {
+		fields = append(fields, d.Subnet)
+	}
+	if d.Port != "" {
+		fields = append(fields, d.Protocol+":"+d.Port)
+	} else if d.Protocol != "" {
+		fields = append(fields, d.Protocol)
+	}
+	return strings.Join(fields, " ")
+}
+
+// parseEgressPort parses a port of the egress, [protocol:]port, [protocol:]first-last or a protocol
+func parseEgressPort(port string) (EgressDestination, error) {
+	protocol, number, found := strings.Cut(port, ":")
+	if !found {
+		if _, err := protocolNumber(port); err == nil {
+			return EgressDestination{Protocol: strings.ToLower(port)}, nil
+		}
+		protocol, number = "tcp", port
+	}
+	destination := EgressDestination{Protocol: strings.ToLower(protocol), Port: number}
+	if _, err := protocolNumber(destination.Protocol); err != nil {
+		return EgressDestination{}, err
+	}
+	if _, _, err := destination.portRange(); err != nil {
+		return EgressDestination{}, err
+	}
+	return destination, nil
+}
+
+// protocolNumber returns the number of the protocol of the egress
+func protocolNumber(protocol string) (uint8, error) {
+	switch strings.ToLower(protocol) {
+	case "tcp":
+		return ProtocolTCP, nil
+	case "udp":
+		return ProtocolUDP, nil
+	case "sctp":
+		return ProtocolSCTP, nil
+	}
+	return 0, fmt.Errorf("protocol [%s] isn't supported", protocol)
+}
+
+// portRange returns the first and the last port of the destination
+func (d EgressDestination) portRange() (uint16, uint16, error) {
+	first, last, isRange := strings.Cut(d.Port, "-")
+	from, err := strconv.ParseUint(first, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to parse port [%s]: %w", d.Port, err)
+	}
+	if !isRange {
+		return uint16(from), uint16(from), nil
+	}
+	to, err := strconv.ParseUint(last, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to parse port [%s]: %w", d.Port, err)
+	}
+	if to < from {
+		return 0, 0, fmt.Errorf("the port range [%s] is reversed", d.Port)
+	}
+	return uint16(from), uint16(to), nil
+}
+
+// matches returns whether the destination of the session is the destination
+func (d EgressDestination) matches(address net.IP, protocol *uint8, port *uint16) bool {
+	if d.Subnet != "" {
+		_, subnet, err := net.ParseCIDR(d.Subnet)
+		if err != nil || !subnet.Contains(address) {
+			return false
+		}
+	}
+	if d.Protocol != "" {
+		number, err := protocolNumber(d.Protocol)
+		if err != nil || protocol == nil || *protocol != number {
+			return false
+		}
+	}
+	if d.Port != "" {
+		first, last, err := d.portRange()
+		if err != nil || port == nil || *port < first || *port > last {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSession returns whether the session goes to any of the destinations, any session matches no destinations
+func matchesSession(destinations []EgressDestination, session ct.Con) bool {
+	if len(destinations) == 0 {
+		return true
+	}
+	var address net.IP
+	if session.Origin.Dst != nil {
+		address = *session.Origin.Dst
+	}
+	var protocol *uint8
+	var port *uint16
+	if session.Origin.Proto != nil {
+		protocol, port = session.Origin.Proto.Number, session.Origin.Proto.DstPort
+	}
+	for _, destination := range destinations {
+		if destination.matches(address, protocol, port) {
+			return true
+		}
+	}
+	return false
+}
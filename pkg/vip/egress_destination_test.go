@@ -0,0 +1,79 @@
+package vip
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseEgressDestinations(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnets string
+		ports   string
+		want    []EgressDestination
+		wantErr bool
+	}{
+		{"no destinations", "", "", nil, false},
+		{"ports", "", "tcp:443,8080,UDP:5000-5010,sctp", []EgressDestination{
+			{Protocol: "tcp", Port: "443"},
+			{Protocol: "tcp", Port: "8080"},
+			{Protocol: "udp", Port: "5000-5010"},
+			{Protocol: "sctp"},
+		}, false},
+		{"subnets", "10.10.0.0/16, 192.168.0.5,fd00::/64", "", []EgressDestination{
+			{Subnet: "10.10.0.0/16"},
+			{Subnet: "192.168.0.5/32"},
+			{Subnet: "fd00::/64"},
+		}, false},
+		{"subnets with ports", "10.10.0.0/16,10.20.0.0/16", "tcp:5432,udp", []EgressDestination{
+			{Subnet: "10.10.0.0/16", Protocol: "tcp", Port: "5432"},
+			{Subnet: "10.10.0.0/16", Protocol: "udp"},
+			{Subnet: "10.20.0.0/16", Protocol: "tcp", Port: "5432"},
+			{Subnet: "10.20.0.0/16", Protocol: "udp"},
+		}, false},
+		{"invalid subnet", "10.10.0.0/33", "", nil, true},
+		{"invalid protocol", "", "icmp:80", nil, true},
+		{"invalid port", "", "tcp:http", nil, true},
+		{"reversed range", "", "tcp:5010-5000", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEgressDestinations(tt.subnets, tt.ports)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEgressDestinations() error = %v, want error %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEgressDestinations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEgressDestinationMatches(t *testing.T) {
+	tcp, udp := uint8(ProtocolTCP), uint8(ProtocolUDP)
+	port := func(p uint16) *uint16 { return &p }
+	destination := EgressDestination{Subnet: "10.10.0.0/16", Protocol: "tcp", Port: "5432-5440"}
+
+	tests := []struct {
+		name     string
+		address  string
+		protocol *uint8
+		port     *uint16
+		want     bool
+	}{
+		{"within the range", "10.10.1.1", &tcp, port(5432), true},
+		{"end of the range", "10.10.1.1", &tcp, port(5440), true},
+		{"outside of the range", "10.10.1.1", &tcp, port(5441), false},
+		{"other protocol", "10.10.1.1", &udp, port(5432), false},
+		{"other subnet", "10.20.1.1", &tcp, port(5432), false},
+		{"no port", "10.10.1.1", &tcp, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := destination.matches(net.ParseIP(tt.address), tt.protocol, tt.port); got != tt.want {
+				t.Errorf("matches() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
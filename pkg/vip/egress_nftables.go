@@ -0,0 +1,395 @@
+package vip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"github.com/google/nftables/userdata"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	iptables "github.com/kube-vip/kube-vip/pkg/iptables"
+)
+
+// This file contains the egress rules that are programmed with nftables (over netlink), rather than with the
+// iptables binaries. They match the rules of the iptables egress:
+
+// 1. A filter chain hooked into prerouting (at the mangle priority) RETURNs the packets going to a service or
+// other pod address, and marks the packets coming from a pod
+// 2. A nat chain hooked into postrouting performs source nating on the marked packets
+
+// NftablesTableName is the table of the egress rules, of each IP family
+const NftablesTableName = "kube_vip_egress"
+
+// nftablesSNATChain is the chain of the source nat rules
+const nftablesSNATChain = MangleChainName + "-SNAT"
+
+// egressMark is the mark of the egress packets, as --set-mark 64/64 with iptables
+const egressMark = 64
+
+// NftablesEgress manages the egress rules with nftables, a rule is identified by its comment
+type NftablesEgress struct {
+	conn    *nftables.Conn
+	table   *nftables.Table
+	ipv6    bool
+	comment string
+}
+
+// CreateNftablesClient returns the nftables egress, of the IP family of the protocol
+func CreateNftablesClient(namespace string, protocol iptables.Protocol) (*NftablesEgress, error) {
+	log.Infof("[egress] Creating an nftables client, IPv6 [%t]", protocol == iptables.ProtocolIPv6)
+	conn, err := nftables.New()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create nftables connection: %w", err)
+	}
+	e := &NftablesEgress{
+		conn:    conn,
+		table:   &nftables.Table{Name: NftablesTableName, Family: nftables.TableFamilyIPv4},
+		ipv6:    protocol == iptables.ProtocolIPv6,
+		comment: Comment + "-" + namespace,
+	}
+	if e.ipv6 {
+		e.table.Family = nftables.TableFamilyIPv6
+	}
+	return e, nil
+}
+
+func (e *NftablesEgress) mangleChain(name string) *nftables.Chain {
+	return &nftables.Chain{
+		Name:     name,
+		Table:    e.table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookPrerouting,
+		Priority: nftables.ChainPriorityMangle,
+	}
+}
+
+func (e *NftablesEgress) snatChain() *nftables.Chain {
+	return &nftables.Chain{
+		Name:     nftablesSNATChain,
+		Table:    e.table,
+		Type:     nftables.ChainTypeNAT,
+		Hooknum:  nftables.ChainHookPostrouting,
+		Priority: nftables.ChainPriorityNATSource,
+	}
+}
+
+func (e *NftablesEgress) CheckMangleChain(name string) (bool, error) {
+	log.Infof("[egress] Checking for Chain [%s]", name)
+	return e.chainExists(name)
+}
+
+func (e *NftablesEgress) chainExists(name string) (bool, error) {
+	chains, err := e.conn.ListChainsOfTableFamily(e.table.Family)
+	if err != nil {
+		return false, err
+	}
+	for _, chain := range chains {
+		if chain.Table.Name == e.table.Name && chain.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CreateMangleChain creates the table, with the chain that marks the packets and the chain that source nats them
+func (e *NftablesEgress) CreateMangleChain(name string) error {
+	log.Infof("[egress] Creating Chain [%s]", name)
+	e.conn.AddTable(e.table)
+	e.conn.AddChain(e.mangleChain(name))
+	e.conn.AddChain(e.snatChain())
+	return e.conn.Flush()
+}
+
+func (e *NftablesEgress) AppendReturnRulesForDestinationSubnet(name, subnet string) error {
+	log.Infof("[egress] Adding jump for subnet [%s] to RETURN to previous chain/rules", subnet)
+	match, err := e.matchAddress(subnet, false)
+	if err != nil {
+		return err
+	}
+	return e.appendRule(e.mangleChain(name), "return "+e.prefix(subnet), append(match, &expr.Verdict{Kind: expr.VerdictReturn}))
+}
+
+func (e *NftablesEgress) AppendReturnRulesForMarking(name, subnet string) error {
+	log.Infof("[egress] Marking packets on network [%s]", subnet)
+	match, err := e.matchAddress(subnet, true)
+	if err != nil {
+		return err
+	}
+	// meta mark set mark & ~64 ^ 64
+	mark := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyMARK, Register: 1},
+		&expr.Bitwise{SourceRegister: 1, DestRegister: 1, Len: 4, Mask: binaryutil.NativeEndian.PutUint32(^uint32(egressMark)), Xor: binaryutil.NativeEndian.PutUint32(egressMark)},
+		&expr.Meta{Key: expr.MetaKeyMARK, SourceRegister: true, Register: 1},
+	}
+	return e.appendRule(e.mangleChain(name), "mark "+e.prefix(subnet), append(match, mark...))
+}
+
+// InsertMangeTableIntoPrerouting has nothing to do, as the chain is hooked into prerouting when it is created
+func (e *NftablesEgress) InsertMangeTableIntoPrerouting(name string) error {
+	log.Debugf("[egress] Chain [%s] is hooked into prerouting", name)
+	return nil
+}
+
+func (e *NftablesEgress) InsertSourceNat(vip, podIP string) error {
+	log.Infof("[egress] Adding source nat from [%s] => [%s]", podIP, vip)
+	return e.insertSourceNat(vip, podIP, EgressDestination{})
+}
+
+func (e *NftablesEgress) InsertSourceNatForDestination(vip, podIP string, destination EgressDestination) error {
+	log.Infof("[egress] Adding source nat from [%s] => [%s], to destination [%s]", podIP, vip, destination)
+	return e.insertSourceNat(vip, podIP, destination)
+}
+
+func (e *NftablesEgress) DeleteMangleMarking(podIP, name string) error {
+	log.Infof("[egress] Stopping marking packets on network [%s]", podIP)
+	if found, err := e.deleteRules(e.mangleChain(name), func(key string) bool { return key == "mark "+e.prefix(podIP) }); err != nil {
+		return err
+	} else if !found {
+		return fmt.Errorf("unable to find source Mangle rule for [%s]", podIP)
+	}
+	return nil
+}
+
+func (e *NftablesEgress) DeleteSourceNat(podIP, vip string) error {
+	log.Infof("[egress] Removing source nat from [%s] => [%s]", podIP, vip)
+	key := e.snatKey(vip, podIP, EgressDestination{})
+	if found, err := e.deleteRules(e.snatChain(), func(k string) bool { return k == key }); err != nil {
+		return err
+	} else if !found {
+		return fmt.Errorf("unable to find source Nat rule for [%s]", podIP)
+	}
+	return nil
+}
+
+func (e *NftablesEgress) DeleteSourceNatForDestination(podIP, vip string, destination EgressDestination) error {
+	log.Infof("[egress] Removing source nat from [%s] => [%s], to destination [%s]", podIP, vip, destination)
+	key := e.snatKey(vip, podIP, destination)
+	if found, err := e.deleteRules(e.snatChain(), func(k string) bool { return k == key }); err != nil {
+		return err
+	} else if !found {
+		return fmt.Errorf("unable to find source Nat rule for [%s], to destination [%s]", podIP, destination)
+	}
+	return nil
+}
+
+// DeleteSourceNatForOtherPods removes the source nat rules of the VIP of the other pods, which are left over from an
+// earlier endpoint of the service
+func (e *NftablesEgress) DeleteSourceNatForOtherPods(vip, podIP string) error {
+	if found, err := e.deleteRules(e.snatChain(), e.otherPodsSourceNat(vip, podIP)); err != nil {
+		return err
+	} else if found {
+		log.Warnf("[egress] Cleaned the source nat rules of vip [%s] of other pods", vip)
+	}
+	return nil
+}
+
+// CleanIPtables removes the egress rules of the namespace
+func (e *NftablesEgress) CleanIPtables() error {
+	exists, err := e.CheckMangleChain(MangleChainName)
+	if err != nil || !exists {
+		log.Warnf("No existing nftables chain [%s] exists", MangleChainName)
+		return err
+	}
+	for _, chain := range []*nftables.Chain{e.snatChain(), e.mangleChain(MangleChainName)} {
+		if _, err := e.deleteRules(chain, func(string) bool { return true }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertSourceNat source nats the marked packets of the pod (to the destination) to the VIP, replacing its existing
+// rule (the rules of the other pods with the VIP are kept)
+func (e *NftablesEgress) insertSourceNat(vip, podIP string, destination EgressDestination) error {
+	address := net.ParseIP(vip)
+	if address == nil {
+		return fmt.Errorf("unable to parse VIP [%s]", vip)
+	}
+	match, err := e.matchAddress(podIP, true)
+	if err != nil {
+		return err
+	}
+	exprs := append(match,
+		// meta mark & 64 == 64
+		&expr.Meta{Key: expr.MetaKeyMARK, Register: 1},
+		&expr.Bitwise{SourceRegister: 1, DestRegister: 1, Len: 4, Mask: binaryutil.NativeEndian.PutUint32(egressMark), Xor: make([]byte, 4)},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.NativeEndian.PutUint32(egressMark)},
+	)
+	if destination.Subnet != "" {
+		subnet, err := e.matchAddress(destination.Subnet, false)
+		if err != nil {
+			return err
+		}
+		exprs = append(exprs, subnet...)
+	}
+	if destination.Protocol != "" {
+		ports, err := matchDestinationPort(destination)
+		if err != nil {
+			return err
+		}
+		exprs = append(exprs, ports...)
+	}
+	family := uint32(unix.NFPROTO_IPV4)
+	data := address.To4()
+	if e.ipv6 {
+		family, data = unix.NFPROTO_IPV6, address.To16()
+	}
+	exprs = append(exprs,
+		&expr.Immediate{Register: 1, Data: data},
+		&expr.NAT{Type: expr.NATTypeSourceNAT, Family: family, RegAddrMin: 1},
+	)
+
+	key := e.snatKey(vip, podIP, destination)
+	if _, err = e.deleteRules(e.snatChain(), func(k string) bool { return k == key }); err != nil {
+		return err
+	}
+	e.conn.AddTable(e.table)
+	e.conn.AddChain(e.snatChain())
+	e.conn.InsertRule(&nftables.Rule{Table: e.table, Chain: e.snatChain(), Exprs: exprs, UserData: e.userData(key)})
+	return e.conn.Flush()
+}
+
+// otherPodsSourceNat matches the source nat rules of the VIP that aren't of the pod
+func (e *NftablesEgress) otherPodsSourceNat(vip, podIP string) func(string) bool {
+	return func(k string) bool {
+		fields := strings.Fields(k)
+		return len(fields) > 2 && fields[0] == "snat" && fields[1] != e.prefix(podIP) && fields[2] == vip
+	}
+}
+
+// appendRule appends the rule to the chain, unless a rule with the same key exists
+func (e *NftablesEgress) appendRule(chain *nftables.Chain, key string, exprs []expr.Any) error {
+	e.conn.AddTable(e.table)
+	e.conn.AddChain(chain)
+	if err := e.conn.Flush(); err != nil {
+		return err
+	}
+	rules, err := e.conn.GetRules(e.table, chain)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if k, ok := e.ruleKey(rule); ok && k == key {
+			return nil
+		}
+	}
+	e.conn.AddRule(&nftables.Rule{Table: e.table, Chain: chain, Exprs: exprs, UserData: e.userData(key)})
+	return e.conn.Flush()
+}
+
+// deleteRules deletes the rules of the chain (of the namespace) whose keys match, and returns whether any matched
+func (e *NftablesEgress) deleteRules(chain *nftables.Chain, match func(string) bool) (bool, error) {
+	// The rules of a chain that doesn't exist can't be listed
+	if exists, err := e.chainExists(chain.Name); err != nil || !exists {
+		return false, err
+	}
+	rules, err := e.conn.GetRules(e.table, chain)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, rule := range rules {
+		if key, ok := e.ruleKey(rule); ok && match(key) {
+			if err = e.conn.DelRule(rule); err != nil {
+				return found, err
+			}
+			found = true
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	return true, e.conn.Flush()
+}
+
+func (e *NftablesEgress) userData(key string) []byte {
+	return userdata.AppendString(nil, userdata.TypeComment, e.comment+" "+key)
+}
+
+// ruleKey returns the key of a rule, if it is a rule of the namespace
+func (e *NftablesEgress) ruleKey(rule *nftables.Rule) (string, bool) {
+	comment, ok := userdata.GetString(rule.UserData, userdata.TypeComment)
+	if !ok {
+		return "", false
+	}
+	return strings.CutPrefix(comment, e.comment+" ")
+}
+
+func (e *NftablesEgress) snatKey(vip, podIP string, destination EgressDestination) string {
+	key := fmt.Sprintf("snat %s %s", e.prefix(podIP), vip)
+	if d := destination.String(); d != "" {
+		key += " " + d
+	}
+	return key
+}
+
+// prefix returns the address as a prefix, the prefix of an address is the address itself
+func (e *NftablesEgress) prefix(address string) string {
+	if strings.Contains(address, "/") {
+		return address
+	}
+	if e.ipv6 {
+		return address + "/128"
+	}
+	return address + "/32"
+}
+
+// matchAddress matches the source (or destination) address of the packets with an address or a subnet
+func (e *NftablesEgress) matchAddress(address string, source bool) ([]expr.Any, error) {
+	address = e.prefix(address)
+	_, subnet, err := net.ParseCIDR(address)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse address [%s]: %w", address, err)
+	}
+	// The offsets of the source and destination addresses in the IPv4 and IPv6 headers
+	offset, length := uint32(16), uint32(net.IPv4len)
+	ip := subnet.IP.To4()
+	if e.ipv6 {
+		offset, length, ip = 24, net.IPv6len, subnet.IP.To16()
+	}
+	if source {
+		offset -= length
+	}
+	if ip == nil || len(subnet.Mask) != int(length) {
+		return nil, fmt.Errorf("address [%s] isn't of the IP family of the table", address)
+	}
+	return []expr.Any{
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: offset, Len: length},
+		&expr.Bitwise{SourceRegister: 1, DestRegister: 1, Len: length, Mask: subnet.Mask, Xor: make([]byte, length)},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: ip},
+	}, nil
+}
+
+// matchDestinationPort matches the protocol and the destination ports (if any) of the packets
+func matchDestinationPort(destination EgressDestination) ([]expr.Any, error) {
+	protocol, err := protocolNumber(destination.Protocol)
+	if err != nil {
+		return nil, err
+	}
+	exprs := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{protocol}},
+	}
+	if destination.Port == "" {
+		return exprs, nil
+	}
+	first, last, err := destination.portRange()
+	if err != nil {
+		return nil, err
+	}
+	exprs = append(exprs, &expr.Payload{DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2})
+	if first == last {
+		return append(exprs, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.BigEndian.PutUint16(first)}), nil
+	}
+	return append(exprs, &expr.Range{
+		Op:       expr.CmpOpEq,
+		Register: 1,
+		FromData: binaryutil.BigEndian.PutUint16(first),
+		ToData:   binaryutil.BigEndian.PutUint16(last),
+	}), nil
+}
@@ -0,0 +1,153 @@
+package vip
+
+import (
+	"net"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+
+	iptables "github.com/kube-vip/kube-vip/pkg/iptables"
+)
+
+func TestNftablesMatchAddress(t *testing.T) {
+	ipv4 := &NftablesEgress{comment: Comment + "-default"}
+	ipv6 := &NftablesEgress{comment: Comment + "-default", ipv6: true}
+
+	tests := []struct {
+		name    string
+		egress  *NftablesEgress
+		address string
+		source  bool
+		offset  uint32
+		data    net.IP
+		wantErr bool
+	}{
+		{"ipv4 subnet destination", ipv4, "10.96.0.0/12", false, 16, net.ParseIP("10.96.0.0").To4(), false},
+		{"ipv4 pod source", ipv4, "10.0.0.5", true, 12, net.ParseIP("10.0.0.5").To4(), false},
+		{"ipv6 pod source", ipv6, "fd00::5", true, 8, net.ParseIP("fd00::5"), false},
+		{"ipv6 subnet destination", ipv6, "fd00:10::/108", false, 24, net.ParseIP("fd00:10::"), false},
+		{"other family", ipv4, "fd00::5", true, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exprs, err := tt.egress.matchAddress(tt.address, tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("matchAddress() error = %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if payload := exprs[0].(*expr.Payload); payload.Offset != tt.offset {
+				t.Errorf("matchAddress() offset = %d, want %d", payload.Offset, tt.offset)
+			}
+			if cmp := exprs[2].(*expr.Cmp); !net.IP(cmp.Data).Equal(tt.data) {
+				t.Errorf("matchAddress() address = %v, want %v", net.IP(cmp.Data), tt.data)
+			}
+		})
+	}
+}
+
+func TestNftablesRuleKeys(t *testing.T) {
+	e := &NftablesEgress{comment: Comment + "-default"}
+	if key := e.snatKey("192.168.0.10", "10.0.0.5", EgressDestination{Protocol: "tcp", Port: "443"}); key != "snat 10.0.0.5/32 192.168.0.10 tcp:443" {
+		t.Errorf("snatKey() = %q", key)
+	}
+
+	rule := &nftables.Rule{UserData: e.userData("mark 10.0.0.5/32")}
+	if key, ok := e.ruleKey(rule); !ok || key != "mark 10.0.0.5/32" {
+		t.Errorf("ruleKey() = %q, %t", key, ok)
+	}
+	other := &NftablesEgress{comment: Comment + "-other"}
+	if _, ok := other.ruleKey(rule); ok {
+		t.Error("ruleKey() of the rule of another namespace = true, want false")
+	}
+
+	if _, err := matchDestinationPort(EgressDestination{Protocol: "icmp", Port: "80"}); err == nil {
+		t.Error("matchDestinationPort() of an unsupported protocol, want error")
+	}
+}
+
+func TestNftablesOtherPodsSourceNat(t *testing.T) {
+	e := &NftablesEgress{comment: Comment + "-default"}
+	tests := []struct {
+		name string
+		key  string
+		want bool
+	}{
+		{"the rule of the pod", "snat 10.0.0.5/32 192.168.0.10", false},
+		{"the rule of the pod with a port", "snat 10.0.0.5/32 192.168.0.10 tcp:443", false},
+		{"another pod with the VIP", "snat 10.0.0.6/32 192.168.0.10", true},
+		{"another pod with the VIP and a port", "snat 10.0.0.6/32 192.168.0.10 tcp:443", true},
+		{"another pod with another VIP", "snat 10.0.0.6/32 192.168.0.11", false},
+		{"the marking of another pod", "mark 10.0.0.6/32", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.otherPodsSourceNat("192.168.0.10", "10.0.0.5")(tt.key); got != tt.want {
+				t.Errorf("otherPodsSourceNat(%q) = %t, want %t", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNftablesSharedVIP programs the source nat of two pods with one VIP, in a network namespace of its own (which
+// requires CAP_SYS_ADMIN, e.g. with unshare -r)
+func TestNftablesSharedVIP(t *testing.T) {
+	// The thread is left in the network namespace, the runtime discards it when the test ends
+	runtime.LockOSThread()
+	if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+		t.Skipf("unable to create a network namespace: %v", err)
+	}
+	e, err := CreateNftablesClient("default", iptables.ProtocolIPv4)
+	if err != nil {
+		t.Skipf("unable to use nftables: %v", err)
+	}
+	if err = e.CreateMangleChain(MangleChainName); err != nil {
+		t.Skipf("unable to create the egress table: %v", err)
+	}
+
+	destination := EgressDestination{Subnet: "10.10.0.0/16", Protocol: "tcp", Port: "5432"}
+	for _, podIP := range []string{"10.0.0.5", "10.0.0.6"} {
+		if err = e.InsertSourceNat("192.168.0.10", podIP); err != nil {
+			t.Fatal(err)
+		}
+		if err = e.InsertSourceNatForDestination("192.168.0.10", podIP, destination); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys := func() []string {
+		rules, err := e.conn.GetRules(e.table, e.snatChain())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, rule := range rules {
+			if key, ok := e.ruleKey(rule); ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		return keys
+	}
+	want := []string{
+		"snat 10.0.0.5/32 192.168.0.10",
+		"snat 10.0.0.5/32 192.168.0.10 10.10.0.0/16 tcp:5432",
+		"snat 10.0.0.6/32 192.168.0.10",
+		"snat 10.0.0.6/32 192.168.0.10 10.10.0.0/16 tcp:5432",
+	}
+	if got := keys(); !slices.Equal(got, want) {
+		t.Fatalf("the rules of the pods = %q, want %q", got, want)
+	}
+
+	// A new endpoint of the service removes the rules of the earlier ones
+	if err = e.DeleteSourceNatForOtherPods("192.168.0.10", "10.0.0.6"); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(); !slices.Equal(got, want[2:]) {
+		t.Errorf("the rules after replacing the endpoint = %q, want %q", got, want[2:])
+	}
+}
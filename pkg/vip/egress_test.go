@@ -34,3 +34,19 @@ func Test_findRules(t *testing.T) {
 		})
 	}
 }
+
+func Test_findOtherPods(t *testing.T) {
+	e := Egress{comment: Comment + "-" + "default"}
+	rules := []string{
+		fmt.Sprintf("-A POSTROUTING -s 10.0.0.5/32 -m mark --mark 0x40/0x40 -m comment --comment \"%s\" -j SNAT --to-source 192.168.0.10", e.comment),
+		fmt.Sprintf("-A POSTROUTING -s 10.0.0.6/32 -d 10.10.0.0/16 -p tcp -m mark --mark 0x40/0x40 -m tcp --dport 5432 -m comment --comment \"%s\" -j SNAT --to-source 192.168.0.10", e.comment),
+		fmt.Sprintf("-A POSTROUTING -s 10.0.0.7/32 -m mark --mark 0x40/0x40 -m comment --comment \"%s\" -j SNAT --to-source 192.168.0.11", e.comment),
+	}
+	got := e.findOtherPods(rules, "192.168.0.10", "10.0.0.6")
+	if len(got) != 1 || got[0][3] != "10.0.0.5/32" {
+		t.Errorf("findOtherPods() = %v, want the rule of 10.0.0.5", got)
+	}
+	if got := e.findOtherPods(rules, "192.168.0.11", "10.0.0.7"); len(got) != 0 {
+		t.Errorf("findOtherPods() of the only pod with the VIP = %v, want none", got)
+	}
+}
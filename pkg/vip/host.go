@@ -0,0 +1,34 @@
+package vip
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Host is the state that the VIPs of an instance of kube-vip share on the host, the subscriptions to the link and
+// route events, the blackholes of the withdrawn routes and the budget of the announcements. A program that embeds
+// more than one manager gives each its own.
+type Host struct {
+	carriers   *carrierMonitor
+	routes     *routeMonitor
+	blackholes *blackholes
+
+	// announcements is shared by all addresses, so that the network isn't flooded when many services flap at once
+	announcements *rate.Limiter
+}
+
+// NewHost returns the state of the VIPs of an instance of kube-vip. The budget of gratuitous ARPs and unsolicited
+// neighbor advertisements is per second across all addresses (zero is unlimited), and a blackhole route is
+// installed for the grace period once the route of a VIP has been withdrawn (zero disables them).
+func NewHost(announcementRateLimit int, blackholeGracePeriod time.Duration) *Host {
+	h := &Host{
+		carriers:   newCarrierMonitor(),
+		routes:     newRouteMonitor(),
+		blackholes: newBlackholes(blackholeGracePeriod),
+	}
+	if announcementRateLimit > 0 {
+		h.announcements = rate.NewLimiter(rate.Limit(announcementRateLimit), announcementRateLimit)
+	}
+	return h
+}
@@ -0,0 +1,173 @@
+package vip
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	// InterfaceDummy is a managed dummy interface, used when the VIPs are advertised by BGP or the routing table
+	InterfaceDummy = "dummy"
+	// InterfaceMacvlan is a managed macvlan interface on top of the physical interface, used with ARP
+	InterfaceMacvlan = "macvlan"
+
+	// DefaultManagedInterface is the name of the managed interface if one isn't specified
+	DefaultManagedInterface = "kube-vip0"
+
+	// maxInterfaceName is the longest name that the kernel allows for an interface (IFNAMSIZ - 1)
+	maxInterfaceName = 15
+)
+
+// EnsureManagedInterface will create (if it doesn't already exist) and bring up an interface that
+// the VIPs are added to, this keeps the VIPs separate from the addresses of the parent interface
+func EnsureManagedInterface(linkType, name, parent string) error {
+	if name == "" {
+		return fmt.Errorf("the name of the managed %s interface is empty", linkType)
+	}
+
+	var link netlink.Link
+	attrs := netlink.NewLinkAttrs()
+	attrs.Name = name
+
+	switch linkType {
+	case InterfaceDummy:
+		link = &netlink.Dummy{LinkAttrs: attrs}
+	case InterfaceMacvlan:
+		parentLink, err := netlink.LinkByName(parent)
+		if err != nil {
+			return fmt.Errorf("could not find parent interface [%s] for macvlan [%s]: %w", parent, name, err)
+		}
+		attrs.ParentIndex = parentLink.Attrs().Index
+		link = &netlink.Macvlan{LinkAttrs: attrs, Mode: netlink.MACVLAN_MODE_BRIDGE}
+	default:
+		return fmt.Errorf("unknown managed interface type [%s], should be %s or %s", linkType, InterfaceDummy, InterfaceMacvlan)
+	}
+
+	existing, err := netlink.LinkByName(name)
+	if err == nil {
+		// Re-use the interface from a previous run, as long as it is the same type
+		if existing.Type() != link.Type() {
+			return fmt.Errorf("interface [%s] already exists with type [%s], expected [%s]", name, existing.Type(), link.Type())
+		}
+		log.Infof("Using existing %s interface [%s] for VIPs", linkType, name)
+		link = existing
+	} else {
+		log.Infof("Creating %s interface [%s] for VIPs", linkType, name)
+		if err = netlink.LinkAdd(link); err != nil {
+			return fmt.Errorf("could not add %s interface [%s]: %w", linkType, name, err)
+		}
+	}
+
+	if err = netlink.LinkSetUp(link); err != nil {
+		return fmt.Errorf("could not bring up interface [%s]: %w", name, err)
+	}
+	return nil
+}
+
+// EnsureVLANInterface will create (if it doesn't already exist) and bring up the VLAN sub-interface
+// <parent>.<id>, it returns the name of the sub-interface and whether it was created (rather than already existing)
+func EnsureVLANInterface(parent string, id int) (string, bool, error) {
+	if id < 1 || id > 4094 {
+		return "", false, fmt.Errorf("invalid VLAN id [%d], should be between 1 and 4094", id)
+	}
+	name := fmt.Sprintf("%s.%d", parent, id)
+	if len(name) > maxInterfaceName {
+		return "", false, fmt.Errorf("VLAN interface name [%s] is longer than %d characters", name, maxInterfaceName)
+	}
+
+	created := false
+	link, err := netlink.LinkByName(name)
+	if err == nil {
+		// Re-use the sub-interface if it already exists, e.g. it is shared with another service
+		vlan, ok := link.(*netlink.Vlan)
+		if !ok || vlan.VlanId != id {
+			return "", false, fmt.Errorf("interface [%s] already exists and isn't a VLAN with id [%d]", name, id)
+		}
+	} else {
+		parentLink, err := netlink.LinkByName(parent)
+		if err != nil {
+			return "", false, fmt.Errorf("could not find parent interface [%s] for VLAN [%d]: %w", parent, id, err)
+		}
+		attrs := netlink.NewLinkAttrs()
+		attrs.Name = name
+		attrs.ParentIndex = parentLink.Attrs().Index
+		link = &netlink.Vlan{LinkAttrs: attrs, VlanId: id}
+
+		log.Infof("Creating VLAN interface [%s] for VIPs", name)
+		if err = netlink.LinkAdd(link); err != nil {
+			return "", false, fmt.Errorf("could not add VLAN interface [%s]: %w", name, err)
+		}
+		created = true
+	}
+
+	if err = netlink.LinkSetUp(link); err != nil {
+		if created {
+			_ = netlink.LinkDel(link)
+		}
+		return "", false, fmt.Errorf("could not bring up interface [%s]: %w", name, err)
+	}
+	return name, created, nil
+}
+
+// VRFTable returns the routing table of the VRF, the routes of the VIPs are installed in it
+func VRFTable(name string) (int, error) {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return 0, fmt.Errorf("could not find VRF [%s]: %w", name, err)
+	}
+	vrf, ok := link.(*netlink.Vrf)
+	if !ok {
+		return 0, fmt.Errorf("interface [%s] is a [%s], not a VRF", name, link.Type())
+	}
+	return int(vrf.Table), nil
+}
+
+// InVRF returns true if the interface is enslaved to the VRF, so that the addresses on it are in the VRF
+func InVRF(iface, name string) (bool, error) {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return false, fmt.Errorf("could not find interface [%s]: %w", iface, err)
+	}
+	vrf, err := netlink.LinkByName(name)
+	if err != nil {
+		return false, fmt.Errorf("could not find VRF [%s]: %w", name, err)
+	}
+	return link.Attrs().MasterIndex == vrf.Attrs().Index, nil
+}
+
+// EnsureVRFMember enslaves an interface that is managed by kube-vip to the VRF, this isn't done for any
+// other interface as it would move all of its addresses (and the connectivity of the node) into the VRF
+func EnsureVRFMember(iface, name string) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("could not find interface [%s]: %w", iface, err)
+	}
+	vrf, err := netlink.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("could not find VRF [%s]: %w", name, err)
+	}
+	if link.Attrs().MasterIndex == vrf.Attrs().Index {
+		return nil
+	}
+	log.Infof("Adding interface [%s] to VRF [%s]", iface, name)
+	if err = netlink.LinkSetMasterByIndex(link, vrf.Attrs().Index); err != nil {
+		return fmt.Errorf("could not add interface [%s] to VRF [%s]: %w", iface, name, err)
+	}
+	return nil
+}
+
+// DeleteManagedInterface removes the managed interface and with it any VIPs that are still on it
+func DeleteManagedInterface(name string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		// Nothing to clean up
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return err
+	}
+	log.Infof("Deleting interface [%s]", name)
+	return netlink.LinkDel(link)
+}
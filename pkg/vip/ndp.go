@@ -1,24 +1,31 @@
 package vip
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"net/netip"
 
 	"github.com/mdlayher/ndp"
+	"golang.org/x/net/ipv6"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// MaxNeighborAdvertisements is the number of unsolicited Neighbor Advertisements that are sent
+// when an address is first announced (MAX_NEIGHBOR_ADVERTISEMENT from RFC 4861)
+const MaxNeighborAdvertisements = 3
+
 // NdpResponder defines the parameters for the NDP connection.
 type NdpResponder struct {
+	host         *Host
 	intf         string
 	hardwareAddr net.HardwareAddr
 	conn         *ndp.Conn
 }
 
 // NewNDPResponder takes an ifaceName and returns a new NDP responder and error if encountered.
-func NewNDPResponder(ifaceName string) (*NdpResponder, error) {
+func (h *Host) NewNDPResponder(ifaceName string) (*NdpResponder, error) {
 	iface, err := net.InterfaceByName(ifaceName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get interface %q: %v", ifaceName, err)
@@ -31,6 +38,7 @@ func NewNDPResponder(ifaceName string) (*NdpResponder, error) {
 	}
 
 	ret := &NdpResponder{
+		host:         h,
 		intf:         iface.Name,
 		hardwareAddr: iface.HardwareAddr,
 		conn:         conn,
@@ -50,14 +58,70 @@ func (n *NdpResponder) SendGratuitous(address string) error {
 		return fmt.Errorf("failed to parse address %s", ip)
 	}
 
+	if !n.host.allowAnnouncement(address, n.intf, announcementNDP) {
+		return nil
+	}
+
 	log.Infof("Broadcasting NDP update for %s (%s) via %s", address, n.hardwareAddr, n.intf)
-	return n.advertise(netip.IPv6LinkLocalAllNodes(), ip, true)
+	return n.advertise(netip.IPv6LinkLocalAllNodes(), ip, false)
+}
+
+// Respond answers the Neighbor Solicitations for an address until the responder is closed, this
+// allows neighbors to resolve the address as soon as it has moved to this node
+func (n *NdpResponder) Respond(address string) error {
+	ip, err := netip.ParseAddr(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse address %s", address)
+	}
+
+	// Solicitations are sent to the solicited-node multicast group of the address
+	group, err := ndp.SolicitedNodeMulticast(ip)
+	if err != nil {
+		return err
+	}
+	if err = n.conn.JoinGroup(group); err != nil {
+		return fmt.Errorf("failed to join group %s on %s: %v", group, n.intf, err)
+	}
+
+	var filter ipv6.ICMPFilter
+	filter.SetAll(true)
+	filter.Accept(ipv6.ICMPTypeNeighborSolicitation)
+	if err = n.conn.SetICMPFilter(&filter); err != nil {
+		return fmt.Errorf("failed to set ICMP filter on %s: %v", n.intf, err)
+	}
+
+	for {
+		m, _, src, err := n.conn.ReadFrom()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		ns, ok := m.(*ndp.NeighborSolicitation)
+		if !ok || ns.TargetAddress != ip {
+			continue
+		}
+
+		// Duplicate address detection is sent from the unspecified address, and is answered to all nodes
+		if src.IsUnspecified() {
+			log.Debugf("Answering duplicate address detection for %s via %s", address, n.intf)
+			err = n.advertise(netip.IPv6LinkLocalAllNodes(), ip, false)
+		} else {
+			log.Debugf("Answering neighbor solicitation for %s from %s via %s", address, src, n.intf)
+			err = n.advertise(src, ip, true)
+		}
+		if err != nil {
+			log.Warnf("failed to answer neighbor solicitation for %s: %v", address, err)
+		}
+	}
 }
 
-func (n *NdpResponder) advertise(dst, target netip.Addr, gratuitous bool) error {
+func (n *NdpResponder) advertise(dst, target netip.Addr, solicited bool) error {
 	m := &ndp.NeighborAdvertisement{
-		Solicited:     !gratuitous,
-		Override:      gratuitous, // Should clients replace existing cache entries
+		Solicited:     solicited,
+		Override:      true, // Should clients replace existing cache entries
 		TargetAddress: target,
 		Options: []ndp.Option{
 			&ndp.LinkLayerAddress{
@@ -66,6 +130,6 @@ func (n *NdpResponder) advertise(dst, target netip.Addr, gratuitous bool) error
 			},
 		},
 	}
-	log.Infof("ndp: %v", m)
+	log.Debugf("ndp: %v", m)
 	return n.conn.WriteTo(m, nil, dst)
 }
@@ -0,0 +1,95 @@
+package vip
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+var routesRestored = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "kube_vip",
+	Subsystem: "vip",
+	Name:      "routes_restored",
+	Help:      "Count the routes of an address that were removed by something other than kube-vip and have been re-installed",
+}, []string{"address", "table"})
+
+// routeMonitor shares a single subscription to the netlink route events between all of the networks that
+// have installed a route, so that a route that is removed out-of-band (e.g. by a network restart or another
+// daemon) is re-installed
+type routeMonitor struct {
+	once     sync.Once
+	mutex    sync.Mutex
+	networks map[*network]bool
+}
+
+func newRouteMonitor() *routeMonitor {
+	return &routeMonitor{networks: make(map[*network]bool)}
+}
+
+// RouteCollectors returns the metrics of the routes that have been restored
+func RouteCollectors() []prometheus.Collector {
+	return []prometheus.Collector{routesRestored}
+}
+
+func (m *routeMonitor) start() {
+	updates := make(chan netlink.RouteUpdate)
+	if err := netlink.RouteSubscribe(updates, nil); err != nil {
+		log.Errorf("unable to subscribe to route events, removed routes won't be restored: %v", err)
+		return
+	}
+	go func() {
+		for update := range updates {
+			if update.Type != unix.RTM_DELROUTE || update.Dst == nil {
+				continue
+			}
+			for _, n := range m.owners(&update.Route) {
+				route := n.PrepareRoute()
+				log.Warnf("[route] route for [%s] in table [%d] has been removed, restoring it", route.Dst, route.Table)
+				if err := netlink.RouteReplace(route); err != nil {
+					// The interface may have gone, in which case the route is restored when it returns
+					log.Warnf("[route] unable to restore route for [%s]: %v", route.Dst, err)
+					continue
+				}
+				routesRestored.WithLabelValues(route.Dst.IP.String(), strconv.Itoa(route.Table)).Inc()
+			}
+		}
+	}()
+}
+
+// owners returns the networks that should still have the route installed
+func (m *routeMonitor) owners(removed *netlink.Route) []*network {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var owners []*network
+	for n := range m.networks {
+		n.mu.Lock()
+		owned := n.routed && n.address != nil && n.address.IPNet.String() == removed.Dst.String() &&
+			n.routeTable == removed.Table && n.routingProtocol == int(removed.Protocol)
+		n.mu.Unlock()
+		if owned {
+			owners = append(owners, n)
+		}
+	}
+	return owners
+}
+
+// add watches for the removal of the route of the network
+func (m *routeMonitor) add(n *network) {
+	m.once.Do(m.start)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.networks[n] = true
+}
+
+// remove stops watching the route of the network, as kube-vip is removing it
+func (m *routeMonitor) remove(n *network) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.networks, n)
+}
@@ -179,6 +179,17 @@ func GenerateMac() (mac string) {
 	return mac
 }
 
+// GetInterfaces returns the interfaces from a comma separated list, a VIP is announced on each of them
+func GetInterfaces(iface string) []string {
+	interfaces := []string{}
+	for _, i := range strings.Split(iface, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			interfaces = append(interfaces, i)
+		}
+	}
+	return interfaces
+}
+
 func GetIPs(vip string) []string {
 	addresses := []string{}
 	vips := strings.Split(vip, ",")
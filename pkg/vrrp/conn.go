@@ -0,0 +1,206 @@
+package vrrp
+
+import (
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// conn sends and receives the advertisements of a virtual router
+type conn interface {
+	// WriteAdvertisement multicasts an advertisement to the VRRP group
+	WriteAdvertisement(a *Advertisement) error
+	// ReadAdvertisement blocks until a valid advertisement is received and returns it with its sender
+	ReadAdvertisement() (*Advertisement, net.IP, error)
+	// LocalAddress is the primary address of the interface, that advertisements are sent from
+	LocalAddress() net.IP
+	Close() error
+}
+
+type ipv4Conn struct {
+	iface *net.Interface
+	src   net.IP
+	pc    *ipv4.PacketConn
+}
+
+type ipv6Conn struct {
+	iface *net.Interface
+	src   net.IP
+	pc    *ipv6.PacketConn
+}
+
+// newConn joins the VRRP multicast group on the interface, for either IPv4 or IPv6
+func newConn(iface *net.Interface, isIPv6 bool) (conn, error) {
+	src, err := primaryAddress(iface, isIPv6)
+	if err != nil {
+		return nil, err
+	}
+
+	if isIPv6 {
+		c, err := net.ListenPacket(fmt.Sprintf("ip6:%d", ProtocolNumber), "::")
+		if err != nil {
+			return nil, fmt.Errorf("unable to listen for VRRP advertisements: %w", err)
+		}
+		pc := ipv6.NewPacketConn(c)
+		if err = setupIPv6(pc, iface); err != nil {
+			pc.Close()
+			return nil, err
+		}
+		return &ipv6Conn{iface: iface, src: src, pc: pc}, nil
+	}
+
+	c, err := net.ListenPacket(fmt.Sprintf("ip4:%d", ProtocolNumber), "0.0.0.0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for VRRP advertisements: %w", err)
+	}
+	pc := ipv4.NewPacketConn(c)
+	if err = setupIPv4(pc, iface); err != nil {
+		pc.Close()
+		return nil, err
+	}
+	return &ipv4Conn{iface: iface, src: src, pc: pc}, nil
+}
+
+func setupIPv4(pc *ipv4.PacketConn, iface *net.Interface) error {
+	if err := pc.JoinGroup(iface, &net.IPAddr{IP: GroupIPv4}); err != nil {
+		return fmt.Errorf("unable to join VRRP group [%s] on [%s]: %w", GroupIPv4, iface.Name, err)
+	}
+	if err := pc.SetMulticastInterface(iface); err != nil {
+		return err
+	}
+	if err := pc.SetMulticastTTL(TTL); err != nil {
+		return err
+	}
+	if err := pc.SetMulticastLoopback(false); err != nil {
+		return err
+	}
+	return pc.SetControlMessage(ipv4.FlagTTL|ipv4.FlagDst|ipv4.FlagInterface, true)
+}
+
+func setupIPv6(pc *ipv6.PacketConn, iface *net.Interface) error {
+	if err := pc.JoinGroup(iface, &net.IPAddr{IP: GroupIPv6}); err != nil {
+		return fmt.Errorf("unable to join VRRP group [%s] on [%s]: %w", GroupIPv6, iface.Name, err)
+	}
+	if err := pc.SetMulticastInterface(iface); err != nil {
+		return err
+	}
+	if err := pc.SetMulticastHopLimit(TTL); err != nil {
+		return err
+	}
+	if err := pc.SetMulticastLoopback(false); err != nil {
+		return err
+	}
+	return pc.SetControlMessage(ipv6.FlagHopLimit|ipv6.FlagDst|ipv6.FlagInterface, true)
+}
+
+// primaryAddress finds the address advertisements are sent from, the first IPv4 address or the
+// IPv6 link-local address of the interface
+func primaryAddress(iface *net.Interface, isIPv6 bool) (net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if isIPv6 && ipNet.IP.To4() == nil && ipNet.IP.IsLinkLocalUnicast() {
+			return ipNet.IP, nil
+		}
+		if !isIPv6 && ipNet.IP.To4() != nil {
+			return ipNet.IP.To4(), nil
+		}
+	}
+	if isIPv6 {
+		return nil, fmt.Errorf("no IPv6 link-local address found on [%s]", iface.Name)
+	}
+	return nil, fmt.Errorf("no IPv4 address found on [%s]", iface.Name)
+}
+
+func (c *ipv4Conn) WriteAdvertisement(a *Advertisement) error {
+	b, err := a.Marshal(c.src, GroupIPv4)
+	if err != nil {
+		return err
+	}
+	_, err = c.pc.WriteTo(b, &ipv4.ControlMessage{IfIndex: c.iface.Index, Src: c.src}, &net.IPAddr{IP: GroupIPv4})
+	return err
+}
+
+func (c *ipv4Conn) ReadAdvertisement() (*Advertisement, net.IP, error) {
+	b := make([]byte, 1500)
+	for {
+		n, cm, src, err := c.pc.ReadFrom(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cm == nil || cm.IfIndex != c.iface.Index {
+			continue
+		}
+		ip := src.(*net.IPAddr).IP
+		// Routers must discard advertisements that may have been forwarded
+		if cm.TTL != TTL {
+			log.Debugf("[VRRP] discarding advertisement from [%s] with TTL [%d]", ip, cm.TTL)
+			continue
+		}
+		a, err := ParseAdvertisement(b[:n], ip, cm.Dst)
+		if err != nil {
+			log.Debugf("[VRRP] %v", err)
+			continue
+		}
+		return a, ip, nil
+	}
+}
+
+func (c *ipv4Conn) LocalAddress() net.IP {
+	return c.src
+}
+
+func (c *ipv4Conn) Close() error {
+	return c.pc.Close()
+}
+
+func (c *ipv6Conn) WriteAdvertisement(a *Advertisement) error {
+	b, err := a.Marshal(c.src, GroupIPv6)
+	if err != nil {
+		return err
+	}
+	_, err = c.pc.WriteTo(b, &ipv6.ControlMessage{IfIndex: c.iface.Index, Src: c.src}, &net.IPAddr{IP: GroupIPv6, Zone: c.iface.Name})
+	return err
+}
+
+func (c *ipv6Conn) ReadAdvertisement() (*Advertisement, net.IP, error) {
+	b := make([]byte, 1500)
+	for {
+		n, cm, src, err := c.pc.ReadFrom(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cm == nil || cm.IfIndex != c.iface.Index {
+			continue
+		}
+		ip := src.(*net.IPAddr).IP
+		// Routers must discard advertisements that may have been forwarded
+		if cm.HopLimit != TTL {
+			log.Debugf("[VRRP] discarding advertisement from [%s] with hop limit [%d]", ip, cm.HopLimit)
+			continue
+		}
+		a, err := ParseAdvertisement(b[:n], ip, cm.Dst)
+		if err != nil {
+			log.Debugf("[VRRP] %v", err)
+			continue
+		}
+		return a, ip, nil
+	}
+}
+
+func (c *ipv6Conn) LocalAddress() net.IP {
+	return c.src
+}
+
+func (c *ipv6Conn) Close() error {
+	return c.pc.Close()
+}
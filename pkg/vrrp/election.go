@@ -0,0 +1,306 @@
+package vrrp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// OwnerPriority is the priority of the router that owns the addresses, it becomes master immediately
+const OwnerPriority = 255
+
+// LeaderElectionConfig allows to configure the VRRP election.
+type LeaderElectionConfig struct {
+	// Interface is the interface the advertisements are sent and received on
+	Interface string
+
+	// VirtualRouterID identifies the VRRP group, it must match the other routers (e.g. keepalived)
+	VirtualRouterID uint8
+
+	// Priority of this router, the router with the highest priority becomes the master
+	Priority uint8
+
+	// AdvertisementInterval is the time between advertisements, in centisecond precision
+	AdvertisementInterval time.Duration
+
+	// Preempt allows a router with a higher priority to take over from the current master
+	Preempt bool
+
+	// Addresses are the virtual addresses that are included in the advertisements
+	Addresses []net.IP
+
+	// Callbacks are callbacks that are triggered during certain lifecycle
+	// events of the election
+	Callbacks LeaderCallbacks
+}
+
+// LeaderCallbacks are callbacks that are triggered during certain
+// lifecycle events of the election.
+type LeaderCallbacks struct {
+	// OnStartedLeading is called when this router becomes the master.
+	OnStartedLeading func(context.Context)
+	// OnStoppedLeading is called when this router stops being the master.
+	OnStoppedLeading func()
+	// OnNewLeader is called when the router observes a master that is
+	// not the previously observed master, with the address of the master.
+	OnNewLeader func(identity string)
+}
+
+type state int
+
+const (
+	stateBackup state = iota
+	stateMaster
+)
+
+type received struct {
+	advertisement *Advertisement
+	src           net.IP
+}
+
+type router struct {
+	config *LeaderElectionConfig
+	conn   conn
+
+	state state
+	// interval is our advertisement interval in centiseconds
+	interval uint16
+	// masterInterval is the advertisement interval of the current master in centiseconds
+	masterInterval uint16
+	master         string
+	timer          *time.Timer
+
+	cancelLeading context.CancelFunc
+}
+
+// RunElectionOrDie behaves the same way as RunElection but panics if there is an error.
+func RunElectionOrDie(ctx context.Context, config *LeaderElectionConfig) {
+	if err := RunElection(ctx, config); err != nil {
+		panic(err)
+	}
+}
+
+// RunElection joins the VRRP group on the interface and runs the VRRPv3 state machine.
+// RunElection blocks until ctx is cancelled or this router stops being the master.
+func RunElection(ctx context.Context, config *LeaderElectionConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	iface, err := net.InterfaceByName(config.Interface)
+	if err != nil {
+		return fmt.Errorf("unable to find interface [%s] for VRRP: %w", config.Interface, err)
+	}
+
+	c, err := newConn(iface, config.Addresses[0].To4() == nil)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	log.Infof("[VRRP] joined virtual router [%d] on [%s] with priority [%d]", config.VirtualRouterID, config.Interface, config.Priority)
+	return newRouter(config, c).run(ctx)
+}
+
+func (config *LeaderElectionConfig) validate() error {
+	if config.VirtualRouterID == 0 {
+		return fmt.Errorf("the VRRP virtual router ID must be between 1 and 255")
+	}
+	if config.Priority == 0 {
+		return fmt.Errorf("the VRRP priority must be between 1 and 255")
+	}
+	if len(config.Addresses) == 0 {
+		return fmt.Errorf("no addresses to advertise with VRRP")
+	}
+	interval := config.AdvertisementInterval / (10 * time.Millisecond)
+	if interval < 1 || interval > maxAdvertisementInterval {
+		return fmt.Errorf("the VRRP advertisement interval [%s] must be between 10ms and 40.95s", config.AdvertisementInterval)
+	}
+	return nil
+}
+
+func newRouter(config *LeaderElectionConfig, c conn) *router {
+	interval := uint16(config.AdvertisementInterval / (10 * time.Millisecond))
+	return &router{
+		config:         config,
+		conn:           c,
+		interval:       interval,
+		masterInterval: interval,
+	}
+}
+
+func (r *router) run(ctx context.Context) error {
+	adverts := make(chan received)
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			a, src, err := r.conn.ReadAdvertisement()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case adverts <- received{advertisement: a, src: src}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Initialize, unless we own the addresses we wait for the current master
+	r.timer = time.NewTimer(r.masterDownInterval())
+	defer r.timer.Stop()
+	if r.config.Priority == OwnerPriority {
+		r.becomeMaster(ctx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if r.state == stateMaster {
+				// Advertise a priority of 0, so that a backup takes over without waiting for the master down timer
+				r.advertise(0)
+				r.stopLeading()
+			}
+			return nil
+		case err := <-errs:
+			if r.state == stateMaster {
+				r.stopLeading()
+			}
+			return fmt.Errorf("unable to read VRRP advertisements: %w", err)
+		case <-r.timer.C:
+			if r.state == stateBackup {
+				// The master down timer has expired
+				r.becomeMaster(ctx)
+				continue
+			}
+			r.advertise(r.config.Priority)
+			r.timer.Reset(centiseconds(r.interval))
+		case rcv := <-adverts:
+			if rcv.advertisement.VirtualRouterID != r.config.VirtualRouterID {
+				continue
+			}
+			if r.handleAdvertisement(rcv.advertisement, rcv.src) {
+				// We've stopped being the master
+				return nil
+			}
+		}
+	}
+}
+
+// handleAdvertisement processes an advertisement for our virtual router, it returns true if this
+// router has stopped being the master
+func (r *router) handleAdvertisement(a *Advertisement, src net.IP) bool {
+	switch r.state {
+	case stateBackup:
+		if a.Priority == 0 {
+			// The master is shutting down, take over after the skew time
+			resetTimer(r.timer, centiseconds(r.skew()))
+			return false
+		}
+		if !r.config.Preempt || a.Priority >= r.config.Priority {
+			r.masterInterval = a.MaxAdvertisementInterval
+			resetTimer(r.timer, r.masterDownInterval())
+			r.observe(src.String())
+		}
+		// Otherwise the master down timer will expire and we will preempt the master
+	case stateMaster:
+		if a.Priority == 0 {
+			r.advertise(r.config.Priority)
+			resetTimer(r.timer, centiseconds(r.interval))
+			return false
+		}
+		if a.Priority > r.config.Priority || (a.Priority == r.config.Priority && bytes.Compare(normalize(src), normalize(r.conn.LocalAddress())) > 0) {
+			log.Infof("[VRRP] router [%s] with priority [%d] is taking over virtual router [%d]", src, a.Priority, r.config.VirtualRouterID)
+			r.state = stateBackup
+			r.masterInterval = a.MaxAdvertisementInterval
+			resetTimer(r.timer, r.masterDownInterval())
+			r.observe(src.String())
+			r.stopLeading()
+			return true
+		}
+	}
+	return false
+}
+
+func (r *router) becomeMaster(ctx context.Context) {
+	log.Infof("[VRRP] becoming master of virtual router [%d]", r.config.VirtualRouterID)
+	r.state = stateMaster
+	r.advertise(r.config.Priority)
+	resetTimer(r.timer, centiseconds(r.interval))
+	r.observe(r.conn.LocalAddress().String())
+
+	var leadingCtx context.Context
+	leadingCtx, r.cancelLeading = context.WithCancel(ctx)
+	if r.config.Callbacks.OnStartedLeading != nil {
+		go r.config.Callbacks.OnStartedLeading(leadingCtx)
+	}
+}
+
+func (r *router) stopLeading() {
+	if r.cancelLeading != nil {
+		r.cancelLeading()
+	}
+	if r.config.Callbacks.OnStoppedLeading != nil {
+		r.config.Callbacks.OnStoppedLeading()
+	}
+}
+
+func (r *router) observe(master string) {
+	if master == r.master {
+		return
+	}
+	r.master = master
+	if r.config.Callbacks.OnNewLeader != nil {
+		r.config.Callbacks.OnNewLeader(master)
+	}
+}
+
+func (r *router) advertise(priority uint8) {
+	err := r.conn.WriteAdvertisement(&Advertisement{
+		VirtualRouterID:          r.config.VirtualRouterID,
+		Priority:                 priority,
+		MaxAdvertisementInterval: r.interval,
+		Addresses:                r.config.Addresses,
+	})
+	if err != nil {
+		log.Warnf("[VRRP] unable to send advertisement: %v", err)
+	}
+}
+
+// skew is the time in centiseconds that lower priority backups wait longer before taking over
+func (r *router) skew() uint16 {
+	return uint16((256 - uint32(r.config.Priority)) * uint32(r.masterInterval) / 256)
+}
+
+func (r *router) masterDownInterval() time.Duration {
+	return centiseconds(3*r.masterInterval + r.skew())
+}
+
+func centiseconds(cs uint16) time.Duration {
+	return time.Duration(cs) * 10 * time.Millisecond
+}
+
+func normalize(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip.To16()
+}
+
+// resetTimer stops and drains the timer before resetting it
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
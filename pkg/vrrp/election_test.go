@@ -0,0 +1,163 @@
+package vrrp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bus is an in-memory multicast group that connects the routers of a test
+type bus struct {
+	mutex sync.Mutex
+	conns []*busConn
+}
+
+type busConn struct {
+	bus     *bus
+	src     net.IP
+	inbox   chan received
+	closed  chan struct{}
+	closing sync.Once
+}
+
+func (b *bus) join(src string) *busConn {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	c := &busConn{bus: b, src: net.ParseIP(src).To4(), inbox: make(chan received, 100), closed: make(chan struct{})}
+	b.conns = append(b.conns, c)
+	return c
+}
+
+func (c *busConn) WriteAdvertisement(a *Advertisement) error {
+	c.bus.mutex.Lock()
+	defer c.bus.mutex.Unlock()
+	for _, peer := range c.bus.conns {
+		if peer != c {
+			peer.inbox <- received{advertisement: a, src: c.src}
+		}
+	}
+	return nil
+}
+
+func (c *busConn) ReadAdvertisement() (*Advertisement, net.IP, error) {
+	select {
+	case rcv := <-c.inbox:
+		return rcv.advertisement, rcv.src, nil
+	case <-c.closed:
+		return nil, nil, errors.New("closed")
+	}
+}
+
+func (c *busConn) LocalAddress() net.IP {
+	return c.src
+}
+
+func (c *busConn) Close() error {
+	c.closing.Do(func() { close(c.closed) })
+	return nil
+}
+
+type testRouter struct {
+	leading chan bool
+	done    chan error
+	cancel  context.CancelFunc
+}
+
+func startRouter(b *bus, src string, priority uint8, preempt bool) *testRouter {
+	tr := &testRouter{leading: make(chan bool, 10), done: make(chan error, 1)}
+	config := &LeaderElectionConfig{
+		VirtualRouterID:       51,
+		Priority:              priority,
+		AdvertisementInterval: 20 * time.Millisecond,
+		Preempt:               preempt,
+		Addresses:             []net.IP{net.ParseIP("192.168.0.100")},
+		Callbacks: LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { tr.leading <- true },
+			OnStoppedLeading: func() { tr.leading <- false },
+		},
+	}
+	var ctx context.Context
+	ctx, tr.cancel = context.WithCancel(context.Background())
+	c := b.join(src)
+	go func() {
+		defer c.Close()
+		tr.done <- newRouter(config, c).run(ctx)
+	}()
+	return tr
+}
+
+func (tr *testRouter) expect(t *testing.T, leading bool) {
+	t.Helper()
+	select {
+	case got := <-tr.leading:
+		if got != leading {
+			t.Fatalf("leading = %t, want %t", got, leading)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for leading = %t", leading)
+	}
+}
+
+func (tr *testRouter) expectNothing(t *testing.T) {
+	t.Helper()
+	select {
+	case got := <-tr.leading:
+		t.Fatalf("unexpected leadership change to %t", got)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestElectionHighestPriorityWins(t *testing.T) {
+	b := &bus{}
+	low := startRouter(b, "10.0.0.1", 100, true)
+	defer low.cancel()
+	high := startRouter(b, "10.0.0.2", 200, true)
+	defer high.cancel()
+
+	high.expect(t, true)
+	low.expectNothing(t)
+}
+
+func TestElectionPreempt(t *testing.T) {
+	b := &bus{}
+	low := startRouter(b, "10.0.0.1", 100, true)
+	defer low.cancel()
+	low.expect(t, true)
+
+	// A higher priority router takes over when it joins, and the election of the old master ends
+	high := startRouter(b, "10.0.0.2", 200, true)
+	defer high.cancel()
+	high.expect(t, true)
+	low.expect(t, false)
+	if err := <-low.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestElectionNoPreempt(t *testing.T) {
+	b := &bus{}
+	low := startRouter(b, "10.0.0.1", 100, false)
+	defer low.cancel()
+	low.expect(t, true)
+
+	high := startRouter(b, "10.0.0.2", 200, false)
+	defer high.cancel()
+	high.expectNothing(t)
+}
+
+func TestElectionMasterShutdown(t *testing.T) {
+	b := &bus{}
+	master := startRouter(b, "10.0.0.1", 200, true)
+	master.expect(t, true)
+	backup := startRouter(b, "10.0.0.2", 100, true)
+	defer backup.cancel()
+	backup.expectNothing(t)
+
+	// The master advertises a priority of 0 as it stops, so the backup takes over
+	master.cancel()
+	master.expect(t, false)
+	backup.expect(t, true)
+}
@@ -0,0 +1,141 @@
+package vrrp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	// Version is the VRRP version that is implemented (RFC 5798)
+	Version = 3
+	// ProtocolNumber is the IP protocol number of VRRP
+	ProtocolNumber = 112
+	// TTL is the TTL (or hop limit) that advertisements must be sent and received with
+	TTL = 255
+
+	advertisementType = 1
+	headerLength      = 8
+	// maxAdvertisementInterval is the largest interval (in centiseconds) that fits the 12 bit field
+	maxAdvertisementInterval = 0x0fff
+)
+
+var (
+	// GroupIPv4 is the multicast group that IPv4 advertisements are sent to
+	GroupIPv4 = net.IPv4(224, 0, 0, 18)
+	// GroupIPv6 is the multicast group that IPv6 advertisements are sent to
+	GroupIPv6 = net.ParseIP("ff02::12")
+)
+
+// Advertisement is a VRRPv3 advertisement
+type Advertisement struct {
+	VirtualRouterID uint8
+	Priority        uint8
+	// MaxAdvertisementInterval is the time between advertisements in centiseconds
+	MaxAdvertisementInterval uint16
+	Addresses                []net.IP
+}
+
+// Marshal encodes the advertisement, the source and destination addresses are needed for the checksum
+func (a *Advertisement) Marshal(src, dst net.IP) ([]byte, error) {
+	if a.MaxAdvertisementInterval > maxAdvertisementInterval {
+		return nil, fmt.Errorf("advertisement interval [%d] is larger than the maximum [%d]", a.MaxAdvertisementInterval, maxAdvertisementInterval)
+	}
+	if len(a.Addresses) > 255 {
+		return nil, fmt.Errorf("too many addresses [%d] in advertisement", len(a.Addresses))
+	}
+	ipv4 := src.To4() != nil
+	size := net.IPv6len
+	if ipv4 {
+		size = net.IPv4len
+	}
+
+	b := make([]byte, headerLength, headerLength+len(a.Addresses)*size)
+	b[0] = Version<<4 | advertisementType
+	b[1] = a.VirtualRouterID
+	b[2] = a.Priority
+	b[3] = uint8(len(a.Addresses))
+	binary.BigEndian.PutUint16(b[4:], a.MaxAdvertisementInterval)
+	for _, address := range a.Addresses {
+		if ipv4 {
+			if address.To4() == nil {
+				return nil, fmt.Errorf("address [%s] isn't IPv4", address)
+			}
+			b = append(b, address.To4()...)
+		} else {
+			if address.To4() != nil {
+				return nil, fmt.Errorf("address [%s] isn't IPv6", address)
+			}
+			b = append(b, address.To16()...)
+		}
+	}
+	binary.BigEndian.PutUint16(b[6:], checksum(b, src, dst))
+	return b, nil
+}
+
+// ParseAdvertisement decodes and validates an advertisement received from src for dst
+func ParseAdvertisement(b []byte, src, dst net.IP) (*Advertisement, error) {
+	if len(b) < headerLength {
+		return nil, fmt.Errorf("advertisement is too short [%d bytes]", len(b))
+	}
+	if version := b[0] >> 4; version != Version {
+		return nil, fmt.Errorf("unsupported VRRP version [%d]", version)
+	}
+	if t := b[0] & 0x0f; t != advertisementType {
+		return nil, fmt.Errorf("unknown VRRP packet type [%d]", t)
+	}
+	if checksum(b, src, dst) != 0 {
+		return nil, fmt.Errorf("invalid checksum from [%s]", src)
+	}
+
+	size := net.IPv6len
+	if src.To4() != nil {
+		size = net.IPv4len
+	}
+	count := int(b[3])
+	if len(b) < headerLength+count*size {
+		return nil, fmt.Errorf("advertisement from [%s] is truncated, expected %d addresses", src, count)
+	}
+
+	a := &Advertisement{
+		VirtualRouterID:          b[1],
+		Priority:                 b[2],
+		MaxAdvertisementInterval: binary.BigEndian.Uint16(b[4:]) & maxAdvertisementInterval,
+	}
+	for i := 0; i < count; i++ {
+		offset := headerLength + i*size
+		a.Addresses = append(a.Addresses, net.IP(append([]byte{}, b[offset:offset+size]...)))
+	}
+	return a, nil
+}
+
+// checksum calculates the internet checksum of the VRRP message and the IPv4 or IPv6 pseudo-header,
+// which is zero when the message already contains a valid checksum
+func checksum(b []byte, src, dst net.IP) uint16 {
+	var pseudo []byte
+	if src.To4() != nil {
+		pseudo = append(pseudo, src.To4()...)
+		pseudo = append(pseudo, dst.To4()...)
+		pseudo = append(pseudo, 0, ProtocolNumber)
+		pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(len(b)))
+	} else {
+		pseudo = append(pseudo, src.To16()...)
+		pseudo = append(pseudo, dst.To16()...)
+		pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(len(b)))
+		pseudo = append(pseudo, 0, 0, 0, ProtocolNumber)
+	}
+
+	var sum uint32
+	for _, data := range [][]byte{pseudo, b} {
+		for i := 0; i+1 < len(data); i += 2 {
+			sum += uint32(data[i])<<8 | uint32(data[i+1])
+		}
+		if len(data)%2 == 1 {
+			sum += uint32(data[len(data)-1]) << 8
+		}
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,86 @@
+package vrrp
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestAdvertisementRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       net.IP
+		dst       net.IP
+		addresses []net.IP
+	}{
+		{
+			name:      "ipv4",
+			src:       net.ParseIP("192.168.0.10").To4(),
+			dst:       GroupIPv4,
+			addresses: []net.IP{net.ParseIP("192.168.0.100").To4(), net.ParseIP("192.168.0.101").To4()},
+		},
+		{
+			name:      "ipv6",
+			src:       net.ParseIP("fe80::1"),
+			dst:       GroupIPv6,
+			addresses: []net.IP{net.ParseIP("fd00::100")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Advertisement{VirtualRouterID: 51, Priority: 150, MaxAdvertisementInterval: 100, Addresses: tt.addresses}
+			b, err := a.Marshal(tt.src, tt.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseAdvertisement(b, tt.src, tt.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, a) {
+				t.Errorf("ParseAdvertisement() = %+v, want %+v", got, a)
+			}
+
+			// A different source changes the pseudo-header, so the checksum is no longer valid
+			if _, err := ParseAdvertisement(b, tt.addresses[0], tt.dst); err == nil {
+				t.Errorf("ParseAdvertisement() with the wrong source should fail")
+			}
+		})
+	}
+}
+
+func TestParseAdvertisementInvalid(t *testing.T) {
+	src := net.ParseIP("10.0.0.1").To4()
+	a := &Advertisement{VirtualRouterID: 1, Priority: 100, MaxAdvertisementInterval: 100, Addresses: []net.IP{net.ParseIP("10.0.0.100").To4()}}
+	valid, err := a.Marshal(src, GroupIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version2 := append([]byte{}, valid...)
+	version2[0] = 2<<4 | advertisementType
+
+	// Claim more addresses than the advertisement contains, with a valid checksum
+	truncated := append([]byte{}, valid...)
+	truncated[3] = 4
+	truncated[6], truncated[7] = 0, 0
+	binary.BigEndian.PutUint16(truncated[6:], checksum(truncated, src, GroupIPv4))
+
+	for name, b := range map[string][]byte{
+		"short":     valid[:4],
+		"version 2": version2,
+		"truncated": truncated,
+	} {
+		if _, err := ParseAdvertisement(b, src, GroupIPv4); err == nil {
+			t.Errorf("ParseAdvertisement() of a %s advertisement should fail", name)
+		}
+	}
+}
+
+func TestMarshalMixedFamilies(t *testing.T) {
+	a := &Advertisement{VirtualRouterID: 1, Priority: 100, MaxAdvertisementInterval: 100, Addresses: []net.IP{net.ParseIP("fd00::1")}}
+	if _, err := a.Marshal(net.ParseIP("10.0.0.1"), GroupIPv4); err == nil {
+		t.Errorf("Marshal() of an IPv6 address from an IPv4 source should fail")
+	}
+}